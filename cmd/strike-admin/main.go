@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/JohnnyGlynn/strike/internal/config"
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+	adminpb "github.com/JohnnyGlynn/strike/msgdef/admin"
+)
+
+const usage = `usage: strike-admin [flags] <command> [args]
+
+Commands:
+  users list [--filter s] [--limit n] [--offset n]
+  users delete <username> [--yes]
+  users disable <username>
+  users enable <username>
+  users reset-keys <username> --encryption-key <path> --signing-key <path>
+  pending list [--limit n]
+  pending replay <message-id> | --all
+  dead-letters list [--limit n]
+  dead-letters replay <message-id> | --all
+  dead-letters purge <message-id> | --all [--yes]
+  peers
+  peers invite [--addr host:port] [--ttl duration]
+  peers join <token> [--addr host:port]
+
+Flags:
+`
+
+// callTimeout bounds each admin RPC.
+const callTimeout = 10 * time.Second
+
+type command func(ctx context.Context, c adminpb.AdminClient, args []string) error
+
+var commands = map[string]command{
+	"users list":          listUsers,
+	"users delete":        deleteUser,
+	"users disable":       setDisabled(true),
+	"users enable":        setDisabled(false),
+	"users reset-keys":    resetKeys,
+	"pending list":        listPending,
+	"pending replay":      replayPending,
+	"dead-letters list":   listDeadLetters,
+	"dead-letters replay": replayDeadLetters,
+	"dead-letters purge":  purgeDeadLetters,
+	"peers":               peers,
+	"peers invite":        invitePeer,
+	"peers join":          joinPeer,
+}
+
+func main() {
+	// Every AdminConfig field is also a flag: --server, --server-certificate-path, --token.
+	loader := config.NewLoader[config.AdminConfig](flag.CommandLine)
+	configFilePath := flag.String("config", "", "Path to configuration file (.json, .yaml or .toml)")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	name, cmd, args, err := lookup(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, _, err := loader.Load(*configFilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid admin config:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	conn, err := dial(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+
+	if err := cmd(ctx, adminpb.NewAdminClient(conn), args); err != nil {
+		if st, ok := status.FromError(err); ok {
+			err = fmt.Errorf("%s (%s)", st.Message(), st.Code())
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// lookup matches the longest command prefix of args, suggesting the closest
+// command when nothing matches.
+func lookup(args []string) (string, command, []string, error) {
+	if len(args) == 0 {
+		return "", nil, nil, fmt.Errorf("missing command")
+	}
+	for n := min(2, len(args)); n > 0; n-- {
+		name := strings.Join(args[:n], " ")
+		if cmd, ok := commands[name]; ok {
+			return name, cmd, args[n:], nil
+		}
+	}
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	input := strings.Join(args[:min(2, len(args))], " ")
+	if s := shared.Suggest(input, names); s != "" {
+		return "", nil, nil, fmt.Errorf("unknown command %q, did you mean %q?", input, s)
+	}
+	return "", nil, nil, fmt.Errorf("unknown command %q", input)
+}
+
+func dial(cfg config.AdminConfig) (*grpc.ClientConn, error) {
+	creds, err := credentials.NewClientTLSFromFile(cfg.ServerCertificatePath, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %v", err)
+	}
+
+	conn, err := grpc.NewClient(cfg.ServerHost,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithPerRPCCredentials(tokenAuth(cfg.Token)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %v", cfg.ServerHost, err)
+	}
+	return conn, nil
+}
+
+// tokenAuth sends the admin token with every call. It is only ever sent over
+// TLS.
+type tokenAuth string
+
+func (t tokenAuth) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t tokenAuth) RequireTransportSecurity() bool { return true }
+
+func listUsers(ctx context.Context, c adminpb.AdminClient, args []string) error {
+	fs := flag.NewFlagSet("users list", flag.ContinueOnError)
+	filter := fs.String("filter", "", "Only list usernames containing this")
+	limit := fs.Int("limit", 0, "Maximum users to list (server default 100)")
+	offset := fs.Int("offset", 0, "Skip this many users")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	resp, err := c.ListUsers(ctx, &adminpb.ListUsersReq{Filter: *filter, Limit: int32(*limit), Offset: int32(*offset)})
+	if err != nil {
+		return err
+	}
+
+	w := table("USERNAME", "USER ID", "STATE", "ONLINE", "CREATED")
+	for _, u := range resp.Users {
+		state := "active"
+		if u.Disabled {
+			state = "disabled"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", u.Username, u.UserId, state, u.Online, timestamp(u.CreatedAt))
+	}
+	return w.Flush()
+}
+
+func deleteUser(ctx context.Context, c adminpb.AdminClient, args []string) error {
+	fs := flag.NewFlagSet("users delete", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "Do not ask for confirmation")
+	username, err := parseTarget(fs, args, "username")
+	if err != nil {
+		return err
+	}
+
+	if !*yes && !confirm(fmt.Sprintf("Delete user %s and their keys? This cannot be undone.", username)) {
+		return fmt.Errorf("cancelled")
+	}
+
+	ack, err := c.DeleteUser(ctx, &adminpb.UserRef{Username: username})
+	return printAck(ack, err)
+}
+
+func setDisabled(disabled bool) command {
+	return func(ctx context.Context, c adminpb.AdminClient, args []string) error {
+		username, err := parseTarget(flag.NewFlagSet("users", flag.ContinueOnError), args, "username")
+		if err != nil {
+			return err
+		}
+		ack, err := c.SetUserDisabled(ctx, &adminpb.SetUserDisabledReq{Username: username, Disabled: disabled})
+		return printAck(ack, err)
+	}
+}
+
+func resetKeys(ctx context.Context, c adminpb.AdminClient, args []string) error {
+	fs := flag.NewFlagSet("users reset-keys", flag.ContinueOnError)
+	encPath := fs.String("encryption-key", "", "Path to the user's new Curve25519 public key")
+	sigPath := fs.String("signing-key", "", "Path to the user's new ED25519 public key")
+	username, err := parseTarget(fs, args, "username")
+	if err != nil {
+		return err
+	}
+	if *encPath == "" || *sigPath == "" {
+		return fmt.Errorf("--encryption-key and --signing-key are required")
+	}
+
+	loaded, err := keys.LoadAndValidateKeys(map[string]keys.KeyDefinition{
+		"EncryptionPublicKey": {Path: *encPath, Type: keys.EncryptionKey},
+		"SigningPublicKey":    {Path: *sigPath, Type: keys.SigningKey},
+	})
+	if err != nil {
+		return err
+	}
+
+	ack, err := c.ResetKeys(ctx, &adminpb.ResetKeysReq{
+		Username:            username,
+		EncryptionPublicKey: loaded["EncryptionPublicKey"],
+		SigningPublicKey:    loaded["SigningPublicKey"],
+	})
+	return printAck(ack, err)
+}
+
+func listPending(ctx context.Context, c adminpb.AdminClient, args []string) error {
+	limit, err := parseLimit("pending list", args)
+	if err != nil {
+		return err
+	}
+	resp, err := c.ListPending(ctx, &adminpb.ListQueueReq{Limit: limit})
+	if err != nil {
+		return err
+	}
+
+	w := table("MESSAGE ID", "FROM", "TO", "TARGET DOMAIN", "ATTEMPTS LEFT", "SIZE", "QUEUED")
+	for _, e := range resp.Entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n", e.MessageId, e.Sender, e.Recipient, e.TargetDomain, e.Attempts, e.Size, timestamp(e.CreatedAt))
+	}
+	return w.Flush()
+}
+
+func replayPending(ctx context.Context, c adminpb.AdminClient, args []string) error {
+	ref, err := parseQueueRef(flag.NewFlagSet("pending replay", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	ack, err := c.ReplayPending(ctx, ref)
+	return printAck(ack, err)
+}
+
+func listDeadLetters(ctx context.Context, c adminpb.AdminClient, args []string) error {
+	limit, err := parseLimit("dead-letters list", args)
+	if err != nil {
+		return err
+	}
+	resp, err := c.ListDeadLetters(ctx, &adminpb.ListQueueReq{Limit: limit})
+	if err != nil {
+		return err
+	}
+
+	w := table("MESSAGE ID", "FROM", "TO", "TARGET DOMAIN", "SIZE", "FAILED", "REASON")
+	for _, e := range resp.Entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", e.MessageId, e.Sender, e.Recipient, e.TargetDomain, e.Size, timestamp(e.FailedAt), e.Reason)
+	}
+	return w.Flush()
+}
+
+func replayDeadLetters(ctx context.Context, c adminpb.AdminClient, args []string) error {
+	ref, err := parseQueueRef(flag.NewFlagSet("dead-letters replay", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	ack, err := c.ReplayDeadLetters(ctx, ref)
+	return printAck(ack, err)
+}
+
+func purgeDeadLetters(ctx context.Context, c adminpb.AdminClient, args []string) error {
+	fs := flag.NewFlagSet("dead-letters purge", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "Do not ask for confirmation")
+	ref, err := parseQueueRef(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if ref.All && !*yes && !confirm("Purge every dead letter? This cannot be undone.") {
+		return fmt.Errorf("cancelled")
+	}
+
+	ack, err := c.PurgeDeadLetters(ctx, ref)
+	return printAck(ack, err)
+}
+
+func peers(ctx context.Context, c adminpb.AdminClient, _ []string) error {
+	resp, err := c.PeerStatus(ctx, &adminpb.PeerStatusReq{})
+	if err != nil {
+		return err
+	}
+
+	w := table("NAME", "ADDRESS", "ONLINE", "HANDSHAKEN", "LAST SEEN", "ID")
+	for _, p := range resp.Peers {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%t\t%s\t%s\n", p.Name, p.Address, p.Online, p.Handshaken, timestamp(p.LastSeen), p.Id)
+	}
+	return w.Flush()
+}
+
+func invitePeer(ctx context.Context, c adminpb.AdminClient, args []string) error {
+	fs := flag.NewFlagSet("peers invite", flag.ContinueOnError)
+	addr := fs.String("addr", "", "Federation address to advertise (default: the server's federation_address)")
+	ttl := fs.Duration("ttl", 0, "How long the invitation is valid (server default 24h)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	resp, err := c.CreateInvite(ctx, &adminpb.CreateInviteReq{Address: *addr, TtlSeconds: int64(ttl.Seconds())})
+	if err != nil {
+		return err
+	}
+	fmt.Println(resp.Token)
+	fmt.Fprintf(os.Stderr, "Valid until %s. Redeem it on the other server with: strike-admin peers join <token>\n", timestamp(resp.ExpiresAt))
+	return nil
+}
+
+func joinPeer(ctx context.Context, c adminpb.AdminClient, args []string) error {
+	fs := flag.NewFlagSet("peers join", flag.ContinueOnError)
+	addr := fs.String("addr", "", "Federation address to advertise (default: the server's federation_address)")
+	token, err := parseTarget(fs, args, "invitation token")
+	if err != nil {
+		return err
+	}
+
+	ack, err := c.JoinFederation(ctx, &adminpb.JoinFederationReq{Token: token, Address: *addr})
+	return printAck(ack, err)
+}
+
+// parseTarget parses fs and returns its single positional argument. Flags
+// may come before or after it.
+func parseTarget(fs *flag.FlagSet, args []string, what string) (string, error) {
+	target, err := positional(fs, args)
+	if err != nil {
+		return "", err
+	}
+	if target == "" {
+		return "", fmt.Errorf("missing %s", what)
+	}
+	return target, nil
+}
+
+func parseQueueRef(fs *flag.FlagSet, args []string) (*adminpb.QueueRef, error) {
+	all := fs.Bool("all", false, "Apply to every entry")
+	id, err := positional(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case *all && id != "":
+		return nil, fmt.Errorf("give a message id or --all, not both")
+	case !*all && id == "":
+		return nil, fmt.Errorf("missing message id (or --all)")
+	}
+	return &adminpb.QueueRef{MessageId: id, All: *all}, nil
+}
+
+func positional(fs *flag.FlagSet, args []string) (string, error) {
+	var arg string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		arg, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	switch {
+	case fs.NArg() == 0:
+	case fs.NArg() == 1 && arg == "":
+		arg = fs.Arg(0)
+	default:
+		return "", fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return arg, nil
+}
+
+func parseLimit(name string, args []string) (int32, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "Maximum entries to list (server default 100)")
+	if err := fs.Parse(args); err != nil {
+		return 0, err
+	}
+	return int32(*limit), nil
+}
+
+func table(headers ...string) *tabwriter.Writer {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	return w
+}
+
+func timestamp(ts *timestamppb.Timestamp) string {
+	if ts == nil {
+		return "-"
+	}
+	return ts.AsTime().Local().Format(time.DateTime)
+}
+
+func printAck(ack *adminpb.AdminAck, err error) error {
+	if err != nil {
+		return err
+	}
+	fmt.Println(ack.Message)
+	return nil
+}
+
+func confirm(prompt string) bool {
+	fmt.Printf("%s [y/N] ", prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -18,6 +18,7 @@ CREATE TABLE IF NOT EXISTS addressbook (
     enc_pkey BLOB NOT NULL,
     sig_pkey BLOB NOT NULL,
     keyex INTEGER DEFAULT 0, --key exchange state
+    verified INTEGER NOT NULL DEFAULT 0, --safety number confirmed out of band
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -27,7 +28,9 @@ CREATE TABLE IF NOT EXISTS friendrequests (
     domain TEXT NOT NULL DEFAULT '',
     enc_pkey BLOB,
     sig_pkey BLOB,
-    direction TEXT NOT NULL
+    direction TEXT NOT NULL,
+    created_at INTEGER NOT NULL DEFAULT 0, --unix ms, for expiry
+    UNIQUE (friendId, direction)
 );
 
 CREATE TABLE IF NOT EXISTS messages (
@@ -39,3 +42,17 @@ CREATE TABLE IF NOT EXISTS messages (
     -- FOREIGN KEY (sender) REFERENCES addressbook(user_id)
 );
 
+CREATE TABLE IF NOT EXISTS blocklist (
+    kind TEXT NOT NULL, -- domain or user
+    value TEXT NOT NULL, -- domain name or user ID
+    label TEXT NOT NULL DEFAULT '', -- user@domain shown for blocked users
+    PRIMARY KEY (kind, value)
+);
+
+-- Filled only with search_index set. tokens are keyed hashes of the words
+-- in each message, so the index holds no plaintext.
+CREATE VIRTUAL TABLE IF NOT EXISTS message_index USING fts5(
+    tokens,
+    message_id UNINDEXED,
+    key_id UNINDEXED -- which index key hashed the tokens
+);
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/JohnnyGlynn/strike/internal/client"
+	"github.com/JohnnyGlynn/strike/internal/client/tui"
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/config"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+)
+
+const usage = `usage: strike-client [flags] [command] [args]
+
+Without a command the interactive shell starts. Commands log in as
+username with password or password_file and print results on stdout,
+as JSON with --json.
+
+Commands:
+  friends list
+  friends add <user@domain>
+  friends remove <user@domain> [--purge]
+  requests list
+  requests accept <user@domain>
+  requests decline <user@domain>
+  send --to <user@domain> --message <text|->
+  daemon
+  tui
+
+Flags:
+`
+
+type command func(ctx context.Context, c *types.Client, out *output, args []string) error
+
+var commands = map[string]command{
+	"friends list":     listFriends,
+	"friends add":      addFriend,
+	"friends remove":   removeFriend,
+	"requests list":    listRequests,
+	"requests accept":  answerRequest(true),
+	"requests decline": answerRequest(false),
+	"send":             send,
+	"daemon":           daemon,
+	"tui":              runTUI,
+}
+
+// lookup matches the longest command prefix of args, suggesting the closest
+// command when nothing matches.
+func lookup(args []string) (string, command, []string, error) {
+	for n := min(2, len(args)); n > 0; n-- {
+		name := strings.Join(args[:n], " ")
+		if cmd, ok := commands[name]; ok {
+			return name, cmd, args[n:], nil
+		}
+	}
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	input := strings.Join(args[:min(2, len(args))], " ")
+	if s := shared.Suggest(input, names); s != "" {
+		return "", nil, nil, fmt.Errorf("unknown command %q, did you mean %q?", input, s)
+	}
+	return "", nil, nil, fmt.Errorf("unknown command %q", input)
+}
+
+// output writes a command's result as text or, with --json, as one JSON
+// value.
+type output struct {
+	w    io.Writer
+	json bool
+}
+
+func (o *output) print(v any, text func(w io.Writer) error) error {
+	if o.json {
+		return json.NewEncoder(o.w).Encode(v)
+	}
+	return text(o.w)
+}
+
+func (o *output) fail(name string, err error) {
+	if o.json {
+		_ = json.NewEncoder(o.w).Encode(map[string]string{"command": name, "error": err.Error()})
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+}
+
+// runCommand logs in with the configured credentials and runs cmd.
+func runCommand(ctx context.Context, c *types.Client, cfg config.ClientConfig, out *output, cmd command, args []string) error {
+	password, err := scriptPassword(cfg)
+	if err != nil {
+		return err
+	}
+	c.Identity.Username = cfg.Username
+	if err := client.Login(c, password); err != nil {
+		return err
+	}
+	if err := client.SyncDomain(c); err != nil {
+		return err
+	}
+	return cmd(ctx, c, out, args)
+}
+
+func scriptPassword(cfg config.ClientConfig) (string, error) {
+	if cfg.Username == "" {
+		return "", fmt.Errorf("commands need username (--username or STRIKE_USERNAME)")
+	}
+	if cfg.PasswordFile != "" {
+		p, err := client.ReadSecretFile(cfg.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("password_file: %w", err)
+		}
+		return string(p), nil
+	}
+	if cfg.Password == "" {
+		return "", fmt.Errorf("commands need password_file or password (STRIKE_PASSWORD_FILE or STRIKE_PASSWORD)")
+	}
+	return cfg.Password, nil
+}
+
+type friendJSON struct {
+	Username      string `json:"username"`
+	Domain        string `json:"domain"`
+	UserID        string `json:"user_id"`
+	Verified      bool   `json:"verified"`
+	KeysExchanged bool   `json:"keys_exchanged"`
+}
+
+type requestJSON struct {
+	Username string `json:"username"`
+	Domain   string `json:"domain"`
+	UserID   string `json:"user_id"`
+}
+
+func listFriends(ctx context.Context, c *types.Client, out *output, args []string) error {
+	if err := noArgs(flag.NewFlagSet("friends list", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	friends, err := client.Friends(c)
+	if err != nil {
+		return err
+	}
+
+	list := make([]friendJSON, 0, len(friends))
+	for _, f := range friends {
+		list = append(list, friendJSON{f.Name, f.Domain, f.Id.String(), f.Verified, f.KeyEx != 0})
+	}
+	return out.print(list, func(w io.Writer) error {
+		t := table(w, "ADDRESS", "USER ID", "VERIFIED", "KEYS EXCHANGED")
+		for _, f := range list {
+			fmt.Fprintf(t, "%s\t%s\t%t\t%t\n", shared.FormatAddress(f.Username, f.Domain), f.UserID, f.Verified, f.KeysExchanged)
+		}
+		return t.Flush()
+	})
+}
+
+func addFriend(ctx context.Context, c *types.Client, out *output, args []string) error {
+	addr, err := parseAddress(flag.NewFlagSet("friends add", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+	u, err := client.AddFriend(ctx, c, addr)
+	if err != nil {
+		return err
+	}
+
+	r := requestJSON{u.Username, u.Domain, u.UserId}
+	return out.print(r, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Friend request sent to %s\n", shared.FormatAddress(r.Username, r.Domain))
+		return err
+	})
+}
+
+func removeFriend(ctx context.Context, c *types.Client, out *output, args []string) error {
+	fs := flag.NewFlagSet("friends remove", flag.ContinueOnError)
+	purge := fs.Bool("purge", false, "Also delete the message history")
+	addr, err := parseAddress(fs, args)
+	if err != nil {
+		return err
+	}
+	u, err := client.Unfriend(ctx, c, addr, *purge)
+	if err != nil {
+		return err
+	}
+
+	r := struct {
+		friendJSON
+		Purged bool `json:"purged"`
+	}{friendJSON{Username: u.Name, Domain: u.Domain, UserID: u.Id.String()}, *purge}
+	return out.print(r, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "%s removed from your friends\n", shared.FormatAddress(u.Name, u.Domain))
+		return err
+	})
+}
+
+func listRequests(ctx context.Context, c *types.Client, out *output, args []string) error {
+	if err := noArgs(flag.NewFlagSet("requests list", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	frs, err := client.FriendRequests(c)
+	if err != nil {
+		return err
+	}
+
+	list := make([]requestJSON, 0, len(frs))
+	for _, fr := range frs {
+		list = append(list, requestJSON{fr.Username, fr.Domain, fr.FriendId.String()})
+	}
+	return out.print(list, func(w io.Writer) error {
+		t := table(w, "ADDRESS", "USER ID")
+		for _, r := range list {
+			fmt.Fprintf(t, "%s\t%s\n", shared.FormatAddress(r.Username, r.Domain), r.UserID)
+		}
+		return t.Flush()
+	})
+}
+
+func answerRequest(accept bool) command {
+	return func(ctx context.Context, c *types.Client, out *output, args []string) error {
+		addr, err := parseAddress(flag.NewFlagSet("requests", flag.ContinueOnError), args)
+		if err != nil {
+			return err
+		}
+		fr, err := client.AnswerFriendRequest(ctx, c, addr, accept)
+		if err != nil {
+			return err
+		}
+
+		r := struct {
+			requestJSON
+			Accepted bool `json:"accepted"`
+		}{requestJSON{fr.Username, fr.Domain, fr.FriendId.String()}, accept}
+		return out.print(r, func(w io.Writer) error {
+			verb := "Declined"
+			if accept {
+				verb = "Accepted"
+			}
+			_, err := fmt.Fprintf(w, "%s friend request from %s\n", verb, shared.FormatAddress(fr.Username, fr.Domain))
+			return err
+		})
+	}
+}
+
+func send(ctx context.Context, c *types.Client, out *output, args []string) error {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	to := fs.String("to", "", "Friend to message (user@domain)")
+	message := fs.String("message", "", "Message text, or - to read it from stdin")
+	if err := noArgs(fs, args); err != nil {
+		return err
+	}
+	if *to == "" || *message == "" {
+		return fmt.Errorf("send needs --to and --message")
+	}
+	addr, err := shared.ParseAddress(*to)
+	if err != nil {
+		return err
+	}
+
+	text := *message
+	if text == "-" {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		text = string(b)
+	}
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n" // as the shell sends it
+	}
+
+	u, key, err := client.FriendChat(ctx, c, addr)
+	if err != nil {
+		return err
+	}
+	if err := client.SendTo(ctx, c, u, key, text); err != nil {
+		return err
+	}
+
+	r := struct {
+		To   string `json:"to"`
+		Sent bool   `json:"sent"`
+	}{shared.FormatAddress(u.Name, u.Domain), true}
+	return out.print(r, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Sent to %s\n", r.To)
+		return err
+	})
+}
+
+func daemon(ctx context.Context, c *types.Client, out *output, args []string) error {
+	if err := noArgs(flag.NewFlagSet("daemon", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	socket := c.Identity.Config.DaemonSocket
+	if socket == "" {
+		return fmt.Errorf("daemon needs daemon_socket (--daemon-socket or DAEMON_SOCKET)")
+	}
+	fmt.Fprintf(os.Stderr, "Strike daemon for %s serving on %s\n", shared.FormatAddress(c.Identity.Username, c.Identity.Domain), socket)
+	return client.ServeDaemon(ctx, c, socket)
+}
+
+// tuiLogs takes the log output while the tui command runs, which shows it
+// on the status line.
+var tuiLogs = tui.NewLogs()
+
+func runTUI(ctx context.Context, c *types.Client, out *output, args []string) error {
+	if err := noArgs(flag.NewFlagSet("tui", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	return tui.Run(ctx, c, tuiLogs)
+}
+
+func noArgs(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return nil
+}
+
+// parseAddress takes one user@domain argument, before or after the flags.
+func parseAddress(fs *flag.FlagSet, args []string) (shared.StrikeAddress, error) {
+	var arg string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		arg, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return shared.StrikeAddress{}, err
+	}
+	switch {
+	case fs.NArg() == 0:
+	case fs.NArg() == 1 && arg == "":
+		arg = fs.Arg(0)
+	default:
+		return shared.StrikeAddress{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if arg == "" {
+		return shared.StrikeAddress{}, fmt.Errorf("missing user@domain")
+	}
+	return shared.ParseAddress(arg)
+}
+
+func table(w io.Writer, headers ...string) *tabwriter.Writer {
+	t := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(t, strings.Join(headers, "\t"))
+	return t
+}
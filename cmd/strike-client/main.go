@@ -7,14 +7,20 @@ import (
 	"embed"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/JohnnyGlynn/strike/internal/client"
 	"github.com/JohnnyGlynn/strike/internal/client/types"
 	"github.com/JohnnyGlynn/strike/internal/config"
 	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/logging"
+	"github.com/JohnnyGlynn/strike/internal/tracing"
 	"github.com/google/uuid"
 
 	pb "github.com/JohnnyGlynn/strike/msgdef/message"
@@ -30,7 +36,13 @@ var schemaFS embed.FS
 // var cDB *sql.DB
 
 func main() {
-	fmt.Println("Strike client")
+	// Set by a failed command; deferred so the database is closed first.
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
 
 	schema, err := schemaFS.ReadFile("client.sql")
 	if err != nil {
@@ -53,50 +65,114 @@ func main() {
 
 	// cDB = idb
 
-	configFilePath := flag.String("config", "", "Path to configuration JSON file")
-	serverFlag := flag.String("server", "", "Override server host (e.g. localhost:8080)")
+	// Every ClientConfig field is also a flag, e.g. --server, --server-certificate-path.
+	loader := config.NewLoader[config.ClientConfig](flag.CommandLine)
+
+	configFilePath := flag.String("config", "", "Path to configuration file (.json, .yaml or .toml)")
+	printConfig := flag.Bool("print-config", false, "Print the effective configuration and where each value came from, then exit")
 	keygen := flag.Bool("keygen", false, "Launch Strike Key generation, creating keypair for user not bringing existing PKI")
 	keydir := flag.String("keydir", ".", "Output directory for generated keys")
+	changePassphrase := flag.Bool("change-passphrase", false, "Change the passphrase protecting the private key files, then exit")
+	agent := flag.Bool("agent", false, "Unlock the keys once and serve them on key_agent_socket until stopped or key_agent_ttl passes")
+	agentLock := flag.Bool("agent-lock", false, "Tell the key agent to wipe its keys and exit")
+	exportBackup := flag.String("export-backup", "", "Write a passphrase encrypted backup of keys, identity, friends and messages to this file, then exit")
+	importBackup := flag.String("import-backup", "", "Restore keys and the client database from a backup file, then exit")
+	jsonOut := flag.Bool("json", false, "Print command results as JSON")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
-	clientCfg, loadedKeys, err := setupClientConfigAndKeys(*configFilePath, *keygen, *keydir)
+	var (
+		name string
+		cmd  command
+		args []string
+		out  = &output{w: os.Stdout, json: *jsonOut}
+
+		progress io.Writer = os.Stdout
+	)
+	if flag.NArg() > 0 {
+		name, cmd, args, err = lookup(flag.Args())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			flag.Usage()
+			exitCode = 2
+			return
+		}
+		// Prompts and progress lines go to stderr so stdout carries only
+		// the result.
+		progress = os.Stderr
+		// Setup failures below only print and return.
+		exitCode = 1
+	} else {
+		fmt.Println("Strike client")
+	}
+
+	clientCfg, loadedKeys, passphrase, err := setupClientConfigAndKeys(loader, *configFilePath, *printConfig, *keygen, *keydir, idb, keyModes{
+		changePassphrase: *changePassphrase,
+		agent:            *agent,
+		agentLock:        *agentLock,
+		exportBackup:     *exportBackup,
+		importBackup:     *importBackup,
+	}, progress)
 	if err != nil {
-		fmt.Printf("error setting up config/keys: %v\n", err)
+		fmt.Fprintf(progress, "error setting up config/keys: %v\n", err)
+		return
+	}
+
+	logOut := io.Writer(os.Stderr)
+	if name == "tui" {
+		logOut = tuiLogs
+	}
+	if _, err := logging.Setup(logOut, clientCfg.LogLevel, clientCfg.LogFormat); err != nil {
+		fmt.Fprintf(progress, "error setting up logging: %v\n", err)
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), clientCfg.Tracing, "strike-client", os.Stderr)
+	if err != nil {
+		fmt.Fprintf(progress, "error setting up tracing: %v\n", err)
+		return
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Warn("flushing traces failed", "error", err)
+		}
+	}()
+
+	if err := client.EnsureSchema(context.TODO(), idb); err != nil {
+		fmt.Fprintf(progress, "Error updating db schema: %v\n", err)
 		return
 	}
 
 	statements, err := client.PrepareStatements(context.TODO(), idb)
 	if err != nil {
-		fmt.Printf("Failed to prepare statements: %v\n", err)
+		fmt.Fprintf(progress, "Failed to prepare statements: %v\n", err)
 		return
 	}
 
 	defer func() {
 		if psErr := client.CloseStatements(statements); psErr != nil {
-			fmt.Printf("error preparing statements: %v\n", psErr)
+			fmt.Fprintf(progress, "error preparing statements: %v\n", psErr)
 			return
 		}
 	}()
 
-	if *serverFlag != "" {
-		clientCfg.ServerHost = *serverFlag
-		log.Printf("Server override: %s\n", clientCfg.ServerHost)
-	}
-
 	conn, err := grpcSetup(clientCfg)
 	if err != nil {
-		fmt.Printf("error establishing grpc conncetion: %v\n", err)
+		fmt.Fprintf(progress, "error establishing grpc conncetion: %v\n", err)
 		return
 	}
 
 	defer func() {
 		if connectionError := conn.Close(); connectionError != nil {
-			fmt.Printf("Failed to connect to Strike Server: %v\n", connectionError)
+			fmt.Fprintf(progress, "Failed to connect to Strike Server: %v\n", connectionError)
 			return
 		}
 	}()
 
-	client := pb.NewStrikeClient(conn)
+	strikeClient := pb.NewStrikeClient(conn)
 
 	clientInfo := &types.Client{
 		Identity: &types.ClientIdentity{
@@ -104,6 +180,8 @@ func main() {
 			ID:       uuid.Nil,
 			Keys:     loadedKeys,
 			Config:   &clientCfg,
+
+			KeyPassphrase: passphrase,
 		},
 		State: &types.ClientState{
 			Cache: types.Cache{
@@ -111,12 +189,28 @@ func main() {
 			},
 			Shell: &types.ShellState{},
 		},
-		PBC: client,
+		PBC: strikeClient,
 		DB:  statements,
 	}
+	clientInfo.State.Output = progress
+
+	if cmd != nil {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := runCommand(ctx, clientInfo, clientCfg, out, cmd, args); err != nil {
+			out.fail(name, err)
+			return
+		}
+		exitCode = 0
+		return
+	}
+
+	retainCtx, stopRetention := context.WithCancel(context.Background())
+	defer stopRetention()
+	go client.RetainMessages(retainCtx, clientInfo)
 
 	if err := launchREPL(clientInfo); err != nil {
-		fmt.Printf("repl error: %v\n", err)
+		fmt.Fprintf(progress, "repl error: %v\n", err)
 		return
 	}
 }
@@ -143,56 +237,121 @@ func initDB(path string, schema []byte) (*sql.DB, error) {
 	return dbOpen, nil
 }
 
-func setupClientConfigAndKeys(cfgPath string, keygen bool, keydir string) (config.ClientConfig, map[string][]byte, error) {
-	// Avoid shadowing
-	var clientCfg config.ClientConfig
-	var err error
+// keyModes are the flags that run a key or backup task instead of the
+// client.
+type keyModes struct {
+	changePassphrase bool
+	agent            bool
+	agentLock        bool
+	exportBackup     string
+	importBackup     string
+}
+
+func setupClientConfigAndKeys(loader *config.Loader[config.ClientConfig], cfgPath string, printConfig, keygen bool, keydir string, db *sql.DB, modes keyModes, progress io.Writer) (config.ClientConfig, map[string][]byte, []byte, error) {
+	reader := bufio.NewReader(os.Stdin)
 
 	if keygen {
-		if err := keys.SigningKeygen(keydir); err != nil {
-			return clientCfg, nil, fmt.Errorf("error generating signing keys: %v", err)
+		passphrase, err := client.NewPassphrase(reader)
+		if err != nil {
+			return config.ClientConfig{}, nil, nil, err
+		}
+
+		if err := keys.SigningKeygen(keydir, passphrase); err != nil {
+			return config.ClientConfig{}, nil, nil, fmt.Errorf("error generating signing keys: %v", err)
 		}
 		fmt.Println("Signing keys generated successfully ")
 
-		if err = keys.EncryptionKeygen(keydir); err != nil {
-			return clientCfg, nil, fmt.Errorf("error generating encryption keys: %v", err)
+		if err := keys.EncryptionKeygen(keydir, passphrase); err != nil {
+			return config.ClientConfig{}, nil, nil, fmt.Errorf("error generating encryption keys: %v", err)
 		}
 		fmt.Println("Encryption keys generated successfully")
 
 		os.Exit(0)
 	}
 
-	if cfgPath != "" {
-		clientCfg, err = config.LoadConfigFile[config.ClientConfig](cfgPath)
-		if err != nil {
-			return clientCfg, nil, fmt.Errorf("failed to load client config: %v", err)
+	clientCfg, sources, err := loader.Load(cfgPath)
+	if err != nil {
+		return clientCfg, nil, nil, fmt.Errorf("invalid client config:\n%v", err)
+	}
+
+	if printConfig {
+		if err := config.Print(os.Stdout, clientCfg, sources); err != nil {
+			return clientCfg, nil, nil, fmt.Errorf("error printing config: %v", err)
 		}
+		os.Exit(0)
+	}
 
-		if err := clientCfg.ValidateConfig(); err != nil {
-			return clientCfg, nil, fmt.Errorf("invalid client config: %v", err)
+	switch {
+	case modes.importBackup != "":
+		if err := client.EnsureSchema(context.TODO(), db); err != nil {
+			return clientCfg, nil, nil, fmt.Errorf("error updating db schema: %v", err)
+		}
+		if err := client.ImportBackup(context.TODO(), db, &clientCfg, modes.importBackup, reader); err != nil {
+			return clientCfg, nil, nil, fmt.Errorf("error restoring backup: %v", err)
 		}
+		os.Exit(0)
 
-	} else {
-		clientCfg = *config.LoadClientConfigEnv()
+	case modes.changePassphrase:
+		if err := client.ChangePassphrase(&clientCfg, reader); err != nil {
+			return clientCfg, nil, nil, fmt.Errorf("error changing passphrase: %v", err)
+		}
+		fmt.Println("Key passphrase changed")
+		os.Exit(0)
 
-		if err := clientCfg.ValidateEnv(); err != nil {
-			return clientCfg, nil, fmt.Errorf("invalid client config: %v", err)
+	case modes.agentLock:
+		if clientCfg.KeyAgentSocket == "" {
+			return clientCfg, nil, nil, fmt.Errorf("--agent-lock requires key_agent_socket")
 		}
+		if err := keys.LockAgent(clientCfg.KeyAgentSocket); err != nil {
+			return clientCfg, nil, nil, err
+		}
+		fmt.Println("Key agent locked")
+		os.Exit(0)
+
+	case modes.agent:
+		if clientCfg.KeyAgentSocket == "" {
+			return clientCfg, nil, nil, fmt.Errorf("--agent requires key_agent_socket")
+		}
+		loadedKeys, _, err := client.UnlockKeys(&clientCfg, reader, os.Stdout)
+		if err != nil {
+			return clientCfg, nil, nil, fmt.Errorf("error loading and validating keys: %v", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		fmt.Printf("Key agent serving on %s\n", clientCfg.KeyAgentSocket)
+		if err := keys.ServeAgent(ctx, clientCfg.KeyAgentSocket, loadedKeys, time.Duration(clientCfg.KeyAgentTTL)); err != nil {
+			return clientCfg, nil, nil, err
+		}
+		os.Exit(0)
 	}
 
-	keysMap := map[string]keys.KeyDefinition{
-		"SigningPrivateKey":    {Path: clientCfg.SigningPrivateKeyPath, Type: keys.SigningKey},
-		"SigningPublicKey":     {Path: clientCfg.SigningPublicKeyPath, Type: keys.SigningKey},
-		"EncryptionPrivateKey": {Path: clientCfg.EncryptionPrivateKeyPath, Type: keys.EncryptionKey},
-		"EncryptionPublicKey":  {Path: clientCfg.EncryptionPublicKeyPath, Type: keys.EncryptionKey},
+	var loadedKeys map[string][]byte
+	var passphrase []byte
+	if clientCfg.KeyAgentSocket != "" {
+		loadedKeys, err = keys.FetchFromAgent(clientCfg.KeyAgentSocket)
+		if err != nil {
+			fmt.Fprintf(progress, "Key agent not used: %v\n", err)
+		}
+	}
+	if loadedKeys == nil {
+		loadedKeys, passphrase, err = client.UnlockKeys(&clientCfg, reader, progress)
+		if err != nil {
+			return clientCfg, nil, nil, fmt.Errorf("error loading and validating keys: %v", err)
+		}
 	}
 
-	loadedKeys, err := keys.LoadAndValidateKeys(keysMap)
-	if err != nil {
-		return clientCfg, nil, fmt.Errorf("error loading and validating keys: %v", err)
+	if modes.exportBackup != "" {
+		if err := client.EnsureSchema(context.TODO(), db); err != nil {
+			return clientCfg, nil, nil, fmt.Errorf("error updating db schema: %v", err)
+		}
+		if err := client.ExportBackup(context.TODO(), db, loadedKeys, modes.exportBackup, reader); err != nil {
+			return clientCfg, nil, nil, fmt.Errorf("error writing backup: %v", err)
+		}
+		os.Exit(0)
 	}
 
-	return clientCfg, loadedKeys, nil
+	return clientCfg, loadedKeys, passphrase, nil
 
 }
 
@@ -204,7 +363,12 @@ func grpcSetup(cfg config.ClientConfig) (*grpc.ClientConn, error) {
 	}
 
 	var opts []grpc.DialOption
-	opts = append(opts, grpc.WithTransportCredentials(creds))
+	opts = append(opts,
+		grpc.WithTransportCredentials(creds),
+		tracing.DialOption(),
+		grpc.WithChainUnaryInterceptor(logging.UnaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(logging.StreamClientInterceptor),
+	)
 
 	conn, err := grpc.NewClient(cfg.ServerHost, opts...)
 	if err != nil {
@@ -294,7 +458,7 @@ func launchREPL(c *types.Client) error {
 			fmt.Print("> ")
 			input, err := inputReader.ReadString('\n')
 			if err != nil {
-				log.Printf("Error reading input: %v\n", err)
+				slog.Error("error reading input", "error", err)
 				continue
 			}
 
@@ -342,9 +506,7 @@ func launchREPL(c *types.Client) error {
 				}
 			}()
 
-			if err := client.MShell(c); err != nil {
-				return err
-			}
+			return client.MShell(c)
 		}
 	}
 }
@@ -2,30 +2,31 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/x509"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/JohnnyGlynn/strike/internal/config"
 	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/logging"
 	"github.com/JohnnyGlynn/strike/internal/server"
+	"github.com/JohnnyGlynn/strike/internal/tracing"
 	// fedpb "github.com/JohnnyGlynn/strike/msgdef/federation"
 	// pb "github.com/JohnnyGlynn/strike/msgdef/message"
-
-	"google.golang.org/grpc/credentials"
 )
 
 func main() {
 	fmt.Println("Strike Server")
 
-	// Avoid shadowing
-	var serverCfg config.ServerConfig
-	var err error
-
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -33,20 +34,28 @@ func main() {
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		s := <-sigCh
-		log.Printf("%s: initiating graceful shutdown", s)
+		slog.Info("initiating graceful shutdown", "signal", s.String())
 		cancel()
 	}()
 
-	// TODO: Refactor, replicated from client
-	configFilePath := flag.String("config", "", "Path to configuration JSON file")
+	// Every ServerConfig field is also a flag, e.g. --name, --listen-address.
+	loader := config.NewLoader[config.ServerConfig](flag.CommandLine)
+
+	configFilePath := flag.String("config", "", "Path to configuration file (.json, .yaml or .toml)")
+	printConfig := flag.Bool("print-config", false, "Print the effective configuration and where each value came from, then exit")
 	keygen := flag.Bool("keygen", false, "Launch Strike Server Key generation, creating keypair and certificate")
 	genCA := flag.Bool("gen-ca", false, "Generate a Strike Federation CA keypair and certificate")
 	genFed := flag.Bool("gen-federation", false, "Generate federation.yaml from peer key directories (use with --peer flags and --output)")
 	outputPath := flag.String("output", "./config/server/federation.yaml", "Output path for generated federation config")
 	keydir := flag.String("keydir", ".", "Output directory for generated keys and certificate")
-	serverName := flag.String("name", "", "Server name for identity file (used with --keygen)")
 	caCertPath := flag.String("ca-cert", "", "Path to CA certificate for signing server cert")
 	caKeyPath := flag.String("ca-key", "", "Path to CA private key for signing server cert")
+	sans := flag.String("san", "", "Comma separated DNS names for the server certificate (default localhost and the dev server names)")
+	ips := flag.String("ip", "", "Comma separated IP addresses for the server certificate")
+	validity := flag.Duration("validity", 0, "Server certificate lifetime (default 8760h, or the current certificate's with --renew)")
+	renew := flag.Bool("renew", false, "Reissue the server certificate in --keydir for its existing key, then exit")
+	revoke := flag.String("revoke", "", "Add this certificate to the CA's revocation list (needs --ca-cert, --ca-key), then exit")
+	crlPath := flag.String("crl", "", "Revocation list written by --revoke (default strike_ca.crl next to --ca-cert)")
 	flag.Parse()
 
 	if *genFed {
@@ -87,29 +96,60 @@ func main() {
 		os.Exit(0)
 	}
 
-	if *keygen {
+	if *revoke != "" {
+		if *caCertPath == "" || *caKeyPath == "" {
+			fmt.Println("usage: --revoke cert.crt --ca-cert strike_ca.crt --ca-key strike_ca.pem [--crl strike_ca.crl]")
+			return
+		}
+		caCert, caKey, err := keys.LoadCA(*caCertPath, *caKeyPath)
+		if err != nil {
+			fmt.Printf("error loading CA: %v\n", err)
+			return
+		}
+		if *crlPath == "" {
+			*crlPath = filepath.Join(filepath.Dir(*caCertPath), "strike_ca.crl")
+		}
+		if err := keys.RevokeCert(*crlPath, *revoke, caCert, caKey); err != nil {
+			fmt.Printf("error revoking certificate: %v\n", err)
+			return
+		}
+		os.Exit(0)
+	}
+
+	if *keygen || *renew {
+		certOpts, err := certOptions(*sans, *ips, *validity, loader.Flag("name"))
+		if err != nil {
+			fmt.Printf("invalid certificate options: %v\n", err)
+			return
+		}
+
+		var caCert *x509.Certificate
+		var caKey ed25519.PrivateKey
 		if *caCertPath != "" && *caKeyPath != "" {
-			caCert, caKey, err := keys.LoadCA(*caCertPath, *caKeyPath)
+			caCert, caKey, err = keys.LoadCA(*caCertPath, *caKeyPath)
 			if err != nil {
 				fmt.Printf("error loading CA: %v\n", err)
 				return
 			}
-			err = keys.GenerateServerKeysAndCertWithCA(*keydir, caCert, caKey)
-			if err != nil {
-				fmt.Printf("error generating server signing keys and certificate: %v\n", err)
-				return
-			}
-		} else {
-			err = keys.GenerateServerKeysAndCert(*keydir)
-			if err != nil {
-				fmt.Printf("error generating server signing keys and certificate: %v\n", err)
+		}
+
+		if *renew {
+			if err := keys.RenewServerCert(*keydir, caCert, caKey, certOpts); err != nil {
+				fmt.Printf("error renewing server certificate: %v\n", err)
 				return
 			}
+			os.Exit(0)
+		}
+
+		err = keys.GenerateServerKeysAndCertWithCA(*keydir, caCert, caKey, certOpts)
+		if err != nil {
+			fmt.Printf("error generating server signing keys and certificate: %v\n", err)
+			return
 		}
 
 		// Generate identity file if --name is provided
-		if *serverName != "" {
-			if err := keys.GenerateIdentityFile(*keydir, *serverName); err != nil {
+		if serverName := loader.Flag("name"); serverName != "" {
+			if err := keys.GenerateIdentityFile(*keydir, serverName); err != nil {
 				fmt.Printf("error generating identity file: %v\n", err)
 				return
 			}
@@ -118,33 +158,32 @@ func main() {
 		os.Exit(0)
 	}
 
-	if *configFilePath != "" && !*keygen {
-		log.Println("Loading Config from File")
-
-		serverCfg, err = config.LoadConfigFile[config.ServerConfig](*configFilePath)
-		if err != nil {
-			fmt.Printf("Failed to load server config: %v", err)
-			return
-		}
+	serverCfg, sources, err := loader.Load(*configFilePath)
+	if err != nil {
+		fmt.Printf("Invalid Server config:\n%v\n", err)
+		os.Exit(1)
+	}
 
-		if err = serverCfg.ValidateConfig(); err != nil {
-			fmt.Printf("Invalid Server config: %v", err)
-			return
+	if *printConfig {
+		if err := config.Print(os.Stdout, serverCfg, sources); err != nil {
+			fmt.Printf("error printing config: %v\n", err)
+			os.Exit(1)
 		}
+		os.Exit(0)
+	}
 
-	} else if !*keygen {
-		log.Println("Loading Config from Envrionment Variables")
+	if _, err := logging.Setup(os.Stderr, serverCfg.LogLevel, serverCfg.LogFormat); err != nil {
+		fmt.Printf("Invalid Server config: %v\n", err)
+		os.Exit(1)
+	}
 
-		serverCfg = *config.LoadServerConfigEnv()
+	slog.Info("loaded server config", "name", serverCfg.Name, "config", *configFilePath)
 
-		if err = serverCfg.ValidateEnv(); err != nil {
-			fmt.Printf("Invalid Server config: %v", err)
-			return
-		}
+	shutdownTracing, err := tracing.Setup(ctx, serverCfg.Tracing, "strike-server", os.Stdout)
+	if err != nil {
+		fatal("tracing setup failed", err)
 	}
 
-	log.Printf("Loaded Server Config: %+v", serverCfg)
-
 	// pgConfig, err := pgxpool.ParseConfig(serverCfg.DBConnectionString)
 	// if err != nil {
 	// 	fmt.Printf("Config parsing failed: %v", err)
@@ -164,10 +203,12 @@ func main() {
 	// 	return
 	// }
 
+	server.WarnCertExpiry(serverCfg)
+
 	// Load TLS credentials for Strike gRPC server
-	creds, err := credentials.NewServerTLSFromFile(serverCfg.CertificatePath, serverCfg.SigningPrivateKeyPath)
+	creds, err := server.LoadStrikeTLSCredentials(serverCfg)
 	if err != nil {
-		log.Fatalf("failed to load TLS credentials: %v", err)
+		fatal("failed to load TLS credentials", err)
 	}
 
 	// Initialize bootstrap
@@ -175,33 +216,85 @@ func main() {
 
 	// Initialize DB
 	if err := bootstrap.InitDb(ctx); err != nil {
-		log.Fatalf("DB initialization failed: %v", err)
+		fatal("database initialization failed", err)
 	}
 
 	// Load federation peers
 	peers, err := server.LoadPeers(serverCfg.FederationPeers)
 	if err != nil {
-		log.Fatalf("Failed to load federation peers: %v", err)
+		fatal("failed to load federation peers", err)
 	}
 
 	// Initialize Strike server
 	if err := bootstrap.InitStrikeServer(creds, peers); err != nil {
-		log.Fatalf("Strike server initialization failed: %v", err)
+		fatal("strike server initialization failed", err)
 	}
 
 	// Initialize Federation server
 	if err := bootstrap.InitFederation(); err != nil {
-		log.Fatalf("Federation server initialization failed: %v", err)
+		fatal("federation server initialization failed", err)
+	}
+
+	// Initialize the strike-admin service, if an admin token is configured
+	if err := bootstrap.InitAdminRPC(creds); err != nil {
+		fatal("admin service initialization failed", err)
+	}
+
+	// Initialize metrics, health and readiness endpoints
+	if err := bootstrap.InitAdmin(); err != nil {
+		fatal("admin listener initialization failed", err)
 	}
 
 	// Start servers and peer connections
 	if err := bootstrap.Start(ctx); err != nil {
-		log.Fatalf("bootstrap start failed: %v", err)
+		fatal("bootstrap start failed", err)
 	}
 
 	// Wait for shutdown signal
 	<-ctx.Done()
-	log.Println("Shutdown signal received")
+	slog.Info("shutdown signal received")
+
+	// ctx is already cancelled; draining gets its own deadline.
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Duration(serverCfg.ShutdownTimeout))
+	defer shutdownCancel()
+
+	bootstrap.Stop(shutdownCtx)
+
+	// Flush spans last so the shutdown itself is exported.
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Warn("flushing traces failed", "error", err)
+	}
+}
+
+// fatal logs err and exits. Deferred cleanup does not run, so it is only used
+// before the servers start.
+// certOptions parses the certificate flags. Unset values are left empty so
+// --renew can carry them over from the current certificate.
+func certOptions(sans, ips string, validity time.Duration, name string) (keys.CertOptions, error) {
+	opts := keys.CertOptions{CommonName: name, Validity: validity}
+	if validity < 0 {
+		return opts, fmt.Errorf("--validity must be positive")
+	}
+
+	for _, san := range strings.Split(sans, ",") {
+		if san = strings.TrimSpace(san); san != "" {
+			opts.DNSNames = append(opts.DNSNames, san)
+		}
+	}
+	for _, ip := range strings.Split(ips, ",") {
+		if ip = strings.TrimSpace(ip); ip == "" {
+			continue
+		}
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return opts, fmt.Errorf("invalid --ip %q", ip)
+		}
+		opts.IPAddresses = append(opts.IPAddresses, parsed)
+	}
+	return opts, nil
+}
 
-	bootstrap.Stop(ctx)
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
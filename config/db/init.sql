@@ -5,7 +5,8 @@ CREATE TABLE users (
     username VARCHAR(255) UNIQUE NOT NULL,
     password_hash TEXT NOT NULL,
     salt BYTEA NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    disabled BOOLEAN NOT NULL DEFAULT false
 );
 
 CREATE TABLE user_keys (
@@ -15,6 +16,85 @@ CREATE TABLE user_keys (
     PRIMARY KEY (user_id)
 );
 
+-- Undelivered payloads persisted across server restarts
+CREATE TABLE pending_messages (
+    message_id UUID PRIMARY KEY,
+    sender UUID NOT NULL,
+    recipient UUID NOT NULL,
+    sender_domain TEXT NOT NULL DEFAULT '',
+    target_domain TEXT NOT NULL DEFAULT '',
+    payload BYTEA NOT NULL,
+    attempts INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    correlation_id TEXT NOT NULL DEFAULT '',
+    trace_parent TEXT NOT NULL DEFAULT ''
+);
+
+-- Payloads that ran out of delivery attempts, kept for strike-admin replay
+CREATE TABLE dead_letters (
+    message_id UUID PRIMARY KEY,
+    sender UUID NOT NULL,
+    recipient UUID NOT NULL,
+    sender_domain TEXT NOT NULL DEFAULT '',
+    target_domain TEXT NOT NULL DEFAULT '',
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    failed_at TIMESTAMPTZ NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    correlation_id TEXT NOT NULL DEFAULT '',
+    trace_parent TEXT NOT NULL DEFAULT ''
+);
+
+-- Renamed and moved accounts, ours and those announced by peers, so lookups
+-- and deliveries for the old address can follow the user
+CREATE TABLE account_redirects (
+    domain TEXT NOT NULL,
+    username TEXT NOT NULL,
+    user_id UUID NOT NULL,
+    new_username TEXT NOT NULL,
+    new_domain TEXT NOT NULL,
+    notice BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (domain, username)
+);
+
+-- Every key rotation, kept so friends who missed one can walk the chain
+-- from the last key they trusted
+CREATE TABLE key_history (
+    user_id UUID REFERENCES users(user_id) ON DELETE CASCADE,
+    serial BIGINT NOT NULL,
+    encryption_public_key BYTEA NOT NULL,
+    signing_public_key BYTEA NOT NULL,
+    rotation BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, serial)
+);
+
+-- Domains and user IDs each user refuses payloads from, replaced as a
+-- whole by a newer signed list
+CREATE TABLE user_blocks (
+    user_id UUID PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    domains TEXT[] NOT NULL DEFAULT '{}',
+    user_ids TEXT[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Federation invitations already redeemed, so each one works once
+CREATE TABLE federation_invites (
+    nonce BYTEA PRIMARY KEY,
+    peer_id TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Failed login tracking for lockout/backoff
+CREATE TABLE login_attempts (
+    username TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ
+);
+
 -- Auto-update updated_at
 -- CREATE OR REPLACE FUNCTION auto_update_timestamp_column()
 -- RETURNS TRIGGER AS $auto_update$
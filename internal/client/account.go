@@ -0,0 +1,252 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+	pb "github.com/JohnnyGlynn/strike/msgdef/message"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// retentionSweep is how often expired messages are purged while the client
+// runs, on top of the purge at startup.
+const retentionSweep = time.Hour
+
+// DeleteAccount deletes the logged in account on the server, which forwards
+// a signed notice to every friend, then wipes the local database.
+func DeleteAccount(ctx context.Context, c *types.Client, password, confirm string) error {
+	passwordHash, err := reauthHash(ctx, c, password)
+	if err != nil {
+		return err
+	}
+
+	priv, err := keys.ParseSigningPrivateKey(c.Identity.Keys["SigningPrivateKey"])
+	if err != nil {
+		return err
+	}
+
+	notice := &common_pb.AccountDeleted{
+		UserId:    c.Identity.ID.String(),
+		Username:  c.Identity.Username,
+		Domain:    c.Identity.Domain,
+		DeletedAt: timestamppb.Now(),
+	}
+	notice.Signature = ed25519.Sign(priv, shared.AccountDeletedBytes(notice))
+
+	notify, err := friendAddresses(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.PBC.DeleteAccount(ctx, &pb.DeleteAccountRequest{
+		Username:     c.Identity.Username,
+		PasswordHash: passwordHash,
+		Confirm:      confirm,
+		Notice:       notice,
+		Notify:       notify,
+	})
+	if err != nil {
+		return fmt.Errorf("account deletion failed: %w", serverError(err))
+	}
+
+	if err := wipeLocalData(ctx, c); err != nil {
+		return fmt.Errorf("account deleted on the server, but clearing local data failed: %v", err)
+	}
+
+	fmt.Println(resp.Message)
+	return nil
+}
+
+// RenameAccount changes the username on the current server. Friends are sent
+// a signed notice and the old name redirects to the new one.
+func RenameAccount(ctx context.Context, c *types.Client, password, newUsername string) error {
+	return moveAccount(ctx, c, password, newUsername, c.Identity.Domain)
+}
+
+// MoveAccount hands the account over to newDomain, where it must already
+// have been created with the same keys. Friends are sent a signed notice and
+// the old address redirects to the new one.
+func MoveAccount(ctx context.Context, c *types.Client, password, newUsername, newDomain string) error {
+	if newDomain == c.Identity.Domain {
+		return fmt.Errorf("already on %s, use /rename", newDomain)
+	}
+	return moveAccount(ctx, c, password, newUsername, newDomain)
+}
+
+func moveAccount(ctx context.Context, c *types.Client, password, newUsername, newDomain string) error {
+	if newUsername == "" {
+		return fmt.Errorf("new username cannot be empty")
+	}
+
+	passwordHash, err := reauthHash(ctx, c, password)
+	if err != nil {
+		return err
+	}
+
+	priv, err := keys.ParseSigningPrivateKey(c.Identity.Keys["SigningPrivateKey"])
+	if err != nil {
+		return err
+	}
+
+	notice := &common_pb.AccountMoved{
+		UserId:      c.Identity.ID.String(),
+		OldUsername: c.Identity.Username,
+		OldDomain:   c.Identity.Domain,
+		NewUsername: newUsername,
+		NewDomain:   newDomain,
+		MovedAt:     timestamppb.Now(),
+	}
+	notice.Signature = ed25519.Sign(priv, shared.AccountMovedBytes(notice))
+
+	notify, err := friendAddresses(c)
+	if err != nil {
+		return err
+	}
+
+	req := &pb.MoveAccountRequest{
+		Username:     c.Identity.Username,
+		PasswordHash: passwordHash,
+		Notice:       notice,
+		Notify:       notify,
+	}
+
+	var resp *pb.ServerResponse
+	if newDomain == c.Identity.Domain {
+		resp, err = c.PBC.RenameAccount(ctx, req)
+	} else {
+		resp, err = c.PBC.MoveAccount(ctx, req)
+	}
+	if err != nil {
+		return fmt.Errorf("account move failed: %w", serverError(err))
+	}
+
+	if _, err := c.DB.ID.Rename.ExecContext(ctx, newUsername, c.Identity.ID.String()); err != nil {
+		return fmt.Errorf("account moved on the server, but updating the local identity failed: %v", err)
+	}
+	c.Identity.Username = newUsername
+	c.Identity.Domain = newDomain
+
+	fmt.Println(resp.Message)
+	return nil
+}
+
+// reauthHash hashes password with the account's salt for RPCs that ask the
+// caller to prove it again.
+func reauthHash(ctx context.Context, c *types.Client, password string) (string, error) {
+	salt, err := c.PBC.SaltMine(ctx, &common_pb.UserInfo{Username: c.Identity.Username})
+	if err != nil {
+		return "", fmt.Errorf("salt retrieval failed: %w", serverError(err))
+	}
+
+	passwordHash, err := shared.HashPassword(password, salt.Salt)
+	if err != nil {
+		return "", fmt.Errorf("password input error: %v", err)
+	}
+	return passwordHash, nil
+}
+
+// friendAddresses lists every address book entry as a notice recipient.
+func friendAddresses(c *types.Client) ([]*common_pb.UserAddress, error) {
+	friends, err := loadFriends(c)
+	if err != nil {
+		return nil, err
+	}
+
+	notify := make([]*common_pb.UserAddress, 0, len(friends))
+	for _, f := range friends {
+		notify = append(notify, &common_pb.UserAddress{
+			Username: f.Name,
+			Domain:   f.Domain,
+			UInfo:    &common_pb.UserInfo{UserId: f.Id.String(), Username: f.Name},
+		})
+	}
+	return notify, nil
+}
+
+// wipeLocalData removes the identity, address book, friend requests,
+// messages and search index from the client database.
+func wipeLocalData(ctx context.Context, c *types.Client) error {
+	if _, err := c.DB.Messages.DeleteAll.ExecContext(ctx); err != nil {
+		return err
+	}
+	if _, err := c.DB.Search.Clear.ExecContext(ctx); err != nil {
+		return err
+	}
+	if _, err := c.DB.FriendRequest.DeleteAll.ExecContext(ctx); err != nil {
+		return err
+	}
+	if _, err := c.DB.Friends.DeleteAll.ExecContext(ctx); err != nil {
+		return err
+	}
+	_, err := c.DB.ID.DeleteID.ExecContext(ctx, c.Identity.ID.String())
+	return err
+}
+
+// PurgeExpiredMessages deletes messages older than the configured retention
+// and returns how many went. A zero retention keeps everything.
+func PurgeExpiredMessages(ctx context.Context, c *types.Client) (int64, error) {
+	retention := time.Duration(c.Identity.Config.MessageRetention)
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	res, err := c.DB.Messages.PurgeBefore.ExecContext(ctx, time.Now().Add(-retention).UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PurgeExpiredFriendRequests deletes friend requests, sent or received,
+// that have gone unanswered for longer than the configured TTL.
+func PurgeExpiredFriendRequests(ctx context.Context, c *types.Client) (int64, error) {
+	ttl := time.Duration(c.Identity.Config.FriendRequestTTL)
+	if ttl <= 0 {
+		return 0, nil
+	}
+
+	res, err := c.DB.FriendRequest.PurgeBefore.ExecContext(ctx, time.Now().Add(-ttl).UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RetainMessages purges expired messages and friend requests now and then
+// every retentionSweep until ctx is done.
+func RetainMessages(ctx context.Context, c *types.Client) {
+	if c.Identity.Config.MessageRetention <= 0 && c.Identity.Config.FriendRequestTTL <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(retentionSweep)
+	defer ticker.Stop()
+
+	for {
+		n, err := PurgeExpiredMessages(ctx, c)
+		if err != nil {
+			slog.Warn("message retention purge failed", "error", err)
+		} else if n > 0 {
+			slog.Debug("expired messages purged", "count", n)
+		}
+		n, err = PurgeExpiredFriendRequests(ctx, c)
+		if err != nil {
+			slog.Warn("friend request expiry purge failed", "error", err)
+		} else if n > 0 {
+			slog.Debug("expired friend requests purged", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/JohnnyGlynn/strike/internal/config"
+	"github.com/JohnnyGlynn/strike/internal/keys"
+)
+
+// BackupType is the PEM block type of a backup archive. The block is sealed
+// with keys.SealPEM, so the passphrase and everything in it, including the
+// Backup-Version header, are checked before anything is restored.
+const BackupType = "STRIKE BACKUP"
+
+const (
+	backupFormat  = "strike-backup"
+	backupVersion = 1
+)
+
+// backupArchive is the sealed content of a backup: the plain private and
+// public keys and every row of the client database.
+type backupArchive struct {
+	Format         string                `json:"format"`
+	Version        int                   `json:"version"`
+	CreatedAt      time.Time             `json:"created_at"`
+	Keys           map[string][]byte     `json:"keys"`
+	Identities     []backupIdentity      `json:"identities"`
+	Friends        []backupFriend        `json:"friends"`
+	FriendRequests []backupFriendRequest `json:"friend_requests"`
+	Messages       []backupMessage       `json:"messages"`
+	Blocks         []backupBlock         `json:"blocks,omitempty"`
+}
+
+type backupIdentity struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	EncKey   []byte `json:"enc_pkey"`
+	SigKey   []byte `json:"sig_pkey"`
+}
+
+type backupFriend struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Domain    string    `json:"domain"`
+	EncKey    []byte    `json:"enc_pkey"`
+	SigKey    []byte    `json:"sig_pkey"`
+	KeyEx     int       `json:"keyex"`
+	Verified  bool      `json:"verified"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type backupFriendRequest struct {
+	FriendID  string `json:"friend_id"`
+	Username  string `json:"username"`
+	Domain    string `json:"domain"`
+	EncKey    []byte `json:"enc_pkey"`
+	SigKey    []byte `json:"sig_pkey"`
+	Direction string `json:"direction"`
+	CreatedAt int64  `json:"created_at,omitempty"`
+}
+
+type backupMessage struct {
+	ID        string `json:"id"`
+	FriendID  string `json:"friend_id"`
+	Direction string `json:"direction"`
+	Content   []byte `json:"content"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type backupBlock struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+}
+
+// ExportBackup writes loaded keys and the whole client database to path,
+// sealed under a passphrase asked for on reader. Messages stay sealed with
+// their chat keys inside the archive.
+func ExportBackup(ctx context.Context, db *sql.DB, loaded map[string][]byte, path string, reader *bufio.Reader) error {
+	archive := backupArchive{
+		Format:    backupFormat,
+		Version:   backupVersion,
+		CreatedAt: time.Now().UTC(),
+		Keys:      loaded,
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }() // read only
+
+	if err := readBackupTables(ctx, tx, &archive); err != nil {
+		return fmt.Errorf("failed to read client database: %v", err)
+	}
+
+	plain, err := json.Marshal(archive)
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := backupPassphrase(reader)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := keys.SealPEM(BackupType, map[string]string{"Backup-Version": strconv.Itoa(backupVersion)}, plain, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, sealed, 0600); err != nil {
+		return fmt.Errorf("failed to write backup: %v", err)
+	}
+
+	fmt.Printf("Backup of %d friends and %d messages written to %s\n", len(archive.Friends), len(archive.Messages), path)
+	return nil
+}
+
+// ImportBackup restores a backup written by ExportBackup into the configured
+// key files and an empty client database. It refuses to overwrite existing
+// key files or an identity already in the database.
+func ImportBackup(ctx context.Context, db *sql.DB, cfg *config.ClientConfig, path string, reader *bufio.Reader) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %v", err)
+	}
+
+	passphrase, err := LoginInput("Backup passphrase > ", reader)
+	if err != nil {
+		return err
+	}
+	headers, plain, err := keys.OpenPEM(BackupType, data, []byte(passphrase))
+	if err != nil {
+		return err
+	}
+	if headers["Backup-Version"] != strconv.Itoa(backupVersion) {
+		return fmt.Errorf("unsupported backup version %q", headers["Backup-Version"])
+	}
+
+	var archive backupArchive
+	if err := json.Unmarshal(plain, &archive); err != nil {
+		return fmt.Errorf("malformed backup: %v", err)
+	}
+	if err := checkBackup(&archive); err != nil {
+		return fmt.Errorf("backup failed integrity check: %v", err)
+	}
+
+	defs := KeyDefinitions(cfg)
+	for _, def := range defs {
+		if _, err := os.Stat(def.Path); err == nil {
+			return fmt.Errorf("%s already exists, move it aside before restoring", def.Path)
+		}
+	}
+	var n int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM identity").Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("the client database already holds an identity, move it aside before restoring")
+	}
+
+	fmt.Println("Choose a passphrase for the restored key files.")
+	keyPassphrase, err := NewPassphrase(reader)
+	if err != nil {
+		return err
+	}
+
+	// Keys go first: a restore that fails part way must not leave a database
+	// holding an identity without the keys to use it. None of the key files
+	// existed before, so a failure removes whatever was written.
+	written, err := writeBackupKeys(defs, archive.Keys, keyPassphrase)
+	if err != nil {
+		removeFiles(written)
+		return err
+	}
+	if err := writeBackupTables(ctx, db, &archive); err != nil {
+		removeFiles(written)
+		return fmt.Errorf("failed to restore client database: %v", err)
+	}
+
+	fmt.Printf("Restored %d friends and %d messages from a backup taken %s\n", len(archive.Friends), len(archive.Messages), archive.CreatedAt.Local().Format(time.DateTime))
+	return nil
+}
+
+func backupPassphrase(reader *bufio.Reader) ([]byte, error) {
+	p, err := LoginInput("Backup passphrase > ", reader)
+	if err != nil {
+		return nil, err
+	}
+	if p == "" {
+		return nil, fmt.Errorf("a backup passphrase is required")
+	}
+	again, err := LoginInput("Repeat passphrase > ", reader)
+	if err != nil {
+		return nil, err
+	}
+	if p != again {
+		return nil, fmt.Errorf("passphrases do not match")
+	}
+	return []byte(p), nil
+}
+
+// checkBackup makes sure the keys in an archive are complete, form pairs,
+// and belong to the identity stored with them.
+func checkBackup(a *backupArchive) error {
+	if a.Format != backupFormat || a.Version != backupVersion {
+		return fmt.Errorf("unsupported format %q version %d", a.Format, a.Version)
+	}
+
+	var errs []error
+	for _, name := range []string{"SigningPrivateKey", "SigningPublicKey", "EncryptionPrivateKey", "EncryptionPublicKey"} {
+		if len(a.Keys[name]) == 0 {
+			errs = append(errs, fmt.Errorf("missing %s", name))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if err := keys.CheckKeyPairs(a.Keys["SigningPrivateKey"], a.Keys["SigningPublicKey"], a.Keys["EncryptionPrivateKey"], a.Keys["EncryptionPublicKey"]); err != nil {
+		return err
+	}
+
+	for _, id := range a.Identities {
+		if !bytes.Equal(id.SigKey, a.Keys["SigningPublicKey"]) || !bytes.Equal(id.EncKey, a.Keys["EncryptionPublicKey"]) {
+			return fmt.Errorf("identity %s does not use the keys in the backup", id.Username)
+		}
+	}
+	return nil
+}
+
+func readBackupTables(ctx context.Context, tx *sql.Tx, a *backupArchive) error {
+	rows, err := tx.QueryContext(ctx, "SELECT user_id, username, enc_pkey, sig_pkey FROM identity")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var r backupIdentity
+		if err := rows.Scan(&r.UserID, &r.Username, &r.EncKey, &r.SigKey); err != nil {
+			rows.Close()
+			return err
+		}
+		a.Identities = append(a.Identities, r)
+	}
+	if err := closeRows(rows); err != nil {
+		return err
+	}
+
+	rows, err = tx.QueryContext(ctx, "SELECT user_id, username, domain, enc_pkey, sig_pkey, keyex, verified, created_at FROM addressbook")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var r backupFriend
+		if err := rows.Scan(&r.UserID, &r.Username, &r.Domain, &r.EncKey, &r.SigKey, &r.KeyEx, &r.Verified, &r.CreatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		a.Friends = append(a.Friends, r)
+	}
+	if err := closeRows(rows); err != nil {
+		return err
+	}
+
+	rows, err = tx.QueryContext(ctx, "SELECT friendId, username, domain, enc_pkey, sig_pkey, direction, created_at FROM friendrequests")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var r backupFriendRequest
+		if err := rows.Scan(&r.FriendID, &r.Username, &r.Domain, &r.EncKey, &r.SigKey, &r.Direction, &r.CreatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		a.FriendRequests = append(a.FriendRequests, r)
+	}
+	if err := closeRows(rows); err != nil {
+		return err
+	}
+
+	rows, err = tx.QueryContext(ctx, "SELECT id, friendId, direction, content, timestamp FROM messages")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var r backupMessage
+		if err := rows.Scan(&r.ID, &r.FriendID, &r.Direction, &r.Content, &r.Timestamp); err != nil {
+			rows.Close()
+			return err
+		}
+		a.Messages = append(a.Messages, r)
+	}
+	if err := closeRows(rows); err != nil {
+		return err
+	}
+
+	rows, err = tx.QueryContext(ctx, "SELECT kind, value, label FROM blocklist")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var r backupBlock
+		if err := rows.Scan(&r.Kind, &r.Value, &r.Label); err != nil {
+			rows.Close()
+			return err
+		}
+		a.Blocks = append(a.Blocks, r)
+	}
+	return closeRows(rows)
+}
+
+// writeBackupKeys writes each key file through a temporary file and a rename,
+// returning the paths written so far.
+func writeBackupKeys(defs map[string]keys.KeyDefinition, loaded map[string][]byte, passphrase []byte) ([]string, error) {
+	var written []string
+	for name, def := range defs {
+		if err := os.MkdirAll(filepath.Dir(def.Path), 0700); err != nil {
+			return written, err
+		}
+		// Public keys are stored as they are; WritePrivateKey only seals
+		// when given a passphrase.
+		var err error
+		switch name {
+		case "SigningPrivateKey", "EncryptionPrivateKey":
+			err = keys.WritePrivateKey(def.Path, loaded[name], passphrase)
+		default:
+			err = keys.WritePrivateKey(def.Path, loaded[name], nil)
+		}
+		if err != nil {
+			return written, fmt.Errorf("failed to write %s: %v", def.Path, err)
+		}
+		written = append(written, def.Path)
+	}
+	return written, nil
+}
+
+func removeFiles(paths []string) {
+	for _, path := range paths {
+		_ = os.Remove(path)
+	}
+}
+
+func writeBackupTables(ctx context.Context, db *sql.DB, a *backupArchive) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after commit
+
+	// Replace what an unused database may hold, e.g. friend requests
+	// received before the first login.
+	for _, table := range []string{"addressbook", "friendrequests", "messages", "message_index", "blocklist"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range a.Identities {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO identity (user_id, username, enc_pkey, sig_pkey) VALUES (?, ?, ?, ?)",
+			r.UserID, r.Username, r.EncKey, r.SigKey); err != nil {
+			return err
+		}
+	}
+	for _, r := range a.Friends {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO addressbook (user_id, username, domain, enc_pkey, sig_pkey, keyex, verified, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			r.UserID, r.Username, r.Domain, r.EncKey, r.SigKey, r.KeyEx, r.Verified, r.CreatedAt); err != nil {
+			return err
+		}
+	}
+	// Archives from before requests were de-duplicated and timestamped may
+	// repeat a request or carry no time; those restart their expiry now.
+	for _, r := range a.FriendRequests {
+		if r.CreatedAt == 0 {
+			r.CreatedAt = time.Now().UnixMilli()
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO friendrequests (friendId, username, domain, enc_pkey, sig_pkey, direction, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			r.FriendID, r.Username, r.Domain, r.EncKey, r.SigKey, r.Direction, r.CreatedAt); err != nil {
+			return err
+		}
+	}
+	for _, r := range a.Messages {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO messages (id, friendId, direction, content, timestamp) VALUES (?, ?, ?, ?, ?)",
+			r.ID, r.FriendID, r.Direction, r.Content, r.Timestamp); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range a.Blocks {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO blocklist (kind, value, label) VALUES (?, ?, ?)", r.Kind, r.Value, r.Label); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func closeRows(rows *sql.Rows) error {
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	return rows.Err()
+}
@@ -0,0 +1,337 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/JohnnyGlynn/strike/internal/config"
+	"github.com/JohnnyGlynn/strike/internal/keys"
+)
+
+// testDB opens an empty client database with the current schema.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	schema, err := os.ReadFile("../../cmd/strike-client/client.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "client.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(string(schema)); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	if err := EnsureSchema(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// testKeys generates a full set of client keys.
+func testKeys(t *testing.T) map[string][]byte {
+	t.Helper()
+	sigPriv, sigPub, err := keys.GenerateSigningKeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encPriv, encPub, err := keys.GenerateEncryptionKeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string][]byte{
+		"SigningPrivateKey":    sigPriv,
+		"SigningPublicKey":     sigPub,
+		"EncryptionPrivateKey": encPriv,
+		"EncryptionPublicKey":  encPub,
+	}
+}
+
+func testKeyConfig(dir string) *config.ClientConfig {
+	return &config.ClientConfig{
+		SigningPrivateKeyPath:    filepath.Join(dir, "strike_signing.pem"),
+		SigningPublicKeyPath:     filepath.Join(dir, "strike_public_signing.pem"),
+		EncryptionPrivateKeyPath: filepath.Join(dir, "strike_encryption.pem"),
+		EncryptionPublicKeyPath:  filepath.Join(dir, "strike_public_encryption.pem"),
+	}
+}
+
+func input(lines ...string) *bufio.Reader {
+	return bufio.NewReader(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+}
+
+// exportTestBackup fills a database and exports it under passphrase.
+func exportTestBackup(t *testing.T, loaded map[string][]byte, passphrase string) string {
+	t.Helper()
+	ctx := context.Background()
+	db := testDB(t)
+
+	stmts := []struct {
+		query string
+		args  []any
+	}{
+		{"INSERT INTO identity (user_id, username, enc_pkey, sig_pkey) VALUES (?, ?, ?, ?)",
+			[]any{"11111111-1111-1111-1111-111111111111", "alice", loaded["EncryptionPublicKey"], loaded["SigningPublicKey"]}},
+		{"INSERT INTO addressbook (user_id, username, domain, enc_pkey, sig_pkey, keyex, verified) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			[]any{"22222222-2222-2222-2222-222222222222", "bob", "b.example", []byte("enc"), []byte("sig"), 1, 1}},
+		{"INSERT INTO messages (id, friendId, direction, content, timestamp) VALUES (?, ?, ?, ?, ?)",
+			[]any{"33333333-3333-3333-3333-333333333333", "22222222-2222-2222-2222-222222222222", "inbound", []byte("sealed"), time.Now().UnixMilli()}},
+		{"INSERT INTO blocklist (kind, value, label) VALUES (?, ?, ?)",
+			[]any{"domain", "spam.example", "spam.example"}},
+	}
+	for _, s := range stmts {
+		if _, err := db.ExecContext(ctx, s.query, s.args...); err != nil {
+			t.Fatalf("%s: %v", s.query, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "strike.backup")
+	if err := ExportBackup(ctx, db, loaded, path, input(passphrase, passphrase)); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	return path
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	loaded := testKeys(t)
+	path := exportTestBackup(t, loaded, "backup pass")
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("backup mode = %04o, want 0600", perm)
+	}
+
+	db := testDB(t)
+	cfg := testKeyConfig(filepath.Join(t.TempDir(), "keys"))
+	if err := ImportBackup(ctx, db, cfg, path, input("backup pass", "key pass", "key pass")); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	restored, err := keys.LoadKeys(KeyDefinitions(cfg), func() ([]byte, error) { return []byte("key pass"), nil })
+	if err != nil {
+		t.Fatalf("load restored keys: %v", err)
+	}
+	for name, want := range loaded {
+		if !bytes.Equal(restored[name], want) {
+			t.Errorf("%s differs after restore", name)
+		}
+	}
+	if priv, _ := os.ReadFile(cfg.SigningPrivateKeyPath); !keys.IsEncryptedPEM(priv) {
+		t.Error("restored private key is not sealed under the new passphrase")
+	}
+
+	counts := map[string]int{"identity": 1, "addressbook": 1, "messages": 1, "blocklist": 1, "friendrequests": 0}
+	for table, want := range counts {
+		var n int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Errorf("%s has %d rows, want %d", table, n, want)
+		}
+	}
+	var verified bool
+	if err := db.QueryRowContext(ctx, "SELECT verified FROM addressbook WHERE username = 'bob'").Scan(&verified); err != nil || !verified {
+		t.Errorf("friend not restored as verified: %v", err)
+	}
+
+	// A second restore must not overwrite what the first wrote.
+	if err := ImportBackup(ctx, db, cfg, path, input("backup pass", "", "")); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("second import error = %v, want existing key files refused", err)
+	}
+}
+
+func TestImportBackupRejects(t *testing.T) {
+	loaded := testKeys(t)
+	path := exportTestBackup(t, loaded, "backup pass")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rewrite := func(mod func(b *pem.Block)) []byte {
+		block, _ := pem.Decode(data)
+		mod(block)
+		return pem.EncodeToMemory(block)
+	}
+	// resealed builds a validly sealed backup around a modified archive or
+	// headers, to reach the checks after decryption.
+	resealed := func(headers map[string]string, mod func(a *backupArchive)) []byte {
+		_, plain, err := keys.OpenPEM(BackupType, data, []byte("backup pass"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var a backupArchive
+		if err := json.Unmarshal(plain, &a); err != nil {
+			t.Fatal(err)
+		}
+		mod(&a)
+		plain, err = json.Marshal(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := keys.SealPEM(BackupType, headers, plain, []byte("backup pass"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	current := map[string]string{"Backup-Version": "1"}
+
+	cases := map[string]struct {
+		data    []byte
+		pass    string
+		errIs   error
+		errText string
+	}{
+		"wrong-passphrase": {
+			data:  data,
+			pass:  "not the passphrase",
+			errIs: keys.ErrBadPassphrase,
+		},
+		"truncated": {
+			data:    data[:len(data)/2],
+			pass:    "backup pass",
+			errText: "failed to decode PEM block",
+		},
+		"tampered-body": {
+			data:  rewrite(func(b *pem.Block) { b.Bytes[len(b.Bytes)/2] ^= 0xff }),
+			pass:  "backup pass",
+			errIs: keys.ErrBadPassphrase,
+		},
+		"tampered-version-header": {
+			data:  rewrite(func(b *pem.Block) { b.Headers["Backup-Version"] = "2" }),
+			pass:  "backup pass",
+			errIs: keys.ErrBadPassphrase,
+		},
+		"newer-version": {
+			data:    resealed(map[string]string{"Backup-Version": "2"}, func(*backupArchive) {}),
+			pass:    "backup pass",
+			errText: `unsupported backup version "2"`,
+		},
+		"archive-version-mismatch": {
+			data:    resealed(current, func(a *backupArchive) { a.Version = 2 }),
+			pass:    "backup pass",
+			errText: "unsupported format",
+		},
+		"missing-key": {
+			data:    resealed(current, func(a *backupArchive) { delete(a.Keys, "EncryptionPrivateKey") }),
+			pass:    "backup pass",
+			errText: "missing EncryptionPrivateKey",
+		},
+		"mismatched-pair": {
+			data:    resealed(current, func(a *backupArchive) { a.Keys["SigningPublicKey"] = testKeys(t)["SigningPublicKey"] }),
+			pass:    "backup pass",
+			errText: "integrity check",
+		},
+		"foreign-identity": {
+			data: resealed(current, func(a *backupArchive) {
+				a.Identities[0].SigKey = testKeys(t)["SigningPublicKey"]
+			}),
+			pass:    "backup pass",
+			errText: "does not use the keys in the backup",
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			db := testDB(t)
+			cfg := testKeyConfig(filepath.Join(t.TempDir(), "keys"))
+			path := filepath.Join(t.TempDir(), "strike.backup")
+			if err := os.WriteFile(path, tc.data, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ImportBackup(context.Background(), db, cfg, path, input(tc.pass, "", ""))
+			switch {
+			case err == nil:
+				t.Fatal("import succeeded")
+			case tc.errIs != nil && !errors.Is(err, tc.errIs):
+				t.Fatalf("error = %v, want %v", err, tc.errIs)
+			case tc.errText != "" && !strings.Contains(err.Error(), tc.errText):
+				t.Fatalf("error = %v, want it to contain %q", err, tc.errText)
+			}
+
+			// Nothing may be restored from a rejected backup.
+			if _, err := os.Stat(cfg.SigningPrivateKeyPath); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("key file written for a rejected backup")
+			}
+			var n int
+			if err := db.QueryRow("SELECT COUNT(*) FROM identity").Scan(&n); err != nil || n != 0 {
+				t.Errorf("identity restored from a rejected backup")
+			}
+		})
+	}
+}
+
+func TestImportBackupFailsCleanly(t *testing.T) {
+	path := exportTestBackup(t, testKeys(t), "backup pass")
+
+	cases := map[string]struct {
+		setup   func(t *testing.T, db *sql.DB, cfg *config.ClientConfig)
+		errText string
+	}{
+		"key-write-fails": {
+			// A regular file where the key directory should be.
+			setup: func(t *testing.T, db *sql.DB, cfg *config.ClientConfig) {
+				blocker := filepath.Join(t.TempDir(), "not-a-dir")
+				if err := os.WriteFile(blocker, nil, 0600); err != nil {
+					t.Fatal(err)
+				}
+				cfg.EncryptionPublicKeyPath = filepath.Join(blocker, "strike_public_encryption.pem")
+			},
+			errText: "not a directory",
+		},
+		"database-write-fails": {
+			setup: func(t *testing.T, db *sql.DB, cfg *config.ClientConfig) {
+				if _, err := db.Exec("CREATE TRIGGER refuse BEFORE INSERT ON addressbook BEGIN SELECT RAISE(ABORT, 'refused'); END"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			errText: "failed to restore client database",
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			db := testDB(t)
+			cfg := testKeyConfig(filepath.Join(t.TempDir(), "keys"))
+			tc.setup(t, db, cfg)
+
+			err := ImportBackup(context.Background(), db, cfg, path, input("backup pass", "", ""))
+			if err == nil || !strings.Contains(err.Error(), tc.errText) {
+				t.Fatalf("error = %v, want it to contain %q", err, tc.errText)
+			}
+
+			// A failed restore leaves neither keys nor an identity behind.
+			for name, def := range KeyDefinitions(cfg) {
+				if _, err := os.Stat(def.Path); err == nil {
+					t.Errorf("%s left behind by a failed restore", name)
+				}
+			}
+			var n int
+			if err := db.QueryRow("SELECT COUNT(*) FROM identity").Scan(&n); err != nil || n != 0 {
+				t.Errorf("identity restored by a failed restore")
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+)
+
+// Kinds of block list entries.
+const (
+	BlockDomain = "domain"
+	BlockUser   = "user"
+)
+
+// BlockEntry is a block list entry. Label is the address a user was blocked
+// under, since the list itself holds user IDs.
+type BlockEntry struct {
+	Kind  string
+	Value string
+	Label string
+}
+
+// Blocks returns the block list, domains first.
+func Blocks(ctx context.Context, c *types.Client) ([]BlockEntry, error) {
+	rows, err := c.DB.Blocks.List.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []BlockEntry
+	for rows.Next() {
+		var b BlockEntry
+		if err := rows.Scan(&b.Kind, &b.Value, &b.Label); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, rows.Err()
+}
+
+// SetBlocked adds value to or removes it from the block list. The whole list
+// is signed and sent to the server first, so the local copy only changes
+// once the server enforces it.
+func SetBlocked(ctx context.Context, c *types.Client, kind, value, label string, blocked bool) (string, error) {
+	blocks, err := Blocks(ctx, c)
+	if err != nil {
+		return "", err
+	}
+
+	var domains, users []string
+	for _, b := range blocks {
+		if b.Kind == kind && b.Value == value {
+			continue
+		}
+		switch b.Kind {
+		case BlockDomain:
+			domains = append(domains, b.Value)
+		case BlockUser:
+			users = append(users, b.Value)
+		}
+	}
+	if blocked && kind == BlockDomain {
+		domains = append(domains, value)
+	} else if blocked {
+		users = append(users, value)
+	}
+
+	msg, err := pushBlockList(ctx, c, domains, users)
+	if err != nil {
+		return "", err
+	}
+
+	if blocked {
+		_, err = c.DB.Blocks.Add.ExecContext(ctx, kind, value, label)
+	} else {
+		_, err = c.DB.Blocks.Remove.ExecContext(ctx, kind, value)
+	}
+	return msg, err
+}
+
+// Block blocks addr and discards any friend request from it.
+func Block(ctx context.Context, c *types.Client, addr shared.StrikeAddress) (string, error) {
+	id, err := resolveUserID(ctx, c, addr)
+	if err != nil {
+		return "", err
+	}
+	msg, err := SetBlocked(ctx, c, BlockUser, id, addr.Format(), true)
+	if err != nil {
+		return "", err
+	}
+	if _, err := c.DB.FriendRequest.DeleteFriendRequest.ExecContext(ctx, id); err != nil {
+		return "", err
+	}
+	return msg, nil
+}
+
+// Unblock removes addr from the block list. The address it was blocked
+// under is tried first, as the account may since have been deleted.
+func Unblock(ctx context.Context, c *types.Client, addr shared.StrikeAddress) (string, error) {
+	blocks, err := Blocks(ctx, c)
+	if err != nil {
+		return "", err
+	}
+	for _, b := range blocks {
+		if b.Kind == BlockUser && (b.Label == addr.Format() || b.Value == addr.Username) {
+			return SetBlocked(ctx, c, BlockUser, b.Value, "", false)
+		}
+	}
+
+	id, err := resolveUserID(ctx, c, addr)
+	if err != nil {
+		return "", err
+	}
+	if !slices.ContainsFunc(blocks, func(b BlockEntry) bool { return b.Kind == BlockUser && b.Value == id }) {
+		return "", fmt.Errorf("%s is not blocked", addr.Format())
+	}
+	return SetBlocked(ctx, c, BlockUser, id, "", false)
+}
+
+// resolveUserID finds the user ID behind addr in the address book or
+// friend requests, asking the server only for strangers.
+func resolveUserID(ctx context.Context, c *types.Client, addr shared.StrikeAddress) (string, error) {
+	var id string
+	err := c.DB.FriendRequest.FindUser.QueryRowContext(ctx, addr.Username, addr.Domain, addr.Username, addr.Domain).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	info, err := c.PBC.UserRequest(ctx, &common_pb.UserAddress{Username: addr.Username, Domain: addr.Domain})
+	if isNotFound(err) || (err == nil && info.UserId == "") {
+		return "", fmt.Errorf("user %s not found", addr.Format())
+	}
+	if err != nil {
+		return "", fmt.Errorf("user lookup failed: %w", serverError(err))
+	}
+	return info.UserId, nil
+}
+
+func pushBlockList(ctx context.Context, c *types.Client, domains, users []string) (string, error) {
+	signer, err := keys.ParseSigningPrivateKey(c.Identity.Keys["SigningPrivateKey"])
+	if err != nil {
+		return "", err
+	}
+
+	l := &common_pb.BlockList{
+		UserId:    c.Identity.ID.String(),
+		Domains:   domains,
+		UserIds:   users,
+		UpdatedAt: timestamppb.Now(),
+	}
+	l.Signature = ed25519.Sign(signer, shared.BlockListBytes(l))
+
+	resp, err := c.PBC.UpdateBlocks(ctx, l)
+	if err != nil {
+		return "", fmt.Errorf("block list update failed: %w", serverError(err))
+	}
+	return resp.Message, nil
+}
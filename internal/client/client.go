@@ -3,16 +3,21 @@ package client
 import (
 	"bufio"
 	"context"
+	"crypto/ed25519"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/JohnnyGlynn/strike/internal/client/crypto"
 	"github.com/JohnnyGlynn/strike/internal/client/network"
 	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/logging"
 	"github.com/JohnnyGlynn/strike/internal/shared"
 	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
 	pb "github.com/JohnnyGlynn/strike/msgdef/message"
@@ -30,8 +35,8 @@ func ConnectPayloadStream(ctx context.Context, c *types.Client) error {
 		SigningPublicKey:    c.Identity.Keys["SigningPublicKey"],
 	})
 	if err != nil {
-		log.Printf("MessageStream Failed: %v", err)
-		return err
+		slog.Error("payload stream failed", "error", err)
+		return serverError(err)
 	}
 
 	// Start our demultiplexer and baseline processor functions
@@ -44,15 +49,15 @@ func ConnectPayloadStream(ctx context.Context, c *types.Client) error {
 		select {
 		case <-ctx.Done():
 			// Graceful exit
-			log.Println("Message stream context canceled. Exiting...")
+			slog.Debug("payload stream context canceled")
 			return nil
 		default:
 			msg, err := stream.Recv()
 			if err == io.EOF {
-				log.Println("Stream closed by server.")
+				slog.Info("payload stream closed by server")
 				return nil
 			} else if err != nil {
-				log.Printf("Error receiving message: %v", err)
+				slog.Error("error receiving message", "error", err)
 				return err
 			}
 
@@ -75,6 +80,16 @@ func Signup(c *types.Client, password string, curve25519key []byte, ed25519key [
 		return fmt.Errorf("password input error: %v", err)
 	}
 
+	// Signing up again with the same keys, e.g. on the server an account is
+	// moving to, keeps the user ID so friends can follow the move.
+	var existing uuid.UUID
+	err = c.DB.ID.GetBySigKey.QueryRowContext(ctx, ed25519key).Scan(&existing)
+	if err == nil {
+		c.Identity.ID = existing
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("identity lookup: %v", err)
+	}
+
 	initUser := pb.InitUser{
 		Username:            c.Identity.Username,
 		UserId:              c.Identity.ID.String(),
@@ -86,8 +101,7 @@ func Signup(c *types.Client, password string, curve25519key []byte, ed25519key [
 
 	serverRes, err := c.PBC.Signup(ctx, &initUser)
 	if err != nil {
-		log.Printf("signup failed: %v\n", err)
-		return err
+		return fmt.Errorf("signup failed: %w", serverError(err))
 	}
 
 	// Save users own details to local client db
@@ -96,7 +110,7 @@ func Signup(c *types.Client, password string, curve25519key []byte, ed25519key [
 		return fmt.Errorf("failed adding to address book: %v", err)
 	}
 
-	fmt.Printf("Server Response: %v\n", serverRes.Success)
+	fmt.Fprintf(c.Out(), "Server Response: %v\n", serverRes.Success)
 	return nil
 }
 
@@ -107,9 +121,11 @@ func Login(c *types.Client, password string) error {
 	localIdentity := false
 
 	salt, err := c.PBC.SaltMine(ctx, &common_pb.UserInfo{Username: c.Identity.Username})
+	if isNotFound(err) {
+		return fmt.Errorf("invalid username or password")
+	}
 	if err != nil {
-		log.Printf("Salt retrieval failed: %v\n", err)
-		return err
+		return fmt.Errorf("salt retrieval failed: %w", serverError(err))
 	}
 
 	passwordHash, err := shared.HashPassword(password, salt.Salt)
@@ -122,8 +138,7 @@ func Login(c *types.Client, password string) error {
 		PasswordHash: passwordHash,
 	})
 	if err != nil {
-		log.Printf("login error: %v\n", err)
-		return err
+		return serverError(err)
 	}
 	if !loginResp.Success {
 		return fmt.Errorf("login failed: %v", loginResp.Message)
@@ -144,8 +159,7 @@ func Login(c *types.Client, password string) error {
 
 		dbsync, err := c.PBC.UserRequest(context.TODO(), &common_pb.UserAddress{Username: c.Identity.Username})
 		if err != nil {
-			log.Printf("error syncing: %v\n", err)
-			return err
+			return fmt.Errorf("error syncing: %w", serverError(err))
 		}
 
 		c.Identity.ID = uuid.MustParse(dbsync.UserId)
@@ -156,15 +170,24 @@ func Login(c *types.Client, password string) error {
 		}
 	}
 
-	fmt.Printf("%v:%s\n", loginResp.Success, loginResp.Message)
+	fmt.Fprintf(c.Out(), "%v:%s\n", loginResp.Success, loginResp.Message)
 	return nil
 
 }
 
+// SendMessage sends message to the chat open in the shell.
 func SendMessage(c *types.Client, message string) error {
-	sealedMessage, err := crypto.Encrypt(c, []byte(message))
+	chat := c.State.Cache.CurrentChat
+	return SendTo(context.Background(), c, &chat.User, chat.EncKey, message)
+}
+
+// SendTo seals message under key and sends it to friend u, then saves it.
+// It reads no chat state, so the daemon and the TUI can send while payload
+// streams change the shell's open chat.
+func SendTo(ctx context.Context, c *types.Client, u *types.User, key []byte, message string) error {
+	sealedMessage, err := crypto.Seal(key, []byte(message))
 	if err != nil {
-		log.Println("Couldnt encrypt message")
+		slog.Error("could not encrypt message", "error", err)
 		return err
 	}
 
@@ -172,28 +195,32 @@ func SendMessage(c *types.Client, message string) error {
 		SenderPublicKey:  c.Identity.Keys["SigningPublicKey"],
 		SentAt:           timestamppb.Now(),
 		FromUser:         c.Identity.ID.String(),
-		ToUser:           c.State.Cache.CurrentChat.User.Id.String(),
+		ToUser:           u.Id.String(),
 		EncryptedMessage: sealedMessage,
 	}
 
 	payloadEnvelope := pb.StreamPayload{
-		Target:       c.State.Cache.CurrentChat.User.Id.String(),
+		Target:       u.Id.String(),
 		Sender:       c.Identity.ID.String(),
-		TargetDomain: c.State.Cache.CurrentChat.User.Domain,
+		TargetDomain: u.Domain,
 		SenderDomain: c.Identity.Domain,
 		Payload:      &pb.StreamPayload_Encenv{Encenv: &encenv},
 		Info:         "Encrypted Payload",
 	}
 
-	_, err = c.PBC.SendPayload(context.Background(), &payloadEnvelope)
+	// The correlation ID is sent as metadata and appears in the server logs
+	// for every hop this message takes.
+	ctx = logging.WithCorrelationID(ctx, uuid.NewString())
+
+	_, err = c.PBC.SendPayload(ctx, &payloadEnvelope)
 	if err != nil {
-		log.Println("Error sending payload")
-		return err
+		return fmt.Errorf("error sending payload: %w", serverError(err))
 	}
+	slog.DebugContext(ctx, "message sent", "to", payloadEnvelope.Target)
 
-	_, err = c.DB.Messages.SaveMessage.ExecContext(context.TODO(), uuid.New().String(), c.State.Cache.CurrentChat.User.Id.String(), "outbound", sealedMessage, time.Now().UnixMilli())
+	_, err = c.DB.Messages.SaveMessage.ExecContext(ctx, uuid.New().String(), u.Id.String(), "outbound", sealedMessage, time.Now().UnixMilli())
 	if err != nil {
-		log.Println("Error saving message")
+		slog.Error("error saving message", "error", err)
 		return err
 	}
 
@@ -224,16 +251,16 @@ func FriendRequest(ctx context.Context, c *types.Client, target *common_pb.UserI
 
 	resp, err := c.PBC.SendPayload(ctx, &payload)
 	if err != nil {
-		return fmt.Errorf("failed to confirm chat: %v", err)
+		return fmt.Errorf("failed to confirm chat: %w", serverError(err))
 	}
 
-	_, err = c.DB.FriendRequest.SaveFriendRequest.ExecContext(context.TODO(), target.UserId, target.Username, targetDomain, nil, nil, "outbound")
+	_, err = c.DB.FriendRequest.SaveFriendRequest.ExecContext(context.TODO(), target.UserId, target.Username, targetDomain, nil, nil, "outbound", time.Now().UnixMilli())
 	if err != nil {
-		fmt.Printf("failed to save Friend Request")
+		fmt.Fprintf(c.Out(), "failed to save Friend Request")
 		return err
 	}
 
-	fmt.Printf("Friend request sent: %+v\n", resp)
+	fmt.Fprintf(c.Out(), "Friend request sent: %+v\n", resp)
 
 	return nil
 }
@@ -263,10 +290,11 @@ func FriendResponse(ctx context.Context, c *types.Client, friendReq *pb.FriendRe
 
 	resp, err := c.PBC.SendPayload(ctx, &payload)
 	if err != nil {
-		return fmt.Errorf("failed to confirm chat: %v", err)
+		return fmt.Errorf("failed to confirm chat: %w", serverError(err))
 	}
 
 	if state {
+		network.WarnKeyChange(ctx, c, friendReq.UserInfo.UserId, friendReq.UserInfo.EncryptionPublicKey, friendReq.UserInfo.SigningPublicKey)
 		_, err = c.DB.Friends.SaveUserDetails.ExecContext(ctx, friendReq.UserInfo.UserId, friendReq.UserInfo.Username, friendReq.SenderDomain, friendReq.UserInfo.EncryptionPublicKey, friendReq.UserInfo.SigningPublicKey)
 		if err != nil {
 			return fmt.Errorf("failed adding to address book: %v", err)
@@ -275,11 +303,11 @@ func FriendResponse(ctx context.Context, c *types.Client, friendReq *pb.FriendRe
 
 	_, err = c.DB.FriendRequest.DeleteFriendRequest.ExecContext(context.TODO(), friendReq.UserInfo.UserId)
 	if err != nil {
-		fmt.Printf("failed deleting friend request: %v", err)
+		fmt.Fprintf(c.Out(), "failed deleting friend request: %v", err)
 		return err
 	}
 
-	fmt.Printf("Friend request acknowledged: %+v\n", resp)
+	fmt.Fprintf(c.Out(), "Friend request acknowledged: %+v\n", resp)
 
 	return nil
 }
@@ -296,18 +324,18 @@ func RegisterStatus(c *types.Client) error {
 
 	stream, err := c.PBC.StatusStream(context.TODO(), &userInfo)
 	if err != nil {
-		log.Printf("status failure: %v\n", err)
-		return err
+		slog.Error("status failure", "error", err)
+		return serverError(err)
 	}
 
 	for {
 		connectionStream, err := stream.Recv()
 		if err != nil {
-			log.Printf("Failed to connect to Status stream: %v\n", err)
+			slog.Error("status stream failed", "error", err)
 			return err
 		}
 
-		fmt.Printf("%s Status: %s\n", c.Identity.Username, connectionStream.Message)
+		fmt.Fprintf(c.Out(), "%s Status: %s\n", c.Identity.Username, connectionStream.Message)
 	}
 
 }
@@ -315,8 +343,8 @@ func RegisterStatus(c *types.Client) error {
 func GetActiveUsers(c *types.Client, uinfo *common_pb.UserInfo) (*common_pb.Users, error) {
 	activeUsers, err := c.PBC.OnlineUsers(context.TODO(), uinfo)
 	if err != nil {
-		log.Printf("error getting active users: %v\n", err)
-		return nil, err
+		slog.Error("error getting active users", "error", err)
+		return nil, serverError(err)
 	}
 
 	return activeUsers, nil
@@ -330,8 +358,8 @@ func PollServer(c *types.Client) (*pb.ServerInfo, error) {
 		SigningPublicKey:    c.Identity.Keys["SigningPublicKey"],
 	})
 	if err != nil {
-		log.Printf("error polling server: %v\n", err)
-		return nil, err
+		slog.Error("error polling server", "error", err)
+		return nil, serverError(err)
 	}
 
 	return sInfo, nil
@@ -345,16 +373,108 @@ func SyncDomain(c *types.Client) error {
 	}
 
 	c.Identity.Domain = sInfo.Domain
-	log.Printf("Home domain: %s\n", c.Identity.Domain)
+	slog.Debug("home domain synced", "domain", c.Identity.Domain)
 	return nil
 }
 
 // TODO: Handle all input like this?
 func LoginInput(prompt string, reader *bufio.Reader) (string, error) {
-	fmt.Print(prompt)
+	return PromptInput(os.Stdout, prompt, reader)
+}
+
+// PromptInput is LoginInput with the prompt written to w.
+func PromptInput(w io.Writer, prompt string, reader *bufio.Reader) (string, error) {
+	fmt.Fprint(w, prompt)
 	input, err := reader.ReadString('\n')
 	if err != nil {
 		return "", fmt.Errorf("error reading input: %w", err)
 	}
 	return strings.TrimSpace(input), nil
 }
+
+// FindFriend looks a friend up by address. Usernames are unique in the
+// address book, so the username picks the entry and the domain must match
+// it; a bare username matches a friend on our own server.
+func FindFriend(ctx context.Context, c *types.Client, addr shared.StrikeAddress) (*types.User, error) {
+	var targetid string
+	if err := c.DB.Friends.GetUserId.QueryRowContext(ctx, addr.Username).Scan(&targetid); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s is not a friend", addr.Format())
+		}
+		return nil, err
+	}
+
+	u := types.User{}
+	var created time.Time
+	row := c.DB.Friends.GetUser.QueryRowContext(ctx, targetid)
+	if err := row.Scan(&u.Id, &u.Name, &u.Domain, &u.Enckey, &u.Sigkey, &u.KeyEx, &created); err != nil {
+		return nil, err
+	}
+
+	domain := addr.Domain
+	if domain == "" {
+		domain = c.Identity.Domain
+	}
+	if u.Domain != "" && u.Domain != domain {
+		return nil, fmt.Errorf("%s is not a friend (%s is)", addr.Format(), shared.FormatAddress(u.Name, u.Domain))
+	}
+	return &u, nil
+}
+
+// FriendChat finds a friend by address and derives the key your messages
+// with them are sealed under, leaving the shell's open chat alone.
+func FriendChat(ctx context.Context, c *types.Client, addr shared.StrikeAddress) (*types.User, []byte, error) {
+	u, err := FindFriend(ctx, c, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := network.FriendChatKey(c, u.Enckey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return u, key, nil
+}
+
+// Unfriend ends a friendship. The friend is sent a signed notice so their
+// client forgets us too, then our address book entry and key exchange state
+// are dropped. History is kept unless purge is set.
+func Unfriend(ctx context.Context, c *types.Client, addr shared.StrikeAddress, purge bool) (*types.User, error) {
+	u, err := FindFriend(ctx, c, addr)
+	if err != nil {
+		return nil, err
+	}
+	targetid := u.Id.String()
+
+	priv, err := keys.ParseSigningPrivateKey(c.Identity.Keys["SigningPrivateKey"])
+	if err != nil {
+		return nil, err
+	}
+	notice := &common_pb.FriendRemoved{
+		UserId:    c.Identity.ID.String(),
+		Target:    targetid,
+		RemovedAt: timestamppb.Now(),
+	}
+	notice.Signature = ed25519.Sign(priv, shared.FriendRemovedBytes(notice))
+
+	payload := pb.StreamPayload{
+		Target:       targetid,
+		Sender:       c.Identity.ID.String(),
+		SenderDomain: c.Identity.Domain,
+		TargetDomain: u.Domain,
+		Payload:      &pb.StreamPayload_FriendRemoved{FriendRemoved: notice},
+		Info:         "Friend Removed payload",
+	}
+	if _, err := c.PBC.SendPayload(ctx, &payload); err != nil {
+		return nil, fmt.Errorf("failed to send removal: %w", serverError(err))
+	}
+
+	if err := network.ForgetFriend(ctx, c, targetid); err != nil {
+		return nil, err
+	}
+	if purge {
+		if _, err := c.DB.Messages.DeleteConversation.ExecContext(ctx, targetid); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
@@ -15,6 +15,20 @@ import (
 )
 
 func DeriveKeys(c *types.Client, sct []byte) ([]byte, []byte, error) {
+	encKey, hmacKey, err := DeriveChatKeys(sct)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.State.Cache.CurrentChat.EncKey = encKey
+	c.State.Cache.CurrentChat.HmacKey = hmacKey
+
+	return encKey, hmacKey, nil
+}
+
+// DeriveChatKeys expands a shared secret into the chat encryption and HMAC
+// keys without touching client state.
+func DeriveChatKeys(sct []byte) ([]byte, []byte, error) {
 
 	if len(sct) == 0 {
 		return nil, nil, fmt.Errorf("shared secret cannot be empty")
@@ -31,17 +45,26 @@ func DeriveKeys(c *types.Client, sct []byte) ([]byte, []byte, error) {
 		return nil, nil, err
 	}
 
-	c.State.Cache.CurrentChat.EncKey = encKey
-
 	if _, err := io.ReadFull(d, hmacKey); err != nil {
 		return nil, nil, err
 	}
 
-	c.State.Cache.CurrentChat.HmacKey = hmacKey
-
 	return encKey, hmacKey, nil
 }
 
+// SearchKey derives the key the local search index hashes words under from
+// the user's encryption private key.
+func SearchKey(priv []byte) ([]byte, error) {
+	if len(priv) == 0 {
+		return nil, fmt.Errorf("private key cannot be empty")
+	}
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, priv, nil, []byte("strike-search-index")), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
 func VerifyEdSignatures(pubKey ed25519.PublicKey, nonce, CurvePublicKey []byte, sigs [][]byte) bool {
 	if len(sigs) < 2 {
 		return false
@@ -55,7 +78,16 @@ func VerifyEdSignatures(pubKey ed25519.PublicKey, nonce, CurvePublicKey []byte,
 }
 
 func Encrypt(c *types.Client, plaintext []byte) ([]byte, error) {
-	block, err := aes.NewCipher(c.State.Cache.CurrentChat.EncKey)
+	return Seal(c.State.Cache.CurrentChat.EncKey, plaintext)
+}
+
+func Decrypt(c *types.Client, sealedMessage []byte) ([]byte, error) {
+	return Open(c.State.Cache.CurrentChat.EncKey, sealedMessage)
+}
+
+// Seal encrypts plaintext with AES-GCM under key, prefixing the nonce.
+func Seal(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
@@ -78,8 +110,9 @@ func Encrypt(c *types.Client, plaintext []byte) ([]byte, error) {
 	return sealedMessage, nil
 }
 
-func Decrypt(c *types.Client, sealedMessage []byte) ([]byte, error) {
-	block, err := aes.NewCipher(c.State.Cache.CurrentChat.EncKey)
+// Open reverses Seal.
+func Open(key, sealedMessage []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,476 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/localsock"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+)
+
+const (
+	// streamRetry is how long to wait before reopening a stream the server
+	// closed.
+	streamRetry = 5 * time.Second
+
+	// daemonEventBuffer is how many events a slow subscriber may fall
+	// behind before further events are dropped for it.
+	daemonEventBuffer = 64
+
+	// daemonMaxRequest bounds one request line.
+	daemonMaxRequest = 1 << 20
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcCallFailed     = -32000
+)
+
+// rpcRequest, rpcResponse and rpcNotification are the daemon's wire format:
+// JSON-RPC 2.0, one object per line over a Unix socket. A connection stays
+// open for any number of calls.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+type rpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type daemonMethod func(ctx context.Context, params json.RawMessage) (any, error)
+
+// daemon serves one logged in client to local frontends.
+type daemon struct {
+	c *types.Client
+
+	// mu serialises calls, so frontends changing friends or answering the
+	// same request do not interleave. Sends and history reads resolve the
+	// friend themselves and never touch the shell's open chat.
+	mu sync.Mutex
+
+	subsMu sync.Mutex
+	subs   map[chan types.Event]struct{}
+}
+
+// ServeDaemon keeps the payload and status streams of a logged in client
+// open and serves a JSON-RPC API on socketPath, so several of the user's
+// frontends can share one session. It returns when ctx is done.
+func ServeDaemon(ctx context.Context, c *types.Client, socketPath string) error {
+	ln, err := localsock.Listen(socketPath)
+	if errors.Is(err, localsock.ErrInUse) {
+		return fmt.Errorf("daemon already running on %s", socketPath)
+	}
+	if err != nil {
+		return fmt.Errorf("daemon: %v", err)
+	}
+	defer os.Remove(socketPath)
+
+	d := &daemon{c: c, subs: make(map[chan types.Event]struct{})}
+	c.State.Notify = d.publish
+
+	KeepStreams(ctx, c)
+	go RetainMessages(ctx, c)
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	slog.Info("daemon listening", "socket", socketPath, "user", c.Identity.Username)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				slog.Info("daemon stopped")
+				return nil
+			}
+			return fmt.Errorf("daemon: %v", err)
+		}
+		go d.serveConn(ctx, conn)
+	}
+}
+
+// KeepStreams runs the payload and status streams in the background until
+// ctx is done, reopening either whenever the server closes it.
+func KeepStreams(ctx context.Context, c *types.Client) {
+	go keepOpen(ctx, "payload stream", func() error { return ConnectPayloadStream(ctx, c) })
+	go keepOpen(ctx, "status stream", func() error { return RegisterStatus(c) })
+}
+
+// keepOpen runs a stream until ctx is done, reopening it whenever it ends.
+func keepOpen(ctx context.Context, name string, run func() error) {
+	for {
+		err := run()
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn(name+" closed, reopening", "error", err, "retry", streamRetry)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(streamRetry):
+		}
+	}
+}
+
+// publish hands ev to every subscriber without waiting on any of them.
+func (d *daemon) publish(ev types.Event) {
+	d.subsMu.Lock()
+	defer d.subsMu.Unlock()
+	for ch := range d.subs {
+		select {
+		case ch <- ev:
+		default:
+			slog.Warn("daemon: subscriber too slow, event dropped", "type", ev.Type)
+		}
+	}
+}
+
+func (d *daemon) subscribe() chan types.Event {
+	ch := make(chan types.Event, daemonEventBuffer)
+	d.subsMu.Lock()
+	d.subs[ch] = struct{}{}
+	d.subsMu.Unlock()
+	return ch
+}
+
+func (d *daemon) unsubscribe(ch chan types.Event) {
+	d.subsMu.Lock()
+	delete(d.subs, ch)
+	d.subsMu.Unlock()
+}
+
+func (d *daemon) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var writeMu sync.Mutex
+	enc := json.NewEncoder(conn)
+	write := func(v any) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if err := enc.Encode(v); err != nil {
+			slog.Debug("daemon: write failed", "error", err)
+		}
+	}
+
+	var events chan types.Event
+	defer func() {
+		if events != nil {
+			d.unsubscribe(events)
+			close(events)
+		}
+	}()
+
+	methods := d.methods()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), daemonMaxRequest)
+
+	for scanner.Scan() {
+		var req rpcRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			write(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, err.Error()}})
+			continue
+		}
+		id := req.ID
+		if id == nil {
+			id = json.RawMessage("null")
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			write(rpcResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{rpcInvalidRequest, "invalid request"}})
+			continue
+		}
+
+		var (
+			result any
+			err    error
+		)
+		if req.Method == "subscribe" {
+			if events == nil {
+				events = d.subscribe()
+				go func(events chan types.Event) {
+					for ev := range events {
+						write(rpcNotification{JSONRPC: "2.0", Method: "event", Params: ev})
+					}
+				}(events)
+			}
+			result = map[string]bool{"subscribed": true}
+		} else if m, ok := methods[req.Method]; ok {
+			d.mu.Lock()
+			result, err = m(ctx, req.Params)
+			d.mu.Unlock()
+		} else {
+			err = &rpcError{rpcMethodNotFound, "unknown method " + req.Method}
+		}
+
+		// Requests without an id are notifications and get no answer.
+		if req.ID == nil {
+			continue
+		}
+		resp := rpcResponse{JSONRPC: "2.0", ID: id, Result: result}
+		if err != nil {
+			var rerr *rpcError
+			if !errors.As(err, &rerr) {
+				rerr = &rpcError{rpcCallFailed, err.Error()}
+			}
+			resp.Result, resp.Error = nil, rerr
+		}
+		write(resp)
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		slog.Debug("daemon: connection closed", "error", err)
+	}
+}
+
+type daemonAddress struct {
+	Address string `json:"address"`
+	Purge   bool   `json:"purge,omitempty"`
+}
+
+type daemonSend struct {
+	To      string `json:"to"`
+	Message string `json:"message"`
+}
+
+type daemonHistory struct {
+	With  string `json:"with"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+type daemonUser struct {
+	Username string `json:"username"`
+	Domain   string `json:"domain"`
+	UserID   string `json:"user_id"`
+}
+
+type daemonFriend struct {
+	daemonUser
+	Verified      bool `json:"verified"`
+	KeysExchanged bool `json:"keys_exchanged"`
+}
+
+type daemonChat struct {
+	daemonUser
+	Messages    int       `json:"messages"`
+	LastMessage time.Time `json:"last_message_at"`
+}
+
+type daemonMessage struct {
+	Direction string    `json:"direction"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+func (d *daemon) methods() map[string]daemonMethod {
+	return map[string]daemonMethod{
+		"send":             d.send,
+		"chats.list":       d.listChats,
+		"messages.list":    d.listMessages,
+		"friends.list":     d.listFriends,
+		"friends.add":      d.addFriend,
+		"friends.remove":   d.removeFriend,
+		"requests.list":    d.listRequests,
+		"requests.accept":  d.answerRequest(true),
+		"requests.decline": d.answerRequest(false),
+	}
+}
+
+// decodeParams unmarshals params into v, reporting bad input as invalid
+// params.
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return &rpcError{rpcInvalidParams, "missing params"}
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{rpcInvalidParams, err.Error()}
+	}
+	return nil
+}
+
+func parseParamAddress(s string) (shared.StrikeAddress, error) {
+	addr, err := shared.ParseAddress(s)
+	if err != nil {
+		return addr, &rpcError{rpcInvalidParams, err.Error()}
+	}
+	return addr, nil
+}
+
+func (d *daemon) send(ctx context.Context, params json.RawMessage) (any, error) {
+	var p daemonSend
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.To == "" || p.Message == "" {
+		return nil, &rpcError{rpcInvalidParams, "send needs to and message"}
+	}
+	addr, err := parseParamAddress(p.To)
+	if err != nil {
+		return nil, err
+	}
+
+	u, key, err := FriendChat(ctx, d.c, addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := SendTo(ctx, d.c, u, key, p.Message); err != nil {
+		return nil, err
+	}
+	return map[string]any{"to": shared.FormatAddress(u.Name, u.Domain), "sent": true}, nil
+}
+
+func (d *daemon) listChats(ctx context.Context, _ json.RawMessage) (any, error) {
+	rows, err := d.c.DB.Messages.ListChats.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chats := []daemonChat{}
+	for rows.Next() {
+		var ch daemonChat
+		var last int64
+		if err := rows.Scan(&ch.UserID, &ch.Username, &ch.Domain, &ch.Messages, &last); err != nil {
+			return nil, err
+		}
+		ch.LastMessage = time.UnixMilli(last)
+		chats = append(chats, ch)
+	}
+	return chats, rows.Err()
+}
+
+func (d *daemon) listMessages(ctx context.Context, params json.RawMessage) (any, error) {
+	var p daemonHistory
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	addr, err := parseParamAddress(p.With)
+	if err != nil {
+		return nil, err
+	}
+
+	u, key, err := FriendChat(ctx, d.c, addr)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := FriendHistory(ctx, d.c, u, key)
+	if err != nil {
+		return nil, err
+	}
+	if p.Limit > 0 && len(msgs) > p.Limit {
+		msgs = msgs[len(msgs)-p.Limit:]
+	}
+
+	list := make([]daemonMessage, 0, len(msgs))
+	for _, m := range msgs {
+		list = append(list, daemonMessage{m.Direction, string(m.Content), time.UnixMilli(m.Timestamp)})
+	}
+	return list, nil
+}
+
+func (d *daemon) listFriends(ctx context.Context, _ json.RawMessage) (any, error) {
+	friends, err := Friends(d.c)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]daemonFriend, 0, len(friends))
+	for _, f := range friends {
+		list = append(list, daemonFriend{daemonUser{f.Name, f.Domain, f.Id.String()}, f.Verified, f.KeyEx != 0})
+	}
+	return list, nil
+}
+
+func (d *daemon) addFriend(ctx context.Context, params json.RawMessage) (any, error) {
+	var p daemonAddress
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	addr, err := parseParamAddress(p.Address)
+	if err != nil {
+		return nil, err
+	}
+	u, err := AddFriend(ctx, d.c, addr)
+	if err != nil {
+		return nil, err
+	}
+	return daemonUser{u.Username, u.Domain, u.UserId}, nil
+}
+
+func (d *daemon) removeFriend(ctx context.Context, params json.RawMessage) (any, error) {
+	var p daemonAddress
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	addr, err := parseParamAddress(p.Address)
+	if err != nil {
+		return nil, err
+	}
+	u, err := Unfriend(ctx, d.c, addr, p.Purge)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"user": daemonUser{u.Name, u.Domain, u.Id.String()}, "purged": p.Purge}, nil
+}
+
+func (d *daemon) listRequests(ctx context.Context, _ json.RawMessage) (any, error) {
+	frs, err := FriendRequests(d.c)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]daemonUser, 0, len(frs))
+	for _, fr := range frs {
+		list = append(list, daemonUser{fr.Username, fr.Domain, fr.FriendId.String()})
+	}
+	return list, nil
+}
+
+func (d *daemon) answerRequest(accept bool) daemonMethod {
+	return func(ctx context.Context, params json.RawMessage) (any, error) {
+		var p daemonAddress
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		addr, err := parseParamAddress(p.Address)
+		if err != nil {
+			return nil, err
+		}
+		fr, err := AnswerFriendRequest(ctx, d.c, addr, accept)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"user": daemonUser{fr.Username, fr.Domain, fr.FriendId.String()}, "accepted": accept}, nil
+	}
+}
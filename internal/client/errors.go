@@ -0,0 +1,115 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUserNotFound is returned when a lookup finds no account at an address.
+var ErrUserNotFound = errors.New("user not found")
+
+// serverError turns a gRPC status from the Strike server into a message a
+// user can act on, using the structured details where the server sent them.
+// Errors that are not gRPC statuses are returned unchanged.
+func serverError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() == codes.OK {
+		return err
+	}
+
+	switch st.Code() {
+	case codes.ResourceExhausted:
+		if retry := retryDelay(st); retry > 0 {
+			return fmt.Errorf("%s, try again in %s", st.Message(), retry)
+		}
+		return fmt.Errorf("%s, try again later", st.Message())
+
+	case codes.InvalidArgument:
+		var fields []string
+		for _, d := range st.Details() {
+			if br, ok := d.(*errdetails.BadRequest); ok {
+				for _, v := range br.FieldViolations {
+					fields = append(fields, v.Field+" "+v.Description)
+				}
+			}
+		}
+		if len(fields) > 0 {
+			return fmt.Errorf("invalid request: %s", strings.Join(fields, "; "))
+		}
+		return fmt.Errorf("invalid request: %s", st.Message())
+
+	case codes.NotFound:
+		if ri := resourceInfo(st); ri != nil {
+			return fmt.Errorf("%s %s not found", ri.ResourceType, ri.ResourceName)
+		}
+		return errors.New(st.Message())
+
+	case codes.AlreadyExists:
+		if ri := resourceInfo(st); ri != nil && ri.ResourceType == "user" {
+			return fmt.Errorf("username %s is already taken", ri.ResourceName)
+		}
+		return errors.New(st.Message())
+
+	case codes.Unauthenticated:
+		return errors.New(st.Message())
+
+	case codes.PermissionDenied:
+		if reason(st) == "ACCOUNT_DISABLED" {
+			return errors.New("this account has been disabled by the server administrator")
+		}
+		return errors.New(st.Message())
+
+	case codes.Unavailable:
+		if reason(st) == "SHUTTING_DOWN" {
+			return errors.New("server is shutting down, reconnect shortly")
+		}
+		return fmt.Errorf("server unavailable (%s), try again shortly", st.Message())
+
+	case codes.DeadlineExceeded:
+		return errors.New("server did not respond in time")
+
+	case codes.Internal, codes.Unknown:
+		return fmt.Errorf("server error: %s", st.Message())
+
+	default:
+		return err
+	}
+}
+
+// isNotFound reports whether err is a NotFound status from the server.
+func isNotFound(err error) bool {
+	return status.Code(err) == codes.NotFound
+}
+
+func retryDelay(st *status.Status) time.Duration {
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok && ri.RetryDelay != nil {
+			return ri.RetryDelay.AsDuration()
+		}
+	}
+	return 0
+}
+
+func resourceInfo(st *status.Status) *errdetails.ResourceInfo {
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.ResourceInfo); ok {
+			return ri
+		}
+	}
+	return nil
+}
+
+func reason(st *status.Status) string {
+	for _, d := range st.Details() {
+		if ei, ok := d.(*errdetails.ErrorInfo); ok {
+			return ei.Reason
+		}
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+)
+
+// ExportFormats are the formats ExportChat writes.
+var ExportFormats = []string{"json", "txt", "html"}
+
+type exportMessage struct {
+	From      string    `json:"from"`
+	Direction string    `json:"direction"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+type exportChat struct {
+	Self       string          `json:"self"`
+	With       string          `json:"with"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Messages   []exportMessage `json:"messages"`
+}
+
+var exportHTML = template.Must(template.New("chat").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Strike chat with {{.With}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; }
+.msg { margin: .4em 0; white-space: pre-wrap; }
+.time { color: #888; font-size: .85em; }
+.from { font-weight: bold; }
+.outbound .from { color: #06c; }
+</style>
+</head>
+<body>
+<h1>Chat with {{.With}}</h1>
+<p class="time">Exported by {{.Self}} on {{.ExportedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+{{range .Messages}}<div class="msg {{.Direction}}"><span class="time">{{.Time.Format "2006-01-02 15:04"}}</span> <span class="from">{{.From}}</span>: {{.Message}}</div>
+{{end}}</body>
+</html>
+`))
+
+// ExportChat writes your decrypted history with a friend to w as json, txt
+// or html and returns how many messages it wrote.
+func ExportChat(ctx context.Context, c *types.Client, addr shared.StrikeAddress, format string, w io.Writer) (int, error) {
+	if !slices.Contains(ExportFormats, format) {
+		return 0, fmt.Errorf("unknown export format %q", format)
+	}
+
+	u, key, err := FriendChat(ctx, c, addr)
+	if err != nil {
+		return 0, err
+	}
+	msgs, err := FriendHistory(ctx, c, u, key)
+	if err != nil {
+		return 0, err
+	}
+
+	chat := exportChat{
+		Self:       shared.FormatAddress(c.Identity.Username, c.Identity.Domain),
+		With:       shared.FormatAddress(u.Name, u.Domain),
+		ExportedAt: time.Now(),
+		Messages:   make([]exportMessage, 0, len(msgs)),
+	}
+	for _, msg := range msgs {
+		from := chat.With
+		if msg.Direction == "outbound" {
+			from = chat.Self
+		}
+		chat.Messages = append(chat.Messages, exportMessage{from, msg.Direction, string(msg.Content), time.UnixMilli(msg.Timestamp)})
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(chat)
+	case "txt":
+		for _, m := range chat.Messages {
+			if _, err = fmt.Fprintf(w, "%s %s: %s\n", m.Time.Local().Format(time.DateTime), m.From, trimNewline(m.Message)); err != nil {
+				break
+			}
+		}
+	case "html":
+		err = exportHTML.Execute(w, chat)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(chat.Messages), nil
+}
+
+// ExportChatFile is ExportChat to a file. The export is rendered in full
+// before anything is written and then moved over path, so a mistyped address
+// or a failed write leaves an existing file as it was.
+func ExportChatFile(ctx context.Context, c *types.Client, addr shared.StrikeAddress, format, path string) (int, error) {
+	var buf bytes.Buffer
+	n, err := ExportChat(ctx, c, addr, format, &buf)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name()) // no-op after rename
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	return n, os.Rename(tmp.Name(), path)
+}
+
+// trimNewline drops the newline the shell sends each message with.
+func trimNewline(s string) string {
+	if len(s) > 0 && s[len(s)-1] == '\n' {
+		return s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JohnnyGlynn/strike/internal/shared"
+)
+
+func TestExportChatFile(t *testing.T) {
+	ctx := context.Background()
+	c, db, ids := searchClient(t, false)
+	bob := shared.StrikeAddress{Username: "bob", Domain: "b.example"}
+
+	path := filepath.Join(t.TempDir(), "bob.txt")
+	const existing = "keep me\n"
+	if err := os.WriteFile(path, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed export leaves the file alone.
+	failures := map[string]struct {
+		addr   shared.StrikeAddress
+		format string
+	}{
+		"unknown-friend": {addr: shared.StrikeAddress{Username: "bbo", Domain: "b.example"}, format: "txt"},
+		"wrong-domain":   {addr: shared.StrikeAddress{Username: "bob", Domain: "c.example"}, format: "txt"},
+		"unknown-format": {addr: bob, format: "pdf"},
+		"unreadable":     {addr: bob, format: "txt"}, // a message in the history does not open
+	}
+	for name, tc := range failures {
+		if _, err := ExportChatFile(ctx, c, tc.addr, tc.format, path); err == nil {
+			t.Errorf("%s: export succeeded", name)
+		}
+		if got, err := os.ReadFile(path); err != nil || string(got) != existing {
+			t.Fatalf("%s: file = %q, %v after a failed export, want it unchanged", name, got, err)
+		}
+	}
+
+	if _, err := db.ExecContext(ctx, "DELETE FROM messages WHERE id = ?", ids["corrupt"]); err != nil {
+		t.Fatal(err)
+	}
+	n, err := ExportChatFile(ctx, c, bob, "txt", path)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("exported %d messages, want 3", n)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "Deploy the release tonight") || strings.Contains(string(got), existing) {
+		t.Errorf("file not replaced by the export:\n%s", got)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %d entries", len(entries))
+	}
+}
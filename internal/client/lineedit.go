@@ -0,0 +1,185 @@
+package client
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/peterh/liner"
+
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+)
+
+// NoShellHistory as shell_history keeps the history for the session only.
+const NoShellHistory = "none"
+
+// lineEditor reads shell input with tab completion and a history of the
+// commands entered. Chat messages are never added to the history.
+type lineEditor struct {
+	line     *liner.State
+	origMode liner.ModeApplier
+	history  string
+
+	// lineMode is the editor's terminal mode while cooked has put the
+	// original one back, nil otherwise.
+	lineMode liner.ModeApplier
+}
+
+func newLineEditor(c *types.Client, cmds map[string]types.Command) *lineEditor {
+	// Captured before liner changes it, so commands that prompt for
+	// passwords or confirmations get an ordinary terminal back.
+	origMode, _ := liner.TerminalMode()
+
+	e := &lineEditor{
+		line:     liner.NewLiner(),
+		origMode: origMode,
+		history:  c.Identity.Config.ShellHistory,
+	}
+	e.line.SetCtrlCAborts(true)
+	e.line.SetTabCompletionStyle(liner.TabPrints)
+	e.line.SetWordCompleter(func(line string, pos int) (string, []string, string) {
+		return completeInput(c, cmds, line, pos)
+	})
+
+	if e.history != "" && e.history != NoShellHistory {
+		f, err := os.Open(e.history)
+		if err == nil {
+			_, _ = e.line.ReadHistory(f)
+			f.Close()
+		} else if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("could not read shell history", "path", e.history, "error", err)
+		}
+	}
+	return e
+}
+
+// readLine prompts for one line. It returns io.EOF when input ends and
+// swallows Ctrl-C, which only clears the line.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	for {
+		input, err := e.line.Prompt(prompt)
+		if errors.Is(err, liner.ErrPromptAborted) {
+			continue
+		}
+		return input, err
+	}
+}
+
+// remember adds a command to the history and rewrites the history file, so
+// nothing is lost when a command exits the shell.
+func (e *lineEditor) remember(input string) {
+	e.line.AppendHistory(input)
+	if e.history == "" || e.history == NoShellHistory {
+		return
+	}
+	f, err := os.OpenFile(e.history, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		slog.Warn("could not save shell history", "path", e.history, "error", err)
+		return
+	}
+	defer f.Close()
+	if _, err := e.line.WriteHistory(f); err != nil {
+		slog.Warn("could not save shell history", "path", e.history, "error", err)
+	}
+}
+
+// cooked runs fn with the terminal mode the shell started with.
+func (e *lineEditor) cooked(fn func()) {
+	if e.origMode == nil {
+		fn()
+		return
+	}
+	lineMode, err := liner.TerminalMode()
+	if err != nil {
+		fn()
+		return
+	}
+	if err := e.origMode.ApplyMode(); err != nil {
+		slog.Debug("could not restore terminal mode", "error", err)
+	}
+	e.lineMode = lineMode
+	defer func() {
+		e.lineMode = nil
+		_ = lineMode.ApplyMode()
+	}()
+	fn()
+}
+
+// prompt reads a line for a command. Under cooked it switches back to the
+// editor's terminal mode for the prompt. Ctrl-C answers with an empty line,
+// which commands take as cancel.
+func (e *lineEditor) prompt(prompt string) (string, error) {
+	if e.lineMode != nil {
+		if err := e.lineMode.ApplyMode(); err == nil {
+			defer func() { _ = e.origMode.ApplyMode() }()
+		}
+	}
+	input, err := e.line.Prompt(prompt)
+	if errors.Is(err, liner.ErrPromptAborted) {
+		return "", nil
+	}
+	return strings.TrimSpace(input), err
+}
+
+func (e *lineEditor) Close() error {
+	return e.line.Close()
+}
+
+// completeInput completes the word under the cursor: a command name in the
+// first word, otherwise whatever the command's Complete offers.
+func completeInput(c *types.Client, cmds map[string]types.Command, line string, pos int) (string, []string, string) {
+	head, tail := line[:pos], line[pos:]
+	start := strings.LastIndexAny(head, " \t") + 1
+	word := head[start:]
+	head = head[:start]
+
+	var candidates []string
+	if strings.TrimSpace(head) == "" {
+		if !strings.HasPrefix(word, "/") {
+			return head, nil, tail
+		}
+		candidates = commandNames(cmds, c.State.Shell.Mode)
+	} else {
+		cmd, ok := cmds[strings.Fields(head)[0]]
+		if !ok || cmd.Complete == nil {
+			return head, nil, tail
+		}
+		candidates = cmd.Complete(c)
+	}
+
+	var matches []string
+	for _, cand := range candidates {
+		if strings.HasPrefix(cand, word) {
+			matches = append(matches, cand+" ")
+		}
+	}
+	return head, matches, tail
+}
+
+// commandNames lists the commands available in mode, sorted.
+func commandNames(cmds map[string]types.Command, mode types.ShellMode) []string {
+	var names []string
+	for name, cmd := range cmds {
+		if slices.Contains(cmd.Scope, mode) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// completeFriends offers the addresses in the address book.
+func completeFriends(c *types.Client) []string {
+	friends, err := loadFriends(c)
+	if err != nil {
+		return nil
+	}
+	addrs := make([]string, 0, len(friends))
+	for _, f := range friends {
+		addrs = append(addrs, shared.FormatAddress(f.Name, f.Domain))
+	}
+	return addrs
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+)
+
+func TestCompleteInput(t *testing.T) {
+	both := []types.ShellMode{types.ModeDefault, types.ModeChat}
+	cmds := map[string]types.Command{
+		"/chat":    {Name: "/chat", Scope: []types.ShellMode{types.ModeDefault}, Complete: func(*types.Client) []string { return []string{"alice@a.example", "alan@b.example", "bob@a.example"} }},
+		"/charset": {Name: "/charset", Scope: both},
+		"/exit":    {Name: "/exit", Scope: both},
+		"/history": {Name: "/history", Scope: []types.ShellMode{types.ModeChat}},
+	}
+
+	cases := map[string]struct {
+		mode    types.ShellMode
+		line    string
+		pos     int // -1 for the end of line
+		head    string
+		matches []string
+		tail    string
+	}{
+		"empty-line":       {line: "", pos: -1},
+		"chat-message":     {line: "hello th", pos: -1, head: "hello "},
+		"bare-word":        {line: "ch", pos: -1},
+		"all-commands":     {line: "/", pos: -1, matches: []string{"/charset ", "/chat ", "/exit "}},
+		"command-prefix":   {line: "/cha", pos: -1, matches: []string{"/charset ", "/chat "}},
+		"unique-command":   {line: "/ex", pos: -1, matches: []string{"/exit "}},
+		"leading-space":    {line: "  /ex", pos: -1, head: "  ", matches: []string{"/exit "}},
+		"no-such-command":  {line: "/zz", pos: -1},
+		"out-of-scope":     {line: "/hist", pos: -1},
+		"in-scope":         {mode: types.ModeChat, line: "/hist", pos: -1, matches: []string{"/history "}},
+		"argument":         {line: "/chat al", pos: -1, head: "/chat ", matches: []string{"alan@b.example ", "alice@a.example "}},
+		"argument-unique":  {line: "/chat b", pos: -1, head: "/chat ", matches: []string{"bob@a.example "}},
+		"every-argument":   {line: "/chat ", pos: -1, head: "/chat ", matches: []string{"alan@b.example ", "alice@a.example ", "bob@a.example "}},
+		"tab-separated":    {line: "/chat\tbo", pos: -1, head: "/chat\t", matches: []string{"bob@a.example "}},
+		"no-completer":     {line: "/exit no", pos: -1, head: "/exit "},
+		"unknown-command":  {line: "/zz al", pos: -1, head: "/zz "},
+		"cursor-mid-line":  {line: "/ex and more", pos: 3, matches: []string{"/exit "}, tail: " and more"},
+		"cursor-mid-arg":   {line: "/chat al bob", pos: 8, head: "/chat ", matches: []string{"alan@b.example ", "alice@a.example "}, tail: " bob"},
+		"cursor-at-start":  {line: "/chat", pos: 0, tail: "/chat"},
+		"no-matching-args": {line: "/chat zed", pos: -1, head: "/chat "},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &types.Client{State: &types.ClientState{Shell: &types.ShellState{Mode: tc.mode}}}
+			pos := tc.pos
+			if pos < 0 {
+				pos = len(tc.line)
+			}
+
+			head, matches, tail := completeInput(c, cmds, tc.line, pos)
+			slices.Sort(matches)
+			if head != tc.head || tail != tc.tail || !slices.Equal(matches, tc.matches) {
+				t.Errorf("completeInput(%q, %d) = %q, %q, %q, want %q, %q, %q",
+					tc.line, pos, head, matches, tail, tc.head, tc.matches, tc.tail)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package network
+
+// Unexported handlers, for the tests in network_test. Those need the client
+// package to prepare a database, and it imports this one.
+var (
+	BlockedSender        = blockedSender
+	ProcessEnvelope      = processEnvelope
+	ProcessFriendRemoved = processFriendRemoved
+)
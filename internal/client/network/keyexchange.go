@@ -8,7 +8,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/JohnnyGlynn/strike/internal/client/types"
 	pb "github.com/JohnnyGlynn/strike/msgdef/message"
@@ -20,7 +20,7 @@ func InitiateKeyExchange(ctx context.Context, c *types.Client, target uuid.UUID,
 	nonce := make([]byte, 32)
 	_, err := rand.Read(nonce)
 	if err != nil {
-		log.Printf("Error generating nonce: %v\n", err)
+		slog.Error("error generating nonce", "error", err)
 		return err
 	}
 
@@ -31,7 +31,7 @@ func InitiateKeyExchange(ctx context.Context, c *types.Client, target uuid.UUID,
 
 	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
 	if err != nil {
-		log.Print("failed to parse private key")
+		slog.Error("failed to parse private key", "error", err)
 		return err
 	}
 	// ok if ed25519
@@ -65,11 +65,11 @@ func InitiateKeyExchange(ctx context.Context, c *types.Client, target uuid.UUID,
 
 	resp, err := c.PBC.SendPayload(ctx, &payload)
 	if err != nil {
-		log.Printf("Error initiating key exchange: %v\n", err)
+		slog.Error("error initiating key exchange", "error", err)
 		return err
 	}
 
-	fmt.Printf("Key Exchange initiated: %v", resp.Success)
+	fmt.Fprintf(c.Out(), "Key Exchange initiated: %v", resp.Success)
 
 	return nil
 }
@@ -79,7 +79,7 @@ func ReciprocateKeyExchange(ctx context.Context, c *types.Client, target uuid.UU
 	nonce := make([]byte, 32)
 	_, err := rand.Read(nonce)
 	if err != nil {
-		log.Printf("Error generating nonce: %v\n", err)
+		slog.Error("error generating nonce", "error", err)
 		return err
 	}
 
@@ -122,11 +122,11 @@ func ReciprocateKeyExchange(ctx context.Context, c *types.Client, target uuid.UU
 
 	resp, err := c.PBC.SendPayload(ctx, &payload)
 	if err != nil {
-		log.Printf("Error reciprocating key exchange: %v\n", err)
+		slog.Error("error reciprocating key exchange", "error", err)
 		return err
 	}
 
-	fmt.Printf("Key Exchange reciprocated: %v", resp.Success)
+	fmt.Fprintf(c.Out(), "Key Exchange reciprocated: %v", resp.Success)
 
 	return nil
 }
@@ -148,11 +148,11 @@ func ConfirmKeyExchange(ctx context.Context, c *types.Client, target uuid.UUID,
 
 	resp, err := c.PBC.SendPayload(ctx, &payload)
 	if err != nil {
-		log.Printf("Error confirming key exchange: %v\n", err)
+		slog.Error("error confirming key exchange", "error", err)
 		return err
 	}
 
-	fmt.Printf("Key exchange confirmed: %v", resp.Success)
+	fmt.Fprintf(c.Out(), "Key exchange confirmed: %v", resp.Success)
 
 	return nil
 }
@@ -178,7 +178,7 @@ func ComputeSharedSecret(privateCurveKey []byte, inboundKey []byte) ([]byte, err
 
 	sharedSecret, err := private.ECDH(public)
 	if err != nil {
-		log.Printf("failed to carry out diffie hellman key exchange: %v", err)
+		slog.Error("failed to carry out diffie hellman key exchange", "error", err)
 		return nil, fmt.Errorf("failed to compute shared secret: %v", err)
 	}
 
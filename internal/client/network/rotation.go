@@ -0,0 +1,162 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/JohnnyGlynn/strike/internal/client/crypto"
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+	"github.com/google/uuid"
+)
+
+// processKeyRotation moves a friend to their new keys once the rotation
+// chains back to the signing key in our address book, then re-runs key
+// exchange. A notice that skips rotations we missed is checked against the
+// full history from the friend's server.
+func processKeyRotation(ctx context.Context, r *common_pb.KeyRotation, c *types.Client) error {
+	u := types.User{}
+	var created time.Time
+	row := c.DB.Friends.GetUser.QueryRowContext(ctx, r.UserId)
+	err := row.Scan(&u.Id, &u.Name, &u.Domain, &u.Enckey, &u.Sigkey, &u.KeyEx, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		slog.Debug("key rotation for unknown user ignored", "user_id", r.UserId)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up rotating user: %v", err)
+	}
+
+	if bytes.Equal(r.NewSigningPublicKey, u.Sigkey) {
+		return nil // already applied
+	}
+
+	latest := r
+	if !bytes.Equal(r.OldSigningPublicKey, u.Sigkey) {
+		hist, err := c.PBC.KeyHistory(ctx, &common_pb.UserAddress{Username: u.Name, Domain: u.Domain})
+		if err != nil {
+			return fmt.Errorf("failed to fetch key history: %v", err)
+		}
+		latest, err = shared.FollowKeyChain(u.Sigkey, hist.Rotations)
+		if err != nil {
+			return err
+		}
+		if latest == nil {
+			return fmt.Errorf("no rotation chains back to the key we hold")
+		}
+	} else if err := shared.VerifyKeyRotation(r, u.Sigkey); err != nil {
+		return err
+	}
+
+	if err := ResealConversation(ctx, c, u.Id, c.Identity.Keys["EncryptionPrivateKey"], u.Enckey, c.Identity.Keys["EncryptionPrivateKey"], latest.NewEncryptionPublicKey); err != nil {
+		return fmt.Errorf("failed to re-seal messages: %v", err)
+	}
+
+	WarnKeyChange(ctx, c, r.UserId, latest.NewEncryptionPublicKey, latest.NewSigningPublicKey)
+	if _, err := c.DB.Friends.UpdateKeys.ExecContext(ctx, latest.NewEncryptionPublicKey, latest.NewSigningPublicKey, r.UserId); err != nil {
+		return err
+	}
+
+	if c.State.Cache.CurrentChat.User.Id == u.Id {
+		if err := refreshChat(c, latest.NewEncryptionPublicKey, latest.NewSigningPublicKey); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(c.Out(), "%s rotated their keys\n", shared.FormatAddress(u.Name, u.Domain))
+
+	return InitiateKeyExchange(ctx, c, u.Id, u.Domain)
+}
+
+// refreshChat rebuilds the open chat session after either side's keys
+// changed.
+func refreshChat(c *types.Client, friendEncKey, friendSigKey []byte) error {
+	sharedSecret, err := ComputeSharedSecret(c.Identity.Keys["EncryptionPrivateKey"], friendEncKey)
+	if err != nil {
+		return err
+	}
+	encKey, hmacKey, err := crypto.DeriveChatKeys(sharedSecret)
+	if err != nil {
+		return err
+	}
+
+	chat := &c.State.Cache.CurrentChat
+	chat.User.Enckey = friendEncKey
+	chat.User.Sigkey = friendSigKey
+	chat.SharedSecret = sharedSecret
+	chat.EncKey = encKey
+	chat.HmacKey = hmacKey
+	return nil
+}
+
+// ResealConversation re-encrypts the stored messages with a friend under the
+// chat key for the new key pair, so history stays readable after either side
+// rotates. Messages the old key cannot open are left alone.
+func ResealConversation(ctx context.Context, c *types.Client, friend uuid.UUID, oldPriv, oldPub, newPriv, newPub []byte) error {
+	oldKey, err := chatKey(oldPriv, oldPub)
+	if err != nil {
+		return err
+	}
+	newKey, err := chatKey(newPriv, newPub)
+	if err != nil {
+		return err
+	}
+
+	rows, err := c.DB.Messages.GetMessages.QueryContext(ctx, friend.String())
+	if err != nil {
+		return err
+	}
+
+	var msgs []types.Message
+	for rows.Next() {
+		var msg types.Message
+		if err := rows.Scan(&msg.Id, &msg.FriendId, &msg.Direction, &msg.Content, &msg.Timestamp); err != nil {
+			rows.Close()
+			return err
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, msg := range msgs {
+		plain, err := crypto.Open(oldKey, msg.Content)
+		if err != nil {
+			slog.Debug("message not sealed with the old key, skipped", "id", msg.Id)
+			continue
+		}
+		sealed, err := crypto.Seal(newKey, plain)
+		if err != nil {
+			return err
+		}
+		if _, err := c.DB.Messages.Reseal.ExecContext(ctx, sealed, msg.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FriendChatKey is the key messages with the friend holding friendEncKey are
+// sealed under.
+func FriendChatKey(c *types.Client, friendEncKey []byte) ([]byte, error) {
+	return chatKey(c.Identity.Keys["EncryptionPrivateKey"], friendEncKey)
+}
+
+func chatKey(priv, pub []byte) ([]byte, error) {
+	sharedSecret, err := ComputeSharedSecret(priv, pub)
+	if err != nil {
+		return nil, err
+	}
+	encKey, _, err := crypto.DeriveChatKeys(sharedSecret)
+	return encKey, err
+}
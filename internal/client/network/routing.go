@@ -1,15 +1,19 @@
 package network
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/JohnnyGlynn/strike/internal/client/crypto"
 	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/keys"
 	"github.com/JohnnyGlynn/strike/internal/shared"
 	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
 	pb "github.com/JohnnyGlynn/strike/msgdef/message"
@@ -27,6 +31,10 @@ type Demultiplexer struct {
 	keyExchangeChannel             chan *pb.KeyExchangeRequest
 	keyExchangeResponseChannel     chan *pb.KeyExchangeResponse
 	keyExchangeConfirmationChannel chan *pb.KeyExchangeConfirmation
+	accountDeletedChannel          chan *common_pb.AccountDeleted
+	accountMovedChannel            chan *common_pb.AccountMoved
+	keyRotationChannel             chan *common_pb.KeyRotation
+	friendRemovedChannel           chan *common_pb.FriendRemoved
 
 	workers map[string]int
 	wrkMu   sync.Mutex
@@ -56,6 +64,10 @@ func NewDemultiplexer(c *types.Client) *Demultiplexer {
 		keyExchangeChannel:             make(chan *pb.KeyExchangeRequest, 20),
 		keyExchangeResponseChannel:     make(chan *pb.KeyExchangeResponse, 20),
 		keyExchangeConfirmationChannel: make(chan *pb.KeyExchangeConfirmation, 20),
+		accountDeletedChannel:          make(chan *common_pb.AccountDeleted, 20),
+		accountMovedChannel:            make(chan *common_pb.AccountMoved, 20),
+		keyRotationChannel:             make(chan *common_pb.KeyRotation, 20),
+		friendRemovedChannel:           make(chan *common_pb.FriendRemoved, 20),
 	}
 
 	mux := demuxRoutes(d, c)
@@ -75,8 +87,16 @@ func NewDemultiplexer(c *types.Client) *Demultiplexer {
 			registerRoute(d, rtype, c)
 		case routeBinding[*pb.KeyExchangeConfirmation]:
 			registerRoute(d, rtype, c)
+		case routeBinding[*common_pb.AccountDeleted]:
+			registerRoute(d, rtype, c)
+		case routeBinding[*common_pb.AccountMoved]:
+			registerRoute(d, rtype, c)
+		case routeBinding[*common_pb.KeyRotation]:
+			registerRoute(d, rtype, c)
+		case routeBinding[*common_pb.FriendRemoved]:
+			registerRoute(d, rtype, c)
 		default:
-			fmt.Printf("route not found %T", r)
+			fmt.Fprintf(c.Out(), "route not found %T", r)
 		}
 	}
 
@@ -108,7 +128,7 @@ func spwanEphemeral[T any](d *Demultiplexer, name string, ch <-chan T, processor
 			d.workers[name]--
 			d.wrkMu.Unlock()
 			d.wg.Done()
-			log.Printf("ephemeral %s shutdown...", name)
+			slog.Debug("ephemeral worker shutdown", "channel", name)
 		}()
 
 		timer := time.NewTimer(idleTimeout)
@@ -128,7 +148,7 @@ func spwanEphemeral[T any](d *Demultiplexer, name string, ch <-chan T, processor
 				timer.Reset(idleTimeout)
 				processor(msg)
 			case <-timer.C:
-				fmt.Println("time out")
+				slog.Debug("ephemeral worker idle", "channel", name)
 				return
 			}
 		}
@@ -138,7 +158,7 @@ func spwanEphemeral[T any](d *Demultiplexer, name string, ch <-chan T, processor
 func (d *Demultiplexer) Shutdown() {
 	d.cancel()
 	d.wg.Wait()
-	fmt.Println("Demux shutdown")
+	slog.Debug("demultiplexer shut down")
 }
 
 func (d *Demultiplexer) Dispatcher(msg *pb.StreamPayload) {
@@ -147,87 +167,164 @@ func (d *Demultiplexer) Dispatcher(msg *pb.StreamPayload) {
 		select {
 		case d.encenvelopeChannel <- payload.Encenv:
 		default:
-			log.Printf("WARNING: Channel full - Envelope dropped - Sender: %v\n", payload.Encenv.FromUser)
+			slog.Warn("channel full, envelope dropped", "sender", payload.Encenv.FromUser)
 		}
 	case *pb.StreamPayload_FriendRequest:
 		select {
 		case d.friendRequestChannel <- payload.FriendRequest:
 		default:
-			log.Printf("WARNING: Channel full - Friend Request dropped - Sender: %v\n", payload.FriendRequest.UserInfo.Username)
+			slog.Warn("channel full, friend request dropped", "sender", payload.FriendRequest.GetUserInfo().GetUsername())
 		}
 	case *pb.StreamPayload_FriendResponse:
 		select {
 		case d.friendResponseChannel <- payload.FriendResponse:
 		default:
-			log.Printf("WARNING: Channel full - Friend Response dropped - Sender: %v\n", payload.FriendResponse.UserInfo.Username)
+			slog.Warn("channel full, friend response dropped", "sender", payload.FriendResponse.GetUserInfo().GetUsername())
 		}
 	case *pb.StreamPayload_KeyExchRequest:
 		select {
 		case d.keyExchangeChannel <- payload.KeyExchRequest:
 		default:
-			log.Printf("WARNING: Channel full - Key exchange request dropped - Sender: %v\n", payload.KeyExchRequest)
+			slog.Warn("channel full, key exchange request dropped", "sender", payload.KeyExchRequest.GetSenderUserId())
 		}
 	case *pb.StreamPayload_KeyExchResponse:
 		select {
 		case d.keyExchangeResponseChannel <- payload.KeyExchResponse:
 		default:
-			log.Printf("WARNING: Channel full - Key exchange response dropped - Sender: %v\n", payload.KeyExchResponse)
+			slog.Warn("channel full, key exchange response dropped", "sender", payload.KeyExchResponse.GetResponderUserId())
 		}
 	case *pb.StreamPayload_KeyExchConfirm:
 		select {
 		case d.keyExchangeConfirmationChannel <- payload.KeyExchConfirm:
 		default:
-			log.Printf("WARNING: Channel full - Key exchange confirmation dropped - Sender: %v\n", payload.KeyExchConfirm)
+			slog.Warn("channel full, key exchange confirmation dropped", "sender", payload.KeyExchConfirm.GetConfirmerUserId())
+		}
+	case *pb.StreamPayload_AccountDeleted:
+		select {
+		case d.accountDeletedChannel <- payload.AccountDeleted:
+		default:
+			slog.Warn("channel full, account deleted notice dropped", "sender", payload.AccountDeleted.GetUserId())
+		}
+	case *pb.StreamPayload_AccountMoved:
+		select {
+		case d.accountMovedChannel <- payload.AccountMoved:
+		default:
+			slog.Warn("channel full, account moved notice dropped", "sender", payload.AccountMoved.GetUserId())
+		}
+	case *pb.StreamPayload_KeyRotation:
+		select {
+		case d.keyRotationChannel <- payload.KeyRotation:
+		default:
+			slog.Warn("channel full, key rotation dropped", "sender", payload.KeyRotation.GetUserId())
+		}
+	case *pb.StreamPayload_FriendRemoved:
+		select {
+		case d.friendRemovedChannel <- payload.FriendRemoved:
+		default:
+			slog.Warn("channel full, friend removal dropped", "sender", payload.FriendRemoved.GetUserId())
 		}
 
 	default:
-		log.Println("Unknown payload type")
+		slog.Warn("unknown payload type", "type", fmt.Sprintf("%T", payload))
+	}
+}
+
+// blockedSender reports whether the local block list names the sender or
+// its domain. The server drops these too; this covers payloads queued before
+// the block reached it.
+func blockedSender(ctx context.Context, c *types.Client, userID, domain string) bool {
+	var blocked bool
+	if err := c.DB.Blocks.IsBlocked.QueryRowContext(ctx, userID, domain).Scan(&blocked); err != nil {
+		slog.Warn("block list lookup failed", "error", err)
+		return false
 	}
+	return blocked
 }
 
 func processEnvelope(ctx context.Context, env *common_pb.EncryptedEnvelope, c *types.Client) error {
-	msg, err := crypto.Decrypt(c, env.EncryptedMessage)
+	if blockedSender(ctx, c, env.FromUser, "") {
+		slog.Debug("message from blocked user dropped", "sender", env.FromUser)
+		return nil
+	}
+	// Only friends can message us; someone removed with /unfriend has to
+	// send a new request first.
+	u := types.User{}
+	var created time.Time
+	err := c.DB.Friends.GetUser.QueryRowContext(ctx, env.FromUser).Scan(&u.Id, &u.Name, &u.Domain, &u.Enckey, &u.Sigkey, &u.KeyEx, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		slog.Debug("message from non-friend dropped", "sender", env.FromUser)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Derive the sender's chat key rather than using the open chat's, so
+	// messages from anyone else decrypt too.
+	key, err := FriendChatKey(c, u.Enckey)
+	if err != nil {
+		return err
+	}
+	msg, err := crypto.Open(key, env.EncryptedMessage)
 	if err != nil {
-		fmt.Printf("Failed to decrypt sealed message")
+		fmt.Fprintf(c.Out(), "Failed to decrypt sealed message")
 		return err
 	}
 
 	// TODO: Batch insert messages?
 	if c.State.Shell.Mode == types.ModeChat && env.FromUser == c.State.Cache.CurrentChat.User.Id.String() {
-		fmt.Printf("[%s]:%s\n", shared.FormatAddress(c.State.Cache.CurrentChat.User.Name, c.State.Cache.CurrentChat.User.Domain), msg)
+		fmt.Fprintf(c.Out(), "[%s]:%s\n", shared.FormatAddress(c.State.Cache.CurrentChat.User.Name, c.State.Cache.CurrentChat.User.Domain), msg)
 	}
 
 	_, err = c.DB.Messages.SaveMessage.ExecContext(ctx, uuid.New().String(), env.FromUser, "inbound", env.EncryptedMessage, env.SentAt.AsTime().UnixMilli())
 	if err != nil {
-		fmt.Printf("Failed to save message")
+		fmt.Fprintf(c.Out(), "Failed to save message")
 		return err
 	}
 
+	notify(c, types.Event{Type: types.EventMessage, UserID: env.FromUser, Username: u.Name, Domain: u.Domain, Message: string(msg), Time: env.SentAt.AsTime()})
 	return nil
 }
 
+// notify passes ev to the client's event hook, if any.
+func notify(c *types.Client, ev types.Event) {
+	if c.State.Notify != nil {
+		c.State.Notify(ev)
+	}
+}
+
 func processFriendRequest(ctx context.Context, fr *pb.FriendRequest, c *types.Client) error {
 
-	fmt.Printf("Friend Request from: %v\n", shared.FormatAddress(fr.UserInfo.Username, fr.SenderDomain))
+	if blockedSender(ctx, c, fr.UserInfo.GetUserId(), fr.SenderDomain) {
+		slog.Debug("friend request from blocked user dropped", "sender", fr.UserInfo.GetUserId())
+		return nil
+	}
 
-	_, err := c.DB.FriendRequest.SaveFriendRequest.ExecContext(ctx, fr.UserInfo.UserId, fr.UserInfo.Username, fr.SenderDomain, fr.UserInfo.EncryptionPublicKey, fr.UserInfo.SigningPublicKey, "inbound")
+	res, err := c.DB.FriendRequest.SaveFriendRequest.ExecContext(ctx, fr.UserInfo.UserId, fr.UserInfo.Username, fr.SenderDomain, fr.UserInfo.EncryptionPublicKey, fr.UserInfo.SigningPublicKey, "inbound", time.Now().UnixMilli())
 	if err != nil {
-		fmt.Printf("failed to save Friend Request")
+		fmt.Fprintf(c.Out(), "failed to save Friend Request")
 		return err
 	}
 
+	// A repeat of a request still waiting for an answer is not shown again.
+	if n, _ := res.RowsAffected(); n > 0 {
+		fmt.Fprintf(c.Out(), "Friend Request from: %v\n", shared.FormatAddress(fr.UserInfo.Username, fr.SenderDomain))
+		notify(c, types.Event{Type: types.EventFriendRequest, UserID: fr.UserInfo.UserId, Username: fr.UserInfo.Username, Domain: fr.SenderDomain, Time: time.Now()})
+	}
+
 	return nil
 
 }
 
 func processFriendResponse(ctx context.Context, fr *pb.FriendResponse, c *types.Client) error {
 
-	fmt.Printf("Friend Response from: %v\n", shared.FormatAddress(fr.UserInfo.Username, fr.SenderDomain))
+	fmt.Fprintf(c.Out(), "Friend Response from: %v\n", shared.FormatAddress(fr.UserInfo.Username, fr.SenderDomain))
 
 	if fr.State {
+		WarnKeyChange(ctx, c, fr.UserInfo.UserId, fr.UserInfo.EncryptionPublicKey, fr.UserInfo.SigningPublicKey)
 		_, err := c.DB.Friends.SaveUserDetails.ExecContext(ctx, fr.UserInfo.UserId, fr.UserInfo.Username, fr.SenderDomain, fr.UserInfo.EncryptionPublicKey, fr.UserInfo.SigningPublicKey)
 		if err != nil {
-			fmt.Printf("failed adding to address book: %v", err)
+			fmt.Fprintf(c.Out(), "failed adding to address book: %v", err)
 			return err
 		}
 
@@ -240,10 +337,11 @@ func processFriendResponse(ctx context.Context, fr *pb.FriendResponse, c *types.
 
 	_, err := c.DB.FriendRequest.DeleteFriendRequest.ExecContext(ctx, fr.UserInfo.UserId)
 	if err != nil {
-		fmt.Printf("failed deleting friend request: %v", err)
+		fmt.Fprintf(c.Out(), "failed deleting friend request: %v", err)
 		return err
 	}
 
+	notify(c, types.Event{Type: types.EventFriendResponse, UserID: fr.UserInfo.UserId, Username: fr.UserInfo.Username, Domain: fr.SenderDomain, Accepted: fr.State, Time: time.Now()})
 	return nil
 }
 
@@ -289,7 +387,7 @@ func processKeyExchangeResponse(ctx context.Context, kx *pb.KeyExchangeResponse,
 
 	err = ConfirmKeyExchange(ctx, c, uuid.MustParse(kx.ResponderUserId), true, u.Domain)
 	if err != nil {
-		fmt.Println("key exchange confirmation failed")
+		fmt.Fprintln(c.Out(), "key exchange confirmation failed")
 		return err
 	}
 
@@ -301,18 +399,18 @@ func processKeyExchangeConfirmation(ctx context.Context, kx *pb.KeyExchangeConfi
 	var confirmed int
 	err := c.DB.Friends.GetKeyEx.QueryRowContext(ctx, kx.ConfirmerUserId).Scan(&confirmed)
 	if err != nil && err != sql.ErrNoRows {
-		fmt.Printf("failed to query key exchange state")
+		fmt.Fprintf(c.Out(), "failed to query key exchange state")
 		return err
 	}
 
 	if confirmed != 0 {
-		fmt.Println("Keys have already been exchanged")
+		fmt.Fprintln(c.Out(), "Keys have already been exchanged")
 		return nil
 	}
 
 	_, err = c.DB.Friends.ConfirmKeyEx.ExecContext(ctx, true, kx.ConfirmerUserId)
 	if err != nil {
-		fmt.Println("failed to confirm key exchange locally")
+		fmt.Fprintln(c.Out(), "failed to confirm key exchange locally")
 		return err
 		//TODO: Retry mechanism?
 	}
@@ -327,16 +425,164 @@ func processKeyExchangeConfirmation(ctx context.Context, kx *pb.KeyExchangeConfi
 
 	err = ConfirmKeyExchange(ctx, c, uuid.MustParse(kx.ConfirmerUserId), true, u.Domain)
 	if err != nil {
-		fmt.Println("key exchange confirmation failed")
+		fmt.Fprintln(c.Out(), "key exchange confirmation failed")
 		return err
 	}
 
 	//Username
-	fmt.Printf("Keys have been exchanged with %s\n", kx.ConfirmerUserId)
+	fmt.Fprintf(c.Out(), "Keys have been exchanged with %s\n", kx.ConfirmerUserId)
 
 	return nil
 }
 
+// processAccountDeleted forgets a friend who deleted their account. The
+// notice must be signed by the key in our address book, so nobody else can
+// remove a friend this way.
+func processAccountDeleted(ctx context.Context, n *common_pb.AccountDeleted, c *types.Client) error {
+	u := types.User{}
+	var created time.Time
+	row := c.DB.Friends.GetUser.QueryRowContext(ctx, n.UserId)
+	err := row.Scan(&u.Id, &u.Name, &u.Domain, &u.Enckey, &u.Sigkey, &u.KeyEx, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		slog.Debug("deletion notice for unknown user ignored", "user_id", n.UserId)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up deleted user: %v", err)
+	}
+
+	pub, err := keys.ParseSigningPublicKey(u.Sigkey)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, shared.AccountDeletedBytes(n), n.Signature) {
+		slog.Warn("deletion notice with bad signature ignored", "user_id", n.UserId)
+		return fmt.Errorf("failed to verify signature")
+	}
+
+	if c.State.Shell.Mode == types.ModeChat && c.State.Cache.CurrentChat.User.Id == u.Id {
+		c.State.Cache.CurrentChat = types.ChatSession{}
+		c.State.Shell.Mode = types.ModeDefault
+	}
+
+	if _, err := c.DB.Messages.DeleteConversation.ExecContext(ctx, n.UserId); err != nil {
+		return err
+	}
+	if _, err := c.DB.FriendRequest.DeleteFriendRequest.ExecContext(ctx, n.UserId); err != nil {
+		return err
+	}
+	if _, err := c.DB.Friends.DeleteUser.ExecContext(ctx, n.UserId); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.Out(), "%s deleted their account and has been removed from your friends\n", shared.FormatAddress(u.Name, u.Domain))
+	return nil
+}
+
+// processFriendRemoved forgets a friend who removed us. As with a deletion
+// notice it must be signed by the key in our address book; the history is
+// left for the user to keep or delete.
+func processFriendRemoved(ctx context.Context, n *common_pb.FriendRemoved, c *types.Client) error {
+	if n.Target != c.Identity.ID.String() {
+		return fmt.Errorf("removal notice addressed to %s", n.Target)
+	}
+
+	u := types.User{}
+	var created time.Time
+	row := c.DB.Friends.GetUser.QueryRowContext(ctx, n.UserId)
+	err := row.Scan(&u.Id, &u.Name, &u.Domain, &u.Enckey, &u.Sigkey, &u.KeyEx, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		slog.Debug("removal notice for unknown user ignored", "user_id", n.UserId)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up removing user: %v", err)
+	}
+
+	pub, err := keys.ParseSigningPublicKey(u.Sigkey)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, shared.FriendRemovedBytes(n), n.Signature) {
+		slog.Warn("removal notice with bad signature ignored", "user_id", n.UserId)
+		return fmt.Errorf("failed to verify signature")
+	}
+	// A replayed notice must not end a friendship made after it.
+	if n.GetRemovedAt().AsTime().Before(created) {
+		slog.Debug("stale removal notice ignored", "user_id", n.UserId)
+		return nil
+	}
+
+	if err := ForgetFriend(ctx, c, u.Id.String()); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.Out(), "%s removed you from their friends\n", shared.FormatAddress(u.Name, u.Domain))
+	notify(c, types.Event{Type: types.EventFriendRemoved, UserID: n.UserId, Username: u.Name, Domain: u.Domain, Time: n.GetRemovedAt().AsTime()})
+	return nil
+}
+
+// ForgetFriend drops a friend's address book entry, and with it the key
+// exchange state, along with any pending request and an open chat. Messages
+// from them are refused until a new friend request is accepted.
+func ForgetFriend(ctx context.Context, c *types.Client, userID string) error {
+	if c.State.Shell.Mode == types.ModeChat && c.State.Cache.CurrentChat.User.Id.String() == userID {
+		c.State.Cache.CurrentChat = types.ChatSession{}
+		c.State.Shell.Mode = types.ModeDefault
+	}
+	if _, err := c.DB.FriendRequest.DeleteFriendRequest.ExecContext(ctx, userID); err != nil {
+		return err
+	}
+	_, err := c.DB.Friends.DeleteUser.ExecContext(ctx, userID)
+	return err
+}
+
+// processAccountMoved follows a friend to their new address. The notice must
+// be signed by the key in our address book, and the new address must serve
+// that same key, before the address book is updated.
+func processAccountMoved(ctx context.Context, n *common_pb.AccountMoved, c *types.Client) error {
+	u := types.User{}
+	var created time.Time
+	row := c.DB.Friends.GetUser.QueryRowContext(ctx, n.UserId)
+	err := row.Scan(&u.Id, &u.Name, &u.Domain, &u.Enckey, &u.Sigkey, &u.KeyEx, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		slog.Debug("move notice for unknown user ignored", "user_id", n.UserId)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up moved user: %v", err)
+	}
+
+	pub, err := keys.ParseSigningPublicKey(u.Sigkey)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, shared.AccountMovedBytes(n), n.Signature) {
+		slog.Warn("move notice with bad signature ignored", "user_id", n.UserId)
+		return fmt.Errorf("failed to verify signature")
+	}
+
+	moved, err := c.PBC.UserRequest(ctx, &common_pb.UserAddress{Username: n.NewUsername, Domain: n.NewDomain})
+	if err != nil {
+		return fmt.Errorf("failed to look up new address: %v", err)
+	}
+	if moved.UserId != n.UserId || !bytes.Equal(moved.SigningPublicKey, u.Sigkey) {
+		return fmt.Errorf("signing key at %s does not match", shared.FormatAddress(n.NewUsername, n.NewDomain))
+	}
+
+	if _, err := c.DB.Friends.UpdateAddress.ExecContext(ctx, n.NewUsername, n.NewDomain, n.UserId); err != nil {
+		return err
+	}
+
+	if c.State.Cache.CurrentChat.User.Id == u.Id {
+		c.State.Cache.CurrentChat.User.Name = n.NewUsername
+		c.State.Cache.CurrentChat.User.Domain = n.NewDomain
+	}
+
+	fmt.Fprintf(c.Out(), "%s is now %s\n", shared.FormatAddress(u.Name, u.Domain), shared.FormatAddress(n.NewUsername, n.NewDomain))
+	return nil
+}
+
 func registerRoute[T any](d *Demultiplexer, binding routeBinding[T], c *types.Client) {
 	d.spawnWorker(binding.name, func() {
 		binding.handler(d.ctx, binding.channel, c) //runs "forever"
@@ -511,6 +757,107 @@ func demuxRoutes(d *Demultiplexer, c *types.Client) []any {
 				}
 			},
 		},
+		routeBinding[*common_pb.AccountDeleted]{
+			name:        "accdel",
+			channel:     d.accountDeletedChannel,
+			threshold:   5,
+			maxWorkers:  2,
+			idleTimeout: 1 * time.Second,
+			processor: func(msg *common_pb.AccountDeleted) {
+				err := processAccountDeleted(d.ctx, msg, c)
+				if err != nil {
+					return
+				}
+			},
+			handler: func(ctx context.Context, ch <-chan *common_pb.AccountDeleted, c *types.Client) {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case msg := <-ch:
+						// A forged notice must not stop the worker.
+						if err := processAccountDeleted(ctx, msg, c); err != nil {
+							slog.Warn("deletion notice rejected", "user_id", msg.GetUserId(), "error", err)
+						}
+					}
+				}
+			},
+		},
+		routeBinding[*common_pb.AccountMoved]{
+			name:        "accmove",
+			channel:     d.accountMovedChannel,
+			threshold:   5,
+			maxWorkers:  2,
+			idleTimeout: 1 * time.Second,
+			processor: func(msg *common_pb.AccountMoved) {
+				err := processAccountMoved(d.ctx, msg, c)
+				if err != nil {
+					return
+				}
+			},
+			handler: func(ctx context.Context, ch <-chan *common_pb.AccountMoved, c *types.Client) {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case msg := <-ch:
+						if err := processAccountMoved(ctx, msg, c); err != nil {
+							slog.Warn("move notice rejected", "user_id", msg.GetUserId(), "error", err)
+						}
+					}
+				}
+			},
+		},
+		routeBinding[*common_pb.KeyRotation]{
+			name:        "keyrot",
+			channel:     d.keyRotationChannel,
+			threshold:   5,
+			maxWorkers:  2,
+			idleTimeout: 1 * time.Second,
+			processor: func(msg *common_pb.KeyRotation) {
+				err := processKeyRotation(d.ctx, msg, c)
+				if err != nil {
+					return
+				}
+			},
+			handler: func(ctx context.Context, ch <-chan *common_pb.KeyRotation, c *types.Client) {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case msg := <-ch:
+						if err := processKeyRotation(ctx, msg, c); err != nil {
+							slog.Warn("key rotation rejected", "user_id", msg.GetUserId(), "error", err)
+						}
+					}
+				}
+			},
+		},
+		routeBinding[*common_pb.FriendRemoved]{
+			name:        "unfriend",
+			channel:     d.friendRemovedChannel,
+			threshold:   5,
+			maxWorkers:  2,
+			idleTimeout: 1 * time.Second,
+			processor: func(msg *common_pb.FriendRemoved) {
+				err := processFriendRemoved(d.ctx, msg, c)
+				if err != nil {
+					return
+				}
+			},
+			handler: func(ctx context.Context, ch <-chan *common_pb.FriendRemoved, c *types.Client) {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case msg := <-ch:
+						if err := processFriendRemoved(ctx, msg, c); err != nil {
+							slog.Warn("friend removal rejected", "user_id", msg.GetUserId(), "error", err)
+						}
+					}
+				}
+			},
+		},
 		//Expansion
 		// routeBinding[*pb.]{
 		// 	name:        "",
@@ -545,13 +892,13 @@ func autoScaler[T any](d *Demultiplexer, name string, ch <-chan T, threshold int
 		for {
 			select {
 			case <-d.ctx.Done():
-				log.Printf("%s payload monitor shutting down", name)
+				slog.Debug("payload monitor shutting down", "channel", name)
 				return
 			case <-ticker.C:
 				d.wrkMu.Lock()
 				workerCount := d.workers[name]
 				if len(ch) > threshold && workerCount < maxWorkers {
-					log.Printf("Spawning ephemeral worker %d (channel: %s)", len(ch), name)
+					slog.Debug("spawning ephemeral worker", "channel", name, "backlog", len(ch))
 					spwanEphemeral(d, name, ch, processor, idleTimeout)
 				}
 				d.wrkMu.Unlock()
@@ -0,0 +1,277 @@
+package network_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	_ "modernc.org/sqlite"
+
+	"github.com/JohnnyGlynn/strike/internal/client"
+	"github.com/JohnnyGlynn/strike/internal/client/crypto"
+	"github.com/JohnnyGlynn/strike/internal/client/network"
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/config"
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+)
+
+// testKeys generates a full set of client keys.
+func testKeys(t *testing.T) map[string][]byte {
+	t.Helper()
+	sigPriv, sigPub, err := keys.GenerateSigningKeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encPriv, encPub, err := keys.GenerateEncryptionKeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string][]byte{
+		"SigningPrivateKey":    sigPriv,
+		"SigningPublicKey":     sigPub,
+		"EncryptionPrivateKey": encPriv,
+		"EncryptionPublicKey":  encPub,
+	}
+}
+
+// testFriend is bob@b.example, in the address book of the client returned
+// by routingClient since an hour ago.
+type testFriend struct {
+	id      string
+	keys    map[string][]byte
+	created time.Time
+}
+
+func (f testFriend) sign(t *testing.T, msg []byte) []byte {
+	t.Helper()
+	priv, err := keys.ParseSigningPrivateKey(f.keys["SigningPrivateKey"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ed25519.Sign(priv, msg)
+}
+
+func routingClient(t *testing.T) (*types.Client, *sql.DB, testFriend) {
+	t.Helper()
+	ctx := context.Background()
+	schema, err := os.ReadFile("../../../cmd/strike-client/client.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "client.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(string(schema)); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	if err := client.EnsureSchema(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := client.PrepareStatements(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &types.Client{
+		Identity: &types.ClientIdentity{ID: uuid.New(), Keys: testKeys(t), Config: &config.ClientConfig{}},
+		State:    &types.ClientState{Shell: &types.ShellState{}, Output: &bytes.Buffer{}},
+		DB:       stmts,
+	}
+
+	bob := testFriend{id: uuid.NewString(), keys: testKeys(t), created: time.Now().Add(-time.Hour).UTC()}
+	if _, err := db.ExecContext(ctx, "INSERT INTO addressbook (user_id, username, domain, enc_pkey, sig_pkey, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		bob.id, "bob", "b.example", bob.keys["EncryptionPublicKey"], bob.keys["SigningPublicKey"], bob.created); err != nil {
+		t.Fatal(err)
+	}
+	return c, db, bob
+}
+
+func count(t *testing.T, db *sql.DB, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestProcessFriendRemoved(t *testing.T) {
+	cases := map[string]struct {
+		notice      func(c *types.Client, bob testFriend) *common_pb.FriendRemoved
+		signer      func(t *testing.T, bob testFriend, msg []byte) []byte
+		errText     string
+		wantRemoved bool
+	}{
+		"removed": {
+			notice:      func(c *types.Client, bob testFriend) *common_pb.FriendRemoved { return removal(c, bob, time.Now()) },
+			wantRemoved: true,
+		},
+		"bad-signature": {
+			notice: func(c *types.Client, bob testFriend) *common_pb.FriendRemoved { return removal(c, bob, time.Now()) },
+			signer: func(t *testing.T, _ testFriend, msg []byte) []byte {
+				return testFriend{keys: testKeys(t)}.sign(t, msg)
+			},
+			errText: "failed to verify signature",
+		},
+		"unsigned": {
+			notice:  func(c *types.Client, bob testFriend) *common_pb.FriendRemoved { return removal(c, bob, time.Now()) },
+			signer:  func(*testing.T, testFriend, []byte) []byte { return nil },
+			errText: "failed to verify signature",
+		},
+		"stale": {
+			// A notice from before the friendship, replayed after bob was
+			// added again.
+			notice: func(c *types.Client, bob testFriend) *common_pb.FriendRemoved {
+				return removal(c, bob, bob.created.Add(-time.Minute))
+			},
+		},
+		"other-target": {
+			notice: func(c *types.Client, bob testFriend) *common_pb.FriendRemoved {
+				n := removal(c, bob, time.Now())
+				n.Target = uuid.NewString()
+				return n
+			},
+			errText: "removal notice addressed to",
+		},
+		"stranger": {
+			notice: func(c *types.Client, bob testFriend) *common_pb.FriendRemoved {
+				return removal(c, testFriend{id: uuid.NewString(), keys: bob.keys}, time.Now())
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c, db, bob := routingClient(t)
+
+			n := tc.notice(c, bob)
+			sign := tc.signer
+			if sign == nil {
+				sign = func(t *testing.T, bob testFriend, msg []byte) []byte { return bob.sign(t, msg) }
+			}
+			n.Signature = sign(t, bob, shared.FriendRemovedBytes(n))
+
+			err := network.ProcessFriendRemoved(context.Background(), n, c)
+			switch {
+			case tc.errText == "" && err != nil:
+				t.Fatalf("ProcessFriendRemoved: %v", err)
+			case tc.errText != "" && (err == nil || !strings.Contains(err.Error(), tc.errText)):
+				t.Fatalf("error = %v, want it to contain %q", err, tc.errText)
+			}
+
+			left := count(t, db, "SELECT COUNT(*) FROM addressbook WHERE user_id = ?", bob.id)
+			if removed := left == 0; removed != tc.wantRemoved {
+				t.Fatalf("friend removed = %v, want %v", removed, tc.wantRemoved)
+			}
+		})
+	}
+}
+
+// removal is bob's notice ending the friendship with c, left unsigned.
+func removal(c *types.Client, bob testFriend, at time.Time) *common_pb.FriendRemoved {
+	return &common_pb.FriendRemoved{UserId: bob.id, Target: c.Identity.ID.String(), RemovedAt: timestamppb.New(at)}
+}
+
+func TestProcessEnvelope(t *testing.T) {
+	cases := map[string]struct {
+		from      func(bob testFriend) string
+		setup     func(t *testing.T, db *sql.DB, bob testFriend)
+		wantSaved bool
+	}{
+		"friend":     {from: func(bob testFriend) string { return bob.id }, wantSaved: true},
+		"non-friend": {from: func(testFriend) string { return uuid.NewString() }},
+		"removed-friend": {
+			from: func(bob testFriend) string { return bob.id },
+			setup: func(t *testing.T, db *sql.DB, bob testFriend) {
+				if _, err := db.Exec("DELETE FROM addressbook WHERE user_id = ?", bob.id); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		"blocked-friend": {
+			from: func(bob testFriend) string { return bob.id },
+			setup: func(t *testing.T, db *sql.DB, bob testFriend) {
+				block(t, db, "user", bob.id)
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c, db, bob := routingClient(t)
+			if tc.setup != nil {
+				tc.setup(t, db, bob)
+			}
+
+			// Sealed as bob would, so only the checks before decryption
+			// can drop it.
+			key, err := network.FriendChatKey(c, bob.keys["EncryptionPublicKey"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			sealed, err := crypto.Seal(key, []byte("hello"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			env := &common_pb.EncryptedEnvelope{FromUser: tc.from(bob), ToUser: c.Identity.ID.String(), EncryptedMessage: sealed, SentAt: timestamppb.Now()}
+
+			if err := network.ProcessEnvelope(context.Background(), env, c); err != nil {
+				t.Fatalf("ProcessEnvelope: %v", err)
+			}
+			if saved := count(t, db, "SELECT COUNT(*) FROM messages") == 1; saved != tc.wantSaved {
+				t.Fatalf("message saved = %v, want %v", saved, tc.wantSaved)
+			}
+		})
+	}
+}
+
+func block(t *testing.T, db *sql.DB, kind, value string) {
+	t.Helper()
+	if _, err := db.Exec("INSERT INTO blocklist (kind, value) VALUES (?, ?)", kind, value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBlockedSender(t *testing.T) {
+	c, db, bob := routingClient(t)
+	block(t, db, "user", bob.id)
+	block(t, db, "domain", "spam.example")
+
+	cases := map[string]struct {
+		userID, domain string
+		want           bool
+	}{
+		"blocked-user":           {userID: bob.id, domain: "b.example", want: true},
+		"blocked-user-no-domain": {userID: bob.id, want: true},
+		"blocked-domain":         {userID: uuid.NewString(), domain: "spam.example", want: true},
+		"domain-of-blocked-user": {userID: uuid.NewString(), domain: "b.example"},
+		"no-domain":              {userID: uuid.NewString()},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := network.BlockedSender(context.Background(), c, tc.userID, tc.domain); got != tc.want {
+				t.Fatalf("BlockedSender(%s, %q) = %v, want %v", tc.userID, tc.domain, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+)
+
+// WarnKeyChange compares keys about to be stored for a user with those in the
+// address book and reports whether they differ. A change to a friend whose
+// safety number was verified gets a warning that is hard to miss; saving the
+// new keys clears the verified flag.
+func WarnKeyChange(ctx context.Context, c *types.Client, userID string, encKey, sigKey []byte) bool {
+	u := types.User{}
+	var created time.Time
+	row := c.DB.Friends.GetUser.QueryRowContext(ctx, userID)
+	err := row.Scan(&u.Id, &u.Name, &u.Domain, &u.Enckey, &u.Sigkey, &u.KeyEx, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		slog.Warn("key change check failed", "user_id", userID, "error", err)
+		return false
+	}
+
+	if bytes.Equal(u.Enckey, encKey) && bytes.Equal(u.Sigkey, sigKey) {
+		return false
+	}
+
+	var verified bool
+	if err := c.DB.Friends.GetVerified.QueryRowContext(ctx, userID).Scan(&verified); err != nil {
+		slog.Warn("key change check failed", "user_id", userID, "error", err)
+	}
+
+	addr := shared.FormatAddress(u.Name, u.Domain)
+	slog.Warn("friend keys changed", "user_id", userID, "address", addr, "verified", verified)
+
+	if verified {
+		fmt.Fprintf(c.Out(), "\n!!! WARNING: the keys of %s, whose safety number you verified, have changed !!!\n", addr)
+		fmt.Fprintf(c.Out(), "!!! Someone may be intercepting this conversation. Run /verify %s and compare again. !!!\n\n", addr)
+	} else {
+		fmt.Fprintf(c.Out(), "Note: the keys of %s have changed. Run /verify %s to compare safety numbers.\n", addr, addr)
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/JohnnyGlynn/strike/internal/config"
+	"github.com/JohnnyGlynn/strike/internal/keys"
+)
+
+const passphraseAttempts = 3
+
+// KeyDefinitions maps the configured key files to the names used in
+// ClientIdentity.Keys.
+func KeyDefinitions(cfg *config.ClientConfig) map[string]keys.KeyDefinition {
+	return map[string]keys.KeyDefinition{
+		"SigningPrivateKey":    {Path: cfg.SigningPrivateKeyPath, Type: keys.SigningKey},
+		"SigningPublicKey":     {Path: cfg.SigningPublicKeyPath, Type: keys.SigningKey},
+		"EncryptionPrivateKey": {Path: cfg.EncryptionPrivateKeyPath, Type: keys.EncryptionKey},
+		"EncryptionPublicKey":  {Path: cfg.EncryptionPublicKeyPath, Type: keys.EncryptionKey},
+	}
+}
+
+// UnlockKeys loads the configured keys, asking for the passphrase on w when
+// the private key files are protected. It returns the passphrase so rotated
+// keys can be written under it; the passphrase is nil for unprotected files.
+func UnlockKeys(cfg *config.ClientConfig, reader *bufio.Reader, w io.Writer) (map[string][]byte, []byte, error) {
+	defs := KeyDefinitions(cfg)
+
+	if cfg.KeyPassphraseFile != "" {
+		pass, err := ReadSecretFile(cfg.KeyPassphraseFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("key_passphrase_file: %w", err)
+		}
+		asked := false
+		loaded, err := keys.LoadKeys(defs, func() ([]byte, error) {
+			asked = true
+			return pass, nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if !asked {
+			pass = nil
+		}
+		return loaded, pass, nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		var pass []byte
+		prompt := func() ([]byte, error) {
+			if pass == nil {
+				p, err := PromptInput(w, "Key passphrase > ", reader)
+				if err != nil {
+					return nil, err
+				}
+				pass = []byte(p)
+			}
+			return pass, nil
+		}
+
+		loaded, err := keys.LoadKeys(defs, prompt)
+		if err == nil {
+			return loaded, pass, nil
+		}
+		if !errors.Is(err, keys.ErrBadPassphrase) || attempt == passphraseAttempts {
+			return nil, nil, err
+		}
+		fmt.Fprintln(w, "Wrong passphrase, try again.")
+	}
+}
+
+// ReadSecretFile reads a password or passphrase from path, dropping the
+// trailing newline an editor or echo leaves.
+func ReadSecretFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b, "\r\n"), nil
+}
+
+// NewPassphrase asks for a passphrase twice. An empty answer means the keys
+// are stored unencrypted.
+func NewPassphrase(reader *bufio.Reader) ([]byte, error) {
+	p, err := LoginInput("New key passphrase (empty for none) > ", reader)
+	if err != nil {
+		return nil, err
+	}
+	if p == "" {
+		fmt.Println("WARNING: private keys will be stored unencrypted, you are responsible for protecting them.")
+		return nil, nil
+	}
+	again, err := LoginInput("Repeat passphrase > ", reader)
+	if err != nil {
+		return nil, err
+	}
+	if p != again {
+		return nil, fmt.Errorf("passphrases do not match")
+	}
+	return []byte(p), nil
+}
+
+// ChangePassphrase re-encrypts both private key files under a new
+// passphrase, or removes the protection when the new one is empty.
+func ChangePassphrase(cfg *config.ClientConfig, reader *bufio.Reader) error {
+	loaded, _, err := UnlockKeys(cfg, reader, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	pass, err := NewPassphrase(reader)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{"SigningPrivateKey", "EncryptionPrivateKey"} {
+		path := KeyDefinitions(cfg)[name].Path
+		if err := keys.WritePrivateKey(path, loaded[name], pass); err != nil {
+			return fmt.Errorf("failed to rewrite %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// keyPassphrase returns the passphrase protecting the key files, asking for
+// it when the keys were loaded from the agent.
+func keyPassphrase(cfg *config.ClientConfig, known []byte, reader *bufio.Reader) ([]byte, error) {
+	if known != nil {
+		return known, nil
+	}
+	key, err := keys.GetKeyFromPath(cfg.SigningPrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	if !keys.IsEncryptedPEM(key) {
+		return nil, nil
+	}
+
+	p, err := LoginInput("Key passphrase > ", reader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := keys.DecryptPrivateKeyPEM(key, []byte(p)); err != nil {
+		return nil, err
+	}
+	return []byte(p), nil
+}
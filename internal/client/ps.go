@@ -10,33 +10,91 @@ import (
 
 const (
 	//Friends
-	sqlGetFriends      = `SELECT user_id, username, domain, enc_pkey, sig_pkey, keyex, created_at FROM addressbook`
+	sqlGetFriends      = `SELECT user_id, username, domain, enc_pkey, sig_pkey, keyex, verified, created_at FROM addressbook`
 	sqlSaveUserDetails = `
     INSERT INTO addressbook (user_id, username, domain, enc_pkey, sig_pkey)
     VALUES (?, ?, ?, ?, ?) ON CONFLICT(user_id) DO UPDATE SET
     username=excluded.username,
     domain=excluded.domain,
     enc_pkey=excluded.enc_pkey,
-    sig_pkey=excluded.sig_pkey
+    sig_pkey=excluded.sig_pkey,
+    verified=CASE WHEN addressbook.enc_pkey = excluded.enc_pkey AND addressbook.sig_pkey = excluded.sig_pkey
+      THEN addressbook.verified ELSE 0 END
   `
-	sqlGetUserId    = "SELECT user_id FROM addressbook WHERE username = ?"
-	sqlGetUser      = "SELECT user_id, username, domain, enc_pkey, sig_pkey, keyex, created_at FROM addressbook WHERE user_id = ?"
-	sqlGetKeyEx     = "SELECT keyex FROM addressbook WHERE user_id = ?"
-	sqlConfirmKeyEx = "UPDATE addressbook SET keyex = ? WHERE user_id = ?"
+	sqlGetUserId     = "SELECT user_id FROM addressbook WHERE username = ?"
+	sqlGetUser       = "SELECT user_id, username, domain, enc_pkey, sig_pkey, keyex, created_at FROM addressbook WHERE user_id = ?"
+	sqlGetKeyEx      = "SELECT keyex FROM addressbook WHERE user_id = ?"
+	sqlConfirmKeyEx  = "UPDATE addressbook SET keyex = ? WHERE user_id = ?"
+	sqlDeleteUser    = "DELETE FROM addressbook WHERE user_id = ?"
+	sqlDeleteFriends = "DELETE FROM addressbook"
+	sqlUpdateAddress = "UPDATE addressbook SET username = ?, domain = ? WHERE user_id = ?"
+	sqlUpdateKeys    = "UPDATE addressbook SET enc_pkey = ?, sig_pkey = ?, keyex = 0, verified = 0 WHERE user_id = ?"
+	sqlResetKeyEx    = "UPDATE addressbook SET keyex = 0"
+	sqlGetVerified   = "SELECT verified FROM addressbook WHERE user_id = ?"
+	sqlSetVerified   = "UPDATE addressbook SET verified = ? WHERE user_id = ?"
 
 	//ID
 	sqlGetID  = "SELECT * FROM identity WHERE username = ?"
 	sqlGetUID = "SELECT user_id FROM identity WHERE username = ?"
-	sqlSaveID = "INSERT INTO identity (user_id, username, enc_pkey, sig_pkey) VALUES (?, ?, ?, ?)"
+	sqlSaveID = `
+    INSERT INTO identity (user_id, username, enc_pkey, sig_pkey)
+    VALUES (?, ?, ?, ?) ON CONFLICT(user_id) DO UPDATE SET
+    username=excluded.username
+  `
+	sqlDeleteID     = "DELETE FROM identity WHERE user_id = ?"
+	sqlRenameID     = "UPDATE identity SET username = ? WHERE user_id = ?"
+	sqlGetBySigKey  = "SELECT user_id FROM identity WHERE sig_pkey = ?"
+	sqlUpdateIDKeys = "UPDATE identity SET enc_pkey = ?, sig_pkey = ? WHERE user_id = ?"
 
 	//Messages
-	sqlSaveMessage = "INSERT INTO messages (id, friendId, direction, content, timestamp) VALUES (?, ?, ?, ?, ?)"
-	sqlGetMessages = "SELECT * FROM messages WHERE friendId = ? ORDER BY timestamp ASC, id ASC"
+	sqlSaveMessage        = "INSERT INTO messages (id, friendId, direction, content, timestamp) VALUES (?, ?, ?, ?, ?)"
+	sqlGetMessages        = "SELECT * FROM messages WHERE friendId = ? ORDER BY timestamp ASC, id ASC"
+	sqlDeleteConversation = "DELETE FROM messages WHERE friendId = ?"
+	sqlDeleteMessages     = "DELETE FROM messages"
+	sqlPurgeMessages      = "DELETE FROM messages WHERE timestamp < ?"
+	sqlResealMessage      = "UPDATE messages SET content = ? WHERE id = ?"
+	sqlGetMessagePage     = "SELECT * FROM messages WHERE friendId = ? ORDER BY timestamp DESC, id DESC LIMIT ? OFFSET ?"
+	sqlAllMessages        = "SELECT id, friendId, direction, content, timestamp FROM messages ORDER BY timestamp ASC, id ASC"
+	sqlListChats          = `
+    SELECT m.friendId, COALESCE(a.username, ''), COALESCE(a.domain, ''), COUNT(*), MAX(m.timestamp)
+    FROM messages m LEFT JOIN addressbook a ON a.user_id = m.friendId
+    GROUP BY m.friendId ORDER BY MAX(m.timestamp) DESC
+  `
 
 	//Friend Requests
-	sqlSaveFriendRequest   = "INSERT INTO friendrequests (friendId, username, domain, enc_pkey, sig_pkey, direction) VALUES (?, ?, ?, ?, ?, ?)"
-	sqlGetFriendRequests   = "SELECT friendId, username, domain, enc_pkey, sig_pkey, direction FROM friendrequests"
-	sqlDeleteFriendRequest = "DELETE FROM friendrequests WHERE friendId = ?"
+	sqlSaveFriendRequest = `
+    INSERT INTO friendrequests (friendId, username, domain, enc_pkey, sig_pkey, direction, created_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT(friendId, direction) DO NOTHING
+  `
+	sqlGetFriendRequests    = "SELECT friendId, username, domain, enc_pkey, sig_pkey, direction FROM friendrequests"
+	sqlDeleteFriendRequest  = "DELETE FROM friendrequests WHERE friendId = ?"
+	sqlDeleteFriendRequests = "DELETE FROM friendrequests"
+	sqlPurgeFriendRequests  = "DELETE FROM friendrequests WHERE created_at < ?"
+	sqlFindUserByAddress    = `
+    SELECT user_id FROM addressbook WHERE username = ? AND domain = ?
+    UNION ALL SELECT friendId FROM friendrequests WHERE username = ? AND domain = ?
+    LIMIT 1
+  `
+
+	//Search index
+	sqlIndexMessage      = "INSERT INTO message_index (tokens, message_id, key_id) VALUES (?, ?, ?)"
+	sqlUnindexedMessages = "SELECT id, friendId, content FROM messages WHERE id NOT IN (SELECT message_id FROM message_index)"
+	sqlPruneIndex        = "DELETE FROM message_index WHERE key_id != ? OR message_id NOT IN (SELECT id FROM messages)"
+	sqlClearIndex        = "DELETE FROM message_index"
+	sqlSearchIndex       = `
+    SELECT m.id, m.friendId, m.direction, m.content, m.timestamp
+    FROM message_index i JOIN messages m ON m.id = i.message_id
+    WHERE message_index MATCH ? ORDER BY m.timestamp ASC, m.id ASC
+  `
+
+	//Block list
+	sqlListBlocks  = "SELECT kind, value, label FROM blocklist ORDER BY kind, value"
+	sqlAddBlock    = "INSERT INTO blocklist (kind, value, label) VALUES (?, ?, ?) ON CONFLICT(kind, value) DO UPDATE SET label = excluded.label"
+	sqlRemoveBlock = "DELETE FROM blocklist WHERE kind = ? AND value = ?"
+	sqlIsBlocked   = `
+    SELECT EXISTS (SELECT 1 FROM blocklist
+    WHERE (kind = 'user' AND value = ?) OR (kind = 'domain' AND value = ?))
+  `
 )
 
 func PrepareStatements(ctx context.Context, db *sql.DB) (*types.ClientDB, error) {
@@ -52,14 +110,44 @@ func PrepareStatements(ctx context.Context, db *sql.DB) (*types.ClientDB, error)
 		{&statements.Friends.GetUser, sqlGetUser},
 		{&statements.Friends.GetKeyEx, sqlGetKeyEx},
 		{&statements.Friends.ConfirmKeyEx, sqlConfirmKeyEx},
+		{&statements.Friends.DeleteUser, sqlDeleteUser},
+		{&statements.Friends.DeleteAll, sqlDeleteFriends},
+		{&statements.Friends.UpdateAddress, sqlUpdateAddress},
+		{&statements.Friends.UpdateKeys, sqlUpdateKeys},
+		{&statements.Friends.ResetKeyEx, sqlResetKeyEx},
+		{&statements.Friends.GetVerified, sqlGetVerified},
+		{&statements.Friends.SetVerified, sqlSetVerified},
 		{&statements.ID.GetID, sqlGetID},
 		{&statements.ID.GetUID, sqlGetUID},
 		{&statements.ID.SaveID, sqlSaveID},
+		{&statements.ID.DeleteID, sqlDeleteID},
+		{&statements.ID.Rename, sqlRenameID},
+		{&statements.ID.GetBySigKey, sqlGetBySigKey},
+		{&statements.ID.UpdateKeys, sqlUpdateIDKeys},
 		{&statements.Messages.SaveMessage, sqlSaveMessage},
 		{&statements.Messages.GetMessages, sqlGetMessages},
+		{&statements.Messages.DeleteConversation, sqlDeleteConversation},
+		{&statements.Messages.DeleteAll, sqlDeleteMessages},
+		{&statements.Messages.PurgeBefore, sqlPurgeMessages},
+		{&statements.Messages.Reseal, sqlResealMessage},
+		{&statements.Messages.ListChats, sqlListChats},
+		{&statements.Messages.GetPage, sqlGetMessagePage},
+		{&statements.Messages.All, sqlAllMessages},
+		{&statements.Search.Index, sqlIndexMessage},
+		{&statements.Search.Unindexed, sqlUnindexedMessages},
+		{&statements.Search.Prune, sqlPruneIndex},
+		{&statements.Search.Clear, sqlClearIndex},
+		{&statements.Search.Match, sqlSearchIndex},
 		{&statements.FriendRequest.SaveFriendRequest, sqlSaveFriendRequest},
 		{&statements.FriendRequest.GetFriendRequests, sqlGetFriendRequests},
 		{&statements.FriendRequest.DeleteFriendRequest, sqlDeleteFriendRequest},
+		{&statements.FriendRequest.DeleteAll, sqlDeleteFriendRequests},
+		{&statements.FriendRequest.PurgeBefore, sqlPurgeFriendRequests},
+		{&statements.FriendRequest.FindUser, sqlFindUserByAddress},
+		{&statements.Blocks.List, sqlListBlocks},
+		{&statements.Blocks.Add, sqlAddBlock},
+		{&statements.Blocks.Remove, sqlRemoveBlock},
+		{&statements.Blocks.IsBlocked, sqlIsBlocked},
 	}
 
 	for _, p := range pq {
@@ -92,20 +180,54 @@ func CloseStatements(c *types.ClientDB) error {
 		c.Friends.ConfirmKeyEx,
 		c.Friends.GetUserId,
 		c.Friends.GetUser,
+		c.Friends.DeleteUser,
+		c.Friends.DeleteAll,
+		c.Friends.UpdateAddress,
+		c.Friends.UpdateKeys,
+		c.Friends.ResetKeyEx,
+		c.Friends.GetVerified,
+		c.Friends.SetVerified,
 
 		// Identity
 		c.ID.GetID,
 		c.ID.GetUID,
 		c.ID.SaveID,
+		c.ID.DeleteID,
+		c.ID.Rename,
+		c.ID.GetBySigKey,
+		c.ID.UpdateKeys,
 
 		// Messages
 		c.Messages.SaveMessage,
 		c.Messages.GetMessages,
+		c.Messages.DeleteConversation,
+		c.Messages.DeleteAll,
+		c.Messages.PurgeBefore,
+		c.Messages.Reseal,
+		c.Messages.ListChats,
+		c.Messages.GetPage,
+		c.Messages.All,
+
+		// Search index
+		c.Search.Index,
+		c.Search.Unindexed,
+		c.Search.Prune,
+		c.Search.Clear,
+		c.Search.Match,
 
 		// Friend requests
 		c.FriendRequest.SaveFriendRequest,
 		c.FriendRequest.GetFriendRequests,
 		c.FriendRequest.DeleteFriendRequest,
+		c.FriendRequest.DeleteAll,
+		c.FriendRequest.PurgeBefore,
+		c.FriendRequest.FindUser,
+
+		// Block list
+		c.Blocks.List,
+		c.Blocks.Add,
+		c.Blocks.Remove,
+		c.Blocks.IsBlocked,
 	}
 
 	for _, stmt := range statements {
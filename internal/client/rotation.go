@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/JohnnyGlynn/strike/internal/client/network"
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+	pb "github.com/JohnnyGlynn/strike/msgdef/message"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RotateKeys replaces both key pairs. The new public keys are signed with
+// the old signing key and registered with the server, which notifies every
+// friend. The new key files replace the configured ones, the old files are
+// kept with a .old suffix, and stored messages are re-sealed. New private
+// key files are sealed with passphrase unless it is empty.
+func RotateKeys(ctx context.Context, c *types.Client, password string, passphrase []byte) error {
+	passwordHash, err := reauthHash(ctx, c, password)
+	if err != nil {
+		return err
+	}
+
+	hist, err := c.PBC.KeyHistory(ctx, &common_pb.UserAddress{Username: c.Identity.Username, Domain: c.Identity.Domain})
+	if err != nil {
+		return fmt.Errorf("key history lookup failed: %w", serverError(err))
+	}
+	var serial uint64 = 1
+	if n := len(hist.Rotations); n > 0 {
+		serial = hist.Rotations[n-1].Serial + 1
+	}
+
+	sigPriv, sigPub, err := keys.GenerateSigningKeyPEM()
+	if err != nil {
+		return err
+	}
+	encPriv, encPub, err := keys.GenerateEncryptionKeyPEM()
+	if err != nil {
+		return err
+	}
+
+	oldSigner, err := keys.ParseSigningPrivateKey(c.Identity.Keys["SigningPrivateKey"])
+	if err != nil {
+		return err
+	}
+	newSigner, err := keys.ParseSigningPrivateKey(sigPriv)
+	if err != nil {
+		return err
+	}
+
+	rotation := &common_pb.KeyRotation{
+		UserId:                 c.Identity.ID.String(),
+		Serial:                 serial,
+		OldSigningPublicKey:    c.Identity.Keys["SigningPublicKey"],
+		NewEncryptionPublicKey: encPub,
+		NewSigningPublicKey:    sigPub,
+		RotatedAt:              timestamppb.Now(),
+	}
+	msg := shared.KeyRotationBytes(rotation)
+	rotation.Signature = ed25519.Sign(oldSigner, msg)
+	rotation.NewSignature = ed25519.Sign(newSigner, msg)
+
+	cfg := c.Identity.Config
+	files := []stagedKey{
+		{"SigningPrivateKey", cfg.SigningPrivateKeyPath, sigPriv, true},
+		{"SigningPublicKey", cfg.SigningPublicKeyPath, sigPub, false},
+		{"EncryptionPrivateKey", cfg.EncryptionPrivateKeyPath, encPriv, true},
+		{"EncryptionPublicKey", cfg.EncryptionPublicKeyPath, encPub, false},
+	}
+
+	// Stage the new files first so a failed write leaves the old keys in
+	// place and the server untouched.
+	for _, f := range files {
+		out := f.pem
+		if f.private && len(passphrase) > 0 {
+			if out, err = keys.EncryptPrivateKeyPEM(f.pem, passphrase); err != nil {
+				removeStaged(files)
+				return err
+			}
+		}
+		if err := os.WriteFile(f.path+".new", out, 0600); err != nil {
+			removeStaged(files)
+			return fmt.Errorf("failed to write new key: %v", err)
+		}
+	}
+
+	friends, err := loadFriends(c)
+	if err != nil {
+		removeStaged(files)
+		return err
+	}
+	notify, err := friendAddresses(c)
+	if err != nil {
+		removeStaged(files)
+		return err
+	}
+
+	resp, err := c.PBC.RotateKeys(ctx, &pb.RotateKeysRequest{
+		Username:     c.Identity.Username,
+		PasswordHash: passwordHash,
+		Rotation:     rotation,
+		Notify:       notify,
+	})
+	if err != nil {
+		removeStaged(files)
+		return fmt.Errorf("key rotation failed: %w", serverError(err))
+	}
+
+	var errs []error
+	for _, f := range files {
+		if err := os.Rename(f.path, f.path+".old"); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := os.Rename(f.path+".new", f.path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("keys rotated on the server, but installing the new key files failed (they are staged with a .new suffix): %v", errors.Join(errs...))
+	}
+
+	for _, f := range friends {
+		err := network.ResealConversation(ctx, c, f.Id, c.Identity.Keys["EncryptionPrivateKey"], f.Enckey, encPriv, f.Enckey)
+		if err != nil {
+			fmt.Fprintf(c.Out(), "failed to re-seal messages with %s: %v\n", shared.FormatAddress(f.Name, f.Domain), err)
+		}
+	}
+
+	for _, f := range files {
+		c.Identity.Keys[f.name] = f.pem
+	}
+	if _, err := c.DB.ID.UpdateKeys.ExecContext(ctx, encPub, sigPub, c.Identity.ID.String()); err != nil {
+		return fmt.Errorf("failed to update local identity: %v", err)
+	}
+	// Friends start a fresh key exchange once they have checked the rotation.
+	if _, err := c.DB.Friends.ResetKeyEx.ExecContext(ctx); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.Out(), resp.Message)
+	return nil
+}
+
+// stagedKey is a new key file written next to the one it replaces.
+type stagedKey struct {
+	name    string
+	path    string
+	pem     []byte
+	private bool
+}
+
+func removeStaged(files []stagedKey) {
+	for _, f := range files {
+		_ = os.Remove(f.path + ".new")
+	}
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// schemaTables are tables added to client.sql after its first release.
+var schemaTables = []string{
+	`CREATE TABLE IF NOT EXISTS blocklist (
+		kind TEXT NOT NULL,
+		value TEXT NOT NULL,
+		label TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (kind, value)
+	)`,
+	`CREATE VIRTUAL TABLE IF NOT EXISTS message_index USING fts5(tokens, message_id UNINDEXED, key_id UNINDEXED)`,
+}
+
+// schemaColumns are columns added to client.sql after its first release.
+// cmd/strike-client only runs client.sql on a new database, so existing ones
+// get them here.
+var schemaColumns = []struct {
+	table  string
+	column string
+	ddl    string
+}{
+	{"addressbook", "verified", "ALTER TABLE addressbook ADD COLUMN verified INTEGER NOT NULL DEFAULT 0"},
+	{"friendrequests", "created_at", "ALTER TABLE friendrequests ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0"},
+	{"blocklist", "label", "ALTER TABLE blocklist ADD COLUMN label TEXT NOT NULL DEFAULT ''"},
+}
+
+// schemaFixups run after the columns exist. friendrequests had no key, so
+// repeated requests piled up; keep the newest of each before indexing, and
+// start the expiry clock on rows saved before created_at existed.
+var schemaFixups = []string{
+	`DELETE FROM friendrequests WHERE rowid NOT IN
+		(SELECT MAX(rowid) FROM friendrequests GROUP BY friendId, direction)`,
+	"CREATE UNIQUE INDEX IF NOT EXISTS friendrequests_friend ON friendrequests (friendId, direction)",
+	"UPDATE friendrequests SET created_at = CAST(strftime('%s', 'now') AS INTEGER) * 1000 WHERE created_at = 0",
+}
+
+// EnsureSchema brings a client database created from an older client.sql up
+// to date. It is safe to run on every startup.
+func EnsureSchema(ctx context.Context, db *sql.DB) error {
+	for _, ddl := range schemaTables {
+		if _, err := db.ExecContext(ctx, ddl); err != nil {
+			return fmt.Errorf("ensure schema: %w", err)
+		}
+	}
+	for _, col := range schemaColumns {
+		var n int
+		err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", col.table, col.column).Scan(&n)
+		if err != nil {
+			return fmt.Errorf("ensure schema: %w", err)
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, col.ddl); err != nil {
+			return fmt.Errorf("ensure schema: %w", err)
+		}
+	}
+	for _, ddl := range schemaFixups {
+		if _, err := db.ExecContext(ctx, ddl); err != nil {
+			return fmt.Errorf("ensure schema: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/JohnnyGlynn/strike/internal/client/crypto"
+	"github.com/JohnnyGlynn/strike/internal/client/network"
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+)
+
+// SearchResult is a decrypted message that matched a search.
+type SearchResult struct {
+	Friend  *types.User
+	Message types.Message
+}
+
+// friendKey is a friend and the key your messages with them are sealed
+// under.
+type friendKey struct {
+	user *types.User
+	key  []byte
+}
+
+// chatKeys maps each friend's user ID to their friendKey. Messages with
+// anyone else cannot be opened.
+type chatKeys map[string]friendKey
+
+func loadChatKeys(c *types.Client) (chatKeys, error) {
+	friends, err := loadFriends(c)
+	if err != nil {
+		return nil, err
+	}
+	keys := chatKeys{}
+	for _, f := range friends {
+		key, err := network.FriendChatKey(c, f.Enckey)
+		if err != nil {
+			return nil, err
+		}
+		keys[f.Id.String()] = friendKey{f, key}
+	}
+	return keys, nil
+}
+
+// searchWords splits text into lower case words.
+func searchWords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	slices.Sort(words)
+	return slices.Compact(words)
+}
+
+// SearchMessages finds the messages with your friends that contain every
+// word of query. Without search_index each message is decrypted and words
+// match anywhere, including inside longer words. With it, the index narrows
+// the messages to decrypt and words must match whole.
+func SearchMessages(ctx context.Context, c *types.Client, query string) ([]SearchResult, error) {
+	words := searchWords(query)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("nothing to search for")
+	}
+	keys, err := loadChatKeys(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows *sql.Rows
+	if c.Identity.Config.SearchIndex {
+		index, err := newSearchIndex(c)
+		if err != nil {
+			return nil, err
+		}
+		if err := index.update(ctx, keys); err != nil {
+			return nil, err
+		}
+		rows, err = c.DB.Search.Match.QueryContext(ctx, index.match(words))
+		if err != nil {
+			return nil, fmt.Errorf("error querying search index: %v", err)
+		}
+	} else {
+		// Turning the index off drops it.
+		if _, err := c.DB.Search.Clear.ExecContext(ctx); err != nil {
+			return nil, err
+		}
+		rows, err = c.DB.Messages.All.QueryContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error querying messages: %v", err)
+		}
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var msg types.Message
+		if err := rows.Scan(&msg.Id, &msg.FriendId, &msg.Direction, &msg.Content, &msg.Timestamp); err != nil {
+			return nil, err
+		}
+		k, ok := keys[msg.FriendId.String()]
+		if !ok {
+			continue
+		}
+		plain, err := crypto.Open(k.key, msg.Content)
+		if err != nil {
+			slog.Debug("search: message could not be opened", "id", msg.Id, "error", err)
+			continue
+		}
+
+		var matched bool
+		if c.Identity.Config.SearchIndex {
+			have := searchWords(string(plain))
+			matched = !slices.ContainsFunc(words, func(w string) bool { _, found := slices.BinarySearch(have, w); return !found })
+		} else {
+			lower := strings.ToLower(string(plain))
+			matched = !slices.ContainsFunc(words, func(w string) bool { return !strings.Contains(lower, w) })
+		}
+		if matched {
+			msg.Content = plain
+			results = append(results, SearchResult{Friend: k.user, Message: msg})
+		}
+	}
+	return results, rows.Err()
+}
+
+// searchIndex keeps message_index in step with the messages table. Each row
+// holds keyed hashes of a message's words, so the index can be matched
+// without storing plaintext; the key comes from the encryption private key
+// and rows hashed under an earlier key are dropped after a rotation.
+//
+// The hashes are deterministic, so whoever reads the database can still see
+// which messages share a word and how often each word is used, though not
+// what the words are. Only whole words can be looked up: there is no prefix
+// or substring matching.
+type searchIndex struct {
+	c     *types.Client
+	key   []byte
+	keyID string
+}
+
+func newSearchIndex(c *types.Client) (*searchIndex, error) {
+	key, err := crypto.SearchKey(c.Identity.Keys["EncryptionPrivateKey"])
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key)
+	return &searchIndex{c: c, key: key, keyID: hex.EncodeToString(sum[:8])}, nil
+}
+
+func (s *searchIndex) token(word string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(word))
+	return hex.EncodeToString(mac.Sum(nil)[:12])
+}
+
+// match is an FTS5 query for rows holding every word.
+func (s *searchIndex) match(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = `"` + s.token(w) + `"`
+	}
+	return strings.Join(quoted, " AND ")
+}
+
+// update drops stale rows and indexes messages saved since the last search.
+func (s *searchIndex) update(ctx context.Context, keys chatKeys) error {
+	if _, err := s.c.DB.Search.Prune.ExecContext(ctx, s.keyID); err != nil {
+		return fmt.Errorf("error pruning search index: %v", err)
+	}
+
+	type pending struct {
+		id, friend string
+		content    []byte
+	}
+	rows, err := s.c.DB.Search.Unindexed.QueryContext(ctx)
+	if err != nil {
+		return fmt.Errorf("error querying messages: %v", err)
+	}
+	// Read everything first: SQLite will not write while the query is open.
+	var todo []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.friend, &p.content); err != nil {
+			rows.Close()
+			return err
+		}
+		todo = append(todo, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range todo {
+		// A message that cannot be opened still gets a row, with no words,
+		// so it is not read again on every search.
+		var tokens []string
+		if k, ok := keys[p.friend]; ok {
+			if plain, err := crypto.Open(k.key, p.content); err == nil {
+				for _, w := range searchWords(string(plain)) {
+					tokens = append(tokens, s.token(w))
+				}
+			}
+		}
+		if _, err := s.c.DB.Search.Index.ExecContext(ctx, strings.Join(tokens, " "), p.id, s.keyID); err != nil {
+			return fmt.Errorf("error updating search index: %v", err)
+		}
+	}
+	if len(todo) > 0 {
+		slog.Debug("search index updated", "messages", len(todo))
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/JohnnyGlynn/strike/internal/client/crypto"
+	"github.com/JohnnyGlynn/strike/internal/client/network"
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/config"
+)
+
+func TestSearchWords(t *testing.T) {
+	cases := map[string]struct {
+		text string
+		want []string
+	}{
+		"empty":          {text: ""},
+		"punctuation":    {text: "?! ... --"},
+		"lower-cased":    {text: "Deploy TONIGHT", want: []string{"deploy", "tonight"}},
+		"split-on-marks": {text: "it's deploy-time, ok?", want: []string{"deploy", "it", "ok", "s", "time"}},
+		"deduplicated":   {text: "go go GO gone", want: []string{"go", "gone"}},
+		"numbers":        {text: "meet at 10:30 in room 4b", want: []string{"10", "30", "4b", "at", "in", "meet", "room"}},
+		"whitespace":     {text: "  tab\tand\nnewline  ", want: []string{"and", "newline", "tab"}},
+		"non-ascii":      {text: "Grüße, Ζεύς! 東京", want: []string{"grüße", "ζεύς", "東京"}},
+		"symbols":        {text: "a+b=c ✓ 🙂", want: []string{"a", "b", "c"}},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := searchWords(tc.text); !slices.Equal(got, tc.want) {
+				t.Errorf("searchWords(%q) = %q, want %q", tc.text, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSearchIndexMatch(t *testing.T) {
+	c := &types.Client{Identity: &types.ClientIdentity{Keys: testKeys(t)}}
+	index, err := newSearchIndex(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := index.match([]string{"deploy", "tonight"})
+	if !regexp.MustCompile(`^"[0-9a-f]{24}" AND "[0-9a-f]{24}"$`).MatchString(query) {
+		t.Fatalf("match = %q, want two quoted tokens joined by AND", query)
+	}
+	if strings.Contains(query, "deploy") || strings.Contains(query, "tonight") {
+		t.Errorf("match %q holds plaintext", query)
+	}
+	if again := index.match([]string{"deploy", "tonight"}); again != query {
+		t.Errorf("match not stable: %q then %q", query, again)
+	}
+	if index.token("deploy") == index.token("Deploy") {
+		t.Error("token does not see case; words are lower cased before hashing")
+	}
+
+	// Another key hashes the same words differently, and is told apart by
+	// its key ID.
+	other, err := newSearchIndex(&types.Client{Identity: &types.ClientIdentity{Keys: testKeys(t)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.token("deploy") == index.token("deploy") {
+		t.Error("tokens do not depend on the key")
+	}
+	if other.keyID == index.keyID {
+		t.Error("two keys share a key ID")
+	}
+}
+
+// searchClient is a client with one friend and some messages with them.
+func searchClient(t *testing.T, searchIndex bool) (*types.Client, *sql.DB, map[string]string) {
+	t.Helper()
+	ctx := context.Background()
+	db := testDB(t)
+	stmts, err := PrepareStatements(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &types.Client{
+		Identity: &types.ClientIdentity{Keys: testKeys(t), Config: &config.ClientConfig{SearchIndex: searchIndex}},
+		DB:       stmts,
+	}
+
+	bob := testKeys(t)
+	bobID := uuid.NewString()
+	if _, err := db.ExecContext(ctx, "INSERT INTO addressbook (user_id, username, domain, enc_pkey, sig_pkey) VALUES (?, ?, ?, ?, ?)",
+		bobID, "bob", "b.example", bob["EncryptionPublicKey"], bob["SigningPublicKey"]); err != nil {
+		t.Fatal(err)
+	}
+	key, err := network.FriendChatKey(c, bob["EncryptionPublicKey"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := map[string]string{}
+	save := func(name, friend string, content []byte) {
+		ids[name] = uuid.NewString()
+		if _, err := db.ExecContext(ctx, "INSERT INTO messages (id, friendId, direction, content, timestamp) VALUES (?, ?, ?, ?, ?)",
+			ids[name], friend, "inbound", content, time.Now().UnixMilli()+int64(len(ids))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seal := func(text string) []byte {
+		sealed, err := crypto.Seal(key, []byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sealed
+	}
+	save("release", bobID, seal("Deploy the release tonight"))
+	save("failed", bobID, seal("deployment failed, again!"))
+	save("sure", bobID, seal("Tonight? Sure."))
+	save("corrupt", bobID, []byte("tonight, but not sealed"))
+	save("stranger", uuid.NewString(), seal("tonight from someone else"))
+	return c, db, ids
+}
+
+func TestSearchMessages(t *testing.T) {
+	cases := map[string]struct {
+		query     string
+		want      []string // messages found without the index
+		wantIndex []string // and with it
+	}{
+		"one-word":           {query: "tonight", want: []string{"release", "sure"}, wantIndex: []string{"release", "sure"}},
+		"every-word":         {query: "TONIGHT deploy", want: []string{"release"}, wantIndex: []string{"release"}},
+		"word-inside-longer": {query: "deploy", want: []string{"failed", "release"}, wantIndex: []string{"release"}},
+		"substring":          {query: "ploy", want: []string{"failed", "release"}},
+		"any-order":          {query: "again failed", want: []string{"failed"}, wantIndex: []string{"failed"}},
+		"punctuation":        {query: "release, tonight!", want: []string{"release"}, wantIndex: []string{"release"}},
+		"one-word-missing":   {query: "tonight nowhere"},
+		"no-match":           {query: "nowhere"},
+	}
+
+	for _, indexed := range []bool{false, true} {
+		c, _, ids := searchClient(t, indexed)
+		names := map[string]string{}
+		for name, id := range ids {
+			names[id] = name
+		}
+
+		// The cases share one database, so they run in turn.
+		for name, tc := range cases {
+			want, mode := tc.want, "scan"
+			if indexed {
+				want, mode = tc.wantIndex, "index"
+			}
+			t.Run(mode+"/"+name, func(t *testing.T) {
+				results, err := SearchMessages(context.Background(), c, tc.query)
+				if err != nil {
+					t.Fatalf("search: %v", err)
+				}
+				var got []string
+				for _, r := range results {
+					got = append(got, names[r.Message.Id.String()])
+					if r.Friend.Name != "bob" {
+						t.Errorf("result from %q, want bob", r.Friend.Name)
+					}
+				}
+				slices.Sort(got)
+				if !slices.Equal(got, want) {
+					t.Errorf("SearchMessages(%q) = %q, want %q", tc.query, got, want)
+				}
+			})
+		}
+
+		if _, err := SearchMessages(context.Background(), c, "?!"); err == nil || !strings.Contains(err.Error(), "nothing to search for") {
+			t.Errorf("search without words: error = %v", err)
+		}
+	}
+}
+
+func TestSearchIndexUpdate(t *testing.T) {
+	ctx := context.Background()
+	c, db, ids := searchClient(t, true)
+	if _, err := SearchMessages(ctx, c, "tonight"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every message has a row afterwards, including the ones that could not
+	// be opened, so none is read again by the next search.
+	if pending := unindexed(t, c); len(pending) != 0 {
+		t.Fatalf("messages left unindexed: %q", pending)
+	}
+	rows := indexRows(t, db)
+	if len(rows) != len(ids) {
+		t.Fatalf("index rows = %d, want %d", len(rows), len(ids))
+	}
+	for _, name := range []string{"corrupt", "stranger"} {
+		if tokens := rows[ids[name]]; tokens != "" {
+			t.Errorf("unreadable message %s indexed with tokens %q", name, tokens)
+		}
+	}
+	for _, tokens := range rows {
+		if strings.Contains(tokens, "tonight") {
+			t.Errorf("plaintext in the index: %q", tokens)
+		}
+	}
+
+	if _, err := SearchMessages(ctx, c, "tonight"); err != nil {
+		t.Fatal(err)
+	}
+	if again := indexRows(t, db); len(again) != len(rows) {
+		t.Errorf("index rows = %d after a second search, want %d", len(again), len(rows))
+	}
+
+	// Turning the index off drops it.
+	c.Identity.Config.SearchIndex = false
+	if _, err := SearchMessages(ctx, c, "tonight"); err != nil {
+		t.Fatal(err)
+	}
+	if rows := indexRows(t, db); len(rows) != 0 {
+		t.Errorf("index rows = %d with search_index off, want 0", len(rows))
+	}
+}
+
+func unindexed(t *testing.T, c *types.Client) []string {
+	t.Helper()
+	rows, err := c.DB.Search.Unindexed.Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id, friend string
+		var content []byte
+		if err := rows.Scan(&id, &friend, &content); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// indexRows maps message IDs to their index tokens.
+func indexRows(t *testing.T, db *sql.DB) map[string]string {
+	t.Helper()
+	rows, err := db.Query("SELECT message_id, tokens FROM message_index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	got := map[string]string{}
+	for rows.Next() {
+		var id, tokens string
+		if err := rows.Scan(&id, &tokens); err != nil {
+			t.Fatal(err)
+		}
+		got[id] = tokens
+	}
+	return got
+}
@@ -5,8 +5,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"maps"
 	"os"
 	"slices"
 	"strconv"
@@ -16,19 +19,20 @@ import (
 	"github.com/JohnnyGlynn/strike/internal/client/crypto"
 	"github.com/JohnnyGlynn/strike/internal/client/network"
 	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/keys"
 	"github.com/JohnnyGlynn/strike/internal/shared"
 	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
 	pb "github.com/JohnnyGlynn/strike/msgdef/message"
 )
 
-func printPrompt(client *types.Client) {
+func shellPrompt(client *types.Client) string {
 	self := shared.FormatAddress(client.Identity.Username, client.Identity.Domain)
 	switch client.State.Shell.Mode {
-	case types.ModeDefault:
-		fmt.Printf("[%s]> ", self)
 	case types.ModeChat:
 		peer := shared.FormatAddress(client.State.Cache.CurrentChat.User.Name, client.State.Cache.CurrentChat.User.Domain)
-		fmt.Printf("[%s -> %s]> ", self, peer)
+		return fmt.Sprintf("[%s -> %s]> ", self, peer)
+	default:
+		return fmt.Sprintf("[%s]> ", self)
 	}
 }
 
@@ -52,20 +56,39 @@ func inputParse(input string) types.ParsedInput {
 	}
 }
 
-func dispatchCommand(cmdMap map[string]types.Command, parsed types.ParsedInput, client *types.Client) {
+// errExitShell is returned by commands that end the shell.
+var errExitShell = errors.New("exit shell")
+
+// dispatchCommand runs a command and reports whether it ended the shell.
+func dispatchCommand(cmdMap map[string]types.Command, parsed types.ParsedInput, client *types.Client) bool {
 	if cmd, exists := cmdMap[parsed.Command]; exists {
 		if slices.Contains(cmd.Scope, client.State.Shell.Mode) {
 			err := cmd.CmdFn(parsed.Args, client)
+			if errors.Is(err, errExitShell) {
+				return true
+			}
 			if err != nil {
 				fmt.Printf("failed to dispatch command: %v\n", err)
-				return
+				return false
 			}
 		} else {
 			fmt.Printf("'%s' command not availble in '%v' mode\n", cmd.Name, client.State.Shell.Mode)
 		}
+	} else if s := shared.Suggest(parsed.Command, slices.Sorted(maps.Keys(cmdMap))); s != "" {
+		fmt.Printf("Unknown command: %s, did you mean %s?\n", parsed.Command, s)
 	} else {
-		fmt.Printf("Unknown command: %s\n", parsed.Command)
+		fmt.Printf("Unknown command: %s\nTry /help for available commands.\n", parsed.Command)
 	}
+	return false
+}
+
+// readInput prompts for a line through the shell's line editor, or from
+// stdin when no shell is running.
+func readInput(c *types.Client, prompt string) (string, error) {
+	if c.State.Shell != nil && c.State.Shell.ReadLine != nil {
+		return c.State.Shell.ReadLine(prompt)
+	}
+	return LoginInput(prompt, bufio.NewReader(os.Stdin))
 }
 
 func buildCommandMap() (map[string]types.Command, error) {
@@ -77,23 +100,67 @@ func buildCommandMap() (map[string]types.Command, error) {
 	}
 
 	register(types.Command{
-		Name: "/testCmd",
-		Desc: "Test command Map builder",
+		Name: "/requests",
+		Desc: "Answer pending friend requests",
 		CmdFn: func(args []string, client *types.Client) error {
-			//TODO: Bad idea to put all the command logic in here?
-			fmt.Println("Building the command map")
-			return nil
+			return shellFriendRequests(context.TODO(), client)
 		},
 		Scope: []types.ShellMode{types.ModeDefault},
 	})
 
+	register(types.Command{
+		Name: "/whoami",
+		Desc: "Show your address, user ID and server",
+		CmdFn: func(args []string, client *types.Client) error {
+			fmt.Printf("%s\n ID: %s\n Server: %s\n", shared.FormatAddress(client.Identity.Username, client.Identity.Domain),
+				client.Identity.ID, client.Identity.Config.ServerHost)
+			return nil
+		},
+		Scope: []types.ShellMode{types.ModeDefault, types.ModeChat},
+	})
+
+	register(types.Command{
+		Name: "/online",
+		Desc: "List which of your friends on this server are online",
+		CmdFn: func(args []string, client *types.Client) error {
+			users, err := GetActiveUsers(client, &common_pb.UserInfo{
+				Username: client.Identity.Username,
+				UserId:   client.Identity.ID.String(),
+			})
+			if err != nil {
+				return err
+			}
+			online := map[string]bool{}
+			for _, u := range users.Users {
+				online[u.UserId] = true
+			}
+
+			friends, err := loadFriends(client)
+			if err != nil {
+				return err
+			}
+			n := 0
+			for _, f := range friends {
+				if online[f.Id.String()] {
+					fmt.Printf("* %s\n", shared.FormatAddress(f.Name, f.Domain))
+					n++
+				}
+			}
+			if n == 0 {
+				fmt.Println("None of your friends on this server are online.")
+			}
+			return nil
+		},
+		Scope: []types.ShellMode{types.ModeDefault, types.ModeChat},
+	})
+
 	register(types.Command{
 		Name: "/pollServer",
 		Desc: "Get a list of active users on a server",
 		CmdFn: func(args []string, client *types.Client) error {
 			sInfo, err := PollServer(client)
 			if err != nil {
-				log.Println("failed to poll server")
+				slog.Error("failed to poll server", "error", err)
 				return err
 			}
 			fmt.Printf("Server Info\n Name: %s\n ID: %s\n Domain: %s\n", sInfo.ServerName, sInfo.ServerId, sInfo.Domain)
@@ -107,11 +174,11 @@ func buildCommandMap() (map[string]types.Command, error) {
 	})
 
 	register(types.Command{
-		Name: "/addfriend",
-		Desc: "Send a friend request (usage: /addfriend or /addfriend user@domain)",
+		Name:  "/addfriend",
+		Desc:  "Send a friend request",
+		Usage: "/addfriend [user@domain]",
 		CmdFn: func(args []string, client *types.Client) error {
-			todoReader := bufio.NewReader(os.Stdin)
-			err := shellAddFriend(args, todoReader, client)
+			err := shellAddFriend(args, client)
 			if err != nil {
 				fmt.Printf("error executing addFriend: %v\n", err)
 				return err
@@ -136,8 +203,39 @@ func buildCommandMap() (map[string]types.Command, error) {
 	})
 
 	register(types.Command{
-		Name: "/chat",
-		Desc: "Chat with a friend (usage: /chat user@domain or /chat user)",
+		Name:     "/unfriend",
+		Desc:     "Remove a friend, optionally deleting your history with them",
+		Usage:    "/unfriend <user@domain> [--purge]",
+		Complete: completeFriends,
+		CmdFn: func(args []string, client *types.Client) error {
+			if len(args) == 0 {
+				fmt.Println("Usage: /unfriend <username@domain> [--purge]")
+				return nil
+			}
+			addr, err := shared.ParseAddress(args[0])
+			if err != nil {
+				fmt.Printf("invalid address: %v\n", err)
+				return nil
+			}
+			purge := slices.Contains(args[1:], "--purge")
+			u, err := Unfriend(context.TODO(), client, addr, purge)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s removed from your friends\n", shared.FormatAddress(u.Name, u.Domain))
+			if purge {
+				fmt.Println("Message history deleted.")
+			}
+			return nil
+		},
+		Scope: []types.ShellMode{types.ModeDefault},
+	})
+
+	register(types.Command{
+		Name:     "/chat",
+		Desc:     "Chat with a friend",
+		Usage:    "/chat <user@domain|user>",
+		Complete: completeFriends,
 		CmdFn: func(args []string, client *types.Client) error {
 			if len(args) == 0 {
 				fmt.Println("Usage: /chat <username@domain> or /chat <username>")
@@ -159,6 +257,359 @@ func buildCommandMap() (map[string]types.Command, error) {
 		Scope: []types.ShellMode{types.ModeDefault},
 	})
 
+	register(types.Command{
+		Name: "/deleteaccount",
+		Desc: "Permanently delete your account and local data, notifying your friends",
+		CmdFn: func(args []string, client *types.Client) error {
+			reader := bufio.NewReader(os.Stdin)
+			fmt.Println("This deletes your account on the server and everything stored locally. It cannot be undone.")
+			confirm, err := LoginInput("Type your username to confirm > ", reader)
+			if err != nil {
+				return err
+			}
+			if confirm != client.Identity.Username {
+				fmt.Println("Username did not match, account not deleted.")
+				return nil
+			}
+			password, err := LoginInput("Password > ", reader)
+			if err != nil {
+				return err
+			}
+
+			if err := DeleteAccount(context.TODO(), client, password, confirm); err != nil {
+				return err
+			}
+			fmt.Println("Account deleted. Exiting mshell")
+			return errExitShell
+		},
+		Scope: []types.ShellMode{types.ModeDefault},
+	})
+
+	register(types.Command{
+		Name:  "/rename",
+		Desc:  "Change your username, notifying your friends",
+		Usage: "/rename <newname>",
+		CmdFn: func(args []string, client *types.Client) error {
+			if len(args) == 0 {
+				fmt.Println("Usage: /rename <newname>")
+				return nil
+			}
+			password, err := LoginInput("Password > ", bufio.NewReader(os.Stdin))
+			if err != nil {
+				return err
+			}
+			return RenameAccount(context.TODO(), client, password, args[0])
+		},
+		Scope: []types.ShellMode{types.ModeDefault},
+	})
+
+	register(types.Command{
+		Name:  "/move",
+		Desc:  "Move your account to another server, notifying your friends",
+		Usage: "/move <user@domain>",
+		CmdFn: func(args []string, client *types.Client) error {
+			if len(args) == 0 {
+				fmt.Println("Usage: /move <username@domain>")
+				return nil
+			}
+			addr, err := shared.ParseAddress(args[0])
+			if err != nil || addr.Domain == "" {
+				fmt.Println("Usage: /move <username@domain>")
+				return nil
+			}
+
+			reader := bufio.NewReader(os.Stdin)
+			fmt.Printf("Sign up on %s with your current keys before moving. Your account here is removed and %s redirects to %s.\n",
+				addr.Domain, client.Identity.Username+"@"+client.Identity.Domain, addr.Format())
+			confirm, err := LoginInput("Type your username to confirm > ", reader)
+			if err != nil {
+				return err
+			}
+			if confirm != client.Identity.Username {
+				fmt.Println("Username did not match, account not moved.")
+				return nil
+			}
+			password, err := LoginInput("Password > ", reader)
+			if err != nil {
+				return err
+			}
+
+			if err := MoveAccount(context.TODO(), client, password, addr.Username, addr.Domain); err != nil {
+				return err
+			}
+			fmt.Printf("Account moved. Reconnect with --server pointing at %s. Exiting mshell\n", addr.Domain)
+			return errExitShell
+		},
+		Scope: []types.ShellMode{types.ModeDefault},
+	})
+
+	register(types.Command{
+		Name:     "/verify",
+		Desc:     "Compare safety numbers with a friend",
+		Usage:    "/verify <user@domain> [code]",
+		Complete: completeFriends,
+		CmdFn: func(args []string, client *types.Client) error {
+			if len(args) == 0 {
+				fmt.Println("Usage: /verify <username@domain> [code]")
+				return nil
+			}
+			addr, err := shared.ParseAddress(args[0])
+			if err != nil {
+				fmt.Printf("invalid address: %v\n", err)
+				return nil
+			}
+
+			u, number, err := SafetyNumber(context.TODO(), client, addr)
+			if err != nil {
+				return err
+			}
+
+			// A code scanned or pasted from the friend's device is checked directly.
+			if len(args) > 1 {
+				if !matchesSafetyCode(strings.Join(args[1:], ""), number) {
+					fmt.Printf("!!! Safety numbers do NOT match. Do not trust %s until this is resolved. !!!\n", addr.Format())
+					return MarkVerified(context.TODO(), client, u, false)
+				}
+				fmt.Printf("Safety numbers match, %s is now verified.\n", addr.Format())
+				return MarkVerified(context.TODO(), client, u, true)
+			}
+
+			fmt.Printf("Safety number with %s:\n\n", addr.Format())
+			groups := strings.Fields(number)
+			for i := 0; i < len(groups); i += 4 {
+				fmt.Printf("  %s\n", strings.Join(groups[i:i+4], " "))
+			}
+			fmt.Printf("\nQR code data: %s\n", keys.SafetyCode(number))
+			if u.Verified {
+				fmt.Println("Status: verified")
+			} else {
+				fmt.Println("Status: not verified")
+			}
+
+			fmt.Println("Compare this with your friend in person or over a channel you trust.")
+			answer, err := readInput(client, "Do the numbers match? [y/N] > ")
+			if err != nil {
+				return err
+			}
+			if strings.EqualFold(answer, "y") {
+				fmt.Printf("%s marked as verified.\n", addr.Format())
+				return MarkVerified(context.TODO(), client, u, true)
+			}
+			fmt.Println("Not marked as verified.")
+			return nil
+		},
+		Scope: []types.ShellMode{types.ModeDefault},
+	})
+
+	register(types.Command{
+		Name:  "/blockdomain",
+		Desc:  "Refuse payloads from every user on a server, or list blocked servers",
+		Usage: "/blockdomain [domain]",
+		CmdFn: func(args []string, client *types.Client) error {
+			if len(args) == 0 {
+				return listBlocks(client, BlockDomain)
+			}
+			msg, err := SetBlocked(context.TODO(), client, BlockDomain, args[0], "", true)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Blocked %s (%s)\n", args[0], msg)
+			return nil
+		},
+		Scope: []types.ShellMode{types.ModeDefault},
+	})
+
+	register(types.Command{
+		Name:  "/unblockdomain",
+		Desc:  "Accept payloads from a blocked server again",
+		Usage: "/unblockdomain <domain>",
+		CmdFn: func(args []string, client *types.Client) error {
+			if len(args) == 0 {
+				fmt.Println("Usage: /unblockdomain <domain>")
+				return nil
+			}
+			msg, err := SetBlocked(context.TODO(), client, BlockDomain, args[0], "", false)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Unblocked %s (%s)\n", args[0], msg)
+			return nil
+		},
+		Scope: []types.ShellMode{types.ModeDefault},
+	})
+
+	register(types.Command{
+		Name:     "/block",
+		Desc:     "Refuse friend requests and messages from a user, or list blocked users",
+		Usage:    "/block [user@domain]",
+		Complete: completeFriends,
+		CmdFn: func(args []string, client *types.Client) error {
+			if len(args) == 0 {
+				return listBlocks(client, BlockUser)
+			}
+			addr, err := shared.ParseAddress(args[0])
+			if err != nil {
+				fmt.Printf("invalid address: %v\n", err)
+				return nil
+			}
+			msg, err := Block(context.TODO(), client, addr)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Blocked %s (%s)\n", addr.Format(), msg)
+			return nil
+		},
+		Scope: []types.ShellMode{types.ModeDefault},
+	})
+
+	register(types.Command{
+		Name:     "/unblock",
+		Desc:     "Accept friend requests and messages from a blocked user again",
+		Usage:    "/unblock <user@domain>",
+		Complete: completeFriends,
+		CmdFn: func(args []string, client *types.Client) error {
+			if len(args) == 0 {
+				fmt.Println("Usage: /unblock <username@domain>")
+				return nil
+			}
+			addr, err := shared.ParseAddress(args[0])
+			if err != nil {
+				fmt.Printf("invalid address: %v\n", err)
+				return nil
+			}
+			msg, err := Unblock(context.TODO(), client, addr)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Unblocked %s (%s)\n", addr.Format(), msg)
+			return nil
+		},
+		Scope: []types.ShellMode{types.ModeDefault},
+	})
+
+	register(types.Command{
+		Name: "/rotatekeys",
+		Desc: "Replace your signing and encryption keys, notifying your friends",
+		CmdFn: func(args []string, client *types.Client) error {
+			fmt.Println("New key files replace the configured ones; the old files are kept with a .old suffix.")
+			reader := bufio.NewReader(os.Stdin)
+			password, err := LoginInput("Password > ", reader)
+			if err != nil {
+				return err
+			}
+			passphrase, err := keyPassphrase(client.Identity.Config, client.Identity.KeyPassphrase, reader)
+			if err != nil {
+				return err
+			}
+			if err := RotateKeys(context.TODO(), client, password, passphrase); err != nil {
+				return err
+			}
+			if client.Identity.Config.KeyAgentSocket != "" {
+				fmt.Println("Restart the key agent to load the new keys.")
+			}
+			return nil
+		},
+		Scope: []types.ShellMode{types.ModeDefault},
+	})
+
+	register(types.Command{
+		Name:  "/history",
+		Desc:  "Show earlier messages in this chat, further back each time",
+		Usage: "/history [n]",
+		CmdFn: func(args []string, client *types.Client) error {
+			n := historyPage
+			if len(args) > 0 {
+				v, err := strconv.Atoi(args[0])
+				if err != nil || v < 1 {
+					fmt.Println("Usage: /history [n]")
+					return nil
+				}
+				n = v
+			}
+			msgs, err := loadHistoryPage(client, n)
+			if err != nil {
+				return err
+			}
+			if len(msgs) == 0 {
+				fmt.Println("No earlier messages.")
+				return nil
+			}
+			printMessages(client, msgs)
+			return nil
+		},
+		Scope: []types.ShellMode{types.ModeChat},
+	})
+
+	register(types.Command{
+		Name:  "/search",
+		Desc:  "Find messages containing every word given, across your chats",
+		Usage: "/search <text>",
+		Help: "Words match anywhere, even inside longer words, unless search_index is on. " +
+			"The index matches whole words only, with no prefixes or substrings, and anyone who can " +
+			"read the client database can tell which messages share a word and how often words recur, " +
+			"though not the words. Messages that cannot be decrypted are never found.",
+		CmdFn: func(args []string, client *types.Client) error {
+			if len(args) == 0 {
+				fmt.Println("Usage: /search <text>")
+				return nil
+			}
+			results, err := SearchMessages(context.TODO(), client, strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			self := shared.FormatAddress(client.Identity.Username, client.Identity.Domain)
+			for _, r := range results {
+				friend := shared.FormatAddress(r.Friend.Name, r.Friend.Domain)
+				from := friend
+				if r.Message.Direction == "outbound" {
+					from = self + " -> " + friend
+				}
+				fmt.Printf("%s [%s]: %s\n", time.UnixMilli(r.Message.Timestamp).Format("2006-01-02 15:04"), from, trimNewline(string(r.Message.Content)))
+			}
+			fmt.Printf("%d message(s) found\n", len(results))
+			return nil
+		},
+		Scope: []types.ShellMode{types.ModeDefault, types.ModeChat},
+	})
+
+	register(types.Command{
+		Name:     "/export",
+		Desc:     "Write your history with a friend to a file",
+		Usage:    "/export <user@domain> [--format json|txt|html] [--out file]",
+		Complete: completeFriends,
+		CmdFn: func(args []string, client *types.Client) error {
+			usage := "Usage: /export <user@domain> [--format json|txt|html] [--out file]"
+			if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+				fmt.Println(usage)
+				return nil
+			}
+			addr, err := shared.ParseAddress(args[0])
+			if err != nil {
+				fmt.Printf("invalid address: %v\n", err)
+				return nil
+			}
+			fs := flag.NewFlagSet("/export", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			format := fs.String("format", "txt", "")
+			out := fs.String("out", "", "")
+			if err := fs.Parse(args[1:]); err != nil || fs.NArg() > 0 || !slices.Contains(ExportFormats, *format) {
+				fmt.Println(usage)
+				return nil
+			}
+			if *out == "" {
+				*out = addr.Format() + "." + *format
+			}
+
+			n, err := ExportChatFile(context.TODO(), client, addr, *format, *out)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Exported %d message(s) to %s\n", n, *out)
+			return nil
+		},
+		Scope: []types.ShellMode{types.ModeDefault},
+	})
+
 	register(types.Command{
 		Name: "/exit",
 		Desc: "Exit mshell",
@@ -169,7 +620,7 @@ func buildCommandMap() (map[string]types.Command, error) {
 				client.State.Shell.Mode = types.ModeDefault
 			case types.ModeDefault:
 				fmt.Println("Exiting mshell")
-				os.Exit(0)
+				return errExitShell
 			}
 
 			return nil
@@ -178,13 +629,44 @@ func buildCommandMap() (map[string]types.Command, error) {
 	})
 
 	register(types.Command{
-		Name: "/help",
-		Desc: "List all available commands",
+		Name:  "/help",
+		Desc:  "List the commands available here, or show how to use one",
+		Usage: "/help [command]",
+		Complete: func(client *types.Client) []string {
+			return commandNames(cmds, client.State.Shell.Mode)
+		},
 		CmdFn: func(args []string, client *types.Client) error {
+			mode := client.State.Shell.Mode
+			if len(args) > 0 {
+				name := "/" + strings.TrimPrefix(args[0], "/")
+				cmd, ok := cmds[name]
+				if !ok {
+					if s := shared.Suggest(name, commandNames(cmds, mode)); s != "" {
+						fmt.Printf("Unknown command: %s, did you mean %s?\n", name, s)
+					} else {
+						fmt.Printf("Unknown command: %s\n", name)
+					}
+					return nil
+				}
+				usage := cmd.Usage
+				if usage == "" {
+					usage = cmd.Name
+				}
+				fmt.Printf("Usage: %s\n%s\n", usage, cmd.Desc)
+				if cmd.Help != "" {
+					fmt.Println(cmd.Help)
+				}
+				if !slices.Contains(cmd.Scope, mode) {
+					fmt.Println("Not available here.")
+				}
+				return nil
+			}
+
 			fmt.Println("Available Commands:")
-			for _, cmd := range cmds {
-				fmt.Printf("%s: %s\n", cmd.Name, cmd.Desc)
+			for _, name := range commandNames(cmds, mode) {
+				fmt.Printf("%s: %s\n", name, cmds[name].Desc)
 			}
+			fmt.Println("Type /help <command> for usage. Tab completes commands and friend addresses.")
 			return nil
 		},
 		Scope: []types.ShellMode{types.ModeDefault, types.ModeChat},
@@ -194,15 +676,22 @@ func buildCommandMap() (map[string]types.Command, error) {
 }
 
 func MShell(client *types.Client) error {
-	reader := bufio.NewReader(os.Stdin)
 	commands, err := buildCommandMap()
 	if err != nil {
 		return fmt.Errorf("failed to build command map: %v", err)
 	}
 
+	editor := newLineEditor(client, commands)
+	defer editor.Close()
+	client.State.Shell.ReadLine = editor.prompt
+	defer func() { client.State.Shell.ReadLine = nil }()
+
 	for {
-		printPrompt(client)
-		input, err := reader.ReadString('\n')
+		input, err := editor.readLine(shellPrompt(client))
+		if errors.Is(err, io.EOF) {
+			fmt.Println("Exiting mshell")
+			return nil
+		}
 		if err != nil {
 			fmt.Printf("Error reading input: %v\n", err)
 			continue
@@ -211,7 +700,12 @@ func MShell(client *types.Client) error {
 		parsed := inputParse(input)
 
 		if parsed.IsCommand {
-			dispatchCommand(commands, parsed, client)
+			editor.remember(parsed.Raw)
+			var exit bool
+			editor.cooked(func() { exit = dispatchCommand(commands, parsed, client) })
+			if exit {
+				return nil
+			}
 		} else {
 			switch client.State.Shell.Mode {
 			case types.ModeChat:
@@ -219,7 +713,7 @@ func MShell(client *types.Client) error {
 					continue
 				}
 				//TODO: active chat
-				if err := SendMessage(client, input); err != nil {
+				if err := SendMessage(client, input+"\n"); err != nil {
 					fmt.Printf("Send failed: %v\n", err)
 					continue
 				}
@@ -230,13 +724,79 @@ func MShell(client *types.Client) error {
 	}
 }
 
+// historyPage is how many messages entering a chat or /history shows.
+const historyPage = 20
+
 func enterChat(c *types.Client, target string) error {
+	if err := OpenChat(c, target); err != nil {
+		return err
+	}
+
+	msgs, err := loadHistoryPage(c, historyPage)
+	if err != nil {
+		fmt.Println("failure loading messages")
+		return err
+	}
+	if len(msgs) == historyPage {
+		fmt.Println("Type /history for earlier messages.")
+	}
+	printMessages(c, msgs)
+
+	return nil
+}
+
+// loadHistoryPage returns up to n messages of the current chat older than
+// those already shown, oldest first.
+func loadHistoryPage(c *types.Client, n int) ([]types.Message, error) {
+	chat := &c.State.Cache.CurrentChat
+	rows, err := c.DB.Messages.GetPage.QueryContext(context.TODO(), chat.User.Id.String(), n, chat.HistoryShown)
+	if err != nil {
+		return nil, fmt.Errorf("error querying messages: %v", err)
+	}
+	defer rows.Close()
+
+	var msgs []types.Message
+	for rows.Next() {
+		var msg types.Message
+		if err := rows.Scan(&msg.Id, &msg.FriendId, &msg.Direction, &msg.Content, &msg.Timestamp); err != nil {
+			return nil, err
+		}
+		if msg.Content, err = crypto.Decrypt(c, msg.Content); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	chat.HistoryShown += len(msgs)
+	slices.Reverse(msgs)
+	return msgs, nil
+}
+
+func printMessages(c *types.Client, msgs []types.Message) {
+	for _, v := range msgs {
+		if v.Direction == "inbound" {
+			fmt.Printf("[%s]: %s", shared.FormatAddress(c.State.Cache.CurrentChat.User.Name, c.State.Cache.CurrentChat.User.Domain), v.Content)
+		} else {
+			fmt.Printf("[%s]: %s", shared.FormatAddress(c.Identity.Username, c.Identity.Domain), v.Content)
+		}
+	}
+}
+
+// OpenChat derives the chat keys for a friend and makes them the current
+// chat, so SendMessage goes to them.
+func OpenChat(c *types.Client, target string) error {
 
 	u := types.User{}
 
 	var targetid string
 	idrow := c.DB.Friends.GetUserId.QueryRowContext(context.TODO(), target)
 	err := idrow.Scan(&targetid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s is not a friend", target)
+	}
 	if err != nil {
 		return err
 	}
@@ -254,7 +814,7 @@ func enterChat(c *types.Client, target string) error {
 
 	sharedSecret, err := network.ComputeSharedSecret(c.Identity.Keys["EncryptionPrivateKey"], u.Enckey)
 	if err != nil {
-		log.Print("failed to compute shared secret")
+		slog.Error("failed to compute shared secret", "error", err)
 		return err
 	}
 
@@ -273,20 +833,6 @@ func enterChat(c *types.Client, target string) error {
 
 	c.State.Cache.CurrentChat = cd
 
-	msgs, err := loadMessages(c)
-	if err != nil {
-		fmt.Println("failure loading messages")
-		return err
-	}
-
-	for _, v := range msgs {
-		if v.Direction == "inbound" {
-			fmt.Printf("[%s]: %s", shared.FormatAddress(c.State.Cache.CurrentChat.User.Name, c.State.Cache.CurrentChat.User.Domain), v.Content)
-		} else {
-			fmt.Printf("[%s]: %s", shared.FormatAddress(c.Identity.Username, c.Identity.Domain), v.Content)
-		}
-	}
-
 	return nil
 }
 
@@ -302,15 +848,13 @@ func shellFriendRequests(ctx context.Context, c *types.Client) error {
 	}
 
 	fmt.Println("Pending Friend requests")
-	inputReader := bufio.NewReader(os.Stdin)
 
 	for _, fr := range frs {
 		fmt.Printf("[%s] %s\n", fr.FriendId, shared.FormatAddress(fr.Username, fr.Domain))
-		fmt.Printf(" y[Accept] / n[Decline] :")
 
-		input, err := inputReader.ReadString('\n')
+		input, err := readInput(c, " y[Accept] / n[Decline] :")
 		if err != nil {
-			log.Printf("Error reading input: %v\n", err)
+			slog.Error("error reading input", "error", err)
 			continue
 		}
 
@@ -344,16 +888,13 @@ func FriendList(c *types.Client) error {
 		return err
 	}
 
-	reader := bufio.NewReader(os.Stdin)
-
 	if len(friends) == 0 {
 		//TODO: Handle query loop here?
 		fmt.Println("No friends yet.")
 
-		fmt.Print("See friend requests?: [y/n]")
-		input, err := reader.ReadString('\n')
+		input, err := readInput(c, "See friend requests?: [y/n]")
 		if err != nil {
-			log.Println("Error reading input")
+			slog.Error("error reading input", "error", err)
 			return err
 		}
 
@@ -372,14 +913,17 @@ func FriendList(c *types.Client) error {
 
 	//TODO: add active status
 	for _, f := range friends {
-		fmt.Printf("[%s] %s\n", f.Id, shared.FormatAddress(f.Name, f.Domain))
+		mark := ""
+		if f.Verified {
+			mark = " (verified)"
+		}
+		fmt.Printf("[%s] %s%s\n", f.Id, shared.FormatAddress(f.Name, f.Domain), mark)
 	}
 
 	//TODO: DRY
-	fmt.Print("See friend requests?: [y/n]")
-	input, err := reader.ReadString('\n')
+	input, err := readInput(c, "See friend requests?: [y/n]")
 	if err != nil {
-		log.Println("Error reading input")
+		slog.Error("error reading input", "error", err)
 		return err
 	}
 
@@ -396,7 +940,7 @@ func FriendList(c *types.Client) error {
 	return nil
 }
 
-func shellAddFriend(args []string, inputReader *bufio.Reader, c *types.Client) error {
+func shellAddFriend(args []string, c *types.Client) error {
 	// Direct address mode: /addfriend user@domain
 	if len(args) > 0 {
 		addr, err := shared.ParseAddress(args[0])
@@ -405,27 +949,14 @@ func shellAddFriend(args []string, inputReader *bufio.Reader, c *types.Client) e
 			return nil
 		}
 
-		if addr.Domain == "" {
-			addr.Domain = c.Identity.Domain
-		}
-
 		fmt.Printf("Looking up %s...\n", addr.Format())
 
-		uInfo, err := c.PBC.UserRequest(context.TODO(), &common_pb.UserAddress{
-			Username: addr.Username,
-			Domain:   addr.Domain,
-		})
-		if err != nil {
-			return fmt.Errorf("user lookup failed: %v", err)
-		}
-		if uInfo == nil || uInfo.UserId == "" {
-			fmt.Printf("User %s not found\n", addr.Format())
+		_, err = AddFriend(context.TODO(), c, addr)
+		if errors.Is(err, ErrUserNotFound) {
+			fmt.Println(err)
 			return nil
 		}
-
-		fmt.Printf("Found: %s (%s)\n", uInfo.Username, uInfo.UserId[:8])
-
-		return FriendRequest(context.TODO(), c, uInfo, addr.Domain)
+		return err
 	}
 
 	// Interactive picker: local online users
@@ -438,7 +969,7 @@ func shellAddFriend(args []string, inputReader *bufio.Reader, c *types.Client) e
 		SigningPublicKey:    c.Identity.Keys["SigningPublicKey"],
 	})
 	if err != nil {
-		log.Println("failed to get active users")
+		slog.Error("failed to get active users", "error", err)
 		return err
 	}
 
@@ -455,10 +986,9 @@ func shellAddFriend(args []string, inputReader *bufio.Reader, c *types.Client) e
 		}
 	}
 
-	fmt.Print("Enter the number of the user you want to invite (Enter to cancel): ")
-	selectedIndexString, err := inputReader.ReadString('\n')
+	selectedIndexString, err := readInput(c, "Enter the number of the user you want to invite (Enter to cancel): ")
 	if err != nil {
-		log.Printf("Error reading input: %v\n", err)
+		slog.Error("error reading input", "error", err)
 		return err
 	}
 
@@ -478,7 +1008,7 @@ func shellAddFriend(args []string, inputReader *bufio.Reader, c *types.Client) e
 
 	err = FriendRequest(context.TODO(), c, selectedUser, c.Identity.Domain)
 	if err != nil {
-		log.Printf("error beginning chat: %v", err)
+		slog.Error("error beginning chat", "error", err)
 	}
 
 	return nil
@@ -486,7 +1016,14 @@ func shellAddFriend(args []string, inputReader *bufio.Reader, c *types.Client) e
 
 // TODO: Need to figure out the best way to display these
 func loadMessages(c *types.Client) ([]types.Message, error) {
-	rows, err := c.DB.Messages.GetMessages.QueryContext(context.TODO(), c.State.Cache.CurrentChat.User.Id.String())
+	chat := c.State.Cache.CurrentChat
+	return FriendHistory(context.TODO(), c, &chat.User, chat.EncKey)
+}
+
+// FriendHistory returns your messages with friend u, opened with key, oldest
+// first.
+func FriendHistory(ctx context.Context, c *types.Client, u *types.User, key []byte) ([]types.Message, error) {
+	rows, err := c.DB.Messages.GetMessages.QueryContext(ctx, u.Id.String())
 	if err != nil {
 
 		return nil, fmt.Errorf("error querying messages: %v", err)
@@ -503,14 +1040,13 @@ func loadMessages(c *types.Client) ([]types.Message, error) {
 	for rows.Next() {
 		var msg types.Message
 		if err := rows.Scan(&msg.Id, &msg.FriendId, &msg.Direction, &msg.Content, &msg.Timestamp); err != nil {
-			log.Printf("error scanning row: %v", err)
+			slog.Error("error scanning row", "error", err)
 			return nil, err
 		}
 
-		decrypted, err := crypto.Decrypt(c, msg.Content)
+		decrypted, err := crypto.Open(key, msg.Content)
 		if err != nil {
-			fmt.Printf("Failed to decrypt sealed message")
-			return nil, err
+			return nil, fmt.Errorf("failed to decrypt message %s: %v", msg.Id, err)
 		}
 
 		msg.Content = decrypted
@@ -518,7 +1054,7 @@ func loadMessages(c *types.Client) ([]types.Message, error) {
 		messages = append(messages, msg)
 	}
 
-	return messages, nil
+	return messages, rows.Err()
 }
 
 // TODO: Generic loading function?
@@ -542,8 +1078,8 @@ func loadFriends(c *types.Client) ([]*types.User, error) {
 		found = true
 		var u types.User
 		var crAt time.Time
-		if err := rows.Scan(&u.Id, &u.Name, &u.Domain, &u.Enckey, &u.Sigkey, &u.KeyEx, &crAt); err != nil {
-			log.Printf("error scanning row: %v", err)
+		if err := rows.Scan(&u.Id, &u.Name, &u.Domain, &u.Enckey, &u.Sigkey, &u.KeyEx, &u.Verified, &crAt); err != nil {
+			slog.Error("error scanning row", "error", err)
 			return nil, err
 		}
 		users = append(users, &u)
@@ -560,6 +1096,101 @@ func loadFriends(c *types.Client) ([]*types.User, error) {
 	return users, nil
 }
 
+// listBlocks prints the block list entries of one kind.
+func listBlocks(c *types.Client, kind string) error {
+	blocks, err := Blocks(context.TODO(), c)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, b := range blocks {
+		if b.Kind != kind {
+			continue
+		}
+		n++
+		if b.Label != "" {
+			fmt.Printf("%s (%s)\n", b.Label, b.Value)
+		} else {
+			fmt.Println(b.Value)
+		}
+	}
+	if n == 0 {
+		fmt.Printf("No blocked %ss.\n", kind)
+	}
+	return nil
+}
+
+// AddFriend looks addr up and sends them a friend request. An address
+// without a domain is on our own server.
+func AddFriend(ctx context.Context, c *types.Client, addr shared.StrikeAddress) (*common_pb.UserInfo, error) {
+	if addr.Domain == "" {
+		addr.Domain = c.Identity.Domain
+	}
+
+	uInfo, err := c.PBC.UserRequest(ctx, &common_pb.UserAddress{
+		Username: addr.Username,
+		Domain:   addr.Domain,
+	})
+	if isNotFound(err) || (err == nil && uInfo.UserId == "") {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, addr.Format())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("user lookup failed: %w", serverError(err))
+	}
+
+	// A moved account answers from its new home.
+	if uInfo.Domain != "" {
+		addr.Domain = uInfo.Domain
+	}
+
+	fmt.Printf("Found: %s (%s)\n", uInfo.Username, uInfo.UserId[:8])
+	network.WarnKeyChange(ctx, c, uInfo.UserId, uInfo.EncryptionPublicKey, uInfo.SigningPublicKey)
+
+	if err := FriendRequest(ctx, c, uInfo, addr.Domain); err != nil {
+		return nil, err
+	}
+	uInfo.Domain = addr.Domain
+	return uInfo, nil
+}
+
+// Friends returns the address book.
+func Friends(c *types.Client) ([]*types.User, error) {
+	return loadFriends(c)
+}
+
+// FriendRequests returns the friend requests waiting for an answer.
+func FriendRequests(c *types.Client) ([]*types.FriendRequest, error) {
+	return loadFriendRequests(c)
+}
+
+// AnswerFriendRequest accepts or declines the pending request from addr.
+func AnswerFriendRequest(ctx context.Context, c *types.Client, addr shared.StrikeAddress, accept bool) (*types.FriendRequest, error) {
+	frs, err := loadFriendRequests(c)
+	if err != nil {
+		return nil, err
+	}
+	for _, fr := range frs {
+		if fr.Username != addr.Username || (addr.Domain != "" && fr.Domain != addr.Domain) {
+			continue
+		}
+		pbfr := pb.FriendRequest{
+			Target: c.Identity.ID.String(),
+			UserInfo: &common_pb.UserInfo{
+				UserId:              fr.FriendId.String(),
+				Username:            fr.Username,
+				EncryptionPublicKey: fr.Enckey,
+				SigningPublicKey:    fr.Sigkey,
+			},
+			SenderDomain: fr.Domain,
+		}
+		if err := FriendResponse(ctx, c, &pbfr, accept, fr.Domain); err != nil {
+			return nil, fmt.Errorf("friend response failure: %v", err)
+		}
+		return fr, nil
+	}
+	return nil, fmt.Errorf("no friend request from %s", addr.Format())
+}
+
 // TODO: DRY?
 func loadFriendRequests(c *types.Client) ([]*types.FriendRequest, error) {
 	rows, err := c.DB.FriendRequest.GetFriendRequests.QueryContext(context.TODO())
@@ -580,7 +1211,7 @@ func loadFriendRequests(c *types.Client) ([]*types.FriendRequest, error) {
 		found = true
 		var fr types.FriendRequest
 		if err := rows.Scan(&fr.FriendId, &fr.Username, &fr.Domain, &fr.Enckey, &fr.Sigkey, &fr.Direction); err != nil {
-			log.Printf("error scanning row: %v", err)
+			slog.Error("error scanning row", "error", err)
 			return nil, err
 		}
 
@@ -0,0 +1,571 @@
+// Package tui is a full-screen terminal frontend for a logged in client:
+// a conversation list with unread counts and presence, the open chat's
+// history, an input line and friend request notifications.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/JohnnyGlynn/strike/internal/client"
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+)
+
+const (
+	// presenceInterval is how often the online list is refreshed.
+	presenceInterval = 15 * time.Second
+
+	listWidth = 30
+)
+
+var (
+	paneStyle     = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8"))
+	focusStyle    = paneStyle.BorderForeground(lipgloss.Color("12"))
+	selectedStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	unreadStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("11"))
+	onlineStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	dimStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	selfStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
+	noticeStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+)
+
+// Run shows the TUI for c, which must be logged in, until the user quits or
+// ctx is done. What the client prints and any lines sent on logs appear on
+// the status line; point the logger at a Logs before calling Run so log
+// output does not draw over the screen.
+func Run(ctx context.Context, c *types.Client, logs Logs) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("tui needs a terminal: %v", err)
+	}
+	defer tty.Close()
+
+	p := tea.NewProgram(newModel(c), tea.WithContext(ctx), tea.WithInput(tty), tea.WithOutput(tty), tea.WithAltScreen())
+
+	output := c.State.Output
+	c.State.Output = statusWriter{p}
+	c.State.Notify = func(ev types.Event) { p.Send(eventMsg(ev)) }
+	defer func() { c.State.Output, c.State.Notify = output, nil }()
+
+	streamCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go func() {
+		for {
+			select {
+			case <-streamCtx.Done():
+				return
+			case l := <-logs:
+				p.Send(statusMsg(l))
+			}
+		}
+	}()
+	client.KeepStreams(streamCtx, c)
+	go client.RetainMessages(streamCtx, c)
+
+	_, err = p.Run()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// Logs carries log lines to the status line. Writes never block: lines
+// arriving faster than the TUI shows them are dropped.
+type Logs chan string
+
+// NewLogs returns a Logs to pass to logging.Setup and Run.
+func NewLogs() Logs {
+	return make(Logs, 64)
+}
+
+func (l Logs) Write(b []byte) (int, error) {
+	select {
+	case l <- strings.TrimSpace(string(b)):
+	default:
+	}
+	return len(b), nil
+}
+
+// statusWriter turns the client's output into status line messages.
+type statusWriter struct{ p *tea.Program }
+
+func (w statusWriter) Write(b []byte) (int, error) {
+	w.p.Send(statusMsg(strings.TrimSpace(string(b))))
+	return len(b), nil
+}
+
+type chat struct {
+	user   *types.User
+	unread int
+	online bool
+}
+
+type line struct {
+	from string
+	text string
+	at   time.Time
+	self bool
+}
+
+type (
+	eventMsg  types.Event
+	statusMsg string
+
+	friendsMsg struct {
+		friends  []*types.User
+		requests []*types.FriendRequest
+		err      error
+	}
+	historyMsg struct {
+		userID string
+		lines  []line
+		err    error
+	}
+	sentMsg struct {
+		userID string
+		text   string
+		err    error
+	}
+	answeredMsg struct {
+		fr     *types.FriendRequest
+		accept bool
+		err    error
+	}
+	presenceMsg struct {
+		online map[string]bool
+		err    error
+	}
+	presenceTick struct{}
+)
+
+type focus int
+
+const (
+	focusList focus = iota
+	focusInput
+)
+
+type model struct {
+	c *types.Client
+
+	chats    []*chat
+	selected int
+	open     *chat
+	history  []line
+	requests []*types.FriendRequest
+	online   map[string]bool
+	status   string
+
+	focus  focus
+	input  textinput.Model
+	view   viewport.Model
+	width  int
+	height int
+}
+
+func newModel(c *types.Client) model {
+	in := textinput.New()
+	in.Placeholder = "Select a chat with enter"
+	in.Prompt = "> "
+	in.CharLimit = 4096
+
+	return model{
+		c:      c,
+		online: map[string]bool{},
+		input:  in,
+		view:   viewport.New(0, 0),
+		status: "tab switches panes · enter opens or sends · ctrl+a/ctrl+d answer requests · ctrl+c quits",
+	}
+}
+
+func (m model) Init() tea.Cmd {
+	return tea.Batch(m.loadFriends(), m.loadPresence())
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.layout()
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.key(msg)
+
+	case friendsMsg:
+		if msg.err != nil {
+			m.status = "loading friends: " + msg.err.Error()
+			return m, nil
+		}
+		m.setFriends(msg.friends)
+		m.requests = msg.requests
+		return m, nil
+
+	case historyMsg:
+		if msg.err != nil {
+			m.status = "loading history: " + msg.err.Error()
+			return m, nil
+		}
+		if m.open != nil && m.open.user.Id.String() == msg.userID {
+			m.history = msg.lines
+			m.render()
+		}
+		return m, nil
+
+	case sentMsg:
+		if msg.err != nil {
+			m.status = "send failed: " + msg.err.Error()
+			return m, nil
+		}
+		if m.open != nil && m.open.user.Id.String() == msg.userID {
+			m.history = append(m.history, line{from: "you", text: msg.text, at: time.Now(), self: true})
+			m.render()
+		}
+		return m, nil
+
+	case answeredMsg:
+		if msg.err != nil {
+			m.status = msg.err.Error()
+			return m, nil
+		}
+		verb := "Declined"
+		if msg.accept {
+			verb = "Accepted"
+		}
+		m.status = fmt.Sprintf("%s friend request from %s", verb, shared.FormatAddress(msg.fr.Username, msg.fr.Domain))
+		return m, m.loadFriends()
+
+	case presenceMsg:
+		if msg.err == nil {
+			m.online = msg.online
+			for _, ch := range m.chats {
+				ch.online = m.online[ch.user.Id.String()]
+			}
+		}
+		return m, tea.Tick(presenceInterval, func(time.Time) tea.Msg { return presenceTick{} })
+
+	case presenceTick:
+		return m, m.loadPresence()
+
+	case eventMsg:
+		return m.event(types.Event(msg))
+
+	case statusMsg:
+		m.status = string(msg)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.view, cmd = m.view.Update(msg)
+	return m, cmd
+}
+
+func (m model) key(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "tab", "shift+tab":
+		if m.focus == focusList {
+			m.focus = focusInput
+			return m, m.input.Focus()
+		}
+		m.focus = focusList
+		m.input.Blur()
+		return m, nil
+	case "ctrl+a", "ctrl+d":
+		if len(m.requests) == 0 {
+			m.status = "No pending friend requests"
+			return m, nil
+		}
+		fr := m.requests[0]
+		m.requests = m.requests[1:]
+		return m, m.answer(fr, msg.String() == "ctrl+a")
+	case "pgup", "pgdown":
+		var cmd tea.Cmd
+		m.view, cmd = m.view.Update(msg)
+		return m, cmd
+	}
+
+	if m.focus == focusList {
+		switch msg.String() {
+		case "up", "k":
+			if m.selected > 0 {
+				m.selected--
+			}
+		case "down", "j":
+			if m.selected < len(m.chats)-1 {
+				m.selected++
+			}
+		case "enter":
+			if m.selected < len(m.chats) {
+				return m, m.openChat(m.chats[m.selected])
+			}
+		case "q", "esc":
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc":
+		m.focus = focusList
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		text := m.input.Value()
+		if m.open == nil || strings.TrimSpace(text) == "" {
+			return m, nil
+		}
+		m.input.Reset()
+		return m, m.send(m.open, text)
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m model) event(ev types.Event) (tea.Model, tea.Cmd) {
+	addr := shared.FormatAddress(ev.Username, ev.Domain)
+	switch ev.Type {
+	case types.EventMessage:
+		if m.open != nil && m.open.user.Id.String() == ev.UserID {
+			m.history = append(m.history, line{from: ev.Username, text: ev.Message, at: ev.Time})
+			m.render()
+			return m, nil
+		}
+		for _, ch := range m.chats {
+			if ch.user.Id.String() == ev.UserID {
+				ch.unread++
+				return m, nil
+			}
+		}
+		return m, m.loadFriends()
+
+	case types.EventFriendRequest:
+		m.status = fmt.Sprintf("Friend request from %s · ctrl+a accept · ctrl+d decline", addr)
+	case types.EventFriendResponse:
+		if ev.Accepted {
+			m.status = addr + " accepted your friend request"
+		} else {
+			m.status = addr + " declined your friend request"
+		}
+	case types.EventFriendRemoved:
+		m.status = addr + " removed you from their friends"
+		if m.open != nil && m.open.user.Id.String() == ev.UserID {
+			m.open, m.history = nil, nil
+			m.render()
+		}
+	}
+	return m, m.loadFriends()
+}
+
+// setFriends replaces the conversation list, keeping unread counts and the
+// selection.
+func (m *model) setFriends(friends []*types.User) {
+	prev := map[string]*chat{}
+	for _, ch := range m.chats {
+		prev[ch.user.Id.String()] = ch
+	}
+	var selected string
+	if m.selected < len(m.chats) {
+		selected = m.chats[m.selected].user.Id.String()
+	}
+
+	sort.Slice(friends, func(i, j int) bool {
+		return shared.FormatAddress(friends[i].Name, friends[i].Domain) < shared.FormatAddress(friends[j].Name, friends[j].Domain)
+	})
+	m.chats = m.chats[:0]
+	m.selected = 0
+	for i, u := range friends {
+		ch := &chat{user: u, online: m.online[u.Id.String()]}
+		if p, ok := prev[u.Id.String()]; ok {
+			ch.unread = p.unread
+		}
+		if m.open != nil && m.open.user.Id == u.Id {
+			m.open = ch
+		}
+		if u.Id.String() == selected {
+			m.selected = i
+		}
+		m.chats = append(m.chats, ch)
+	}
+}
+
+func (m *model) layout() {
+	m.view.Width = max(m.width-listWidth-6, 10)
+	m.view.Height = max(m.height-7, 3)
+	m.input.Width = m.view.Width - 3
+	m.render()
+}
+
+func (m *model) render() {
+	if m.open == nil {
+		m.view.SetContent(dimStyle.Render("No chat open."))
+		return
+	}
+	var b strings.Builder
+	for _, l := range m.history {
+		from := l.from
+		if l.self {
+			from = selfStyle.Render(from)
+		}
+		text := strings.TrimRight(l.text, "\n")
+		fmt.Fprintf(&b, "%s %s: %s\n", dimStyle.Render(l.at.Local().Format("15:04")), from, text)
+	}
+	m.view.SetContent(lipgloss.NewStyle().Width(m.view.Width).Render(strings.TrimRight(b.String(), "\n")))
+	m.view.GotoBottom()
+}
+
+func (m model) View() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	var list strings.Builder
+	fmt.Fprintf(&list, "%s\n\n", lipgloss.NewStyle().Bold(true).Render("Chats"))
+	if len(m.chats) == 0 {
+		list.WriteString(dimStyle.Render("No friends yet"))
+	}
+	for i, ch := range m.chats {
+		dot := dimStyle.Render("○")
+		if ch.online {
+			dot = onlineStyle.Render("●")
+		}
+		name := shared.FormatAddress(ch.user.Name, ch.user.Domain)
+		if w := listWidth - 8; len(name) > w {
+			name = name[:w-1] + "…"
+		}
+		if i == m.selected {
+			name = selectedStyle.Render(name)
+		}
+		fmt.Fprintf(&list, "%s %s", dot, name)
+		if ch.unread > 0 {
+			fmt.Fprintf(&list, " %s", unreadStyle.Render(fmt.Sprintf("(%d)", ch.unread)))
+		}
+		list.WriteString("\n")
+	}
+	if n := len(m.requests); n > 0 {
+		fmt.Fprintf(&list, "\n%s", noticeStyle.Render(fmt.Sprintf("%d friend request(s)", n)))
+	}
+
+	listPane, chatPane := paneStyle, paneStyle
+	if m.focus == focusList {
+		listPane = focusStyle
+	} else {
+		chatPane = focusStyle
+	}
+
+	title := "Strike"
+	if m.open != nil {
+		title = shared.FormatAddress(m.open.user.Name, m.open.user.Domain)
+		if m.open.online {
+			title += " " + onlineStyle.Render("online")
+		}
+	}
+
+	left := listPane.Width(listWidth).Height(m.height - 4).Render(list.String())
+	right := chatPane.Width(m.view.Width + 2).Height(m.height - 4).Render(
+		lipgloss.JoinVertical(lipgloss.Left, lipgloss.NewStyle().Bold(true).Render(title), m.view.View(), "", m.input.View()))
+
+	status := dimStyle.Render(truncate(m.status, m.width))
+	return lipgloss.JoinVertical(lipgloss.Left, lipgloss.JoinHorizontal(lipgloss.Top, left, right), status)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n || n < 1 {
+		return s
+	}
+	return s[:n-1] + "…"
+}
+
+func (m model) loadFriends() tea.Cmd {
+	c := m.c
+	return func() tea.Msg {
+		friends, err := client.Friends(c)
+		if err != nil {
+			return friendsMsg{err: err}
+		}
+		requests, err := client.FriendRequests(c)
+		return friendsMsg{friends, requests, err}
+	}
+}
+
+func (m model) loadPresence() tea.Cmd {
+	c := m.c
+	return func() tea.Msg {
+		users, err := client.GetActiveUsers(c, &common_pb.UserInfo{
+			Username: c.Identity.Username,
+			UserId:   c.Identity.ID.String(),
+		})
+		if err != nil {
+			return presenceMsg{err: err}
+		}
+		online := map[string]bool{}
+		for _, u := range users.Users {
+			online[u.UserId] = true
+		}
+		return presenceMsg{online: online}
+	}
+}
+
+func (m *model) openChat(ch *chat) tea.Cmd {
+	m.open, m.history = ch, nil
+	ch.unread = 0
+	m.focus = focusInput
+	m.input.Placeholder = "Message " + ch.user.Name
+	m.render()
+
+	c, u := m.c, ch.user
+	return tea.Batch(m.input.Focus(), func() tea.Msg {
+		ctx := context.Background()
+		friend, key, err := client.FriendChat(ctx, c, shared.StrikeAddress{Username: u.Name, Domain: u.Domain})
+		if err != nil {
+			return historyMsg{userID: u.Id.String(), err: err}
+		}
+		msgs, err := client.FriendHistory(ctx, c, friend, key)
+		if err != nil {
+			return historyMsg{userID: u.Id.String(), err: err}
+		}
+		lines := make([]line, 0, len(msgs))
+		for _, msg := range msgs {
+			l := line{from: u.Name, text: string(msg.Content), at: time.UnixMilli(msg.Timestamp)}
+			if msg.Direction == "outbound" {
+				l.from, l.self = "you", true
+			}
+			lines = append(lines, l)
+		}
+		return historyMsg{userID: u.Id.String(), lines: lines}
+	})
+}
+
+func (m model) send(ch *chat, text string) tea.Cmd {
+	c, u := m.c, ch.user
+	return func() tea.Msg {
+		ctx := context.Background()
+		friend, key, err := client.FriendChat(ctx, c, shared.StrikeAddress{Username: u.Name, Domain: u.Domain})
+		if err == nil {
+			err = client.SendTo(ctx, c, friend, key, text+"\n") // as the shell sends it
+		}
+		return sentMsg{userID: u.Id.String(), text: text, err: err}
+	}
+}
+
+func (m model) answer(fr *types.FriendRequest, accept bool) tea.Cmd {
+	c := m.c
+	return func() tea.Msg {
+		_, err := client.AnswerFriendRequest(context.Background(), c, shared.StrikeAddress{Username: fr.Username, Domain: fr.Domain}, accept)
+		return answeredMsg{fr, accept, err}
+	}
+}
@@ -2,6 +2,9 @@ package types
 
 import (
 	"database/sql"
+	"io"
+	"os"
+	"time"
 	// "sync"
 
 	"github.com/JohnnyGlynn/strike/internal/config"
@@ -15,13 +18,55 @@ type ClientIdentity struct {
 	ID       uuid.UUID
 	Keys     map[string][]byte
 	Config   *config.ClientConfig
+
+	// KeyPassphrase protects the private key files. It is nil when the keys
+	// are stored in the clear or came from the key agent.
+	KeyPassphrase []byte
 }
 
 type ClientState struct {
 	Cache Cache
 	Shell *ShellState
+
+	// Notify, when set, is called with every incoming message and friend
+	// event. The daemon uses it to feed its subscribers; it is called from
+	// the demultiplexer goroutines and must not block.
+	Notify func(Event)
+
+	// Output receives what the client prints as payloads arrive and calls
+	// complete: incoming messages, key changes, server responses. Nil means
+	// os.Stdout. The TUI points it at its status line and commands at
+	// stderr, keeping stdout for results.
+	Output io.Writer
 }
 
+// Out is where the client prints; see ClientState.Output.
+func (c *Client) Out() io.Writer {
+	if c.State == nil || c.State.Output == nil {
+		return os.Stdout
+	}
+	return c.State.Output
+}
+
+// Event is an incoming payload as seen by the client.
+type Event struct {
+	Type     string    `json:"type"`
+	UserID   string    `json:"user_id"`
+	Username string    `json:"username"`
+	Domain   string    `json:"domain"`
+	Message  string    `json:"message,omitempty"`
+	Accepted bool      `json:"accepted,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// Event types.
+const (
+	EventMessage        = "message"
+	EventFriendRequest  = "friend_request"
+	EventFriendResponse = "friend_response"
+	EventFriendRemoved  = "friend_removed"
+)
+
 type Cache struct {
 	// mu             sync.RWMutex
 	FriendRequests map[uuid.UUID]*pb.FriendRequest
@@ -33,6 +78,9 @@ type ChatSession struct {
 	SharedSecret []byte
 	EncKey       []byte
 	HmacKey      []byte
+	// HistoryShown counts the messages already printed, newest first, so
+	// /history pages further back.
+	HistoryShown int
 	//TODO:IsGroup
 }
 
@@ -44,12 +92,13 @@ type Client struct {
 }
 
 type User struct {
-	Id     uuid.UUID
-	Name   string
-	Domain string
-	Enckey []byte
-	Sigkey []byte
-	KeyEx  int
+	Id       uuid.UUID
+	Name     string
+	Domain   string
+	Enckey   []byte
+	Sigkey   []byte
+	KeyEx    int
+	Verified bool
 }
 
 type Message struct {
@@ -78,23 +127,59 @@ type ClientDB struct {
 		GetFriends      *sql.Stmt
 		GetKeyEx        *sql.Stmt
 		ConfirmKeyEx    *sql.Stmt
+		DeleteUser      *sql.Stmt
+		DeleteAll       *sql.Stmt
+		UpdateAddress   *sql.Stmt
+		UpdateKeys      *sql.Stmt
+		ResetKeyEx      *sql.Stmt
+		GetVerified     *sql.Stmt
+		SetVerified     *sql.Stmt
 	}
 
 	ID struct {
-		GetID  *sql.Stmt
-		GetUID *sql.Stmt
-		SaveID *sql.Stmt
+		GetID       *sql.Stmt
+		GetUID      *sql.Stmt
+		SaveID      *sql.Stmt
+		DeleteID    *sql.Stmt
+		Rename      *sql.Stmt
+		GetBySigKey *sql.Stmt
+		UpdateKeys  *sql.Stmt
 	}
 
 	Messages struct {
-		SaveMessage *sql.Stmt
-		GetMessages *sql.Stmt
+		SaveMessage        *sql.Stmt
+		GetMessages        *sql.Stmt
+		DeleteConversation *sql.Stmt
+		DeleteAll          *sql.Stmt
+		PurgeBefore        *sql.Stmt
+		Reseal             *sql.Stmt
+		ListChats          *sql.Stmt
+		GetPage            *sql.Stmt
+		All                *sql.Stmt
+	}
+
+	Search struct {
+		Index     *sql.Stmt
+		Unindexed *sql.Stmt
+		Prune     *sql.Stmt
+		Clear     *sql.Stmt
+		Match     *sql.Stmt
 	}
 
 	FriendRequest struct {
 		SaveFriendRequest   *sql.Stmt
 		GetFriendRequests   *sql.Stmt
 		DeleteFriendRequest *sql.Stmt
+		DeleteAll           *sql.Stmt
+		PurgeBefore         *sql.Stmt
+		FindUser            *sql.Stmt
+	}
+
+	Blocks struct {
+		List      *sql.Stmt
+		Add       *sql.Stmt
+		Remove    *sql.Stmt
+		IsBlocked *sql.Stmt
 	}
 }
 
@@ -107,13 +192,23 @@ const (
 
 type ShellState struct {
 	Mode ShellMode
+
+	// ReadLine, set while the shell runs, prompts through its line editor.
+	// Commands that ask for input use it rather than reading stdin, which
+	// the editor is already buffering.
+	ReadLine func(prompt string) (string, error)
 }
 
 type Command struct {
 	Name  string
 	Desc  string
+	Usage string // how to call it, shown by /help <command>
+	Help  string // anything more to know, shown by /help <command>
 	CmdFn func(args []string, client *Client) error
 	Scope []ShellMode
+
+	// Complete, when set, offers tab completions for the arguments.
+	Complete func(client *Client) []string
 }
 
 type ParsedInput struct {
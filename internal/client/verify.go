@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"strings"
+
+	"github.com/JohnnyGlynn/strike/internal/client/types"
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+)
+
+// SafetyNumber returns the safety number shared with the friend at addr,
+// computed from both parties' keys as held locally. Comparing it out of band
+// shows that no server swapped the keys in between. The domain must match,
+// so the number printed and verified is always for the address given.
+func SafetyNumber(ctx context.Context, c *types.Client, addr shared.StrikeAddress) (*types.User, string, error) {
+	u, err := FindFriend(ctx, c, addr)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := c.DB.Friends.GetVerified.QueryRowContext(ctx, u.Id.String()).Scan(&u.Verified); err != nil {
+		return nil, "", err
+	}
+
+	local := keys.Fingerprint(c.Identity.ID.String(), c.Identity.Keys["SigningPublicKey"], c.Identity.Keys["EncryptionPublicKey"])
+	remote := keys.Fingerprint(u.Id.String(), u.Sigkey, u.Enckey)
+	return u, keys.SafetyNumber(local, remote), nil
+}
+
+// MarkVerified records that the safety number with a friend was compared.
+func MarkVerified(ctx context.Context, c *types.Client, u *types.User, verified bool) error {
+	_, err := c.DB.Friends.SetVerified.ExecContext(ctx, verified, u.Id.String())
+	return err
+}
+
+// matchesSafetyCode accepts either the SafetyCode token or the digits with
+// any spacing.
+func matchesSafetyCode(input, safetyNumber string) bool {
+	input = strings.TrimPrefix(strings.TrimSpace(input), "STRIKE1:")
+	return strings.Join(strings.Fields(input), "") == strings.ReplaceAll(safetyNumber, " ", "")
+}
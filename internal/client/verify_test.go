@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+)
+
+func TestMatchesSafetyCode(t *testing.T) {
+	alice := keys.Fingerprint("11111111-1111-1111-1111-111111111111", []byte("alice sig"), []byte("alice enc"))
+	bob := keys.Fingerprint("22222222-2222-2222-2222-222222222222", []byte("bob sig"), []byte("bob enc"))
+	number := keys.SafetyNumber(alice, bob)
+	digits := strings.ReplaceAll(number, " ", "")
+
+	// One digit changed, as a substituted key would leave most of it alone.
+	last := digits[len(digits)-1]
+	changed := digits[:len(digits)-1] + string('0'+(last-'0'+1)%10)
+
+	cases := map[string]struct {
+		input string
+		match bool
+	}{
+		"code":                 {input: keys.SafetyCode(number), match: true},
+		"code-from-other-side": {input: keys.SafetyCode(keys.SafetyNumber(bob, alice)), match: true},
+		"number-as-shown":      {input: number, match: true},
+		"digits-only":          {input: digits, match: true},
+		"surrounding-space":    {input: "  " + keys.SafetyCode(number) + "\n", match: true},
+		"regrouped":            {input: digits[:30] + "\n" + digits[30:], match: true},
+		"one-digit-changed":    {input: changed},
+		"code-one-digit-off":   {input: "STRIKE1:" + changed},
+		"truncated":            {input: digits[:59]},
+		"extra-digit":          {input: digits + "0"},
+		"own-fingerprint":      {input: alice},
+		"other-number":         {input: keys.SafetyNumber(alice, alice)},
+		"other-prefix":         {input: "STRIKE2:" + digits},
+		"empty":                {input: ""},
+		"prefix-only":          {input: "STRIKE1:"},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := matchesSafetyCode(tc.input, number); got != tc.match {
+				t.Errorf("matchesSafetyCode(%q) = %v, want %v", tc.input, got, tc.match)
+			}
+		})
+	}
+}
+
+func TestSafetyNumberChecksDomain(t *testing.T) {
+	ctx := context.Background()
+	c, _, _ := searchClient(t, false)
+	c.Identity.ID = uuid.New()
+	c.Identity.Domain = "a.example"
+
+	cases := map[string]struct {
+		addr string
+		text string // empty when bob is found
+	}{
+		"friend":       {addr: "bob@b.example"},
+		"other-domain": {addr: "bob@c.example", text: "bob@c.example is not a friend (bob@b.example is)"},
+		"own-domain":   {addr: "bob", text: "is not a friend (bob@b.example is)"},
+		"stranger":     {addr: "carol@b.example", text: "carol@b.example is not a friend"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			addr, err := shared.ParseAddress(tc.addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, number, err := SafetyNumber(ctx, c, addr)
+			if tc.text != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.text) {
+					t.Fatalf("error = %v, want it to contain %q", err, tc.text)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			local := keys.Fingerprint(c.Identity.ID.String(), c.Identity.Keys["SigningPublicKey"], c.Identity.Keys["EncryptionPublicKey"])
+			if want := keys.SafetyNumber(local, keys.Fingerprint(u.Id.String(), u.Sigkey, u.Enckey)); number != want {
+				t.Errorf("SafetyNumber = %q, want %q", number, want)
+			}
+			if u.Name != "bob" || u.Domain != "b.example" || u.Verified {
+				t.Errorf("friend = %s@%s verified %v, want bob@b.example unverified", u.Name, u.Domain, u.Verified)
+			}
+		})
+	}
+}
@@ -1,140 +1,461 @@
 package config
 
 import (
-	"encoding/json"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
+// Defaults applied to optional ServerConfig fields left unset.
+const (
+	DefaultListenAddress           = ":8080"
+	DefaultFederationListenAddress = ":9090"
+	DefaultAdminAddress            = ":8090"
+	DefaultAdminRPCAddress         = "127.0.0.1:8091"
+	DefaultTLSMinVersion           = "1.2"
+	DefaultKeepaliveTime           = 2 * time.Minute
+	DefaultKeepaliveTimeout        = 20 * time.Second
+	DefaultKeepaliveMinTime        = 30 * time.Second
+	DefaultMaxMsgSize              = 4 << 20 // 4 MiB, the gRPC default
+	DefaultDeliveryTimeout         = 5 * time.Second
+	DefaultRelayTimeout            = 10 * time.Second
+	DefaultShutdownTimeout         = 15 * time.Second
+	DefaultLogLevel                = "info"
+	DefaultClientLogLevel          = "warn"
+	DefaultLogFormat               = "text"
+	DefaultTracingExporter         = "none"
+	DefaultTracingEndpoint         = "localhost:4317"
+	DefaultTracingSampleRatio      = 1.0
+
+	DefaultUserRate          = 10.0
+	DefaultUserBurst         = 20
+	DefaultIPRate            = 20.0
+	DefaultIPBurst           = 40
+	DefaultAuthRate          = 0.5
+	DefaultAuthBurst         = 5
+	DefaultMaxPendingPerUser = 500
+	DefaultLoginMaxFailures  = 5
+	DefaultLoginLockout      = time.Minute
+	DefaultLoginLockoutMax   = time.Hour
+
+	DefaultFriendRequestRate  = 1.0 / 60
+	DefaultFriendRequestBurst = 10
+)
+
+// DefaultFriendRequestTTL is how long the client keeps an unanswered friend
+// request.
+const DefaultFriendRequestTTL = 30 * 24 * time.Hour
+
+// DefaultShellHistory is where the shell keeps command history, next to the
+// client database.
+const DefaultShellHistory = ".strike_history"
+
+// Duration is a time.Duration that reads and writes as a Go duration string
+// ("30s", "2m") in every config format and in environment variables.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Struct tags drive the Loader: json/yaml/toml name the file key, env names
+// the override variable, flag overrides the derived --flag-name, required
+// marks mandatory fields and secret redacts the value in --print-config.
 type ServerConfig struct {
-	Name                  string `json:"name" yaml:"name"`
-	SigningPrivateKeyPath string `json:"private_server_signing_key_path" yaml:"private_server_singing_key_path"`
-	SigningPublicKeyPath  string `json:"public_server_signing_key_path" yaml:"public_server_signing_key_path"`
-	CertificatePath       string `json:"certificate_path" yaml:"certificate_path"`
-	FederationCAPath      string `json:"federation_ca_path" yaml:"federation_ca_path"`
-	FederationPeers       string `json:"federation_peers" yaml:"federation_peers"`
-	IdentityFile          string `json:"id_file" yaml:"id_file"`
-	DBConnectionString    string `json:"db_connection_string" yaml:"db_connection_string"`
+	Name                  string `json:"name" yaml:"name" toml:"name" env:"SERVER_NAME" required:"true" usage:"Server name, also used as the federation domain"`
+	SigningPrivateKeyPath string `json:"private_server_signing_key_path" yaml:"private_server_signing_key_path" toml:"private_server_signing_key_path" env:"PRIVATE_SERVER_SIGNING_KEY_PATH" required:"true" usage:"Path to the server ED25519 private key"`
+	SigningPublicKeyPath  string `json:"public_server_signing_key_path" yaml:"public_server_signing_key_path" toml:"public_server_signing_key_path" env:"PUBLIC_SERVER_SIGNING_KEY_PATH" required:"true" usage:"Path to the server ED25519 public key"`
+	CertificatePath       string `json:"certificate_path" yaml:"certificate_path" toml:"certificate_path" env:"CERT_PATH" required:"true" usage:"Path to the server certificate"`
+	FederationCAPath      string `json:"federation_ca_path" yaml:"federation_ca_path" toml:"federation_ca_path" env:"FED_CA_PATH" required:"true" usage:"Path to the federation CA certificate"`
+	FederationCRLPath     string `json:"federation_crl_path" yaml:"federation_crl_path" toml:"federation_crl_path" env:"FED_CRL_PATH" usage:"Path to the federation CA revocation list; peers whose certificate is listed are refused"`
+	FederationPeers       string `json:"federation_peers" yaml:"federation_peers" toml:"federation_peers" env:"FEDERATION_PEERS" required:"true" usage:"Path to federation.yaml"`
+	IdentityFile          string `json:"id_file" yaml:"id_file" toml:"id_file" env:"IDENTITY_FILE" required:"true" usage:"Path to the server identity file"`
+	DBConnectionString    string `json:"db_connection_string" yaml:"db_connection_string" toml:"db_connection_string" env:"DB_CONNECTION_STRING" required:"true" secret:"true" usage:"Postgres connection string"`
+	AdminAddress          string `json:"admin_address" yaml:"admin_address" toml:"admin_address" env:"ADMIN_ADDRESS" usage:"HTTP admin listener address"`
+
+	// The admin gRPC service used by strike-admin only starts when a token
+	// is configured.
+	AdminRPCAddress string `json:"admin_rpc_address" yaml:"admin_rpc_address" toml:"admin_rpc_address" env:"ADMIN_RPC_ADDRESS" usage:"Admin gRPC listener address"`
+	AdminToken      string `json:"admin_token" yaml:"admin_token" toml:"admin_token" env:"ADMIN_TOKEN" secret:"true" usage:"Bearer token required by the admin gRPC service (unset disables it)"`
+
+	ListenAddress           string `json:"listen_address" yaml:"listen_address" toml:"listen_address" env:"LISTEN_ADDRESS" usage:"Strike gRPC listen address"`
+	FederationListenAddress string `json:"federation_listen_address" yaml:"federation_listen_address" toml:"federation_listen_address" env:"FED_LISTEN_ADDRESS" usage:"Federation gRPC listen address"`
+	FederationAddress       string `json:"federation_address" yaml:"federation_address" toml:"federation_address" env:"FED_ADDRESS" usage:"Address peers reach the federation listener on, advertised in invitations"`
+
+	// Client-facing TLS, defaulting to the server certificate and signing key.
+	TLSCertificatePath string `json:"tls_certificate_path" yaml:"tls_certificate_path" toml:"tls_certificate_path" env:"TLS_CERT_PATH" usage:"Client-facing TLS certificate (default certificate_path)"`
+	TLSKeyPath         string `json:"tls_key_path" yaml:"tls_key_path" toml:"tls_key_path" env:"TLS_KEY_PATH" usage:"Client-facing TLS key (default private_server_signing_key_path)"`
+	TLSMinVersion      string `json:"tls_min_version" yaml:"tls_min_version" toml:"tls_min_version" env:"TLS_MIN_VERSION" usage:"Minimum client TLS version, 1.2 or 1.3"`
+
+	KeepaliveTime                Duration `json:"keepalive_time" yaml:"keepalive_time" toml:"keepalive_time" env:"KEEPALIVE_TIME" usage:"Ping idle clients after this long"`
+	KeepaliveTimeout             Duration `json:"keepalive_timeout" yaml:"keepalive_timeout" toml:"keepalive_timeout" env:"KEEPALIVE_TIMEOUT" usage:"Close connections whose ping is not acknowledged in this long"`
+	KeepaliveMinTime             Duration `json:"keepalive_min_time" yaml:"keepalive_min_time" toml:"keepalive_min_time" env:"KEEPALIVE_MIN_TIME" usage:"Minimum interval allowed between client pings"`
+	KeepalivePermitWithoutStream *bool    `json:"keepalive_permit_without_stream" yaml:"keepalive_permit_without_stream" toml:"keepalive_permit_without_stream" env:"KEEPALIVE_PERMIT_WITHOUT_STREAM" usage:"Allow client pings with no active stream"`
+
+	MaxRecvMsgSize int `json:"max_recv_msg_size" yaml:"max_recv_msg_size" toml:"max_recv_msg_size" env:"MAX_RECV_MSG_SIZE" usage:"Maximum inbound message size in bytes"`
+	MaxSendMsgSize int `json:"max_send_msg_size" yaml:"max_send_msg_size" toml:"max_send_msg_size" env:"MAX_SEND_MSG_SIZE" usage:"Maximum outbound message size in bytes"`
+
+	DeliveryTimeout Duration `json:"delivery_timeout" yaml:"delivery_timeout" toml:"delivery_timeout" env:"DELIVERY_TIMEOUT" usage:"Timeout handing a payload to a connected client"`
+	RelayTimeout    Duration `json:"relay_timeout" yaml:"relay_timeout" toml:"relay_timeout" env:"RELAY_TIMEOUT" usage:"Timeout relaying a payload to a federation peer"`
+
+	ShutdownTimeout Duration `json:"shutdown_timeout" yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" usage:"Deadline for draining on shutdown"`
+
+	RateLimit RateLimitConfig `json:"rate_limit" yaml:"rate_limit" toml:"rate_limit"`
+
+	LogLevel  string `json:"log_level" yaml:"log_level" toml:"log_level" env:"LOG_LEVEL" usage:"Log level: debug, info, warn or error"`
+	LogFormat string `json:"log_format" yaml:"log_format" toml:"log_format" env:"LOG_FORMAT" usage:"Log format: text or json"`
+
+	Tracing TracingConfig `json:"tracing" yaml:"tracing" toml:"tracing"`
+}
+
+// TracingConfig selects where OpenTelemetry spans are exported. The default,
+// "none", still propagates trace context between servers.
+type TracingConfig struct {
+	Exporter    string  `json:"exporter" yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER" usage:"Span exporter: none, stdout or otlp"`
+	Endpoint    string  `json:"endpoint" yaml:"endpoint" toml:"endpoint" env:"TRACING_ENDPOINT" usage:"OTLP gRPC collector address"`
+	Insecure    bool    `json:"insecure" yaml:"insecure" toml:"insecure" env:"TRACING_INSECURE" usage:"Connect to the OTLP collector without TLS"`
+	SampleRatio float64 `json:"sample_ratio" yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" usage:"Fraction of new traces sampled, between 0 and 1 (0 means 1)"`
+}
+
+// RateLimitConfig bounds what a single client can ask of the Strike service.
+// Rates are requests per second; a negative rate disables that limit.
+type RateLimitConfig struct {
+	UserRate  float64 `json:"user_rate" yaml:"user_rate" toml:"user_rate" env:"RATE_LIMIT_USER_RATE" usage:"Requests per second allowed per user"`
+	UserBurst int     `json:"user_burst" yaml:"user_burst" toml:"user_burst" env:"RATE_LIMIT_USER_BURST" usage:"Burst allowed per user"`
+	IPRate    float64 `json:"ip_rate" yaml:"ip_rate" toml:"ip_rate" env:"RATE_LIMIT_IP_RATE" usage:"Requests per second allowed per client IP"`
+	IPBurst   int     `json:"ip_burst" yaml:"ip_burst" toml:"ip_burst" env:"RATE_LIMIT_IP_BURST" usage:"Burst allowed per client IP"`
+
+	// Signup, Login, SaltMine and UserRequest share a stricter per-IP bucket.
+	AuthRate  float64 `json:"auth_rate" yaml:"auth_rate" toml:"auth_rate" env:"RATE_LIMIT_AUTH_RATE" usage:"Signup/login/lookup requests per second allowed per client IP"`
+	AuthBurst int     `json:"auth_burst" yaml:"auth_burst" toml:"auth_burst" env:"RATE_LIMIT_AUTH_BURST" usage:"Signup/login/lookup burst allowed per client IP"`
+
+	MaxPendingPerUser int `json:"max_pending_per_user" yaml:"max_pending_per_user" toml:"max_pending_per_user" env:"RATE_LIMIT_MAX_PENDING_PER_USER" usage:"Undelivered payloads a single sender may have queued"`
+
+	LoginMaxFailures int      `json:"login_max_failures" yaml:"login_max_failures" toml:"login_max_failures" env:"LOGIN_MAX_FAILURES" usage:"Failed logins before an account is locked"`
+	LoginLockout     Duration `json:"login_lockout" yaml:"login_lockout" toml:"login_lockout" env:"LOGIN_LOCKOUT" usage:"First lockout period, doubled on each further failure"`
+	LoginLockoutMax  Duration `json:"login_lockout_max" yaml:"login_lockout_max" toml:"login_lockout_max" env:"LOGIN_LOCKOUT_MAX" usage:"Longest lockout period"`
+
+	// Friend requests are limited per sender on top of the user limit, for
+	// local senders and for senders relayed by peers alike.
+	FriendRequestRate  float64 `json:"friend_request_rate" yaml:"friend_request_rate" toml:"friend_request_rate" env:"RATE_LIMIT_FRIEND_REQUEST_RATE" usage:"Friend requests per second allowed per sender"`
+	FriendRequestBurst int     `json:"friend_request_burst" yaml:"friend_request_burst" toml:"friend_request_burst" env:"RATE_LIMIT_FRIEND_REQUEST_BURST" usage:"Friend request burst allowed per sender"`
 }
 
 type ClientConfig struct {
-	ServerHost               string `json:"server_host" yaml:"server_host"`
-	SigningPrivateKeyPath    string `json:"private_signing_key_path" yaml:"private_singing_key_path"`
-	SigningPublicKeyPath     string `json:"public_signing_key_path" yaml:"public_signing_key_path"`
-	EncryptionPrivateKeyPath string `json:"private_encryption_key_path" yaml:"private_encryption_key_path"`
-	EncryptionPublicKeyPath  string `json:"public_encryption_key_path" yaml:"public_encryption_key_path"`
-	ServerCertificatePath    string `json:"server_certificate_path" yaml:"server_certificate_key_path"`
+	ServerHost               string `json:"server_host" yaml:"server_host" toml:"server_host" env:"SERVER_HOST" flag:"server" required:"true" usage:"Strike server host (e.g. localhost:8080)"`
+	SigningPrivateKeyPath    string `json:"private_signing_key_path" yaml:"private_signing_key_path" toml:"private_signing_key_path" env:"PRIVATE_SIGNING_KEY_PATH" required:"true" usage:"Path to your ED25519 private key"`
+	SigningPublicKeyPath     string `json:"public_signing_key_path" yaml:"public_signing_key_path" toml:"public_signing_key_path" env:"PUBLIC_SIGNING_KEY_PATH" required:"true" usage:"Path to your ED25519 public key"`
+	EncryptionPrivateKeyPath string `json:"private_encryption_key_path" yaml:"private_encryption_key_path" toml:"private_encryption_key_path" env:"PRIVATE_ENCRYPTION_KEY_PATH" required:"true" usage:"Path to your Curve25519 private key"`
+	EncryptionPublicKeyPath  string `json:"public_encryption_key_path" yaml:"public_encryption_key_path" toml:"public_encryption_key_path" env:"PUBLIC_ENCRYPTION_KEY_PATH" required:"true" usage:"Path to your Curve25519 public key"`
+	ServerCertificatePath    string `json:"server_certificate_path" yaml:"server_certificate_path" toml:"server_certificate_path" env:"SERVER_CERT_PATH" required:"true" usage:"Path to the certificate used to verify the server"`
+
+	// Client logs go to stderr; the default keeps them out of the shell.
+	LogLevel  string `json:"log_level" yaml:"log_level" toml:"log_level" env:"LOG_LEVEL" usage:"Log level: debug, info, warn or error"`
+	LogFormat string `json:"log_format" yaml:"log_format" toml:"log_format" env:"LOG_FORMAT" usage:"Log format: text or json"`
+
+	// The stdout exporter writes to stderr on the client, alongside the logs.
+	Tracing TracingConfig `json:"tracing" yaml:"tracing" toml:"tracing"`
+
+	MessageRetention Duration `json:"message_retention" yaml:"message_retention" toml:"message_retention" env:"MESSAGE_RETENTION" usage:"Delete local messages older than this; 0 keeps them forever"`
+	FriendRequestTTL Duration `json:"friend_request_ttl" yaml:"friend_request_ttl" toml:"friend_request_ttl" env:"FRIEND_REQUEST_TTL" usage:"Discard unanswered friend requests older than this"`
+	SearchIndex      bool     `json:"search_index" yaml:"search_index" toml:"search_index" env:"SEARCH_INDEX" usage:"Keep a local full-text index of hashed message words so /search does not decrypt every message"`
+	ShellHistory     string   `json:"shell_history" yaml:"shell_history" toml:"shell_history" env:"SHELL_HISTORY" usage:"File the shell keeps command history in; none keeps it for the session only"`
+
+	// The agent holds unlocked private keys so the passphrase is asked once
+	// per session rather than at every start.
+	KeyAgentSocket string   `json:"key_agent_socket" yaml:"key_agent_socket" toml:"key_agent_socket" env:"KEY_AGENT_SOCKET" usage:"Unix socket of the key agent (strike-client --agent); keys are fetched from it when it is running"`
+	KeyAgentTTL    Duration `json:"key_agent_ttl" yaml:"key_agent_ttl" toml:"key_agent_ttl" env:"KEY_AGENT_TTL" usage:"How long --agent keeps unlocked keys; 0 until it is stopped"`
+
+	// strike-client daemon keeps the streams open and serves frontends here.
+	DaemonSocket string `json:"daemon_socket" yaml:"daemon_socket" toml:"daemon_socket" env:"DAEMON_SOCKET" usage:"Unix socket strike-client daemon listens on"`
+
+	// Subcommands (strike-client friends list, send, ...) run without a
+	// terminal, so they take their credentials from here.
+	Username          string `json:"username" yaml:"username" toml:"username" env:"STRIKE_USERNAME" usage:"Account the client subcommands log in as"`
+	Password          string `json:"password" yaml:"password" toml:"password" env:"STRIKE_PASSWORD" secret:"true" usage:"Password for username; prefer password_file"`
+	PasswordFile      string `json:"password_file" yaml:"password_file" toml:"password_file" env:"STRIKE_PASSWORD_FILE" usage:"File holding the password for username"`
+	KeyPassphraseFile string `json:"key_passphrase_file" yaml:"key_passphrase_file" toml:"key_passphrase_file" env:"KEY_PASSPHRASE_FILE" usage:"File holding the key passphrase, read instead of asking for it"`
+}
+
+// AdminConfig is read by strike-admin. The token must match the server's
+// admin_token.
+type AdminConfig struct {
+	ServerHost            string `json:"server_host" yaml:"server_host" toml:"server_host" env:"STRIKE_ADMIN_SERVER" flag:"server" usage:"Admin gRPC address of the Strike server"`
+	ServerCertificatePath string `json:"server_certificate_path" yaml:"server_certificate_path" toml:"server_certificate_path" env:"SERVER_CERT_PATH" required:"true" usage:"Path to the certificate used to verify the server"`
+	Token                 string `json:"admin_token" yaml:"admin_token" toml:"admin_token" env:"ADMIN_TOKEN" flag:"token" required:"true" secret:"true" usage:"Admin bearer token"`
 }
 
-func LoadServerConfigEnv() *ServerConfig {
-	return &ServerConfig{
-		Name:                  os.Getenv("SERVER_NAME"),
-		SigningPrivateKeyPath: os.Getenv("PRIVATE_SERVER_SIGNING_KEY_PATH"),
-		SigningPublicKeyPath:  os.Getenv("PUBLIC_SERVER_SIGNING_KEY_PATH"),
-		CertificatePath:       os.Getenv("CERT_PATH"),
-		FederationCAPath:      os.Getenv("FED_CA_PATH"),
-		FederationPeers:       os.Getenv("FEDERATION_PEERS"),
-		IdentityFile:          os.Getenv("IDENTITY_FILE"),
-		DBConnectionString:    os.Getenv("DB_CONNECTION_STRING"),
+// MinAdminTokenLength keeps the admin token out of guessing range.
+const MinAdminTokenLength = 16
+
+func (c *AdminConfig) Validate() error {
+	if c.ServerHost == "" {
+		c.ServerHost = DefaultAdminRPCAddress
+	}
+	if _, _, err := net.SplitHostPort(c.ServerHost); err != nil {
+		return fmt.Errorf("server_host: %v (want host:port)", err)
 	}
+	return nil
 }
 
-func LoadClientConfigEnv() *ClientConfig {
-	return &ClientConfig{
-		ServerHost:               os.Getenv("SERVER_HOST"),
-		SigningPrivateKeyPath:    os.Getenv("PRIVATE_SIGNING_KEY_PATH"),
-		SigningPublicKeyPath:     os.Getenv("PUBLIC_SIGNING_KEY_PATH"),
-		EncryptionPrivateKeyPath: os.Getenv("PRIVATE_ENCRYPTION_KEY_PATH"),
-		EncryptionPublicKeyPath:  os.Getenv("PUBLIC_ENCRYPTION_KEY_PATH"),
-		ServerCertificatePath:    os.Getenv("SERVER_CERT_PATH"),
+func expandHome(path, homeDir string) string {
+	if strings.HasPrefix(path, "~") {
+		return filepath.Join(homeDir, path[1:])
 	}
+	return path
 }
 
-// Generic to support either Server or Client config
-func LoadConfigFile[cfg any](filePath string) (cfg, error) {
+// applyDefaults fills optional listener, TLS and timeout settings.
+func (c *ServerConfig) applyDefaults() {
+	setString := func(v *string, def string) {
+		if *v == "" {
+			*v = def
+		}
+	}
+	setDuration := func(v *Duration, def time.Duration) {
+		if *v == 0 {
+			*v = Duration(def)
+		}
+	}
+	setInt := func(v *int, def int) {
+		if *v == 0 {
+			*v = def
+		}
+	}
 
-	var c cfg
+	setString(&c.ListenAddress, DefaultListenAddress)
+	setString(&c.FederationListenAddress, DefaultFederationListenAddress)
+	setString(&c.AdminAddress, DefaultAdminAddress)
+	setString(&c.AdminRPCAddress, DefaultAdminRPCAddress)
+	setString(&c.TLSCertificatePath, c.CertificatePath)
+	setString(&c.TLSKeyPath, c.SigningPrivateKeyPath)
+	setString(&c.TLSMinVersion, DefaultTLSMinVersion)
+	setString(&c.LogLevel, DefaultLogLevel)
+	setString(&c.LogFormat, DefaultLogFormat)
 
-	configFile, err := os.ReadFile(filePath)
-	if err != nil {
-		return c, fmt.Errorf("failed to read config file: %v", err)
-	}
+	setDuration(&c.KeepaliveTime, DefaultKeepaliveTime)
+	setDuration(&c.KeepaliveTimeout, DefaultKeepaliveTimeout)
+	setDuration(&c.KeepaliveMinTime, DefaultKeepaliveMinTime)
+	setDuration(&c.DeliveryTimeout, DefaultDeliveryTimeout)
+	setDuration(&c.RelayTimeout, DefaultRelayTimeout)
+	setDuration(&c.ShutdownTimeout, DefaultShutdownTimeout)
+
+	setInt(&c.MaxRecvMsgSize, DefaultMaxMsgSize)
+	setInt(&c.MaxSendMsgSize, DefaultMaxMsgSize)
 
-	if err := json.Unmarshal(configFile, &c); err != nil {
-		return c, fmt.Errorf("failed to unmarshall config JSON: %v", err)
+	setFloat := func(v *float64, def float64) {
+		if *v == 0 {
+			*v = def
+		}
 	}
 
-	return c, nil
+	rl := &c.RateLimit
+	setFloat(&rl.UserRate, DefaultUserRate)
+	setInt(&rl.UserBurst, DefaultUserBurst)
+	setFloat(&rl.IPRate, DefaultIPRate)
+	setInt(&rl.IPBurst, DefaultIPBurst)
+	setFloat(&rl.AuthRate, DefaultAuthRate)
+	setInt(&rl.AuthBurst, DefaultAuthBurst)
+	setInt(&rl.MaxPendingPerUser, DefaultMaxPendingPerUser)
+	setInt(&rl.LoginMaxFailures, DefaultLoginMaxFailures)
+	setDuration(&rl.LoginLockout, DefaultLoginLockout)
+	setDuration(&rl.LoginLockoutMax, DefaultLoginLockoutMax)
+	setFloat(&rl.FriendRequestRate, DefaultFriendRequestRate)
+	setInt(&rl.FriendRequestBurst, DefaultFriendRequestBurst)
+
+	c.Tracing.applyDefaults()
+
+	if c.KeepalivePermitWithoutStream == nil {
+		permit := true
+		c.KeepalivePermitWithoutStream = &permit
+	}
 }
 
-// Field validation and Path sanitizing
-func ValidateFields(cfg map[string]*string) error {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("error finding user home directory: %w", err)
+// Validate applies defaults and checks the settings the Loader's required
+// and type checks cannot express.
+func (c *ServerConfig) Validate() error {
+	c.applyDefaults()
+
+	var errs []error
+
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		c.TLSCertificatePath = expandHome(c.TLSCertificatePath, homeDir)
+		c.TLSKeyPath = expandHome(c.TLSKeyPath, homeDir)
+	}
+
+	addrs := []struct{ key, addr string }{
+		{"listen_address", c.ListenAddress},
+		{"federation_listen_address", c.FederationListenAddress},
+		{"admin_address", c.AdminAddress},
+		{"admin_rpc_address", c.AdminRPCAddress},
+	}
+	seen := map[string]string{}
+	for _, a := range addrs {
+		if _, _, err := net.SplitHostPort(a.addr); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v (want host:port, e.g. \":8080\")", a.key, err))
+			continue
+		}
+		if other, ok := seen[a.addr]; ok {
+			errs = append(errs, fmt.Errorf("%s: %s is already used by %s", a.key, a.addr, other))
+		}
+		seen[a.addr] = a.key
+	}
+
+	if c.AdminToken != "" && len(c.AdminToken) < MinAdminTokenLength {
+		errs = append(errs, fmt.Errorf("admin_token: must be at least %d characters", MinAdminTokenLength))
 	}
 
-	for key, value := range cfg {
-		if *value == "" {
-			return fmt.Errorf("%s is required", key)
+	if _, err := ParseTLSVersion(c.TLSMinVersion); err != nil {
+		errs = append(errs, fmt.Errorf("tls_min_version: %v", err))
+	}
+
+	durations := []struct {
+		key string
+		d   Duration
+	}{
+		{"keepalive_time", c.KeepaliveTime},
+		{"keepalive_timeout", c.KeepaliveTimeout},
+		{"keepalive_min_time", c.KeepaliveMinTime},
+		{"delivery_timeout", c.DeliveryTimeout},
+		{"relay_timeout", c.RelayTimeout},
+		{"shutdown_timeout", c.ShutdownTimeout},
+		{"rate_limit.login_lockout", c.RateLimit.LoginLockout},
+		{"rate_limit.login_lockout_max", c.RateLimit.LoginLockoutMax},
+	}
+	for _, d := range durations {
+		if d.d < 0 {
+			errs = append(errs, fmt.Errorf("%s: must be positive, got %s", d.key, time.Duration(d.d)))
 		}
+	}
+
+	if c.MaxRecvMsgSize < 0 {
+		errs = append(errs, fmt.Errorf("max_recv_msg_size: must be positive, got %d", c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize < 0 {
+		errs = append(errs, fmt.Errorf("max_send_msg_size: must be positive, got %d", c.MaxSendMsgSize))
+	}
 
-		// Only expand paths for keys containing "PATH" or "path"
-		if strings.Contains(key, "PATH") || strings.Contains(key, "path") {
-			if strings.HasPrefix(*value, "~") {
-				*value = filepath.Join(homeDir, (*value)[1:])
-			}
+	bursts := []struct {
+		key   string
+		burst int
+	}{
+		{"rate_limit.user_burst", c.RateLimit.UserBurst},
+		{"rate_limit.ip_burst", c.RateLimit.IPBurst},
+		{"rate_limit.auth_burst", c.RateLimit.AuthBurst},
+	}
+	for _, b := range bursts {
+		if b.burst < 1 {
+			errs = append(errs, fmt.Errorf("%s: must be at least 1, got %d", b.key, b.burst))
 		}
 	}
+
+	if err := validateLogging(c.LogLevel, c.LogFormat); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Tracing.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.RateLimit.LoginLockoutMax < c.RateLimit.LoginLockout {
+		errs = append(errs, fmt.Errorf("rate_limit.login_lockout_max: %s is shorter than login_lockout %s",
+			time.Duration(c.RateLimit.LoginLockoutMax), time.Duration(c.RateLimit.LoginLockout)))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate applies the logging and tracing defaults and checks them.
+func (c *ClientConfig) Validate() error {
+	if c.LogLevel == "" {
+		c.LogLevel = DefaultClientLogLevel
+	}
+	if c.LogFormat == "" {
+		c.LogFormat = DefaultLogFormat
+	}
+	if c.ShellHistory == "" {
+		c.ShellHistory = DefaultShellHistory
+	}
+	c.Tracing.applyDefaults()
+
+	var retentionErr, requestErr, agentErr, passwordErr error
+	if c.MessageRetention < 0 {
+		retentionErr = fmt.Errorf("message_retention: must not be negative")
+	}
+	switch {
+	case c.FriendRequestTTL == 0:
+		c.FriendRequestTTL = Duration(DefaultFriendRequestTTL)
+	case c.FriendRequestTTL < 0:
+		requestErr = fmt.Errorf("friend_request_ttl: must not be negative")
+	}
+	if c.KeyAgentTTL < 0 {
+		agentErr = fmt.Errorf("key_agent_ttl: must not be negative")
+	}
+	if c.Password != "" && c.PasswordFile != "" {
+		passwordErr = fmt.Errorf("password and password_file: set only one")
+	}
+	return errors.Join(validateLogging(c.LogLevel, c.LogFormat), c.Tracing.validate(), retentionErr, requestErr, agentErr, passwordErr)
+}
+
+func (t *TracingConfig) applyDefaults() {
+	if t.Exporter == "" {
+		t.Exporter = DefaultTracingExporter
+	}
+	if t.Endpoint == "" {
+		t.Endpoint = DefaultTracingEndpoint
+	}
+	if t.SampleRatio == 0 {
+		t.SampleRatio = DefaultTracingSampleRatio
+	}
+}
+
+func (t *TracingConfig) validate() error {
+	switch strings.ToLower(t.Exporter) {
+	case "none", "stdout", "otlp":
+	default:
+		return fmt.Errorf("tracing.exporter: unsupported exporter %q (want none, stdout or otlp)", t.Exporter)
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		return fmt.Errorf("tracing.sample_ratio: must be between 0 and 1, got %g", t.SampleRatio)
+	}
 	return nil
 }
 
-func (c *ServerConfig) ValidateConfig() error {
-	return ValidateFields(map[string]*string{
-		"name":                            &c.Name,
-		"private_server_signing_key_path": &c.SigningPrivateKeyPath,
-		"public_server_signing_key_path":  &c.SigningPublicKeyPath,
-		"certificate_path":                &c.CertificatePath,
-		"federation_ca_path":              &c.FederationCAPath,
-		"federation_peers":                &c.FederationPeers,
-		"id_file":                         &c.IdentityFile,
-		"db_connection_string":            &c.DBConnectionString,
-	})
-}
-
-func (c *ClientConfig) ValidateConfig() error {
-	return ValidateFields(map[string]*string{
-		"server_host":                 &c.ServerHost,
-		"private_signing_key_path":    &c.SigningPrivateKeyPath,
-		"public_signing_key_path":     &c.SigningPublicKeyPath,
-		"private_encryption_key_path": &c.EncryptionPrivateKeyPath,
-		"public_encryption_key_path":  &c.EncryptionPublicKeyPath,
-		"server_certificate_path":     &c.ServerCertificatePath,
-	})
-}
-
-func (c *ServerConfig) ValidateEnv() error {
-	return ValidateFields(map[string]*string{
-		"SERVER_NAME":                     &c.Name,
-		"PRIVATE_SERVER_SIGNING_KEY_PATH": &c.SigningPrivateKeyPath,
-		"PUBLIC_SERVER_SIGNING_KEY_PATH":  &c.SigningPublicKeyPath,
-		"CERT_PATH":                       &c.CertificatePath,
-		"FED_CA_PATH":                     &c.FederationCAPath,
-		"FEDERATION_PEERS":                &c.FederationPeers,
-		"IDENTITY_FILE":                   &c.IdentityFile,
-		"DB_CONNECTION_STRING":            &c.DBConnectionString,
-	})
-}
-
-func (c *ClientConfig) ValidateEnv() error {
-	return ValidateFields(map[string]*string{
-		"SERVER_HOST":                 &c.ServerHost,
-		"PRIVATE_SIGNING_KEY_PATH":    &c.SigningPrivateKeyPath,
-		"PUBLIC_SIGNING_KEY_PATH":     &c.SigningPublicKeyPath,
-		"PRIVATE_ENCRYPTION_KEY_PATH": &c.EncryptionPrivateKeyPath,
-		"PUBLIC_ENCRYPTION_KEY_PATH":  &c.EncryptionPublicKeyPath,
-		"SERVER_CERT_PATH":            &c.ServerCertificatePath,
-	})
+func validateLogging(level, format string) error {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("log_level: unsupported level %q (want debug, info, warn or error)", level)
+	}
+	switch strings.ToLower(format) {
+	case "text", "json":
+	default:
+		return fmt.Errorf("log_format: unsupported format %q (want text or json)", format)
+	}
+	return nil
+}
+
+// ParseTLSVersion maps "1.2"/"1.3" to the crypto/tls version constant.
+func ParseTLSVersion(v string) (uint16, error) {
+	switch v {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q (want 1.2 or 1.3)", v)
+	}
 }
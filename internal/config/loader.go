@@ -0,0 +1,469 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/JohnnyGlynn/strike/internal/shared"
+)
+
+// Loader builds a config of type T from layered sources. Later layers win:
+//
+//	defaults < config file (JSON, YAML or TOML) < environment < flags
+//
+// Every field is addressable in every layer via its struct tags, see
+// ServerConfig. Flags are registered on construction, so NewLoader must be
+// called before the FlagSet is parsed.
+type Loader[T any] struct {
+	fs    *flag.FlagSet
+	flags map[string]*flagValue
+}
+
+// Sources records which layer supplied each key, for errors and --print-config.
+type Sources map[string]string
+
+type field struct {
+	key      string
+	env      string
+	flag     string
+	usage    string
+	required bool
+	secret   bool
+	value    reflect.Value
+}
+
+type flagValue struct {
+	raw    string
+	isBool bool
+}
+
+func (f *flagValue) String() string     { return f.raw }
+func (f *flagValue) Set(s string) error { f.raw = s; return nil }
+func (f *flagValue) IsBoolFlag() bool   { return f.isBool }
+
+func NewLoader[T any](fs *flag.FlagSet) *Loader[T] {
+	l := &Loader[T]{fs: fs, flags: map[string]*flagValue{}}
+
+	var zero T
+	for _, f := range fields(reflect.ValueOf(&zero).Elem(), "") {
+		fv := &flagValue{isBool: isBoolKind(f.value)}
+		usage := f.usage
+		if f.env != "" {
+			usage = fmt.Sprintf("%s (env %s)", usage, f.env)
+		}
+		fs.Var(fv, f.flag, usage)
+		l.flags[f.key] = fv
+	}
+
+	return l
+}
+
+// Flag returns the raw value of the flag bound to key, for callers that need
+// a setting before a full config can be loaded (e.g. keygen).
+func (l *Loader[T]) Flag(key string) string {
+	if fv, ok := l.flags[key]; ok {
+		return fv.raw
+	}
+	return ""
+}
+
+// Load layers the file at path (optional), the environment and any flags set
+// on the command line, expands a leading ~ in string values, then checks
+// required fields and calls Validate.
+func (l *Loader[T]) Load(path string) (T, Sources, error) {
+	var cfg T
+	src := Sources{}
+	fs := fields(reflect.ValueOf(&cfg).Elem(), "")
+
+	if path != "" {
+		if err := decodeFile(path, &cfg, fs); err != nil {
+			return cfg, src, err
+		}
+		for _, f := range fs {
+			if !f.value.IsZero() {
+				src[f.key] = "file " + path
+			}
+		}
+	}
+
+	var errs []error
+
+	for _, f := range fs {
+		if f.env == "" {
+			continue
+		}
+		raw, ok := os.LookupEnv(f.env)
+		if !ok || raw == "" {
+			continue
+		}
+		if err := setString(f.value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s (env %s): %v", f.key, f.env, err))
+			continue
+		}
+		src[f.key] = "env " + f.env
+	}
+
+	set := map[string]bool{}
+	l.fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	for _, f := range fs {
+		if !set[f.flag] {
+			continue
+		}
+		raw := l.flags[f.key].raw
+		if err := setString(f.value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s (flag --%s): %v", f.key, f.flag, err))
+			continue
+		}
+		src[f.key] = "flag --" + f.flag
+	}
+
+	if len(errs) > 0 {
+		return cfg, src, errors.Join(errs...)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return cfg, src, fmt.Errorf("error finding user home directory: %w", err)
+	}
+
+	for _, f := range fs {
+		if f.value.Kind() == reflect.String {
+			f.value.SetString(expandHome(f.value.String(), homeDir))
+		}
+		if f.required && f.value.IsZero() {
+			hint := fmt.Sprintf("set %q in the config file", f.key)
+			if f.env != "" {
+				hint += ", " + f.env + " in the environment"
+			}
+			hint += " or --" + f.flag
+			errs = append(errs, fmt.Errorf("%s is required: %s", f.key, hint))
+		}
+	}
+
+	if len(errs) > 0 {
+		return cfg, src, errors.Join(errs...)
+	}
+
+	if v, ok := any(&cfg).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return cfg, src, err
+		}
+	}
+
+	return cfg, src, nil
+}
+
+// Print writes cfg as YAML, annotating each key with the layer it came from
+// and redacting secrets.
+func Print[T any](w io.Writer, cfg T, src Sources) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+
+	for _, f := range fields(reflect.ValueOf(&cfg).Elem(), "") {
+		parent := root
+		parts := strings.Split(f.key, ".")
+		for _, p := range parts[:len(parts)-1] {
+			parent = childMapping(parent, p)
+		}
+
+		val := &yaml.Node{}
+		if f.secret && !f.value.IsZero() {
+			val.SetString(Redact(f.value.String()))
+		} else if err := val.Encode(f.value.Interface()); err != nil {
+			return fmt.Errorf("encode %s: %v", f.key, err)
+		}
+
+		origin, ok := src[f.key]
+		if !ok {
+			origin = "default"
+		}
+
+		parent.Content = append(parent.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: parts[len(parts)-1], LineComment: origin},
+			val,
+		)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(root)
+}
+
+func childMapping(parent *yaml.Node, key string) *yaml.Node {
+	for i := 0; i < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			return parent.Content[i+1]
+		}
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+	return child
+}
+
+var dsnPassword = regexp.MustCompile(`(password=)('[^']*'|\S+)`)
+
+// Redact masks the password in a Postgres connection string (URL or
+// keyword/value form). Anything else is masked entirely.
+func Redact(secret string) string {
+	if u, err := url.Parse(secret); err == nil && u.Scheme != "" && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+		return u.String()
+	}
+	if dsnPassword.MatchString(secret) {
+		return dsnPassword.ReplaceAllString(secret, "${1}xxxxx")
+	}
+	return "xxxxx"
+}
+
+// fields flattens the tagged fields of v, recursing into nested structs with
+// dotted keys (e.g. "rate_limit.login_burst").
+func fields(v reflect.Value, prefix string) []field {
+	var out []field
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		key = prefix + key
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct && !isTextType(fv) {
+			out = append(out, fields(fv, key+".")...)
+			continue
+		}
+
+		flagName := sf.Tag.Get("flag")
+		if flagName == "" {
+			flagName = strings.NewReplacer("_", "-", ".", "-").Replace(key)
+		}
+
+		out = append(out, field{
+			key:      key,
+			env:      sf.Tag.Get("env"),
+			flag:     flagName,
+			usage:    sf.Tag.Get("usage"),
+			required: sf.Tag.Get("required") == "true",
+			secret:   sf.Tag.Get("secret") == "true",
+			value:    fv,
+		})
+	}
+
+	return out
+}
+
+func isTextType(v reflect.Value) bool {
+	_, ok := v.Addr().Interface().(encoding.TextUnmarshaler)
+	return ok
+}
+
+func isBoolKind(v reflect.Value) bool {
+	if v.Kind() == reflect.Pointer {
+		return v.Type().Elem().Kind() == reflect.Bool
+	}
+	return v.Kind() == reflect.Bool
+}
+
+// setString parses raw into v, the way an env var or flag is interpreted.
+func setString(v reflect.Value, raw string) error {
+	if tu, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(raw))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("want an integer, got %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("want a number, got %q", raw)
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("want true or false, got %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setString(elem.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// decodeFile decodes path by extension into cfg. Keys are checked against
+// the known fields first so every unknown key is reported at once, with a
+// suggestion when it looks like a typo.
+func decodeFile(path string, cfg any, fs []field) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	var raw map[string]any
+	var decode func() error
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("%s: %v", path, jsonError(data, err))
+		}
+		decode = func() error {
+			if err := json.Unmarshal(data, cfg); err != nil {
+				return jsonError(data, err)
+			}
+			return nil
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		decode = func() error {
+			dec := yaml.NewDecoder(bytes.NewReader(data))
+			dec.KnownFields(true)
+			if err := dec.Decode(cfg); err != nil && err != io.EOF {
+				return err
+			}
+			return nil
+		}
+	case ".toml":
+		if _, err := toml.Decode(string(data), &raw); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		decode = func() error {
+			_, err := toml.Decode(string(data), cfg)
+			return err
+		}
+	default:
+		return fmt.Errorf("%s: unsupported config format %q (want .json, .yaml, .yml or .toml)", path, ext)
+	}
+
+	known := make([]string, 0, len(fs))
+	for _, f := range fs {
+		known = append(known, f.key)
+	}
+
+	if errs := unknownKeys(raw, "", known); len(errs) > 0 {
+		return fmt.Errorf("%s: %w", path, errors.Join(errs...))
+	}
+
+	if err := decode(); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	return nil
+}
+
+func unknownKeys(raw map[string]any, prefix string, known []string) []error {
+	var errs []error
+
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		full := prefix + k
+		if nested, ok := raw[k].(map[string]any); ok && hasPrefix(known, full+".") {
+			errs = append(errs, unknownKeys(nested, full+".", known)...)
+			continue
+		}
+		if contains(known, full) {
+			continue
+		}
+
+		msg := fmt.Sprintf("unknown key %q", full)
+		if s := shared.Suggest(full, known); s != "" {
+			msg += fmt.Sprintf(", did you mean %q?", s)
+		}
+		errs = append(errs, errors.New(msg))
+	}
+
+	return errs
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(list []string, prefix string) bool {
+	for _, v := range list {
+		if strings.HasPrefix(v, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonError adds a line number to encoding/json errors, which only carry a
+// byte offset.
+func jsonError(data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+		if typeErr.Field != "" {
+			err = fmt.Errorf("%s: want %s, got JSON %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+	default:
+		return err
+	}
+
+	line := 1 + bytes.Count(data[:min(int(offset), len(data))], []byte("\n"))
+	return fmt.Errorf("line %d: %v", line, err)
+}
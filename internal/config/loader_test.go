@@ -0,0 +1,116 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoaderPrecedence(t *testing.T) {
+	cases := map[string]struct {
+		file, ext string
+		env       map[string]string
+		args      []string
+		want      string
+		errText   string
+	}{
+		"json-file": {
+			file: `{"server_host": "file:1", "private_signing_key_path": "a", "public_signing_key_path": "b", "private_encryption_key_path": "c", "public_encryption_key_path": "d", "server_certificate_path": "e"}`,
+			ext:  ".json",
+			want: "file:1",
+		},
+		"yaml-env-over-file": {
+			file: "server_host: file:1\nprivate_signing_key_path: a\npublic_signing_key_path: b\nprivate_encryption_key_path: c\npublic_encryption_key_path: d\nserver_certificate_path: e\n",
+			ext:  ".yaml",
+			env:  map[string]string{"SERVER_HOST": "env:2"},
+			want: "env:2",
+		},
+		"toml-flag-over-env": {
+			file: "server_host = \"file:1\"\nprivate_signing_key_path = \"a\"\npublic_signing_key_path = \"b\"\nprivate_encryption_key_path = \"c\"\npublic_encryption_key_path = \"d\"\nserver_certificate_path = \"e\"\n",
+			ext:  ".toml",
+			env:  map[string]string{"SERVER_HOST": "env:2"},
+			args: []string{"--server=flag:3"},
+			want: "flag:3",
+		},
+		"unknown-key": {
+			file:    `{"server_hots": "x"}`,
+			ext:     ".json",
+			errText: `did you mean "server_host"`,
+		},
+		"missing-required": {
+			file:    `{"server_host": "x"}`,
+			ext:     ".json",
+			errText: "PRIVATE_SIGNING_KEY_PATH",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			path := filepath.Join(t.TempDir(), "config"+tc.ext)
+			if err := os.WriteFile(path, []byte(tc.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			fs := flag.NewFlagSet(name, flag.ContinueOnError)
+			loader := NewLoader[ClientConfig](fs)
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, _, err := loader.Load(path)
+			if tc.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errText) {
+					t.Fatalf("error: want %q, got %v", tc.errText, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load error: %v", err)
+			}
+
+			if cfg.ServerHost != tc.want {
+				t.Errorf("server_host: want %q, got %q", tc.want, cfg.ServerHost)
+			}
+		})
+	}
+}
+
+func TestServerConfigDefaults(t *testing.T) {
+	cfg := ServerConfig{ListenAddress: ":9000"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate error: %v", err)
+	}
+
+	if cfg.ListenAddress != ":9000" || cfg.FederationListenAddress != DefaultFederationListenAddress {
+		t.Errorf("addresses: got %q, %q", cfg.ListenAddress, cfg.FederationListenAddress)
+	}
+	if time.Duration(cfg.ShutdownTimeout) != DefaultShutdownTimeout {
+		t.Errorf("shutdown_timeout: got %s", time.Duration(cfg.ShutdownTimeout))
+	}
+
+	clash := ServerConfig{ListenAddress: ":9090"}
+	if err := clash.Validate(); err == nil {
+		t.Error("error: duplicate listen address accepted")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	cases := map[string]string{
+		"postgres://admin:hunter2@db:5432/strike": "postgres://admin:xxxxx@db:5432/strike",
+		"host=db user=admin password=hunter2":     "host=db user=admin password=xxxxx",
+		"hunter2":                                 "xxxxx",
+	}
+
+	for in, want := range cases {
+		if got := Redact(in); got != want {
+			t.Errorf("Redact(%q): want %q, got %q", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package keys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/JohnnyGlynn/strike/internal/localsock"
+)
+
+// agentRequest and agentResponse are the key agent's wire format: one JSON
+// object per line over a Unix socket.
+type agentRequest struct {
+	Op string `json:"op"` // "keys" or "lock"
+}
+
+type agentResponse struct {
+	Keys  map[string][]byte `json:"keys,omitempty"`
+	Error string            `json:"error,omitempty"`
+}
+
+// ServeAgent keeps unlocked keys in memory and hands them to anyone who can
+// open socketPath, which localsock.Listen keeps to the current user. It returns
+// when ctx is done, ttl passes (if positive) or a client sends "lock"; the
+// keys are wiped on the way out.
+func ServeAgent(ctx context.Context, socketPath string, loaded map[string][]byte, ttl time.Duration) error {
+	ln, err := localsock.Listen(socketPath)
+	if errors.Is(err, localsock.ErrInUse) {
+		return fmt.Errorf("key agent already running on %s", socketPath)
+	}
+	if err != nil {
+		return fmt.Errorf("key agent: %v", err)
+	}
+	defer os.Remove(socketPath)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if ttl > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeout(ctx, ttl)
+		defer stop()
+	}
+
+	var mu sync.Mutex
+	defer func() {
+		mu.Lock()
+		for _, k := range loaded {
+			clear(k)
+		}
+		mu.Unlock()
+	}()
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	slog.Info("key agent listening", "socket", socketPath, "ttl", ttl)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				slog.Info("key agent stopped", "reason", context.Cause(ctx))
+				return nil
+			}
+			return fmt.Errorf("key agent: %v", err)
+		}
+
+		go func() {
+			defer conn.Close()
+			_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			var req agentRequest
+			if err := json.NewDecoder(conn).Decode(&req); err != nil {
+				return
+			}
+
+			var resp agentResponse
+			switch req.Op {
+			case "keys":
+				mu.Lock()
+				resp.Keys = loaded
+				_ = json.NewEncoder(conn).Encode(resp)
+				mu.Unlock()
+				return
+			case "lock":
+				cancel()
+			default:
+				resp.Error = fmt.Sprintf("unknown op %q", req.Op)
+			}
+			_ = json.NewEncoder(conn).Encode(resp)
+		}()
+	}
+}
+
+// FetchFromAgent asks the agent on socketPath for its unlocked keys.
+func FetchFromAgent(socketPath string) (map[string][]byte, error) {
+	resp, err := agentCall(socketPath, "keys")
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Keys) == 0 {
+		return nil, errors.New("key agent holds no keys")
+	}
+	return resp.Keys, nil
+}
+
+// LockAgent tells the agent on socketPath to wipe its keys and exit.
+func LockAgent(socketPath string) error {
+	_, err := agentCall(socketPath, "lock")
+	return err
+}
+
+func agentCall(socketPath, op string) (*agentResponse, error) {
+	conn, err := net.DialTimeout("unix", socketPath, 2*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("key agent unavailable: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(agentRequest{Op: op}); err != nil {
+		return nil, fmt.Errorf("key agent: %v", err)
+	}
+
+	var resp agentResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("key agent: %v", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("key agent: %s", resp.Error)
+	}
+	return &resp, nil
+}
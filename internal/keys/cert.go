@@ -0,0 +1,253 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CertOptions are the names and lifetime of a server certificate. Unset
+// fields fall back to DefaultCertOptions.
+type CertOptions struct {
+	CommonName  string
+	DNSNames    []string
+	IPAddresses []net.IP
+	Validity    time.Duration
+}
+
+// DefaultCertOptions names the local and k8s development servers.
+func DefaultCertOptions() CertOptions {
+	return CertOptions{
+		CommonName: "strike-server",
+		DNSNames:   []string{"localhost", "strike-server1", "strike-server2", "strike-server1.strike.svc.cluster.local", "strike-server2.strike.svc.cluster.local"},
+		Validity:   365 * 24 * time.Hour, // 1 year
+	}
+}
+
+// CRLValidity is how long a CRL written by RevokeCert stays current. Servers
+// warn once it is past its next update, so re-run --revoke to refresh it.
+const CRLValidity = 30 * 24 * time.Hour
+
+// withDefaults fills fields left unset from DefaultCertOptions. Names are
+// only defaulted when neither DNS names nor IP addresses were given.
+func (o CertOptions) withDefaults() CertOptions {
+	d := DefaultCertOptions()
+	if o.CommonName == "" {
+		o.CommonName = d.CommonName
+	}
+	if len(o.DNSNames) == 0 && len(o.IPAddresses) == 0 {
+		o.DNSNames = d.DNSNames
+	}
+	if o.Validity == 0 {
+		o.Validity = d.Validity
+	}
+	return o
+}
+
+// issueServerCert creates a PEM certificate for pub, signed by the CA when
+// one is given and self-signed with priv otherwise.
+func issueServerCert(pub ed25519.PublicKey, priv ed25519.PrivateKey, caCert *x509.Certificate, caKey ed25519.PrivateKey, opts CertOptions) ([]byte, error) {
+	opts = opts.withDefaults()
+
+	serialNumber, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	strikeCert := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: opts.CommonName},
+		NotBefore:    now,
+		NotAfter:     now.Add(opts.Validity),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageClientAuth,
+			x509.ExtKeyUsageServerAuth,
+		},
+		BasicConstraintsValid: true,
+		DNSNames:              opts.DNSNames,
+		IPAddresses:           opts.IPAddresses,
+	}
+
+	// Sign with CA if provided, otherwise self-sign
+	var signerCert *x509.Certificate
+	var signerKey interface{}
+	if caCert != nil && caKey != nil {
+		signerCert = caCert
+		signerKey = caKey
+	} else {
+		signerCert = &strikeCert
+		signerKey = priv
+	}
+
+	signedCert, err := x509.CreateCertificate(rand.Reader, &strikeCert, signerCert, pub, signerKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server certificate: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: signedCert}), nil
+}
+
+// RenewServerCert reissues strike_server.crt in outputDir for the existing
+// server key, so the server ID and federation.yaml entry stay the same. Names
+// not set in opts are carried over from the current certificate, which is
+// kept with a .old suffix.
+func RenewServerCert(outputDir string, caCert *x509.Certificate, caKey ed25519.PrivateKey, opts CertOptions) error {
+	privPath := filepath.Join(outputDir, "strike_server.pem")
+	certPath := filepath.Join(outputDir, "strike_server.crt")
+
+	keyPEM, err := GetKeyFromPath(privPath)
+	if err != nil {
+		return err
+	}
+	priv, err := ParseSigningPrivateKey(keyPEM)
+	if err != nil {
+		return err
+	}
+
+	old, err := LoadCertificate(certPath)
+	if err == nil {
+		if opts.CommonName == "" {
+			opts.CommonName = old.Subject.CommonName
+		}
+		if len(opts.DNSNames) == 0 && len(opts.IPAddresses) == 0 {
+			opts.DNSNames = old.DNSNames
+			opts.IPAddresses = old.IPAddresses
+		}
+		if opts.Validity == 0 {
+			opts.Validity = old.NotAfter.Sub(old.NotBefore)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	certPEM, err := issueServerCert(priv.Public().(ed25519.PublicKey), priv, caCert, caKey, opts)
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		if err := os.Rename(certPath, certPath+".old"); err != nil {
+			return fmt.Errorf("failed to keep old certificate: %v", err)
+		}
+	}
+	if err := os.WriteFile(certPath, certPEM, 0600); err != nil {
+		return fmt.Errorf("failed to write server certificate: %v", err)
+	}
+
+	fmt.Printf("Strike Server Certificate renewed and saved to %s\n", certPath)
+	return nil
+}
+
+// LoadCertificate reads the first certificate in a PEM file.
+func LoadCertificate(path string) (*x509.Certificate, error) {
+	certPEM, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate in %s", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// RevokeCert adds the certificate at certPath to the CA's revocation list
+// at crlPath, creating the list if needed, and re-signs it.
+func RevokeCert(crlPath, certPath string, caCert *x509.Certificate, caKey ed25519.PrivateKey) error {
+	cert, err := LoadCertificate(certPath)
+	if err != nil {
+		return err
+	}
+	if err := cert.CheckSignatureFrom(caCert); err != nil {
+		return fmt.Errorf("%s was not issued by this CA: %v", certPath, err)
+	}
+
+	var entries []x509.RevocationListEntry
+	number := big.NewInt(1)
+	if _, err := os.Stat(crlPath); err == nil {
+		crl, err := LoadCRL(crlPath, caCert)
+		if err != nil {
+			return err
+		}
+		entries = crl.RevokedCertificateEntries
+		number.Add(crl.Number, big.NewInt(1))
+	}
+
+	now := time.Now()
+	listed := false
+	for _, e := range entries {
+		if e.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			listed = true
+		}
+	}
+	if !listed {
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: cert.SerialNumber, RevocationTime: now})
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    number,
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(CRLValidity),
+		RevokedCertificateEntries: entries,
+	}, caCert, caKey)
+	if err != nil {
+		return fmt.Errorf("failed to create CRL: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(crlPath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(crlPath, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), 0644); err != nil {
+		return fmt.Errorf("failed to write CRL: %v", err)
+	}
+
+	fmt.Printf("Certificate %s (serial %x) revoked, %d entries in %s\n", cert.Subject.CommonName, cert.SerialNumber, len(entries), crlPath)
+	return nil
+}
+
+// LoadCRL reads a PEM or DER revocation list and checks it is signed by one
+// of the given CAs.
+func LoadCRL(path string, cas ...*x509.Certificate) (*x509.RevocationList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CRL %s: %v", path, err)
+	}
+	for _, ca := range cas {
+		if crl.CheckSignatureFrom(ca) == nil {
+			return crl, nil
+		}
+	}
+	return nil, fmt.Errorf("CRL %s is not signed by the federation CA", path)
+}
+
+func newSerial() (*big.Int, error) {
+	// Generate x509 serial no bigger than 20 bytes
+	twentyBytes := new(big.Int).Lsh(big.NewInt(1), 160)
+	serialNumber, err := rand.Int(rand.Reader, twentyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure non-negative
+	if serialNumber.Sign() < 0 {
+		serialNumber.Abs(serialNumber)
+	}
+	return serialNumber, nil
+}
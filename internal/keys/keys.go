@@ -1,6 +1,7 @@
 package keys
 
 import (
+	"bytes"
 	"crypto/ecdh"
 	"crypto/ed25519"
 	"crypto/rand"
@@ -16,6 +17,7 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -32,32 +34,28 @@ type KeyDefinition struct {
 	Type KeyType
 }
 
-func SigningKeygen(outputDir string) error {
+// SigningKeygen writes a new signing pair to outputDir, the private key
+// sealed with passphrase unless it is empty.
+func SigningKeygen(outputDir string, passphrase []byte) error {
 	fmt.Println("WARNING: You (the user) are responsible for the safety of these key files. You will not be able to recover these files if they are lost")
 
-	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	privatePEM, publicPEM, err := GenerateSigningKeyPEM()
 	if err != nil {
-		return fmt.Errorf("error Generating Signing keys: %v", err)
+		return err
 	}
 
-	// Encode PKCS#8 format
-	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
-	if err != nil {
-		return fmt.Errorf("error encoding private key: %v", err)
+	if len(passphrase) > 0 {
+		if privatePEM, err = EncryptPrivateKeyPEM(privatePEM, passphrase); err != nil {
+			return fmt.Errorf("error encrypting private key: %v", err)
+		}
 	}
 
-	err = writeToPem(privateKeyBytes, "PRIVATE KEY", "strike_signing.pem", outputDir)
+	err = writePEMFile(privatePEM, "strike_signing.pem", outputDir)
 	if err != nil {
 		return fmt.Errorf("failed to write private key: %v", err)
 	}
 
-	// Encode PKIX format
-	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
-	if err != nil {
-		return fmt.Errorf("error encoding public key: %v", err)
-	}
-
-	err = writeToPem(publicKeyBytes, "PUBLIC KEY", "strike_public_signing.pem", outputDir)
+	err = writePEMFile(publicPEM, "strike_public_signing.pem", outputDir)
 	if err != nil {
 		return fmt.Errorf("failed to write public key: %v", err)
 	}
@@ -66,6 +64,30 @@ func SigningKeygen(outputDir string) error {
 	return nil
 }
 
+// GenerateSigningKeyPEM returns a new ED25519 pair as PEM, the private key
+// PKCS#8 and the public key PKIX.
+func GenerateSigningKeyPEM() ([]byte, []byte, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error Generating Signing keys: %v", err)
+	}
+
+	// Encode PKCS#8 format
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error encoding private key: %v", err)
+	}
+
+	// Encode PKIX format
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error encoding public key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyBytes}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes}), nil
+}
+
 func ValidateSigningKeys(keyBytes []byte) error {
 	// Decode PEM.
 	block, _ := pem.Decode(keyBytes)
@@ -107,23 +129,92 @@ func ValidateSigningKeys(keyBytes []byte) error {
 	return nil
 }
 
-func EncryptionKeygen(outputDir string) error {
-	curve := ecdh.X25519()
+// ParseSigningPrivateKey decodes a PEM PKCS#8 ED25519 private key.
+func ParseSigningPrivateKey(keyBytes []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(keyBytes)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid ED25519 private key")
+	}
+	return priv, nil
+}
+
+// ParseSigningPublicKey decodes a PEM PKIX ED25519 public key.
+func ParseSigningPublicKey(keyBytes []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(keyBytes)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid ED25519 public key")
+	}
+	return pub, nil
+}
+
+// CheckKeyPairs reports whether each private key belongs to the public key
+// stored with it.
+func CheckKeyPairs(sigPriv, sigPub, encPriv, encPub []byte) error {
+	signer, err := ParseSigningPrivateKey(sigPriv)
+	if err != nil {
+		return err
+	}
+	verifier, err := ParseSigningPublicKey(sigPub)
+	if err != nil {
+		return err
+	}
+	if !verifier.Equal(signer.Public()) {
+		return fmt.Errorf("signing private key does not match the public key")
+	}
+
+	privBlock, _ := pem.Decode(encPriv)
+	pubBlock, _ := pem.Decode(encPub)
+	if privBlock == nil || pubBlock == nil {
+		return fmt.Errorf("failed to decode PEM block")
+	}
+	priv, err := ecdh.X25519().NewPrivateKey(privBlock.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid X25519 private key: %w", err)
+	}
+	if !bytes.Equal(priv.PublicKey().Bytes(), pubBlock.Bytes) {
+		return fmt.Errorf("encryption private key does not match the public key")
+	}
+	return nil
+}
 
-	privateKey, err := curve.GenerateKey(rand.Reader)
+// EncryptionKeygen writes a new encryption pair to outputDir, the private
+// key sealed with passphrase unless it is empty.
+func EncryptionKeygen(outputDir string, passphrase []byte) error {
+	privatePEM, publicPEM, err := GenerateEncryptionKeyPEM()
 	if err != nil {
-		return fmt.Errorf("error Generating encryption key: %v", err)
+		return err
+	}
+
+	if len(passphrase) > 0 {
+		if privatePEM, err = EncryptPrivateKeyPEM(privatePEM, passphrase); err != nil {
+			return fmt.Errorf("error encrypting private key: %v", err)
+		}
 	}
 
-	// Extract the private and public keys as byte slices
-	privateKeyBytes := privateKey.Bytes()
-	err = writeToPem(privateKeyBytes, "PRIVATE KEY", "strike_encryption.pem", outputDir)
+	err = writePEMFile(privatePEM, "strike_encryption.pem", outputDir)
 	if err != nil {
 		return fmt.Errorf("failed to write private key: %v", err)
 	}
 
-	publicKeyBytes := privateKey.PublicKey().Bytes()
-	err = writeToPem(publicKeyBytes, "PUBLIC KEY", "strike_public_encryption.pem", outputDir)
+	err = writePEMFile(publicPEM, "strike_public_encryption.pem", outputDir)
 	if err != nil {
 		return fmt.Errorf("failed to write private key: %v", err)
 	}
@@ -132,6 +223,18 @@ func EncryptionKeygen(outputDir string) error {
 	return nil
 }
 
+// GenerateEncryptionKeyPEM returns a new Curve25519 pair as PEM wrapped raw
+// key bytes.
+func GenerateEncryptionKeyPEM() ([]byte, []byte, error) {
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error Generating encryption key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKey.Bytes()}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: privateKey.PublicKey().Bytes()}), nil
+}
+
 func ValidateEncryptionKeys(keyBytes []byte) error {
 	// Decode PEM
 	block, _ := pem.Decode(keyBytes)
@@ -173,12 +276,17 @@ func ValidateEncryptionKeys(keyBytes []byte) error {
 }
 
 func LoadAndValidateKeys(keyMap map[string]KeyDefinition) (map[string][]byte, error) {
+	return LoadKeys(keyMap, nil)
+}
+
+// LoadKeys is LoadAndValidateKeys for key sets that may include passphrase
+// protected private keys, which are opened with passphrase.
+func LoadKeys(keyMap map[string]KeyDefinition, passphrase func() ([]byte, error)) (map[string][]byte, error) {
 	loadedKeys := make(map[string][]byte)
 
 	for name, def := range keyMap {
-		key, err := GetKeyFromPath(def.Path)
+		key, err := ReadPrivateKey(def.Path, passphrase)
 		if err != nil {
-			fmt.Printf("failed to read key from path: %v", err)
 			return nil, err
 		}
 
@@ -221,19 +329,14 @@ func GetKeyFromPath(path string) ([]byte, error) {
 	return key, nil
 }
 
-func writeToPem(keyBytes []byte, keyType string, keyNameDotPem string, outputDir string) error {
+func writePEMFile(pemBytes []byte, keyNameDotPem string, outputDir string) error {
 	fullPath := filepath.Join(outputDir, keyNameDotPem)
 
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("error creating key directory: %v", err)
 	}
 
-	keyPEM := pem.Block{
-		Type:  keyType,
-		Bytes: keyBytes,
-	}
-
-	err := os.WriteFile(fullPath, pem.EncodeToMemory(&keyPEM), 0600)
+	err := os.WriteFile(fullPath, pemBytes, 0600)
 	if err != nil {
 		return fmt.Errorf("failed to write key: %v", err)
 	}
@@ -381,11 +484,49 @@ func DeriveID(pubPEM []byte) string {
 	return hex.EncodeToString(di[:16])
 }
 
+// Fingerprint summarises one user's keys as 30 digits in groups of five. Like
+// DeriveID it is a SHA-256 over the PEM bytes, with the user ID mixed in so
+// the same keys on two accounts read differently.
+func Fingerprint(userID string, sigPEM, encPEM []byte) string {
+	h := sha256.New()
+	h.Write([]byte("strike-fingerprint"))
+	for _, b := range [][]byte{[]byte(userID), sigPEM, encPEM} {
+		h.Write([]byte{0})
+		h.Write(b)
+	}
+	sum := h.Sum(nil)
+
+	groups := make([]string, 6)
+	for i := range groups {
+		var n uint64
+		for _, b := range sum[i*5 : i*5+5] {
+			n = n<<8 | uint64(b)
+		}
+		groups[i] = fmt.Sprintf("%05d", n%100000)
+	}
+	return strings.Join(groups, " ")
+}
+
+// SafetyNumber combines both parties' fingerprints, lower first, so each side
+// of a conversation computes the same 60 digits.
+func SafetyNumber(localFingerprint, remoteFingerprint string) string {
+	if remoteFingerprint < localFingerprint {
+		localFingerprint, remoteFingerprint = remoteFingerprint, localFingerprint
+	}
+	return localFingerprint + " " + remoteFingerprint
+}
+
+// SafetyCode is the safety number as a single token, for a QR code or for
+// pasting into /verify on the other device.
+func SafetyCode(safetyNumber string) string {
+	return "STRIKE1:" + strings.ReplaceAll(safetyNumber, " ", "")
+}
+
 func GenerateServerKeysAndCert(outputDir string) error {
-	return GenerateServerKeysAndCertWithCA(outputDir, nil, nil)
+	return GenerateServerKeysAndCertWithCA(outputDir, nil, nil, DefaultCertOptions())
 }
 
-func GenerateServerKeysAndCertWithCA(outputDir string, caCert *x509.Certificate, caKey ed25519.PrivateKey) error {
+func GenerateServerKeysAndCertWithCA(outputDir string, caCert *x509.Certificate, caKey ed25519.PrivateKey, opts CertOptions) error {
 	fmt.Println("Server Keys and Cert Generator")
 
 	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
@@ -433,54 +574,12 @@ func GenerateServerKeysAndCertWithCA(outputDir string, caCert *x509.Certificate,
 		return fmt.Errorf("failed to write server private key: %v", err)
 	}
 
-	// Generate x509 serial no bigger than 20 bytes
-	twentyBytes := new(big.Int).Lsh(big.NewInt(1), 160)
-	serialNumber, err := rand.Int(rand.Reader, twentyBytes)
+	certPEM, err := issueServerCert(publicKey, privateKey, caCert, caKey, opts)
 	if err != nil {
 		return err
 	}
 
-	// Make sure non-negative
-	if serialNumber.Sign() < 0 {
-		serialNumber.Abs(serialNumber)
-	}
-
-	strikeCert := x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject:      pkix.Name{CommonName: "strike-server"},
-		NotBefore:    time.Now(),
-		NotAfter:     time.Now().Add(365 * 24 * time.Hour), // 1 year
-		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		ExtKeyUsage: []x509.ExtKeyUsage{
-			x509.ExtKeyUsageClientAuth,
-			x509.ExtKeyUsageServerAuth,
-		},
-		BasicConstraintsValid: true,
-		DNSNames:              []string{"localhost", "strike-server1", "strike-server2", "strike-server1.strike.svc.cluster.local", "strike-server2.strike.svc.cluster.local"},
-	}
-
-	// Sign with CA if provided, otherwise self-sign
-	var signerCert *x509.Certificate
-	var signerKey interface{}
-	if caCert != nil && caKey != nil {
-		signerCert = caCert
-		signerKey = caKey
-	} else {
-		signerCert = &strikeCert
-		signerKey = privateKey
-	}
-
-	signedCert, err := x509.CreateCertificate(rand.Reader, &strikeCert, signerCert, publicKey, signerKey)
-	if err != nil {
-		return fmt.Errorf("failed to create server certificate: %v", err)
-	}
-
-	certPEM := pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: signedCert,
-	}
-
-	err = os.WriteFile(certFullPath, pem.EncodeToMemory(&certPEM), 0600)
+	err = os.WriteFile(certFullPath, certPEM, 0600)
 	if err != nil {
 		return fmt.Errorf("failed to create server.crt: %v", err)
 	}
@@ -0,0 +1,80 @@
+package keys
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const testUserID = "11111111-1111-1111-1111-111111111111"
+
+func TestFingerprint(t *testing.T) {
+	// Fingerprints are compared across clients and versions, so the
+	// algorithm must not drift.
+	const want = "25706 68792 19252 22510 27048 66095"
+	if got := Fingerprint(testUserID, []byte("sig"), []byte("enc")); got != want {
+		t.Fatalf("Fingerprint = %q, want %q", got, want)
+	}
+
+	format := regexp.MustCompile(`^\d{5}( \d{5}){5}$`)
+	base := Fingerprint(testUserID, []byte("sig"), []byte("enc"))
+
+	cases := map[string]struct {
+		userID   string
+		sig, enc string
+	}{
+		"other-user":        {userID: "22222222-2222-2222-2222-222222222222", sig: "sig", enc: "enc"},
+		"other-signing-key": {userID: testUserID, sig: "sig2", enc: "enc"},
+		"other-enc-key":     {userID: testUserID, sig: "sig", enc: "enc2"},
+		"keys-swapped":      {userID: testUserID, sig: "enc", enc: "sig"},
+		"shifted-boundary":  {userID: testUserID, sig: "si", enc: "genc"},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := Fingerprint(tc.userID, []byte(tc.sig), []byte(tc.enc))
+			if !format.MatchString(got) {
+				t.Fatalf("Fingerprint = %q, want six groups of five digits", got)
+			}
+			if got == base {
+				t.Errorf("Fingerprint does not change with the %s", name)
+			}
+			if again := Fingerprint(tc.userID, []byte(tc.sig), []byte(tc.enc)); again != got {
+				t.Errorf("Fingerprint not stable: %q then %q", got, again)
+			}
+		})
+	}
+}
+
+func TestSafetyNumber(t *testing.T) {
+	alice := Fingerprint(testUserID, []byte("alice sig"), []byte("alice enc"))
+	bob := Fingerprint("22222222-2222-2222-2222-222222222222", []byte("bob sig"), []byte("bob enc"))
+	mallory := Fingerprint("22222222-2222-2222-2222-222222222222", []byte("mallory sig"), []byte("mallory enc"))
+
+	number := SafetyNumber(alice, bob)
+	if other := SafetyNumber(bob, alice); other != number {
+		t.Fatalf("safety number differs by side: %q and %q", number, other)
+	}
+	if !regexp.MustCompile(`^\d{5}( \d{5}){11}$`).MatchString(number) {
+		t.Fatalf("SafetyNumber = %q, want twelve groups of five digits", number)
+	}
+	if !strings.Contains(number, alice) || !strings.Contains(number, bob) {
+		t.Errorf("SafetyNumber %q does not contain both fingerprints", number)
+	}
+
+	// A key substituted in the middle shows up on one side only.
+	if SafetyNumber(alice, mallory) == number {
+		t.Error("safety number unchanged by a substituted key")
+	}
+
+	code := SafetyCode(number)
+	if code != "STRIKE1:"+strings.ReplaceAll(number, " ", "") {
+		t.Errorf("SafetyCode = %q", code)
+	}
+	if len(code) != len("STRIKE1:")+60 {
+		t.Errorf("SafetyCode has %d characters, want 68", len(code))
+	}
+}
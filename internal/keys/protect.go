@@ -0,0 +1,247 @@
+package keys
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// EncryptedKeyType is the PEM block type of a passphrase protected private
+// key, written by SealPEM with the original block type in Inner-Type.
+const EncryptedKeyType = "STRIKE ENCRYPTED PRIVATE KEY"
+
+// Argon2id parameters for new key files. Decryption reads them from the
+// file, so they can be raised without breaking existing keys.
+const (
+	kdfTime    = 3
+	kdfMemory  = 64 * 1024 // KiB
+	kdfThreads = 4
+	kdfSaltLen = 16
+)
+
+// ErrBadPassphrase is returned when a key file does not open with the
+// passphrase given.
+var ErrBadPassphrase = errors.New("wrong passphrase or corrupted key file")
+
+// IsEncryptedPEM reports whether keyPEM is a passphrase protected key.
+func IsEncryptedPEM(keyPEM []byte) bool {
+	block, _ := pem.Decode(keyPEM)
+	return block != nil && block.Type == EncryptedKeyType
+}
+
+// EncryptPrivateKeyPEM seals a PEM private key under a key derived from
+// passphrase with Argon2id.
+func EncryptPrivateKeyPEM(keyPEM, passphrase []byte) ([]byte, error) {
+	inner, _ := pem.Decode(keyPEM)
+	if inner == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+	return SealPEM(EncryptedKeyType, map[string]string{"Inner-Type": inner.Type}, inner.Bytes, passphrase)
+}
+
+// DecryptPrivateKeyPEM opens a key sealed by EncryptPrivateKeyPEM and returns
+// the plain PEM. Unencrypted keys are returned unchanged.
+func DecryptPrivateKeyPEM(keyPEM, passphrase []byte) ([]byte, error) {
+	if !IsEncryptedPEM(keyPEM) {
+		if block, _ := pem.Decode(keyPEM); block == nil {
+			return nil, fmt.Errorf("failed to decode PEM block")
+		}
+		return keyPEM, nil
+	}
+
+	headers, plain, err := OpenPEM(EncryptedKeyType, keyPEM, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: headers["Inner-Type"], Bytes: plain}), nil
+}
+
+// SealPEM encrypts plain with AES-256-GCM under a key derived from
+// passphrase with Argon2id and wraps it in a PEM block of blockType. The KDF
+// parameters, salt and the caller's headers are stored in the clear and
+// authenticated with the ciphertext.
+func SealPEM(blockType string, headers map[string]string, plain, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+
+	salt := make([]byte, kdfSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	h := make(map[string]string, len(headers)+4)
+	for k, v := range headers {
+		h[k] = v
+	}
+	h["Kdf"] = "argon2id"
+	h["Kdf-Params"] = fmt.Sprintf("t=%d,m=%d,p=%d", kdfTime, kdfMemory, kdfThreads)
+	h["Salt"] = base64.StdEncoding.EncodeToString(salt)
+	h["Cipher"] = "aes-256-gcm"
+
+	gcm, err := keyCipher(passphrase, salt, kdfTime, kdfMemory, kdfThreads)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plain, headerAAD(blockType, h))
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Headers: h, Bytes: sealed}), nil
+}
+
+// OpenPEM opens a block written by SealPEM, returning its headers and the
+// plaintext. ErrBadPassphrase covers both a wrong passphrase and tampering.
+func OpenPEM(blockType string, data, passphrase []byte) (map[string]string, []byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, fmt.Errorf("failed to decode PEM block")
+	}
+	if block.Type != blockType {
+		return nil, nil, fmt.Errorf("unexpected PEM block %q, want %q", block.Type, blockType)
+	}
+
+	if block.Headers["Kdf"] != "argon2id" || block.Headers["Cipher"] != "aes-256-gcm" {
+		return nil, nil, fmt.Errorf("unsupported encryption %q/%q", block.Headers["Kdf"], block.Headers["Cipher"])
+	}
+	t, m, p, err := parseKdfParams(block.Headers["Kdf-Params"])
+	if err != nil {
+		return nil, nil, err
+	}
+	salt, err := base64.StdEncoding.DecodeString(block.Headers["Salt"])
+	if err != nil || len(salt) == 0 {
+		return nil, nil, fmt.Errorf("invalid salt")
+	}
+
+	gcm, err := keyCipher(passphrase, salt, t, m, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(block.Bytes) < gcm.NonceSize() {
+		return nil, nil, ErrBadPassphrase
+	}
+
+	nonce, sealed := block.Bytes[:gcm.NonceSize()], block.Bytes[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, sealed, headerAAD(block.Type, block.Headers))
+	if err != nil {
+		return nil, nil, ErrBadPassphrase
+	}
+	return block.Headers, plain, nil
+}
+
+// ReadPrivateKey reads a key file, decrypting it with passphrase when it is
+// protected. passphrase is only called for protected files.
+func ReadPrivateKey(path string, passphrase func() ([]byte, error)) ([]byte, error) {
+	key, err := GetKeyFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if !IsEncryptedPEM(key) {
+		return key, nil
+	}
+	if passphrase == nil {
+		return nil, fmt.Errorf("%s is passphrase protected", path)
+	}
+
+	pass, err := passphrase()
+	if err != nil {
+		return nil, err
+	}
+	return DecryptPrivateKeyPEM(key, pass)
+}
+
+// WritePrivateKey writes a private key to path, sealed with passphrase unless
+// it is empty. The file is replaced atomically.
+func WritePrivateKey(path string, keyPEM, passphrase []byte) error {
+	out := keyPEM
+	if len(passphrase) > 0 {
+		var err error
+		out, err = EncryptPrivateKeyPEM(keyPEM, passphrase)
+		if err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write key: %v", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after rename
+
+	if _, err := tmp.Write(out); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write key: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write key: %v", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func keyCipher(passphrase, salt []byte, t, m uint32, p uint8) (cipher.AEAD, error) {
+	key := argon2.IDKey(passphrase, salt, t, m, p, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// headerAAD binds the block type and every header to the ciphertext so none
+// can be swapped.
+func headerAAD(blockType string, h map[string]string) []byte {
+	names := make([]string, 0, len(h))
+	for k := range h {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	parts := []string{blockType}
+	for _, k := range names {
+		parts = append(parts, k+": "+h[k])
+	}
+	return []byte(strings.Join(parts, "\x00"))
+}
+
+func parseKdfParams(s string) (t, m uint32, p uint8, err error) {
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return 0, 0, 0, fmt.Errorf("invalid kdf params %q", s)
+		}
+		n, perr := strconv.ParseUint(v, 10, 32)
+		if perr != nil {
+			return 0, 0, 0, fmt.Errorf("invalid kdf params %q", s)
+		}
+		switch k {
+		case "t":
+			t = uint32(n)
+		case "m":
+			m = uint32(n)
+		case "p":
+			if n > 255 {
+				return 0, 0, 0, fmt.Errorf("invalid kdf params %q", s)
+			}
+			p = uint8(n)
+		}
+	}
+	// A tampered file must not make us allocate unbounded memory.
+	if t == 0 || m == 0 || p == 0 || t > 64 || m > 4*1024*1024 {
+		return 0, 0, 0, fmt.Errorf("invalid kdf params %q", s)
+	}
+	return t, m, p, nil
+}
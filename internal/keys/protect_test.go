@@ -0,0 +1,233 @@
+package keys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testBlockType = "STRIKE TEST BLOCK"
+
+// reseal rewrites a sealed block after mod changes it, keeping the
+// ciphertext so tampering can be checked.
+func reseal(t *testing.T, sealed []byte, mod func(b *pem.Block)) []byte {
+	t.Helper()
+	block, _ := pem.Decode(sealed)
+	if block == nil {
+		t.Fatal("sealed block does not decode")
+	}
+	mod(block)
+	return pem.EncodeToMemory(block)
+}
+
+func TestSealOpenPEM(t *testing.T) {
+	plain := []byte("private key bytes")
+	pass := []byte("correct horse")
+
+	sealed, err := SealPEM(testBlockType, map[string]string{"Inner-Type": "ED25519 PRIVATE KEY"}, plain, pass)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+
+	cases := map[string]struct {
+		data    []byte
+		pass    []byte
+		errIs   error
+		errText string
+	}{
+		"round-trip": {
+			data: sealed,
+			pass: pass,
+		},
+		"wrong-passphrase": {
+			data:  sealed,
+			pass:  []byte("wrong"),
+			errIs: ErrBadPassphrase,
+		},
+		"tampered-ciphertext": {
+			data: reseal(t, sealed, func(b *pem.Block) {
+				b.Bytes[len(b.Bytes)-1] ^= 1
+			}),
+			pass:  pass,
+			errIs: ErrBadPassphrase,
+		},
+		"truncated-ciphertext": {
+			data: reseal(t, sealed, func(b *pem.Block) {
+				b.Bytes = b.Bytes[:4]
+			}),
+			pass:  pass,
+			errIs: ErrBadPassphrase,
+		},
+		"tampered-inner-type": {
+			data: reseal(t, sealed, func(b *pem.Block) {
+				b.Headers["Inner-Type"] = "X25519 PRIVATE KEY"
+			}),
+			pass:  pass,
+			errIs: ErrBadPassphrase,
+		},
+		"added-header": {
+			data: reseal(t, sealed, func(b *pem.Block) {
+				b.Headers["Comment"] = "swapped"
+			}),
+			pass:  pass,
+			errIs: ErrBadPassphrase,
+		},
+		"tampered-salt": {
+			data: reseal(t, sealed, func(b *pem.Block) {
+				b.Headers["Salt"] = base64.StdEncoding.EncodeToString([]byte("another salt 16b"))
+			}),
+			pass:  pass,
+			errIs: ErrBadPassphrase,
+		},
+		"lowered-kdf-params": {
+			data: reseal(t, sealed, func(b *pem.Block) {
+				b.Headers["Kdf-Params"] = "t=1,m=8,p=1"
+			}),
+			pass:  pass,
+			errIs: ErrBadPassphrase,
+		},
+		"unsupported-cipher": {
+			data: reseal(t, sealed, func(b *pem.Block) {
+				b.Headers["Cipher"] = "aes-128-cbc"
+			}),
+			pass:    pass,
+			errText: "unsupported encryption",
+		},
+		"other-block-type": {
+			data: reseal(t, sealed, func(b *pem.Block) {
+				b.Type = EncryptedKeyType
+			}),
+			pass:    pass,
+			errText: "unexpected PEM block",
+		},
+		"not-pem": {
+			data:    []byte("not a key"),
+			pass:    pass,
+			errText: "failed to decode PEM block",
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			headers, got, err := OpenPEM(testBlockType, tc.data, tc.pass)
+			switch {
+			case tc.errIs != nil:
+				if !errors.Is(err, tc.errIs) {
+					t.Fatalf("error = %v, want %v", err, tc.errIs)
+				}
+				return
+			case tc.errText != "":
+				if err == nil || !strings.Contains(err.Error(), tc.errText) {
+					t.Fatalf("error = %v, want it to contain %q", err, tc.errText)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Errorf("plaintext = %q, want %q", got, plain)
+			}
+			if headers["Inner-Type"] != "ED25519 PRIVATE KEY" {
+				t.Errorf("Inner-Type = %q", headers["Inner-Type"])
+			}
+		})
+	}
+}
+
+func TestSealPEMRejectsEmptyPassphrase(t *testing.T) {
+	if _, err := SealPEM(testBlockType, nil, []byte("x"), nil); err == nil {
+		t.Fatal("sealed with an empty passphrase")
+	}
+}
+
+func TestPrivateKeyPEMRoundTrip(t *testing.T) {
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "ED25519 PRIVATE KEY", Bytes: []byte("0123456789abcdef0123456789abcdef")})
+
+	sealed, err := EncryptPrivateKeyPEM(keyPEM, []byte("pass"))
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !IsEncryptedPEM(sealed) {
+		t.Fatal("sealed key is not recognised as encrypted")
+	}
+
+	got, err := DecryptPrivateKeyPEM(sealed, []byte("pass"))
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if !bytes.Equal(got, keyPEM) {
+		t.Errorf("decrypted key differs from the original")
+	}
+
+	plain, err := DecryptPrivateKeyPEM(keyPEM, nil)
+	if err != nil || !bytes.Equal(plain, keyPEM) {
+		t.Errorf("unencrypted key not returned unchanged: %v", err)
+	}
+}
+
+func TestParseKdfParams(t *testing.T) {
+	cases := map[string]struct {
+		in      string
+		t, m    uint32
+		p       uint8
+		invalid bool
+	}{
+		"defaults":         {in: "t=3,m=65536,p=4", t: 3, m: 65536, p: 4},
+		"upper-bounds":     {in: "t=64,m=4194304,p=255", t: 64, m: 4194304, p: 255},
+		"zero-time":        {in: "t=0,m=65536,p=4", invalid: true},
+		"zero-memory":      {in: "t=3,m=0,p=4", invalid: true},
+		"zero-threads":     {in: "t=3,m=65536,p=0", invalid: true},
+		"missing-threads":  {in: "t=3,m=65536", invalid: true},
+		"too-many-passes":  {in: "t=65,m=65536,p=4", invalid: true},
+		"too-much-memory":  {in: "t=3,m=4194305,p=4", invalid: true},
+		"threads-overflow": {in: "t=3,m=65536,p=256", invalid: true},
+		"negative":         {in: "t=-1,m=65536,p=4", invalid: true},
+		"not-a-number":     {in: "t=three,m=65536,p=4", invalid: true},
+		"no-equals":        {in: "t3,m=65536,p=4", invalid: true},
+		"empty":            {in: "", invalid: true},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			gotT, gotM, gotP, err := parseKdfParams(tc.in)
+			if tc.invalid {
+				if err == nil {
+					t.Fatalf("accepted %q", tc.in)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse %q: %v", tc.in, err)
+			}
+			if gotT != tc.t || gotM != tc.m || gotP != tc.p {
+				t.Errorf("got t=%d m=%d p=%d, want t=%d m=%d p=%d", gotT, gotM, gotP, tc.t, tc.m, tc.p)
+			}
+		})
+	}
+}
+
+func TestOpenPEMRejectsOutOfRangeKdfParams(t *testing.T) {
+	sealed, err := SealPEM(testBlockType, nil, []byte("x"), []byte("pass"))
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	huge := reseal(t, sealed, func(b *pem.Block) {
+		b.Headers["Kdf-Params"] = "t=3,m=4294967295,p=4"
+	})
+
+	_, _, err = OpenPEM(testBlockType, huge, []byte("pass"))
+	if err == nil || errors.Is(err, ErrBadPassphrase) || !strings.Contains(err.Error(), "invalid kdf params") {
+		t.Fatalf("error = %v, want invalid kdf params before deriving a key", err)
+	}
+}
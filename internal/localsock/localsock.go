@@ -0,0 +1,54 @@
+// Package localsock opens the Unix sockets the key agent and the client
+// daemon serve on. Both hand out keys or a logged in session to whoever can
+// connect, so only the user running them may reach the socket.
+package localsock
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// ErrInUse is returned by Listen when another process answers on the path.
+var ErrInUse = errors.New("socket in use")
+
+// Listen listens on the Unix socket at path. Its directory is created 0700
+// when missing and is refused when other users can enter it, so nobody else
+// can connect even in the moment before the socket itself is made 0600. A
+// socket left by a process that died is replaced. The caller removes path
+// when done.
+func Listen(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return nil, fmt.Errorf("%s is accessible to other users (mode %04o), use a directory only you can enter", dir, perm)
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, ErrInUse
+	}
+	_ = os.Remove(path) // stale socket from a process that died
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return ln, nil
+}
@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// CorrelationHeader is the gRPC metadata key carrying the correlation ID.
+const CorrelationHeader = "x-correlation-id"
+
+type correlationKey struct{}
+
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// incomingCorrelation takes the caller's correlation ID from the request
+// metadata, or starts a new one.
+func incomingCorrelation(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(CorrelationHeader); len(ids) > 0 && ids[0] != "" && len(ids[0]) <= 64 {
+			return WithCorrelationID(ctx, ids[0])
+		}
+	}
+	return WithCorrelationID(ctx, uuid.NewString())
+}
+
+// UnaryServerInterceptor attaches a correlation ID to the request context,
+// echoes it in the response header and logs the call.
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx = incomingCorrelation(ctx)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(CorrelationHeader, CorrelationID(ctx)))
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streams. The call is
+// logged when the stream ends.
+func StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := incomingCorrelation(ss.Context())
+	_ = ss.SetHeader(metadata.Pairs(CorrelationHeader, CorrelationID(ctx)))
+
+	start := time.Now()
+	err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	logCall(ctx, info.FullMethod, start, err)
+	return err
+}
+
+// UnaryClientInterceptor sends the context's correlation ID, starting one if
+// the context has none, so server logs can be matched to the client call.
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(outgoing(ctx), method, req, reply, cc, opts...)
+}
+
+func StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(outgoing(ctx), desc, cc, method, opts...)
+}
+
+func outgoing(ctx context.Context) context.Context {
+	id := CorrelationID(ctx)
+	if id == "" {
+		id = uuid.NewString()
+		ctx = WithCorrelationID(ctx, id)
+	}
+	return metadata.AppendToOutgoingContext(ctx, CorrelationHeader, id)
+}
+
+// logCall logs at debug on success, info for errors caused by the caller and
+// warn for errors on our side.
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+
+	level := slog.LevelDebug
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss, codes.DeadlineExceeded:
+		level = slog.LevelWarn
+	default:
+		level = slog.LevelInfo
+	}
+
+	attrs := []any{"method", method, "code", code.String(), "duration", time.Since(start)}
+	if err != nil && code != codes.OK {
+		attrs = append(attrs, "error", status.Convert(err).Message())
+	}
+	slog.Log(ctx, level, "rpc", attrs...)
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,108 @@
+// Package logging configures the log/slog logger shared by the Strike
+// binaries and carries a correlation ID through contexts and gRPC metadata,
+// so one payload can be followed from SendPayload to the peer that delivers it.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/JohnnyGlynn/strike/internal/config"
+)
+
+// CorrelationKey is the log attribute holding the correlation ID.
+const CorrelationKey = "correlation_id"
+
+// sensitiveKeys are attribute keys whose values are never logged.
+var sensitiveKeys = map[string]bool{
+	"password":             true,
+	"password_hash":        true,
+	"salt":                 true,
+	"secret":               true,
+	"token":                true,
+	"private_key":          true,
+	"passphrase":           true,
+	"db_connection_string": true,
+	"dsn":                  true,
+}
+
+// Setup builds a logger writing to w at the given level ("debug", "info",
+// "warn", "error") and format ("text" or "json"), and installs it as the
+// slog default. The standard log package is routed through it too.
+func Setup(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unsupported log format %q (want text or json)", format)
+	}
+
+	logger := slog.New(contextHandler{h})
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+func ParseLevel(level string) (slog.Level, error) {
+	var lvl slog.Level
+	if level == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return lvl, fmt.Errorf("unsupported log level %q (want debug, info, warn or error)", level)
+	}
+	return lvl, nil
+}
+
+// redact masks sensitive attributes by key, and connection strings that slip
+// into string values.
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, "[REDACTED]")
+	}
+	if a.Value.Kind() == slog.KindString {
+		v := a.Value.String()
+		if strings.Contains(v, "password=") || (strings.Contains(v, "://") && strings.Contains(v, "@")) {
+			return slog.String(a.Key, config.Redact(v))
+		}
+	}
+	return a
+}
+
+// contextHandler adds the correlation ID and the current trace and span IDs
+// from the context to each record logged with one of the *Context methods.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := CorrelationID(ctx); id != "" {
+		r.AddAttrs(slog.String(CorrelationKey, id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRedactionAndCorrelation(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := Setup(&buf, "debug", "text")
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+
+	ctx := WithCorrelationID(context.Background(), "corr-123")
+	logger.InfoContext(ctx, "connecting",
+		"dsn", "postgres://admin:hunter2@db/strike",
+		"target", "host=db password=hunter2",
+		"password_hash", "abc",
+	)
+
+	out := buf.String()
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "abc") {
+		t.Errorf("secret logged: %s", out)
+	}
+	if !strings.Contains(out, "correlation_id=corr-123") {
+		t.Errorf("correlation id missing: %s", out)
+	}
+}
+
+func TestSetupRejectsUnknownLevel(t *testing.T) {
+	if _, err := Setup(&bytes.Buffer{}, "loud", "text"); err == nil {
+		t.Error("error: unknown level accepted")
+	}
+}
@@ -0,0 +1,326 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/logging"
+	"github.com/JohnnyGlynn/strike/internal/server/types"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+	"github.com/JohnnyGlynn/strike/internal/tracing"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+	fedpb "github.com/JohnnyGlynn/strike/msgdef/federation"
+	pb "github.com/JohnnyGlynn/strike/msgdef/message"
+)
+
+const (
+	// maxNotices caps how many addresses one account deletion, rename or
+	// move can queue notices for.
+	maxNotices = 1000
+
+	// noticeClockSkew is how far a signed notice's timestamp may be from our
+	// clock.
+	noticeClockSkew = 10 * time.Minute
+)
+
+// DeleteAccount removes the caller's account after checking their password
+// and the signed deletion notice, then forwards the notice to the addresses
+// the client listed and to every connected federation peer.
+func (s *StrikeServer) DeleteAccount(ctx context.Context, req *pb.DeleteAccountRequest) (*pb.ServerResponse, error) {
+	if req.GetUsername() == "" {
+		return nil, invalidArgument("username", "missing username")
+	}
+	if req.Confirm != req.Username {
+		return nil, invalidArgument("confirm", "must repeat the username")
+	}
+	if req.Notice == nil {
+		return nil, invalidArgument("notice", "missing signed deletion notice")
+	}
+	if len(req.Notify) > maxNotices {
+		return nil, invalidArgument("notify", fmt.Sprintf("at most %d addresses", maxNotices))
+	}
+	if s.isDraining() {
+		return nil, shuttingDown()
+	}
+
+	uInfo, err := s.reauthenticate(ctx, req.Username, req.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkNotice(req.Notice, uInfo); err != nil {
+		return nil, err
+	}
+
+	id, dropped, err := s.deleteUser(ctx, req.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	queued := s.queueNotices(ctx, id, req.Notify, &pb.StreamPayload{
+		Payload: &pb.StreamPayload_AccountDeleted{AccountDeleted: req.Notice},
+		Info:    "Account deleted notice",
+	})
+
+	fedReq := &fedpb.UserDeletedReq{OriginServer: s.ID.String(), Notice: req.Notice}
+	s.notifyPeers(ctx, "deletion", func(ctx context.Context, c fedpb.FederationClient) error {
+		_, err := c.UserDeleted(ctx, fedReq)
+		return err
+	})
+
+	slog.InfoContext(ctx, "account deleted", "username", req.Username, "user_id", id, "pending_dropped", dropped, "notices", queued)
+	return &pb.ServerResponse{Success: true, Message: fmt.Sprintf("account %s deleted, %d friends notified", req.Username, queued)}, nil
+}
+
+// reauthenticate checks the password for a destructive account operation
+// the same way Login does, lockout included, and returns the user's keys.
+func (s *StrikeServer) reauthenticate(ctx context.Context, username, passwordHash string) (*common_pb.UserInfo, error) {
+	if err := s.checkLockout(ctx, username); err != nil {
+		return nil, err
+	}
+
+	var storedHash string
+	var disabled bool
+	err := s.DBpool.QueryRow(ctx, s.PStatements.User.LoginUser, username).Scan(&storedHash, &disabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		s.recordLoginFailure(ctx, username)
+		return nil, unauthenticated("invalid username or password")
+	}
+	if err != nil {
+		return nil, dbError("reauthenticate", "user", username, err)
+	}
+
+	passMatch, err := shared.VerifyPassword(passwordHash, storedHash)
+	if err != nil {
+		return nil, internalError("reauthenticate: verify password", err)
+	}
+	if !passMatch {
+		s.recordLoginFailure(ctx, username)
+		return nil, unauthenticated("invalid username or password")
+	}
+	s.clearLoginFailures(ctx, username)
+
+	if disabled {
+		return nil, accountDisabled(username)
+	}
+
+	return s.localUserLookup(ctx, username)
+}
+
+// checkActive refuses a user who has been disabled or whose account is no
+// longer here, so a client kicked by the admin cannot simply reconnect.
+func (s *StrikeServer) checkActive(ctx context.Context, userID uuid.UUID) error {
+	var username string
+	var disabled bool
+	err := s.DBpool.QueryRow(ctx, s.PStatements.User.GetStatus, userID).Scan(&username, &disabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return accountGone(userID.String())
+	}
+	if err != nil {
+		return dbError("check account", "user", userID.String(), err)
+	}
+	if disabled {
+		return accountDisabled(username)
+	}
+	return nil
+}
+
+// checkNotice verifies that the deletion notice names this user on this
+// server and is signed with their registered signing key.
+func (s *StrikeServer) checkNotice(n *common_pb.AccountDeleted, uInfo *common_pb.UserInfo) error {
+	if n.UserId != uInfo.UserId || n.Username != uInfo.Username || n.Domain != s.Name {
+		return invalidArgument("notice", "does not match the account")
+	}
+	if n.DeletedAt == nil {
+		return invalidArgument("notice", "missing deleted_at")
+	}
+	if skew := time.Since(n.DeletedAt.AsTime()).Abs(); skew > noticeClockSkew {
+		return invalidArgument("notice", "deleted_at too far from server time")
+	}
+
+	pub, err := keys.ParseSigningPublicKey(uInfo.SigningPublicKey)
+	if err != nil {
+		return internalError("delete account: parse signing key", err)
+	}
+	if !ed25519.Verify(pub, shared.AccountDeletedBytes(n), n.Signature) {
+		return invalidArgument("notice", "bad signature")
+	}
+	return nil
+}
+
+// deleteUser removes username and its keys, forgets its failed logins, ends
+// its streams and drops payloads still queued for it.
+func (s *StrikeServer) deleteUser(ctx context.Context, username string) (uuid.UUID, int, error) {
+	id, err := s.removeUser(ctx, username)
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+	return id, s.dropPendingFor(id), nil
+}
+
+// removeUser is deleteUser without dropping queued payloads, which a move
+// forwards to the user's new server instead.
+func (s *StrikeServer) removeUser(ctx context.Context, username string) (uuid.UUID, error) {
+	var id uuid.UUID
+	if err := s.DBpool.QueryRow(ctx, s.PStatements.User.DeleteUser, username).Scan(&id); err != nil {
+		return uuid.Nil, dbError("delete user", "user", username, err)
+	}
+	if _, err := s.DBpool.Exec(ctx, s.PStatements.Login.ClearFailures, username); err != nil {
+		slog.WarnContext(ctx, "delete user: clearing login failures failed", "username", username, "error", err)
+	}
+
+	s.disconnect(id)
+	return id, nil
+}
+
+// queueNotices queues sp from the user to each address and returns how many
+// were queued. It bypasses the per-sender pending limit, which would
+// otherwise cut off users with many friends.
+func (s *StrikeServer) queueNotices(ctx context.Context, from uuid.UUID, notify []*common_pb.UserAddress, sp *pb.StreamPayload) int {
+	queued := 0
+	for _, addr := range notify {
+		if err := s.queueNotice(ctx, from, addr, sp); err != nil {
+			slog.WarnContext(ctx, "notice not queued", "user_id", from, "recipient", addr.GetUInfo().GetUserId(), "info", sp.Info, "error", err)
+			continue
+		}
+		queued++
+	}
+	return queued
+}
+
+func (s *StrikeServer) queueNotice(ctx context.Context, from uuid.UUID, addr *common_pb.UserAddress, sp *pb.StreamPayload) error {
+	to, err := uuid.Parse(addr.GetUInfo().GetUserId())
+	if err != nil {
+		return fmt.Errorf("not a valid user id")
+	}
+
+	out := proto.Clone(sp).(*pb.StreamPayload)
+	out.Target = to.String()
+	out.Sender = from.String()
+	out.SenderDomain = s.Name
+	out.TargetDomain = addr.Domain
+
+	payload, err := proto.Marshal(out)
+	if err != nil {
+		return err
+	}
+
+	msgID := uuid.New()
+	s.mu.Lock()
+	s.mapInit()
+	s.Pending[msgID] = &types.PendingMsg{
+		MessageID:    msgID,
+		From:         from,
+		To:           to,
+		SenderDomain: s.Name,
+		TargetDomain: addr.Domain,
+		Payload:      payload,
+		Created:      time.Now(),
+		Attempts:     deliveryAttempts,
+
+		CorrelationID: logging.CorrelationID(ctx),
+		TraceParent:   tracing.Inject(ctx),
+	}
+	s.mu.Unlock()
+
+	s.startDelivery(msgID)
+	return nil
+}
+
+// notifyPeers makes call against every connected federation peer in the
+// background, so a slow peer does not hold up the client.
+func (s *StrikeServer) notifyPeers(ctx context.Context, what string, call func(context.Context, fedpb.FederationClient) error) {
+	if s.PeerMgr == nil {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	for peerID, client := range s.PeerMgr.Clients() {
+		go func(ctx context.Context) {
+			if s.RelayTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, s.RelayTimeout)
+				defer cancel()
+			}
+
+			if err := call(ctx, client); err != nil {
+				slog.WarnContext(ctx, "federation: "+what+" notice failed", "peer", s.PeerMgr.NameOf(peerID), "error", err)
+			}
+		}(ctx)
+	}
+}
+
+// checkRemoteDeletion verifies a deletion notice relayed by peer. The user
+// must not be one of ours and must be homed on peer, and the notice must be
+// signed with the key remembered from the last lookup of them.
+func (s *StrikeServer) checkRemoteDeletion(ctx context.Context, peer types.PeerConfig, user uuid.UUID, n *common_pb.AccountDeleted) error {
+	if n.Domain != peer.Name {
+		return fmt.Errorf("%s is not the home server of %s", peer.Name, shared.FormatAddress(n.Username, n.Domain))
+	}
+
+	var encKey, sigKey []byte
+	err := s.DBpool.QueryRow(ctx, s.PStatements.Keys.GetPublicKeys, user).Scan(&encKey, &sigKey)
+	if err == nil {
+		return fmt.Errorf("user %s is local", user)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("local user check: %v", err)
+	}
+
+	s.mu.Lock()
+	owner, present := s.RemotePresence[user]
+	movedTo, moved := s.moved[user]
+	pub, known := s.remoteKeys[user]
+	s.mu.Unlock()
+
+	if (present && owner != peer.ID.String()) || (moved && movedTo != peer.Name) {
+		return fmt.Errorf("user %s is not homed on %s", user, peer.Name)
+	}
+	if !known {
+		return fmt.Errorf("no signing key known for user %s", user)
+	}
+	if !ed25519.Verify(pub, shared.AccountDeletedBytes(n), n.Signature) {
+		return fmt.Errorf("bad signature")
+	}
+	return nil
+}
+
+// forgetRemoteUser drops presence and the remembered key for a remote user
+// announced as deleted by peerID, along with anything still queued for them.
+// Callers check the notice with checkRemoteDeletion first.
+func (s *StrikeServer) forgetRemoteUser(user uuid.UUID, peerID string) int {
+	s.mu.Lock()
+	if owner, ok := s.RemotePresence[user]; ok && owner == peerID {
+		delete(s.RemotePresence, user)
+	}
+	delete(s.remoteKeys, user)
+	s.mu.Unlock()
+
+	return s.dropPendingFor(user)
+}
+
+// rememberRemoteKey records the signing key a peer returned for one of its
+// users, so a later deletion notice from that peer can be verified.
+func (s *StrikeServer) rememberRemoteKey(uInfo *common_pb.UserInfo) {
+	user, err := uuid.Parse(uInfo.GetUserId())
+	if err != nil {
+		return
+	}
+	pub, err := keys.ParseSigningPublicKey(uInfo.GetSigningPublicKey())
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.mapInit()
+	s.remoteKeys[user] = pub
+	s.mu.Unlock()
+}
@@ -0,0 +1,466 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/JohnnyGlynn/strike/internal/server/types"
+	adminpb "github.com/JohnnyGlynn/strike/msgdef/admin"
+)
+
+// Listing limits for the admin service.
+const (
+	defaultAdminListLimit = 100
+	maxAdminListLimit     = 1000
+)
+
+// AdminServer implements the admin gRPC service used by strike-admin. Every
+// call must carry the configured token as "authorization: Bearer <token>".
+type AdminServer struct {
+	adminpb.UnimplementedAdminServer
+
+	strike *StrikeServer
+	token  string
+}
+
+func NewAdminServer(s *StrikeServer, token string) *AdminServer {
+	return &AdminServer{strike: s, token: token}
+}
+
+// UnaryAuth rejects calls without the admin token.
+func (a *AdminServer) UnaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !a.authorized(ctx) {
+		slog.WarnContext(ctx, "admin: rejected call", "method", info.FullMethod, "ip", peerIP(ctx))
+		return nil, unauthenticated("invalid admin token")
+	}
+	return handler(ctx, req)
+}
+
+func (a *AdminServer) authorized(ctx context.Context) bool {
+	if a.token == "" {
+		return false
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, v := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(v, "Bearer "); ok &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *AdminServer) ListUsers(ctx context.Context, req *adminpb.ListUsersReq) (*adminpb.ListUsersResp, error) {
+	limit, err := listLimit(req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if req.Offset < 0 {
+		return nil, invalidArgument("offset", "must not be negative")
+	}
+
+	rows, err := a.strike.DBpool.Query(ctx, a.strike.PStatements.Admin.ListUsers, req.Filter, limit, req.Offset)
+	if err != nil {
+		return nil, dbError("admin: list users", "users", req.Filter, err)
+	}
+	defer rows.Close()
+
+	a.strike.mu.Lock()
+	online := make(map[uuid.UUID]bool, len(a.strike.Connected))
+	for id := range a.strike.Connected {
+		online[id] = true
+	}
+	a.strike.mu.Unlock()
+
+	resp := &adminpb.ListUsersResp{}
+	for rows.Next() {
+		var id uuid.UUID
+		var created *time.Time
+		u := &adminpb.AdminUser{}
+		if err := rows.Scan(&id, &u.Username, &u.Disabled, &created); err != nil {
+			return nil, dbError("admin: list users", "users", req.Filter, err)
+		}
+		u.UserId = id.String()
+		u.Online = online[id]
+		if created != nil {
+			u.CreatedAt = timestamppb.New(*created)
+		}
+		resp.Users = append(resp.Users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("admin: list users", "users", req.Filter, err)
+	}
+
+	return resp, nil
+}
+
+// DeleteUser removes the account and its keys, ends the user's streams and
+// drops payloads still queued for them.
+func (a *AdminServer) DeleteUser(ctx context.Context, req *adminpb.UserRef) (*adminpb.AdminAck, error) {
+	if req.Username == "" {
+		return nil, invalidArgument("username", "missing username")
+	}
+
+	id, dropped, err := a.strike.deleteUser(ctx, req.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "admin: user deleted", "username", req.Username, "user_id", id, "pending_dropped", dropped)
+	return &adminpb.AdminAck{Message: fmt.Sprintf("deleted %s, dropped %d pending payloads", req.Username, dropped), Affected: 1}, nil
+}
+
+// SetUserDisabled blocks or restores logins. Disabling also ends the user's
+// current streams, and checkActive refuses to reopen them or send for the
+// user until they are enabled again.
+func (a *AdminServer) SetUserDisabled(ctx context.Context, req *adminpb.SetUserDisabledReq) (*adminpb.AdminAck, error) {
+	if req.Username == "" {
+		return nil, invalidArgument("username", "missing username")
+	}
+
+	var id uuid.UUID
+	if err := a.strike.DBpool.QueryRow(ctx, a.strike.PStatements.Admin.SetDisabled, req.Username, req.Disabled).Scan(&id); err != nil {
+		return nil, dbError("admin: set disabled", "user", req.Username, err)
+	}
+
+	state := "enabled"
+	if req.Disabled {
+		state = "disabled"
+		a.strike.disconnect(id)
+	}
+
+	slog.InfoContext(ctx, "admin: user "+state, "username", req.Username, "user_id", id)
+	return &adminpb.AdminAck{Message: req.Username + " " + state, Affected: 1}, nil
+}
+
+// ResetKeys replaces a user's public keys, for example after they lost their
+// private keys. The user is disconnected so their session picks up the new
+// keys when they log back in.
+func (a *AdminServer) ResetKeys(ctx context.Context, req *adminpb.ResetKeysReq) (*adminpb.AdminAck, error) {
+	if req.Username == "" {
+		return nil, invalidArgument("username", "missing username")
+	}
+	if !isPublicKeyPEM(req.EncryptionPublicKey) {
+		return nil, invalidArgument("encryption_public_key", "not a PEM encoded public key")
+	}
+	if !isPublicKeyPEM(req.SigningPublicKey) {
+		return nil, invalidArgument("signing_public_key", "not a PEM encoded public key")
+	}
+
+	var id uuid.UUID
+	err := a.strike.DBpool.QueryRow(ctx, a.strike.PStatements.Admin.ResetKeys, req.Username, req.EncryptionPublicKey, req.SigningPublicKey).Scan(&id)
+	if err != nil {
+		return nil, dbError("admin: reset keys", "user", req.Username, err)
+	}
+
+	a.strike.disconnect(id)
+
+	slog.InfoContext(ctx, "admin: keys reset", "username", req.Username, "user_id", id)
+	return &adminpb.AdminAck{Message: "keys reset for " + req.Username, Affected: 1}, nil
+}
+
+func (a *AdminServer) ListPending(ctx context.Context, req *adminpb.ListQueueReq) (*adminpb.QueueEntries, error) {
+	limit, err := listLimit(req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	a.strike.mu.Lock()
+	pending := make([]*types.PendingMsg, 0, len(a.strike.Pending))
+	for _, pmsg := range a.strike.Pending {
+		copied := *pmsg
+		pending = append(pending, &copied)
+	}
+	a.strike.mu.Unlock()
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Created.Before(pending[j].Created) })
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+
+	resp := &adminpb.QueueEntries{}
+	for _, pmsg := range pending {
+		resp.Entries = append(resp.Entries, queueEntry(pmsg))
+	}
+	return resp, nil
+}
+
+// ReplayPending retries queued payloads now, with a fresh set of attempts,
+// instead of waiting for the recipient to reconnect. Payloads already being
+// delivered are left to that delivery; asking for one of them by ID is
+// refused.
+func (a *AdminServer) ReplayPending(ctx context.Context, ref *adminpb.QueueRef) (*adminpb.AdminAck, error) {
+	ids, err := queueSelection(ref)
+	if err != nil {
+		return nil, err
+	}
+	if a.strike.isDraining() {
+		return nil, shuttingDown()
+	}
+
+	a.strike.mu.Lock()
+	if ref.All {
+		for id := range a.strike.Pending {
+			ids = append(ids, id)
+		}
+	}
+	a.strike.mapInit()
+	var replay []uuid.UUID
+	busy := 0
+	for _, id := range ids {
+		pmsg, ok := a.strike.Pending[id]
+		if !ok {
+			continue
+		}
+		if _, running := a.strike.delivering[id]; running {
+			busy++
+			continue
+		}
+		pmsg.Attempts = deliveryAttempts
+		replay = append(replay, id)
+	}
+	a.strike.mu.Unlock()
+
+	if !ref.All && busy > 0 {
+		return nil, failedPrecondition(ref.MessageId, "payload is already being delivered")
+	}
+	if !ref.All && len(replay) == 0 {
+		return nil, notFound("pending payload", ref.MessageId)
+	}
+	started := 0
+	for _, id := range replay {
+		if a.strike.startDelivery(id) {
+			started++
+		} else {
+			busy++
+		}
+	}
+
+	slog.InfoContext(ctx, "admin: pending payloads replayed", "count", started, "already_delivering", busy)
+	msg := fmt.Sprintf("replaying %d pending payloads", started)
+	if busy > 0 {
+		msg += fmt.Sprintf(", %d already being delivered", busy)
+	}
+	return &adminpb.AdminAck{Message: msg, Affected: int32(started)}, nil
+}
+
+func (a *AdminServer) ListDeadLetters(ctx context.Context, req *adminpb.ListQueueReq) (*adminpb.QueueEntries, error) {
+	limit, err := listLimit(req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := a.strike.DBpool.Query(ctx, a.strike.PStatements.DeadLetter.List, limit)
+	if err != nil {
+		return nil, dbError("admin: list dead letters", "dead letters", "", err)
+	}
+	defer rows.Close()
+
+	resp := &adminpb.QueueEntries{}
+	for rows.Next() {
+		dl, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, dbError("admin: list dead letters", "dead letters", "", err)
+		}
+		entry := queueEntry(&dl.PendingMsg)
+		entry.FailedAt = timestamppb.New(dl.FailedAt)
+		entry.Reason = dl.Reason
+		resp.Entries = append(resp.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("admin: list dead letters", "dead letters", "", err)
+	}
+
+	return resp, nil
+}
+
+// ReplayDeadLetters moves dead letters back onto the pending queue and
+// starts delivering them.
+func (a *AdminServer) ReplayDeadLetters(ctx context.Context, ref *adminpb.QueueRef) (*adminpb.AdminAck, error) {
+	ids, err := queueSelection(ref)
+	if err != nil {
+		return nil, err
+	}
+	if a.strike.isDraining() {
+		return nil, shuttingDown()
+	}
+
+	query, args := a.strike.PStatements.DeadLetter.TakeAll, []any{}
+	if !ref.All {
+		query, args = a.strike.PStatements.DeadLetter.TakeOne, []any{ids[0]}
+	}
+
+	rows, err := a.strike.DBpool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, dbError("admin: replay dead letters", "dead letter", ref.MessageId, err)
+	}
+	defer rows.Close()
+
+	var replay []uuid.UUID
+	for rows.Next() {
+		dl, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, dbError("admin: replay dead letters", "dead letter", ref.MessageId, err)
+		}
+		pmsg := dl.PendingMsg
+		pmsg.Attempts = deliveryAttempts
+
+		a.strike.mu.Lock()
+		a.strike.mapInit()
+		a.strike.Pending[pmsg.MessageID] = &pmsg
+		a.strike.mu.Unlock()
+		replay = append(replay, pmsg.MessageID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("admin: replay dead letters", "dead letter", ref.MessageId, err)
+	}
+
+	if !ref.All && len(replay) == 0 {
+		return nil, notFound("dead letter", ref.MessageId)
+	}
+	for _, id := range replay {
+		a.strike.startDelivery(id)
+	}
+
+	slog.InfoContext(ctx, "admin: dead letters replayed", "count", len(replay))
+	return &adminpb.AdminAck{Message: fmt.Sprintf("replaying %d dead letters", len(replay)), Affected: int32(len(replay))}, nil
+}
+
+func (a *AdminServer) PurgeDeadLetters(ctx context.Context, ref *adminpb.QueueRef) (*adminpb.AdminAck, error) {
+	ids, err := queueSelection(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	query, args := a.strike.PStatements.DeadLetter.PurgeAll, []any{}
+	if !ref.All {
+		query, args = a.strike.PStatements.DeadLetter.PurgeOne, []any{ids[0]}
+	}
+
+	tag, err := a.strike.DBpool.Exec(ctx, query, args...)
+	if err != nil {
+		return nil, dbError("admin: purge dead letters", "dead letter", ref.MessageId, err)
+	}
+	if !ref.All && tag.RowsAffected() == 0 {
+		return nil, notFound("dead letter", ref.MessageId)
+	}
+
+	slog.InfoContext(ctx, "admin: dead letters purged", "count", tag.RowsAffected())
+	return &adminpb.AdminAck{Message: fmt.Sprintf("purged %d dead letters", tag.RowsAffected()), Affected: int32(tag.RowsAffected())}, nil
+}
+
+func (a *AdminServer) PeerStatus(ctx context.Context, _ *adminpb.PeerStatusReq) (*adminpb.PeerStatusResp, error) {
+	peers := a.strike.PeerMgr.Snapshot()
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+
+	resp := &adminpb.PeerStatusResp{}
+	for _, p := range peers {
+		state := &adminpb.PeerState{
+			Id:         p.ID,
+			Name:       p.Name,
+			Address:    p.Address,
+			Online:     p.Online,
+			Handshaken: p.Handshaken,
+		}
+		if !p.LastSeen.IsZero() {
+			state.LastSeen = timestamppb.New(p.LastSeen)
+		}
+		resp.Peers = append(resp.Peers, state)
+	}
+	return resp, nil
+}
+
+// kickLocked returns the channel closed when user is disconnected by an
+// admin. s.mu must be held.
+func (s *StrikeServer) kickLocked(user uuid.UUID) <-chan struct{} {
+	ch, ok := s.kicks[user]
+	if !ok {
+		ch = make(chan struct{})
+		s.kicks[user] = ch
+	}
+	return ch
+}
+
+// disconnect ends every stream the user has open.
+func (s *StrikeServer) disconnect(user uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ch, ok := s.kicks[user]; ok {
+		close(ch)
+		delete(s.kicks, user)
+	}
+}
+
+// dropPendingFor removes queued payloads addressed to user.
+func (s *StrikeServer) dropPendingFor(user uuid.UUID) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dropped := 0
+	for id, pmsg := range s.Pending {
+		if pmsg.To == user {
+			delete(s.Pending, id)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+func listLimit(limit int32) (int, error) {
+	switch {
+	case limit == 0:
+		return defaultAdminListLimit, nil
+	case limit < 0 || limit > maxAdminListLimit:
+		return 0, invalidArgument("limit", fmt.Sprintf("must be between 1 and %d", maxAdminListLimit))
+	default:
+		return int(limit), nil
+	}
+}
+
+// queueSelection validates a QueueRef, returning the parsed ID unless it
+// selects everything.
+func queueSelection(ref *adminpb.QueueRef) ([]uuid.UUID, error) {
+	if ref.All {
+		if ref.MessageId != "" {
+			return nil, invalidArgument("message_id", "cannot be combined with all")
+		}
+		return nil, nil
+	}
+	id, err := uuid.Parse(ref.MessageId)
+	if err != nil {
+		return nil, invalidArgument("message_id", "not a valid message id")
+	}
+	return []uuid.UUID{id}, nil
+}
+
+func queueEntry(pmsg *types.PendingMsg) *adminpb.QueueEntry {
+	return &adminpb.QueueEntry{
+		MessageId:     pmsg.MessageID.String(),
+		Sender:        pmsg.From.String(),
+		Recipient:     pmsg.To.String(),
+		SenderDomain:  pmsg.SenderDomain,
+		TargetDomain:  pmsg.TargetDomain,
+		Attempts:      int32(pmsg.Attempts),
+		Size:          int32(len(pmsg.Payload)),
+		CreatedAt:     timestamppb.New(pmsg.Created),
+		CorrelationId: pmsg.CorrelationID,
+	}
+}
+
+func isPublicKeyPEM(b []byte) bool {
+	block, _ := pem.Decode(b)
+	return block != nil && block.Type == "PUBLIC KEY"
+}
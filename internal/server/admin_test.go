@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+
+	adminpb "github.com/JohnnyGlynn/strike/msgdef/admin"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+	pb "github.com/JohnnyGlynn/strike/msgdef/message"
+
+	"github.com/JohnnyGlynn/strike/internal/server/types"
+)
+
+type testPayloadStream struct{ testStream }
+
+func (testPayloadStream) Send(*pb.StreamPayload) error { return nil }
+
+type testStatusStream struct{ testStream }
+
+func (testStatusStream) Send(*pb.StatusUpdate) error { return nil }
+
+// TestDisabledUserRefused runs against STRIKE_TEST_DATABASE_URL.
+func TestDisabledUserRefused(t *testing.T) {
+	s := &StrikeServer{Name: "a.example"}
+	useTestDatabase(t, s)
+	admin := NewAdminServer(s, "")
+	ctx := context.Background()
+
+	id := uuid.New()
+	user := &common_pb.UserInfo{UserId: id.String(), Username: "disabled-" + id.String()[:8]}
+	if _, err := s.DBpool.Exec(ctx, s.PStatements.User.CreateUser, id, user.Username, "hash", []byte("salt")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _, _ = s.DBpool.Exec(ctx, s.PStatements.User.DeleteUser, user.Username) })
+
+	// An open stream ends when the user is disabled...
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- s.PayloadStream(user, testPayloadStream{testStream{ctx: streamCtx}}) }()
+	for range 100 {
+		s.mu.Lock()
+		_, open := s.PayloadStreams[id]
+		s.mu.Unlock()
+		if open {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := admin.SetUserDisabled(ctx, &adminpb.SetUserDisabledReq{Username: user.Username, Disabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("kicked stream: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream still open after the user was disabled")
+	}
+
+	// ...and cannot be reopened, nor can the user send.
+	send := &pb.StreamPayload{Sender: id.String(), Target: uuid.NewString(), Info: "hello"}
+	refused := func(t *testing.T, text string) {
+		t.Helper()
+		wantCode(t, s.PayloadStream(user, testPayloadStream{testStream{ctx: ctx}}), codes.PermissionDenied, text)
+		wantCode(t, s.StatusStream(user, testStatusStream{testStream{ctx: ctx}}), codes.PermissionDenied, text)
+		_, err := s.SendPayload(ctx, send)
+		wantCode(t, err, codes.PermissionDenied, text)
+	}
+	refused(t, "is disabled")
+
+	// Nor can an account that is gone, such as one deleted or moved away.
+	if _, err := s.DBpool.Exec(ctx, s.PStatements.User.DeleteUser, user.Username); err != nil {
+		t.Fatal(err)
+	}
+	refused(t, "no account for user")
+}
+
+func TestReplayPendingSkipsRunningDeliveries(t *testing.T) {
+	s := &StrikeServer{Name: "a.example", PeerMgr: NewPeerManager(nil, "a.example")}
+	s.initLifecycle()
+	s.mapInit()
+	admin := NewAdminServer(s, "")
+	ctx := context.Background()
+
+	queue := func() uuid.UUID {
+		id := uuid.New()
+		s.Pending[id] = &types.PendingMsg{MessageID: id, To: uuid.New(), TargetDomain: "b.example", Attempts: 1}
+		return id
+	}
+	busy, idle := queue(), queue()
+	s.delivering[busy] = struct{}{}
+
+	_, err := admin.ReplayPending(ctx, &adminpb.QueueRef{MessageId: busy.String()})
+	wantCode(t, err, codes.FailedPrecondition, "already being delivered")
+	if got := s.Pending[busy].Attempts; got != 1 {
+		t.Errorf("refused replay reset attempts to %d", got)
+	}
+
+	ack, err := admin.ReplayPending(ctx, &adminpb.QueueRef{All: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.Affected != 1 {
+		t.Errorf("affected = %d, want 1", ack.Affected)
+	}
+	if err := s.WaitDeliveries(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Pending[busy].Attempts; got != 1 {
+		t.Errorf("running payload replayed: attempts = %d, want 1", got)
+	}
+	if got := s.Pending[idle].Attempts; got != deliveryAttempts-1 {
+		t.Errorf("idle payload: attempts = %d, want %d", got, deliveryAttempts-1)
+	}
+	if _, running := s.delivering[idle]; running {
+		t.Error("finished delivery still marked as running")
+	}
+	if s.startDelivery(busy) {
+		t.Error("startDelivery started a second delivery of a running payload")
+	}
+}
@@ -3,15 +3,19 @@ package server
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net"
-	"os"
+	"net/http"
 	"time"
 
 	"github.com/JohnnyGlynn/strike/internal/config"
 	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/logging"
 	"github.com/JohnnyGlynn/strike/internal/server/types"
+	"github.com/JohnnyGlynn/strike/internal/tracing"
+	adminpb "github.com/JohnnyGlynn/strike/msgdef/admin"
 	fedpb "github.com/JohnnyGlynn/strike/msgdef/federation"
 	pb "github.com/JohnnyGlynn/strike/msgdef/message"
 
@@ -19,6 +23,9 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 )
 
 type Bootstrap struct {
@@ -31,8 +38,12 @@ type Bootstrap struct {
 
 	grpcStrike *grpc.Server
 	grpcFed    *grpc.Server
+	grpcAdmin  *grpc.Server
 
-	fedTLS *tls.Config
+	admin  *http.Server
+	health *health.Server
+
+	fedTrust *FederationTrust
 }
 
 func InitBootstrap(cfg config.ServerConfig) *Bootstrap {
@@ -50,6 +61,10 @@ func (b *Bootstrap) InitDb(ctx context.Context) error {
 		return err
 	}
 
+	if err := EnsureSchema(ctx, b.DB); err != nil {
+		return err
+	}
+
 	b.Statements, err = InitStatements(ctx, b.DB)
 	return err
 }
@@ -70,7 +85,7 @@ func dbWithRetry(ctx context.Context, pgConfig *pgxpool.Config) (*pgxpool.Pool,
 			err = pingErr
 		}
 
-		fmt.Printf("DB not ready (%v). Retrying: %s...", err, backoff)
+		slog.Warn("database not ready, retrying", "error", err, "backoff", backoff)
 
 		select {
 		case <-ctx.Done():
@@ -94,18 +109,50 @@ func (b *Bootstrap) InitStrikeServer(creds credentials.TransportCredentials, pee
 
 	id := DeriveServerID(key)
 
+	privBytes, err := keys.GetKeyFromPath(b.Cfg.SigningPrivateKeyPath)
+	if err != nil {
+		return err
+	}
+	signingKey, err := keys.ParseSigningPrivateKey(privBytes)
+	if err != nil {
+		return err
+	}
+
 	b.Strike = &StrikeServer{
 		Name:           b.Cfg.Name,
 		ID:             uuid.MustParse(id),
 		DBpool:         b.DB,
 		PStatements:    b.Statements,
-		PeerMgr:        NewPeerManager(peers),
+		PeerMgr:        NewPeerManager(peers, b.Cfg.Name),
 		Pending:        make(map[uuid.UUID]*types.PendingMsg),
 		RemotePresence: make(map[uuid.UUID]string),
+
+		DeliveryTimeout: time.Duration(b.Cfg.DeliveryTimeout),
+		RelayTimeout:    time.Duration(b.Cfg.RelayTimeout),
+		Limits:          NewRateLimiter(b.Cfg.RateLimit),
+
+		signingKey: signingKey,
 	}
-	b.grpcStrike = grpc.NewServer(
-		grpc.Creds(creds),
+	b.Strike.initLifecycle()
+	b.Strike.PeerMgr.OnConnect(b.Strike.flushPendingTo)
+
+	if err := b.Strike.LoadRedirects(context.Background()); err != nil {
+		return err
+	}
+
+	restored, err := b.Strike.RestorePending(context.Background())
+	if err != nil {
+		return err
+	}
+	if restored > 0 {
+		slog.Info("restored pending payloads", "count", restored)
+	}
+
+	opts := append(b.serverOptions(creds),
+		grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor, b.Strike.UnaryRateLimit),
+		grpc.ChainStreamInterceptor(logging.StreamServerInterceptor, b.Strike.StreamRateLimit),
 	)
+	b.grpcStrike = grpc.NewServer(opts...)
 
 	pb.RegisterStrikeServer(b.grpcStrike, b.Strike)
 	return nil
@@ -114,62 +161,101 @@ func (b *Bootstrap) InitStrikeServer(creds credentials.TransportCredentials, pee
 func (b *Bootstrap) InitFederation() error {
 	var err error
 
-	b.fedTLS, err = LoadFederationTLSConfig(
+	b.fedTrust, err = LoadFederationTrust(
 		b.Cfg.CertificatePath,
 		b.Cfg.SigningPrivateKeyPath,
 		b.Cfg.FederationCAPath,
+		b.Cfg.FederationCRLPath,
+		b.Strike.PeerMgr.configs(),
 	)
 	if err != nil {
 		return err
 	}
+	b.Strike.fedTrust = b.fedTrust
+	b.Strike.federationPeers = b.Cfg.FederationPeers
+	b.Strike.federationAddress = b.Cfg.FederationAddress
 
 	b.Orchestrator = NewFederationOrchestrator(b.Strike)
 
-	b.grpcFed = grpc.NewServer(
-		grpc.Creds(credentials.NewTLS(b.fedTLS)),
+	opts := append(b.serverOptions(credentials.NewTLS(b.fedTrust.ServerConfig())),
+		grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor, b.fedTrust.UnaryAuth),
+		grpc.ChainStreamInterceptor(logging.StreamServerInterceptor, b.fedTrust.StreamAuth),
 	)
+	b.grpcFed = grpc.NewServer(opts...)
 
 	fedpb.RegisterFederationServer(b.grpcFed, b.Orchestrator)
 	return nil
 }
 
-func LoadFederationTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
-	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
-	if err != nil {
-		return nil, err
+// InitAdminRPC builds the admin gRPC service used by strike-admin, served
+// with the client-facing TLS credentials. It is skipped when no admin_token
+// is configured.
+func (b *Bootstrap) InitAdminRPC(creds credentials.TransportCredentials) error {
+	if b.Strike == nil {
+		return fmt.Errorf("admin service requires the strike server")
+	}
+	if b.Cfg.AdminToken == "" {
+		slog.Info("admin service disabled: no admin_token configured")
+		return nil
 	}
 
-	caPEM, err := os.ReadFile(caFile)
-	if err != nil {
-		return nil, err
+	admin := NewAdminServer(b.Strike, b.Cfg.AdminToken)
+
+	opts := append(b.serverOptions(creds),
+		grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor, admin.UnaryAuth),
+	)
+	b.grpcAdmin = grpc.NewServer(opts...)
+
+	adminpb.RegisterAdminServer(b.grpcAdmin, admin)
+	return nil
+}
+
+// serverOptions applies the configured keepalive, enforcement and message
+// size limits, and the tracing stats handler, shared by the Strike and
+// Federation servers.
+func (b *Bootstrap) serverOptions(creds credentials.TransportCredentials) []grpc.ServerOption {
+	permit := b.Cfg.KeepalivePermitWithoutStream != nil && *b.Cfg.KeepalivePermitWithoutStream
+
+	opts := []grpc.ServerOption{
+		grpc.Creds(creds),
+		tracing.ServerOption(),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    time.Duration(b.Cfg.KeepaliveTime),
+			Timeout: time.Duration(b.Cfg.KeepaliveTimeout),
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             time.Duration(b.Cfg.KeepaliveMinTime),
+			PermitWithoutStream: permit,
+		}),
 	}
 
-	caPool := x509.NewCertPool()
-	if !caPool.AppendCertsFromPEM(caPEM) {
-		return nil, fmt.Errorf("invalid CA pem")
+	if b.Cfg.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(b.Cfg.MaxRecvMsgSize))
+	}
+	if b.Cfg.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(b.Cfg.MaxSendMsgSize))
 	}
 
-	return &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		RootCAs:      caPool,
-		ClientCAs:    caPool,
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		MinVersion:   tls.VersionTLS13,
-	}, nil
+	return opts
 }
 
-func (b *Bootstrap) InitFederationTLS() (*tls.Config, error) {
-	tlsConf, err := LoadFederationTLSConfig(
-		b.Cfg.CertificatePath,       // server cert
-		b.Cfg.SigningPrivateKeyPath, // server key
-		b.Cfg.FederationCAPath,      // CA that signs peer certs
-	)
+// LoadStrikeTLSCredentials builds the client-facing TLS credentials from the
+// configured certificate, key and minimum version.
+func LoadStrikeTLSCredentials(cfg config.ServerConfig) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCertificatePath, cfg.TLSKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("load strike tls keypair: %w", err)
+	}
+
+	minVersion, err := config.ParseTLSVersion(cfg.TLSMinVersion)
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Println("Loaded federation mTLS config")
-	return tlsConf, nil
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   minVersion,
+	}), nil
 }
 
 func (b *Bootstrap) Start(ctx context.Context) error {
@@ -177,43 +263,83 @@ func (b *Bootstrap) Start(ctx context.Context) error {
 		return fmt.Errorf("bootstrap not initialized")
 	}
 
+	// Bind every listener before serving any, so a port clash fails startup
+	// rather than leaving a half-running server.
+	strikeLis, err := net.Listen("tcp", b.Cfg.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("strike listener %s: %w", b.Cfg.ListenAddress, err)
+	}
+
+	fedLis, err := net.Listen("tcp", b.Cfg.FederationListenAddress)
+	if err != nil {
+		strikeLis.Close()
+		return fmt.Errorf("federation listener %s: %w", b.Cfg.FederationListenAddress, err)
+	}
+
+	var adminLis net.Listener
+	if b.admin != nil {
+		adminLis, err = net.Listen("tcp", b.admin.Addr)
+		if err != nil {
+			strikeLis.Close()
+			fedLis.Close()
+			return fmt.Errorf("admin listener %s: %w", b.admin.Addr, err)
+		}
+	}
+
+	var adminRPCLis net.Listener
+	if b.grpcAdmin != nil {
+		adminRPCLis, err = net.Listen("tcp", b.Cfg.AdminRPCAddress)
+		if err != nil {
+			strikeLis.Close()
+			fedLis.Close()
+			if adminLis != nil {
+				adminLis.Close()
+			}
+			return fmt.Errorf("admin rpc listener %s: %w", b.Cfg.AdminRPCAddress, err)
+		}
+	}
+
+	slog.Info("listening", "strike", strikeLis.Addr().String(), "federation", fedLis.Addr().String())
+
 	go func() {
-		lis, _ := net.Listen("tcp", ":8080")
-		b.grpcStrike.Serve(lis)
+		if err := b.grpcStrike.Serve(strikeLis); err != nil {
+			slog.Error("strike server stopped", "error", err)
+		}
 	}()
 
 	go func() {
-		lis, _ := net.Listen("tcp", ":9090")
-		b.grpcFed.Serve(lis)
+		if err := b.grpcFed.Serve(fedLis); err != nil {
+			slog.Error("federation server stopped", "error", err)
+		}
 	}()
 
+	if adminRPCLis != nil {
+		slog.Info("admin service listening", "address", adminRPCLis.Addr().String())
+		go func() {
+			if err := b.grpcAdmin.Serve(adminRPCLis); err != nil {
+				slog.Error("admin service stopped", "error", err)
+			}
+		}()
+	}
+
+	if adminLis != nil {
+		go func() {
+			if err := b.admin.Serve(adminLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("admin listener stopped", "error", err)
+			}
+		}()
+	}
+
 	go func() {
 		b.Strike.PeerMgr.ConnectAll(
 			ctx,
-			b.fedTLS,
+			b.fedTrust,
 			b.Strike.ID.String(),
 			b.Strike.Name,
 		)
 	}()
 
-	return nil
-}
+	b.setServing(healthpb.HealthCheckResponse_SERVING)
 
-func (b *Bootstrap) Stop(ctx context.Context) {
-
-	fmt.Println("Strike shutting down")
-
-	if b.grpcStrike != nil {
-		fmt.Println("shutdown strike server")
-	}
-
-	if b.grpcFed != nil {
-		fmt.Println("shutdown strike federation server")
-	}
-
-	if b.DB != nil {
-		b.DB.Close()
-	}
-
-	fmt.Println("Shutdown complete")
+	return nil
 }
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/JohnnyGlynn/strike/internal/config"
+	"github.com/JohnnyGlynn/strike/internal/keys"
+)
+
+// certExpiryWarning is how far ahead of expiry startup starts warning.
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// crlChecker refuses federation peers whose certificate serial is on the
+// CA's revocation list. The file is re-read when it changes, so revoking a
+// peer does not need a restart.
+type crlChecker struct {
+	path string
+	cas  []*x509.Certificate
+
+	mu      sync.Mutex
+	modTime time.Time
+	revoked map[string]struct{}
+}
+
+func newCRLChecker(path string, cas []*x509.Certificate) (*crlChecker, error) {
+	c := &crlChecker{path: path, cas: cas}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload reads the CRL if it changed since the last read. The caller holds
+// c.mu, or owns c.
+func (c *crlChecker) reload() error {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return fmt.Errorf("federation CRL: %w", err)
+	}
+	if info.ModTime().Equal(c.modTime) && c.revoked != nil {
+		return nil
+	}
+
+	crl, err := keys.LoadCRL(c.path, c.cas...)
+	if err != nil {
+		return err
+	}
+	if !crl.NextUpdate.IsZero() && time.Now().After(crl.NextUpdate) {
+		slog.Warn("federation CRL is past its next update, refresh it with strike-server --revoke", "path", c.path, "next_update", crl.NextUpdate)
+	}
+
+	revoked := make(map[string]struct{}, len(crl.RevokedCertificateEntries))
+	for _, e := range crl.RevokedCertificateEntries {
+		revoked[e.SerialNumber.String()] = struct{}{}
+	}
+	c.revoked = revoked
+	c.modTime = info.ModTime()
+	slog.Info("loaded federation CRL", "path", c.path, "revoked", len(revoked))
+	return nil
+}
+
+// check refuses a verified chain containing a revoked certificate.
+func (c *crlChecker) check(chain []*x509.Certificate) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.reload(); err != nil {
+		// Keep enforcing the last good list rather than failing open or
+		// cutting off every peer.
+		slog.Warn("federation CRL reload failed", "path", c.path, "error", err)
+	}
+
+	for _, cert := range chain {
+		if _, ok := c.revoked[cert.SerialNumber.String()]; ok {
+			return fmt.Errorf("certificate %q (serial %x) is revoked", cert.Subject.CommonName, cert.SerialNumber)
+		}
+	}
+	return nil
+}
+
+// parseCerts returns every certificate in a PEM bundle.
+func parseCerts(pemBytes []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, pemBytes = pem.Decode(pemBytes)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// WarnCertExpiry logs the expiry of the server, client-facing and CA
+// certificates, warning when one expires within certExpiryWarning and
+// reporting an error once it has expired.
+func WarnCertExpiry(cfg config.ServerConfig) {
+	paths := []struct{ name, path string }{
+		{"certificate", cfg.CertificatePath},
+		{"tls_certificate", cfg.TLSCertificatePath},
+		{"federation_ca", cfg.FederationCAPath},
+	}
+
+	seen := map[string]bool{}
+	for _, p := range paths {
+		if p.path == "" || seen[p.path] {
+			continue
+		}
+		seen[p.path] = true
+
+		cert, err := keys.LoadCertificate(p.path)
+		if err != nil {
+			slog.Warn("could not check certificate expiry", "cert", p.name, "path", p.path, "error", err)
+			continue
+		}
+
+		left := time.Until(cert.NotAfter)
+		switch {
+		case left <= 0:
+			slog.Error("certificate has expired", "cert", p.name, "path", p.path, "not_after", cert.NotAfter)
+		case left < certExpiryWarning:
+			slog.Warn("certificate expires soon", "cert", p.name, "path", p.path, "not_after", cert.NotAfter, "days_left", int(left.Hours()/24))
+		default:
+			slog.Debug("certificate valid", "cert", p.name, "path", p.path, "not_after", cert.NotAfter)
+		}
+	}
+}
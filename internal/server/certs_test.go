@@ -0,0 +1,165 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JohnnyGlynn/strike/internal/keys"
+)
+
+// writeCRL writes a CRL signed by ca listing certs, valid from thisUpdate
+// to nextUpdate.
+func writeCRL(t *testing.T, path string, ca *testCA, thisUpdate, nextUpdate time.Time, certs ...*x509.Certificate) {
+	t.Helper()
+	var entries []x509.RevocationListEntry
+	for _, c := range certs {
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: c.SerialNumber, RevocationTime: thisUpdate})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(time.Now().UnixNano()),
+		ThisUpdate:                thisUpdate,
+		NextUpdate:                nextUpdate,
+		RevokedCertificateEntries: entries,
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, path)
+}
+
+// touch moves path's modification time forward, so a reload notices a
+// rewrite made within the file system's timestamp resolution.
+func touch(t *testing.T, path string) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := time.Now().Add(time.Minute)
+	if !info.ModTime().Before(next) {
+		next = info.ModTime().Add(time.Minute)
+	}
+	if err := os.Chtimes(path, next, next); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCRLChecker(t *testing.T) {
+	ca := newTestCA(t)
+	revoked := newTestPeer(t, ca, "revoked")
+	later := newTestPeer(t, ca, "later")
+	member := newTestPeer(t, ca, "member")
+
+	crlPath := filepath.Join(t.TempDir(), "federation.crl")
+	if err := keys.RevokeCert(crlPath, revoked.certPath, ca.cert, ca.key); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := newCRLChecker(crlPath, []*x509.Certificate{ca.cert})
+	if err != nil {
+		t.Fatalf("load CRL: %v", err)
+	}
+	if err := c.check([]*x509.Certificate{revoked.cert, ca.cert}); err == nil || !strings.Contains(err.Error(), "is revoked") {
+		t.Fatalf("revoked certificate error = %v", err)
+	}
+	if err := c.check([]*x509.Certificate{member.cert, ca.cert}); err != nil {
+		t.Fatalf("unrevoked certificate refused: %v", err)
+	}
+	if err := c.check([]*x509.Certificate{later.cert, ca.cert}); err != nil {
+		t.Fatalf("unrevoked certificate refused: %v", err)
+	}
+
+	// Revoking another certificate takes effect without a new checker.
+	if err := keys.RevokeCert(crlPath, later.certPath, ca.cert, ca.key); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, crlPath)
+	if err := c.check([]*x509.Certificate{later.cert, ca.cert}); err == nil {
+		t.Fatal("certificate revoked after startup still accepted")
+	}
+
+	// A CRL signed by another CA is not loaded, and the last good list
+	// stays in force rather than failing open.
+	writeCRL(t, crlPath, newTestCA(t), time.Now(), time.Now().Add(time.Hour))
+	for _, cert := range []*x509.Certificate{revoked.cert, later.cert} {
+		if err := c.check([]*x509.Certificate{cert, ca.cert}); err == nil {
+			t.Errorf("%s accepted after the CRL was replaced by a forged one", cert.Subject.CommonName)
+		}
+	}
+	if err := c.check([]*x509.Certificate{member.cert, ca.cert}); err != nil {
+		t.Errorf("unrevoked certificate refused after a failed reload: %v", err)
+	}
+
+	// So does a CRL that can no longer be read.
+	if err := os.WriteFile(crlPath, []byte("not a CRL"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, crlPath)
+	if err := c.check([]*x509.Certificate{revoked.cert, ca.cert}); err == nil {
+		t.Error("revoked certificate accepted after the CRL became unreadable")
+	}
+}
+
+func TestCRLCheckerStaleListStillEnforced(t *testing.T) {
+	ca := newTestCA(t)
+	revoked := newTestPeer(t, ca, "revoked")
+	member := newTestPeer(t, ca, "member")
+
+	crlPath := filepath.Join(t.TempDir(), "federation.crl")
+	writeCRL(t, crlPath, ca, time.Now().Add(-60*24*time.Hour), time.Now().Add(-30*24*time.Hour), revoked.cert)
+
+	c, err := newCRLChecker(crlPath, []*x509.Certificate{ca.cert})
+	if err != nil {
+		t.Fatalf("stale CRL not loaded: %v", err)
+	}
+	if err := c.check([]*x509.Certificate{revoked.cert, ca.cert}); err == nil {
+		t.Error("revoked certificate accepted because the CRL is past its next update")
+	}
+	if err := c.check([]*x509.Certificate{member.cert, ca.cert}); err != nil {
+		t.Errorf("unrevoked certificate refused: %v", err)
+	}
+}
+
+func TestNewCRLCheckerRejects(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+
+	foreign := filepath.Join(dir, "foreign.crl")
+	writeCRL(t, foreign, newTestCA(t), time.Now(), time.Now().Add(time.Hour))
+
+	garbage := filepath.Join(dir, "garbage.crl")
+	if err := os.WriteFile(garbage, []byte("-----BEGIN X509 CRL-----\nAAAA\n-----END X509 CRL-----\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		path string
+		text string
+	}{
+		"signed-by-another-ca": {path: foreign, text: "not signed by the federation CA"},
+		"unparseable":          {path: garbage, text: "failed to parse CRL"},
+		"missing":              {path: filepath.Join(dir, "missing.crl"), text: "no such file"},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := newCRLChecker(tc.path, []*x509.Certificate{ca.cert})
+			if err == nil || !strings.Contains(err.Error(), tc.text) {
+				t.Fatalf("error = %v, want it to contain %q", err, tc.text)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/JohnnyGlynn/strike/internal/server/types"
+)
+
+// deadLetter records a payload that ran out of delivery attempts so an admin
+// can inspect and replay it. It runs after the payload has left the Pending
+// map, so it gets its own deadline even if delivery was cancelled.
+func (s *StrikeServer) deadLetter(ctx context.Context, pmsg *types.PendingMsg, reason string) {
+	s.Metrics.observeDeadLetter()
+
+	if s.DBpool == nil {
+		slog.WarnContext(ctx, "payload dropped after retries", "message_id", pmsg.MessageID, "target_domain", pmsg.TargetDomain, "reason", reason)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.DeliveryTimeout)
+	defer cancel()
+
+	_, err := s.DBpool.Exec(ctx, s.PStatements.DeadLetter.Save,
+		pmsg.MessageID, pmsg.From, pmsg.To, pmsg.SenderDomain, pmsg.TargetDomain, pmsg.Payload, pmsg.Created, reason, pmsg.CorrelationID, pmsg.TraceParent)
+	if err != nil {
+		slog.ErrorContext(ctx, "payload dropped: saving dead letter failed", "message_id", pmsg.MessageID, "reason", reason, "error", err)
+		return
+	}
+
+	slog.WarnContext(ctx, "payload dead-lettered after retries", "message_id", pmsg.MessageID, "target_domain", pmsg.TargetDomain, "reason", reason)
+}
+
+func scanDeadLetter(row pgx.Row) (types.DeadLetter, error) {
+	var dl types.DeadLetter
+	err := row.Scan(&dl.MessageID, &dl.From, &dl.To, &dl.SenderDomain, &dl.TargetDomain, &dl.Payload,
+		&dl.Created, &dl.FailedAt, &dl.Reason, &dl.CorrelationID, &dl.TraceParent)
+	return dl, err
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// errorDomain identifies Strike in ErrorInfo details.
+const errorDomain = "strike"
+
+// Reasons carried in ErrorInfo details, stable for clients to switch on.
+const (
+	reasonShuttingDown       = "SHUTTING_DOWN"
+	reasonInvalidCredentials = "INVALID_CREDENTIALS"
+	reasonDatabase           = "DATABASE_UNAVAILABLE"
+	reasonPeerUnavailable    = "PEER_UNAVAILABLE"
+	reasonAccountDisabled    = "ACCOUNT_DISABLED"
+	reasonAccountGone        = "ACCOUNT_GONE"
+)
+
+// withDetails attaches details to a status, falling back to the bare status
+// if they cannot be marshalled.
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) error {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// invalidArgument reports a malformed request field as a BadRequest detail.
+func invalidArgument(field, description string) error {
+	return withDetails(status.New(codes.InvalidArgument, field+": "+description), &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: description}},
+	})
+}
+
+// notFound reports a missing resource as a ResourceInfo detail.
+func notFound(resourceType, name string) error {
+	return withDetails(status.New(codes.NotFound, resourceType+" "+name+" not found"),
+		&errdetails.ResourceInfo{ResourceType: resourceType, ResourceName: name})
+}
+
+func alreadyExists(resourceType, name string) error {
+	return withDetails(status.New(codes.AlreadyExists, resourceType+" "+name+" already exists"),
+		&errdetails.ResourceInfo{ResourceType: resourceType, ResourceName: name})
+}
+
+func unauthenticated(msg string) error {
+	return withDetails(status.New(codes.Unauthenticated, msg),
+		&errdetails.ErrorInfo{Reason: reasonInvalidCredentials, Domain: errorDomain})
+}
+
+func accountDisabled(username string) error {
+	return withDetails(status.New(codes.PermissionDenied, "account "+username+" is disabled"),
+		&errdetails.ErrorInfo{Reason: reasonAccountDisabled, Domain: errorDomain})
+}
+
+func accountGone(userID string) error {
+	return withDetails(status.New(codes.PermissionDenied, "no account for user "+userID+" on this server"),
+		&errdetails.ErrorInfo{Reason: reasonAccountGone, Domain: errorDomain})
+}
+
+// failedPrecondition reports state that must change before the request can
+// succeed, as a PreconditionFailure detail.
+func failedPrecondition(subject, description string) error {
+	return withDetails(status.New(codes.FailedPrecondition, description),
+		&errdetails.PreconditionFailure{Violations: []*errdetails.PreconditionFailure_Violation{{Type: "STATE", Subject: subject, Description: description}}})
+}
+
+func shuttingDown() error {
+	return withDetails(status.New(codes.Unavailable, "server shutting down"),
+		&errdetails.ErrorInfo{Reason: reasonShuttingDown, Domain: errorDomain})
+}
+
+// peerUnavailable reports a federation peer that could not be reached. The
+// underlying error is logged rather than returned to the client.
+func peerUnavailable(domain string, err error) error {
+	slog.Warn("peer unavailable", "peer", domain, "error", err)
+	return withDetails(status.New(codes.Unavailable, "server "+domain+" is unavailable"),
+		&errdetails.ErrorInfo{Reason: reasonPeerUnavailable, Domain: errorDomain, Metadata: map[string]string{"peer": domain}})
+}
+
+// internalError logs err and returns an Internal status that does not leak it.
+func internalError(op string, err error) error {
+	slog.Error(op+" failed", "error", err)
+	return status.Error(codes.Internal, op+" failed")
+}
+
+// dbError maps a database error to a status. pgx.ErrNoRows becomes NotFound
+// for resourceType/name; connection failures become Unavailable so clients
+// know to retry; cancellations keep their context code.
+func dbError(op, resourceType, name string, err error) error {
+	var pgErr *pgconn.PgError
+	var connErr *pgconn.ConnectError
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return notFound(resourceType, name)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		return alreadyExists(resourceType, name)
+	case errors.As(err, &connErr), pgconn.SafeToRetry(err):
+		slog.Error(op+" failed: database unavailable", "error", err)
+		return withDetails(status.New(codes.Unavailable, "database unavailable, try again"),
+			&errdetails.ErrorInfo{Reason: reasonDatabase, Domain: errorDomain})
+	default:
+		return internalError(op, err)
+	}
+}
@@ -6,12 +6,15 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"fmt"
+	"log/slog"
 	"os"
 
 	"github.com/JohnnyGlynn/strike/internal/server/types"
 	"gopkg.in/yaml.v3"
 
 	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/JohnnyGlynn/strike/msgdef/federation"
 )
@@ -43,7 +46,7 @@ func (fo *FederationOrchestrator) Handshake(
 	//TODO: TLS identity binding
 	// peerID := deriveFromTLS(ctx)
 
-	fmt.Printf("federation handshake from server %s\n", req.ServerId)
+	slog.InfoContext(ctx, "federation handshake", "server_id", req.ServerId, "server_name", req.ServerName)
 
 	return &pb.HandshakeAck{
 		Ok:       true,
@@ -65,7 +68,45 @@ func (fo *FederationOrchestrator) Relay(
 		}, nil
 	}
 
-	senderID, err := uuid.Parse(rp.Sender.UInfo.UserId)
+	slog.DebugContext(ctx, "relay received", "envelope_id", rp.EnvelopeId, "origin", rp.OriginServer)
+
+	peer, _ := fo.strike.callingPeer(ctx)
+	if err := fo.strike.checkRelayPolicy(ctx, peer, rp); err != nil {
+		slog.InfoContext(ctx, "relay refused by peer policy", "envelope_id", rp.EnvelopeId, "peer", peer.Name, "error", err)
+		return &pb.RelayAck{
+			EnvelopeId: rp.EnvelopeId,
+			Accepted:   false,
+			Info:       status.Convert(err).Message(),
+		}, nil
+	}
+
+	if to, err := uuid.Parse(rp.Recipient.GetUInfo().GetUserId()); err == nil {
+		blocked, err := fo.strike.blockedBy(ctx, to, rp.Sender.GetUInfo().GetUserId(), rp.Sender.Domain, peer.Name)
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			slog.DebugContext(ctx, "relay refused: sender blocked by recipient", "envelope_id", rp.EnvelopeId, "peer", peer.Name)
+			return &pb.RelayAck{
+				EnvelopeId: rp.EnvelopeId,
+				Accepted:   false,
+				Info:       "blocked by recipient",
+			}, nil
+		}
+	}
+
+	if t, _ := payloadType(rp.PayloadData); t == "friend_request" {
+		sender := rp.Sender.Domain + "/" + rp.Sender.GetUInfo().GetUserId()
+		if err := fo.strike.allowFriendRequest(pb.Federation_Relay_FullMethodName, sender); err != nil {
+			return &pb.RelayAck{
+				EnvelopeId: rp.EnvelopeId,
+				Accepted:   false,
+				Info:       status.Convert(err).Message(),
+			}, nil
+		}
+	}
+
+	senderID, err := uuid.Parse(rp.Sender.GetUInfo().GetUserId())
 	if err == nil {
 		fo.strike.UpdateRemotePresence(senderID, rp.OriginServer)
 	}
@@ -74,7 +115,7 @@ func (fo *FederationOrchestrator) Relay(
 		return &pb.RelayAck{
 			EnvelopeId: rp.EnvelopeId,
 			Accepted:   false,
-			Info:       err.Error(),
+			Info:       status.Convert(err).Message(),
 		}, nil
 	}
 
@@ -93,14 +134,20 @@ func (fo *FederationOrchestrator) UserLookup(
 	if req.Username == "" {
 		return &pb.UserLookupResp{Found: false}, nil
 	}
+	if err := fo.checkLookupPolicy(ctx); err != nil {
+		return nil, err
+	}
 
 	uInfo, err := fo.strike.localUserLookup(ctx, req.Username)
-	if err != nil {
-		return &pb.UserLookupResp{Found: false}, nil
+	if status.Code(err) == codes.NotFound {
+		moved, rerr := fo.strike.redirectFor(ctx, fo.strike.Name, req.Username)
+		if rerr != nil {
+			return nil, rerr
+		}
+		return &pb.UserLookupResp{Found: false, MovedTo: moved, Domain: fo.strike.Name}, nil
 	}
-
-	if uInfo == nil {
-		return &pb.UserLookupResp{Found: false}, nil
+	if err != nil {
+		return nil, err
 	}
 
 	return &pb.UserLookupResp{
@@ -110,6 +157,98 @@ func (fo *FederationOrchestrator) UserLookup(
 	}, nil
 }
 
+// checkLookupPolicy refuses user lookups from peers whose policy sets
+// deny_user_lookup.
+func (fo *FederationOrchestrator) checkLookupPolicy(ctx context.Context) error {
+	peer, _ := fo.strike.callingPeer(ctx)
+	if peer.Policy.DenyLookups {
+		return status.Error(codes.PermissionDenied, "user lookups are not allowed for "+peer.Name)
+	}
+	return nil
+}
+
+// UserDeleted forgets a remote user whose home server reports the account
+// deleted. The calling peer must be that home server, and the notice must
+// carry the user's signature under the key we learned for them in a lookup.
+func (fo *FederationOrchestrator) UserDeleted(
+	ctx context.Context,
+	req *pb.UserDeletedReq,
+) (*pb.UserDeletedAck, error) {
+
+	peer, ok := fo.strike.callingPeer(ctx)
+	if !ok {
+		return &pb.UserDeletedAck{Accepted: false, Info: "unknown peer"}, nil
+	}
+	userID, err := uuid.Parse(req.GetNotice().GetUserId())
+	if err != nil || req.OriginServer != peer.ID.String() {
+		return &pb.UserDeletedAck{Accepted: false, Info: "invalid notice"}, nil
+	}
+
+	if err := fo.strike.checkRemoteDeletion(ctx, peer, userID, req.Notice); err != nil {
+		slog.WarnContext(ctx, "federation: deletion notice rejected", "peer", peer.Name, "user_id", userID, "error", err)
+		return &pb.UserDeletedAck{Accepted: false, Info: err.Error()}, nil
+	}
+
+	dropped := fo.strike.forgetRemoteUser(userID, peer.ID.String())
+
+	slog.InfoContext(ctx, "federation: remote user deleted", "user_id", userID, "domain", req.Notice.Domain, "peer", peer.Name, "pending_dropped", dropped)
+
+	return &pb.UserDeletedAck{Accepted: true, Info: "forgotten"}, nil
+}
+
+// AccountMoved records a redirect countersigned by the user's old server so
+// lookups and deliveries for the old address follow the user.
+func (fo *FederationOrchestrator) AccountMoved(
+	ctx context.Context,
+	req *pb.AccountMovedReq,
+) (*pb.AccountMovedAck, error) {
+
+	n := req.GetNotice()
+	userID, err := uuid.Parse(n.GetUserId())
+	if err != nil {
+		return &pb.AccountMovedAck{Accepted: false, Info: "invalid notice"}, nil
+	}
+
+	if err := fo.strike.checkRedirect(req.OriginServer, n); err != nil {
+		slog.WarnContext(ctx, "federation: redirect rejected", "origin", req.OriginServer, "user_id", userID, "error", err)
+		return &pb.AccountMovedAck{Accepted: false, Info: err.Error()}, nil
+	}
+
+	if err := fo.strike.saveRedirect(ctx, fo.strike.DBpool, n); err != nil {
+		return &pb.AccountMovedAck{Accepted: false, Info: status.Convert(err).Message()}, nil
+	}
+	fo.strike.applyMove(userID, n)
+
+	slog.InfoContext(ctx, "federation: remote user moved", "user_id", userID, "old_domain", n.OldDomain, "new_domain", n.NewDomain)
+
+	return &pb.AccountMovedAck{Accepted: true, Info: "redirect recorded"}, nil
+}
+
+// KeyHistory returns the key rotations of one of our users so a friend on
+// the calling server can walk the chain.
+func (fo *FederationOrchestrator) KeyHistory(
+	ctx context.Context,
+	req *pb.UserLookupReq,
+) (*pb.KeyHistoryResp, error) {
+
+	if req.Username == "" {
+		return &pb.KeyHistoryResp{Found: false}, nil
+	}
+	if err := fo.checkLookupPolicy(ctx); err != nil {
+		return nil, err
+	}
+
+	rotations, err := fo.strike.localKeyHistory(ctx, req.Username)
+	if status.Code(err) == codes.NotFound {
+		return &pb.KeyHistoryResp{Found: false}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.KeyHistoryResp{Found: true, Rotations: rotations}, nil
+}
+
 func LoadPeers(path string) ([]types.PeerConfig, error) {
 	peerConfig, err := os.ReadFile(path)
 	if err != nil {
@@ -139,11 +278,24 @@ func LoadPeers(path string) ([]types.PeerConfig, error) {
 		}
 
 		cfg.Peers[i].PubKey = pubKey
+
+		if err := validatePolicy(cfg.Peers[i].Policy); err != nil {
+			return nil, fmt.Errorf("invalid policy for peer %s: %v", cfg.Peers[i].Name, err)
+		}
+
+		if cfg.Peers[i].RawCA != "" {
+			rawCA, err := base64.StdEncoding.DecodeString(cfg.Peers[i].RawCA)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode ca for peer %s: %v", cfg.Peers[i].Name, err)
+			}
+			if cfg.Peers[i].CACert, err = x509.ParseCertificate(rawCA); err != nil {
+				return nil, fmt.Errorf("failed to parse ca for peer %s: %v", cfg.Peers[i].Name, err)
+			}
+		}
 	}
 
-	fmt.Println("Available peers")
 	for _, p := range cfg.Peers {
-		fmt.Printf("%s@%s\n", p.Name, p.Address)
+		slog.Info("federation peer configured", "peer", p.Name, "addr", p.Address)
 	}
 
 	return cfg.Peers, nil
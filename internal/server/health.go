@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/JohnnyGlynn/strike/internal/config"
+	"github.com/JohnnyGlynn/strike/internal/server/types"
+	fedpb "github.com/JohnnyGlynn/strike/msgdef/federation"
+	pb "github.com/JohnnyGlynn/strike/msgdef/message"
+)
+
+type checkResult struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// InitAdmin builds the HTTP admin listener (/metrics, /healthz, /readyz) and
+// registers the gRPC health service on both the Strike and Federation servers.
+func (b *Bootstrap) InitAdmin() error {
+	if b.grpcStrike == nil || b.grpcFed == nil || b.Strike == nil {
+		return fmt.Errorf("admin requires strike and federation servers")
+	}
+
+	b.health = health.NewServer()
+	healthpb.RegisterHealthServer(b.grpcStrike, b.health)
+	healthpb.RegisterHealthServer(b.grpcFed, b.health)
+	b.setServing(healthpb.HealthCheckResponse_NOT_SERVING)
+
+	b.Strike.Metrics = NewMetrics(b.Strike)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(b.Strike.Metrics.Registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeCheck(w, http.StatusOK, checkResult{Status: "ok"})
+	})
+	mux.HandleFunc("/readyz", b.readyz)
+
+	addr := b.Cfg.AdminAddress
+	if addr == "" {
+		addr = config.DefaultAdminAddress
+	}
+
+	b.admin = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return nil
+}
+
+// readyz fails on the database and on peers marked required in
+// federation.yaml. Other peers are reported but do not gate readiness: peers
+// become ready independently, and gating on each other would deadlock a
+// fresh federation behind its own Services.
+func (b *Bootstrap) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	res := checkResult{Status: "ok", Checks: map[string]string{}}
+	code := http.StatusOK
+
+	if err := b.DB.Ping(ctx); err != nil {
+		res.Status = "unavailable"
+		res.Checks["database"] = err.Error()
+		code = http.StatusServiceUnavailable
+	} else {
+		res.Checks["database"] = "ok"
+	}
+
+	federation, ok := peerReadiness(b.Strike.PeerMgr.Snapshot())
+	res.Checks["federation"] = federation
+	if !ok {
+		res.Status = "unavailable"
+		code = http.StatusServiceUnavailable
+	}
+
+	writeCheck(w, code, res)
+}
+
+// peerReadiness summarises peer state for /readyz and reports whether every
+// required peer is up.
+func peerReadiness(peers []types.PeerStatus) (string, bool) {
+	up := 0
+	var down []string
+	for _, p := range peers {
+		if p.Online && p.Handshaken {
+			up++
+		} else if p.Required {
+			down = append(down, p.Name)
+		}
+	}
+	summary := fmt.Sprintf("%d/%d peers up", up, len(peers))
+	if len(down) == 0 {
+		return summary, true
+	}
+	slices.Sort(down)
+	return summary + ", required peers down: " + strings.Join(down, ", "), false
+}
+
+func writeCheck(w http.ResponseWriter, code int, res checkResult) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		slog.Warn("admin: failed to write response", "error", err)
+	}
+}
+
+func (b *Bootstrap) setServing(status healthpb.HealthCheckResponse_ServingStatus) {
+	if b.health == nil {
+		return
+	}
+	b.health.SetServingStatus("", status)
+	b.health.SetServingStatus(pb.Strike_ServiceDesc.ServiceName, status)
+	b.health.SetServingStatus(fedpb.Federation_ServiceDesc.ServiceName, status)
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JohnnyGlynn/strike/internal/server/types"
+)
+
+func TestPeerReadiness(t *testing.T) {
+	up := func(name string, required bool) types.PeerStatus {
+		return types.PeerStatus{Name: name, Online: true, Handshaken: true, Required: required}
+	}
+	down := func(name string, required bool) types.PeerStatus {
+		return types.PeerStatus{Name: name, Required: required}
+	}
+
+	cases := map[string]struct {
+		peers   []types.PeerStatus
+		ready   bool
+		summary string
+	}{
+		"no-peers":                {ready: true, summary: "0/0 peers up"},
+		"optional-peers-down":     {peers: []types.PeerStatus{down("a", false), down("b", false)}, ready: true, summary: "0/2 peers up"},
+		"required-peer-up":        {peers: []types.PeerStatus{up("a", true), down("b", false)}, ready: true, summary: "1/2 peers up"},
+		"required-peer-down":      {peers: []types.PeerStatus{up("a", false), down("b", true)}, summary: "1/2 peers up, required peers down: b"},
+		"required-not-handshaken": {peers: []types.PeerStatus{{Name: "a", Online: true, Required: true}}, summary: "0/1 peers up, required peers down: a"},
+		"several-required-down":   {peers: []types.PeerStatus{down("c", true), down("a", true), down("b", false)}, summary: "0/3 peers up, required peers down: a, c"},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			summary, ready := peerReadiness(tc.peers)
+			if ready != tc.ready {
+				t.Errorf("ready = %v, want %v", ready, tc.ready)
+			}
+			if summary != tc.summary {
+				t.Errorf("summary = %q, want %q", summary, tc.summary)
+			}
+		})
+	}
+}
+
+func TestLoadPeersRequired(t *testing.T) {
+	ca := newTestCA(t)
+	p, err := peerConfigFromDetails(newTestPeer(t, ca, "peer").details(t, ca.cert))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "federation.yaml")
+	yaml := "peers:\n  - id: " + p.ID.String() + "\n    name: peer\n    addr: peer:9000\n    pubkey: " + p.RawKey +
+		"\n    required: true\n"
+	if err := os.WriteFile(path, []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	peers, err := LoadPeers(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || !peers[0].Required {
+		t.Fatalf("peers = %+v, want one required peer", peers)
+	}
+}
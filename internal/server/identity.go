@@ -3,6 +3,7 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -19,7 +20,7 @@ func InitID(svrCfg config.ServerConfig, idCfg string) (*Identity, error) {
 
 	//First run, check for keys
 	if _, err := os.Stat(svrCfg.SigningPrivateKeyPath); os.IsNotExist(err) {
-		fmt.Println("No server identity found: Bootstrapping")
+		slog.Info("no server identity found, bootstrapping", "key_dir", filepath.Dir(svrCfg.SigningPrivateKeyPath))
 
 		// Use the directory containing the expected private key path as output
 		keyDir := filepath.Dir(svrCfg.SigningPrivateKeyPath)
@@ -0,0 +1,499 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v3"
+
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/server/types"
+	adminpb "github.com/JohnnyGlynn/strike/msgdef/admin"
+	fedpb "github.com/JohnnyGlynn/strike/msgdef/federation"
+)
+
+// Invitation lifetimes accepted by CreateInvite.
+const (
+	defaultInviteTTL = 24 * time.Hour
+	maxInviteTTL     = 7 * 24 * time.Hour
+
+	inviteTokenPrefix = "strike-invite:"
+	joinTimeout       = 8 * time.Second // within strike-admin's call timeout
+)
+
+// peersFileMu serialises joins, so a peer is checked, written to
+// federation.yaml and added to the PeerManager as one step.
+var peersFileMu sync.Mutex
+
+// inviteBytes is what the inviting server signs. Fields are joined with a
+// NUL like the account notices in the shared package.
+func inviteBytes(inv *fedpb.FederationInvite) []byte {
+	d := inv.GetInviter()
+	b := []byte("strike-federation-invite")
+	for _, f := range []string{
+		d.GetServerId(),
+		d.GetServerName(),
+		d.GetAddress(),
+		string(d.GetPublicKey()),
+		string(d.GetCaCertificate()),
+		string(inv.GetNonce()),
+		strconv.FormatInt(inv.GetExpiresAt().AsTime().UnixNano(), 10),
+	} {
+		b = append(b, 0)
+		b = append(b, f...)
+	}
+	return b
+}
+
+// serverIDFromKey derives a server ID from an SPKI DER key the same way
+// DeriveServerID does from the PEM file.
+func serverIDFromKey(der []byte) string {
+	return keys.DeriveID(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// selfDetails describes this server to a peer reaching it on addr.
+func (s *StrikeServer) selfDetails(addr string) (*fedpb.PeerDetails, error) {
+	if s.fedTrust == nil {
+		return nil, failedPrecondition("federation", "federation is not running")
+	}
+	if addr == "" {
+		addr = s.federationAddress
+	}
+	if addr == "" {
+		return nil, invalidArgument("address", "required when federation_address is not set")
+	}
+
+	pub, err := x509.MarshalPKIXPublicKey(s.signingKey.Public())
+	if err != nil {
+		return nil, internalError("federation: marshal server key", err)
+	}
+
+	return &fedpb.PeerDetails{
+		ServerId:      serverIDFromKey(pub),
+		ServerName:    s.Name,
+		Address:       addr,
+		PublicKey:     pub,
+		CaCertificate: s.fedTrust.CACertificate(),
+	}, nil
+}
+
+// peerConfigFromDetails checks a peer's details and turns them into a
+// federation.yaml entry.
+func peerConfigFromDetails(d *fedpb.PeerDetails) (types.PeerConfig, error) {
+	var cfg types.PeerConfig
+
+	if d.GetServerName() == "" {
+		return cfg, invalidArgument("server_name", "required")
+	}
+	if d.GetAddress() == "" {
+		return cfg, invalidArgument("address", "required")
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(d.GetPublicKey())
+	if err != nil {
+		return cfg, invalidArgument("public_key", "not an SPKI public key")
+	}
+	pub, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return cfg, invalidArgument("public_key", "not ed25519")
+	}
+	if d.GetServerId() != serverIDFromKey(d.GetPublicKey()) {
+		return cfg, invalidArgument("server_id", "does not match public_key")
+	}
+
+	ca, err := x509.ParseCertificate(d.GetCaCertificate())
+	if err != nil {
+		return cfg, invalidArgument("ca_certificate", "not a DER certificate")
+	}
+
+	return types.PeerConfig{
+		ID:      uuid.MustParse(d.GetServerId()),
+		Name:    d.GetServerName(),
+		Address: d.GetAddress(),
+		PubKey:  pub,
+		RawKey:  base64.StdEncoding.EncodeToString(d.GetPublicKey()),
+		RawCA:   base64.StdEncoding.EncodeToString(ca.Raw),
+		CACert:  ca,
+	}, nil
+}
+
+// checkNewPeer refuses ourselves, a peer we already have, and a name that is
+// taken by a different server. The caller holds peersFileMu.
+func (s *StrikeServer) checkNewPeer(cfg types.PeerConfig) error {
+	if cfg.ID == s.ID || cfg.Name == s.Name {
+		return invalidArgument("server", "a server cannot federate with itself")
+	}
+	if _, ok := s.PeerMgr.PubKey(cfg.ID.String()); ok {
+		return alreadyExists("peer", cfg.Name)
+	}
+	if _, ok := s.PeerMgr.IDByName(cfg.Name); ok {
+		return alreadyExists("peer", cfg.Name)
+	}
+	return nil
+}
+
+// addPeer records a peer that joined by invitation in federation.yaml, trusts
+// its CA and connects to it. The caller holds peersFileMu.
+func (s *StrikeServer) addPeer(ctx context.Context, cfg types.PeerConfig) error {
+	if err := appendPeerConfig(s.federationPeers, cfg); err != nil {
+		return internalError("federation: update "+s.federationPeers, err)
+	}
+	s.fedTrust.AddPeerCA(cfg.CACert, cfg.PubKey)
+	s.PeerMgr.Add(cfg)
+
+	slog.InfoContext(ctx, "federation peer added by invitation", "peer", cfg.Name, "addr", cfg.Address, "id", cfg.ID)
+	return nil
+}
+
+// peerEntry is how appendPeerConfig writes a peer to federation.yaml.
+type peerEntry struct {
+	ID     string `yaml:"id"`
+	Name   string `yaml:"name"`
+	Addr   string `yaml:"addr"`
+	PubKey string `yaml:"pubkey"`
+	CA     string `yaml:"ca,omitempty"`
+}
+
+// appendPeerConfig adds a peer to the peers list in federation.yaml, keeping
+// the rest of the file as it is, and replaces the file atomically. A peer
+// already listed under its ID leaves the file untouched.
+func appendPeerConfig(path string, cfg types.PeerConfig) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: expected a mapping", path)
+	}
+
+	var peers *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "peers" {
+			peers = root.Content[i+1]
+		}
+	}
+	if peers == nil {
+		peers = &yaml.Node{Kind: yaml.SequenceNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "peers"}, peers)
+	}
+	if peers.Kind == yaml.ScalarNode && peers.Tag == "!!null" {
+		*peers = yaml.Node{Kind: yaml.SequenceNode}
+	}
+	if peers.Kind != yaml.SequenceNode {
+		return fmt.Errorf("%s: peers is not a list", path)
+	}
+
+	// IDs are written without dashes, as --gen-federation writes them.
+	id := strings.ReplaceAll(cfg.ID.String(), "-", "")
+	for _, p := range peers.Content {
+		for i := 0; p.Kind == yaml.MappingNode && i+1 < len(p.Content); i += 2 {
+			if p.Content[i].Value == "id" && strings.EqualFold(strings.ReplaceAll(p.Content[i+1].Value, "-", ""), id) {
+				return nil
+			}
+		}
+	}
+
+	var node yaml.Node
+	if err := node.Encode(peerEntry{
+		ID:     id,
+		Name:   cfg.Name,
+		Addr:   cfg.Address,
+		PubKey: cfg.RawKey,
+		CA:     cfg.RawCA,
+	}); err != nil {
+		return err
+	}
+	peers.Content = append(peers.Content, &node)
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".federation-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after rename
+	if _, err := tmp.Write(out.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// parseInviteToken decodes an invitation and checks it is signed by the key
+// it names and has not expired.
+func parseInviteToken(token string) (*fedpb.FederationInvite, error) {
+	raw, ok := strings.CutPrefix(strings.TrimSpace(token), inviteTokenPrefix)
+	if !ok {
+		return nil, invalidArgument("token", "not a strike invitation")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, invalidArgument("token", "malformed invitation")
+	}
+
+	var inv fedpb.FederationInvite
+	if err := proto.Unmarshal(data, &inv); err != nil {
+		return nil, invalidArgument("token", "malformed invitation")
+	}
+	if err := verifyInvite(&inv, inv.GetInviter().GetPublicKey()); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// verifyInvite checks an invitation's signature against the SPKI key signer
+// and its expiry.
+func verifyInvite(inv *fedpb.FederationInvite, signer []byte) error {
+	parsed, err := x509.ParsePKIXPublicKey(signer)
+	if err != nil {
+		return invalidArgument("invite", "bad inviter key")
+	}
+	pub, ok := parsed.(ed25519.PublicKey)
+	if !ok || !ed25519.Verify(pub, inviteBytes(inv), inv.GetSignature()) {
+		return invalidArgument("invite", "bad signature")
+	}
+	if inv.GetExpiresAt() == nil || time.Now().After(inv.GetExpiresAt().AsTime()) {
+		return failedPrecondition("invite", "invitation has expired")
+	}
+	return nil
+}
+
+// CreateInvite issues a signed invitation another server can redeem with
+// JoinFederation.
+func (a *AdminServer) CreateInvite(ctx context.Context, req *adminpb.CreateInviteReq) (*adminpb.CreateInviteResp, error) {
+	ttl := time.Duration(req.TtlSeconds) * time.Second
+	switch {
+	case req.TtlSeconds < 0:
+		return nil, invalidArgument("ttl_seconds", "must not be negative")
+	case ttl == 0:
+		ttl = defaultInviteTTL
+	case ttl > maxInviteTTL:
+		return nil, invalidArgument("ttl_seconds", fmt.Sprintf("must be at most %s", maxInviteTTL))
+	}
+
+	details, err := a.strike.selfDetails(req.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, internalError("admin: invite nonce", err)
+	}
+
+	inv := &fedpb.FederationInvite{
+		Inviter:   details,
+		Nonce:     nonce,
+		ExpiresAt: timestamppb.New(time.Now().Add(ttl)),
+	}
+	inv.Signature = ed25519.Sign(a.strike.signingKey, inviteBytes(inv))
+
+	data, err := proto.Marshal(inv)
+	if err != nil {
+		return nil, internalError("admin: marshal invite", err)
+	}
+
+	slog.InfoContext(ctx, "admin: federation invite created", "addr", details.Address, "expires_at", inv.ExpiresAt.AsTime())
+	return &adminpb.CreateInviteResp{
+		Token:     inviteTokenPrefix + base64.RawURLEncoding.EncodeToString(data),
+		ExpiresAt: inv.ExpiresAt,
+	}, nil
+}
+
+// JoinFederation redeems an invitation: it calls Join on the inviting server
+// and, once that server has added us, adds it here.
+func (a *AdminServer) JoinFederation(ctx context.Context, req *adminpb.JoinFederationReq) (*adminpb.AdminAck, error) {
+	s := a.strike
+
+	inv, err := parseInviteToken(req.Token)
+	if err != nil {
+		return nil, err
+	}
+	inviter, err := peerConfigFromDetails(inv.Inviter)
+	if err != nil {
+		return nil, err
+	}
+	self, err := s.selfDetails(req.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	peersFileMu.Lock()
+	defer peersFileMu.Unlock()
+
+	if err := s.checkNewPeer(inviter); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.callJoin(ctx, inviter, &fedpb.JoinReq{Invite: inv, Joiner: self})
+	if err != nil {
+		return nil, peerUnavailable(inviter.Name, err)
+	}
+	if !resp.Ok {
+		return nil, failedPrecondition("invite", inviter.Name+" refused the invitation: "+resp.Message)
+	}
+	if resp.Inviter.GetServerId() != inv.Inviter.ServerId || !bytes.Equal(resp.Inviter.GetPublicKey(), inv.Inviter.PublicKey) {
+		return nil, failedPrecondition("invite", inviter.Name+" answered with a different identity")
+	}
+
+	if err := s.addPeer(ctx, inviter); err != nil {
+		return nil, err
+	}
+	return &adminpb.AdminAck{Message: "joined " + inviter.Name + " at " + inviter.Address, Affected: 1}, nil
+}
+
+// callJoin dials the inviting server, trusting only the CA in its invitation
+// and only a certificate for the key that signed it.
+func (s *StrikeServer) callJoin(ctx context.Context, inviter types.PeerConfig, req *fedpb.JoinReq) (*fedpb.JoinResp, error) {
+	roots := x509.NewCertPool()
+	roots.AddCert(inviter.CACert)
+
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{s.fedTrust.cert},
+		RootCAs:      roots,
+		MinVersion:   tls.VersionTLS13,
+		VerifyPeerCertificate: func(_ [][]byte, chains [][]*x509.Certificate) error {
+			for _, chain := range chains {
+				leaf, ok := chain[0].PublicKey.(ed25519.PublicKey)
+				if !ok || !leaf.Equal(inviter.PubKey) {
+					return fmt.Errorf("certificate is not for the server that signed the invitation")
+				}
+			}
+			return nil
+		},
+	}
+
+	conn, err := grpc.NewClient(inviter.Address, grpc.WithTransportCredentials(credentials.NewTLS(conf)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, joinTimeout)
+	defer cancel()
+	return fedpb.NewFederationClient(conn).Join(ctx, req)
+}
+
+// Join accepts a server redeeming one of our invitations. The caller proves
+// it holds the key it is joining with by presenting a certificate for it,
+// issued by the CA it sends.
+func (fo *FederationOrchestrator) Join(ctx context.Context, req *fedpb.JoinReq) (*fedpb.JoinResp, error) {
+	s := fo.strike
+
+	self, err := s.selfDetails(req.GetInvite().GetInviter().GetAddress())
+	if err != nil {
+		return nil, err
+	}
+	inv := req.GetInvite()
+	if inv.GetInviter().GetServerId() != self.ServerId {
+		return &fedpb.JoinResp{Ok: false, Message: "invitation was not issued by this server"}, nil
+	}
+	if err := verifyInvite(inv, self.PublicKey); err != nil {
+		slog.WarnContext(ctx, "federation: join refused", "ip", peerIP(ctx), "error", err)
+		return &fedpb.JoinResp{Ok: false, Message: "invalid or expired invitation"}, nil
+	}
+
+	joiner, err := peerConfigFromDetails(req.GetJoiner())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkJoinerCertificate(peerCertificates(ctx), joiner); err != nil {
+		slog.WarnContext(ctx, "federation: join refused", "peer", joiner.Name, "ip", peerIP(ctx), "error", err)
+		return nil, unauthenticated("federation certificate does not match the joining server")
+	}
+
+	peersFileMu.Lock()
+	defer peersFileMu.Unlock()
+
+	if err := s.checkNewPeer(joiner); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.DBpool.Exec(ctx, s.PStatements.Invite.PurgeExpired); err != nil {
+		return nil, dbError("federation: purge invites", "invite", joiner.Name, err)
+	}
+	tag, err := s.DBpool.Exec(ctx, s.PStatements.Invite.Redeem, inv.Nonce, joiner.ID.String(), inv.ExpiresAt.AsTime())
+	if err != nil {
+		return nil, dbError("federation: redeem invite", "invite", joiner.Name, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return &fedpb.JoinResp{Ok: false, Message: "invitation has already been used"}, nil
+	}
+
+	if err := s.addPeer(ctx, joiner); err != nil {
+		return nil, err
+	}
+	return &fedpb.JoinResp{Ok: true, Inviter: inv.Inviter, Message: "welcome " + joiner.Name}, nil
+}
+
+// checkJoinerCertificate requires the caller's TLS certificate to be for the
+// joining server's key and issued by the CA it sent.
+func checkJoinerCertificate(certs []*x509.Certificate, joiner types.PeerConfig) error {
+	if len(certs) == 0 {
+		return fmt.Errorf("no client certificate")
+	}
+	leaf, ok := certs[0].PublicKey.(ed25519.PublicKey)
+	if !ok || !leaf.Equal(joiner.PubKey) {
+		return fmt.Errorf("certificate key is not the joining server's key")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(joiner.CACert)
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err
+}
@@ -0,0 +1,657 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	adminpb "github.com/JohnnyGlynn/strike/msgdef/admin"
+	fedpb "github.com/JohnnyGlynn/strike/msgdef/federation"
+)
+
+// testCA is a federation CA as --gen-ca writes it.
+type testCA struct {
+	certPath string
+	cert     *x509.Certificate
+	key      ed25519.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	dir := t.TempDir()
+	if err := keys.GenerateCA(dir); err != nil {
+		t.Fatal(err)
+	}
+	ca := &testCA{certPath: filepath.Join(dir, "strike_ca.crt")}
+	var err error
+	if ca.cert, ca.key, err = keys.LoadCA(ca.certPath, filepath.Join(dir, "strike_ca.pem")); err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+// testPeer is a server key and certificate as --gen-keys writes them.
+type testPeer struct {
+	name     string
+	certPath string
+	keyPath  string
+	cert     *x509.Certificate
+	key      ed25519.PrivateKey
+}
+
+// newTestPeer issues a certificate for a new server key from ca, or a
+// self-signed one when ca is nil.
+func newTestPeer(t *testing.T, ca *testCA, name string) *testPeer {
+	t.Helper()
+	dir := t.TempDir()
+
+	var caCert *x509.Certificate
+	var caKey ed25519.PrivateKey
+	if ca != nil {
+		caCert, caKey = ca.cert, ca.key
+	}
+	if err := keys.GenerateServerKeysAndCertWithCA(dir, caCert, caKey, keys.CertOptions{CommonName: name, DNSNames: []string{name}}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &testPeer{
+		name:     name,
+		certPath: filepath.Join(dir, "strike_server.crt"),
+		keyPath:  filepath.Join(dir, "strike_server.pem"),
+	}
+	var err error
+	if p.cert, err = keys.LoadCertificate(p.certPath); err != nil {
+		t.Fatal(err)
+	}
+	keyPEM, err := keys.GetKeyFromPath(p.keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.key, err = keys.ParseSigningPrivateKey(keyPEM); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func (p *testPeer) pub() ed25519.PublicKey {
+	return p.key.Public().(ed25519.PublicKey)
+}
+
+// details is how p describes itself to another server, vouched for by ca.
+func (p *testPeer) details(t *testing.T, ca *x509.Certificate) *fedpb.PeerDetails {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(p.pub())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fedpb.PeerDetails{
+		ServerId:      serverIDFromKey(der),
+		ServerName:    p.name,
+		Address:       p.name + ":9000",
+		PublicKey:     der,
+		CaCertificate: ca.Raw,
+	}
+}
+
+// newInviter returns a federating server with its own CA and an empty
+// federation.yaml.
+func newInviter(t *testing.T) *StrikeServer {
+	t.Helper()
+	ca := newTestCA(t)
+	p := newTestPeer(t, ca, "inviter")
+
+	trust, err := LoadFederationTrust(p.certPath, p.keyPath, ca.certPath, "", nil)
+	if err != nil {
+		t.Fatalf("load trust: %v", err)
+	}
+	peersFile := filepath.Join(t.TempDir(), "federation.yaml")
+	if err := os.WriteFile(peersFile, []byte("peers: []\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &StrikeServer{
+		ID:                uuid.MustParse(p.details(t, ca.cert).ServerId),
+		Name:              p.name,
+		PeerMgr:           NewPeerManager(nil, p.name),
+		signingKey:        p.key,
+		fedTrust:          trust,
+		federationPeers:   peersFile,
+		federationAddress: p.name + ":9000",
+	}
+	s.mapInit()
+	return s
+}
+
+func createInvite(t *testing.T, s *StrikeServer) (string, *fedpb.FederationInvite) {
+	t.Helper()
+	resp, err := NewAdminServer(s, "token").CreateInvite(context.Background(), &adminpb.CreateInviteReq{})
+	if err != nil {
+		t.Fatalf("create invite: %v", err)
+	}
+	inv, err := parseInviteToken(resp.Token)
+	if err != nil {
+		t.Fatalf("parse fresh invite: %v", err)
+	}
+	return resp.Token, inv
+}
+
+func inviteToken(t *testing.T, inv *fedpb.FederationInvite) string {
+	t.Helper()
+	data, err := proto.Marshal(inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inviteTokenPrefix + base64.RawURLEncoding.EncodeToString(data)
+}
+
+// peerContext is the context of a call from a peer presenting certs.
+func peerContext(certs ...*x509.Certificate) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr:     &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 4000},
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: certs}},
+	})
+}
+
+func wantCode(t *testing.T, err error, code codes.Code, text string) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Fatalf("error = %v, want %s", err, code)
+	}
+	if !strings.Contains(status.Convert(err).Message(), text) {
+		t.Fatalf("error = %v, want it to contain %q", err, text)
+	}
+}
+
+func TestParseInviteToken(t *testing.T) {
+	s := newInviter(t)
+	token, inv := createInvite(t, s)
+	other := newTestPeer(t, nil, "other")
+
+	// edited copies the invitation, applies mod and re-signs it with key
+	// when one is given.
+	edited := func(mod func(inv *fedpb.FederationInvite), key ed25519.PrivateKey) string {
+		c := proto.Clone(inv).(*fedpb.FederationInvite)
+		mod(c)
+		if key != nil {
+			c.Signature = ed25519.Sign(key, inviteBytes(c))
+		}
+		return inviteToken(t, c)
+	}
+	past := timestamppb.New(time.Now().Add(-time.Minute))
+
+	cases := map[string]struct {
+		token string
+		code  codes.Code
+		text  string
+	}{
+		"valid":        {token: token},
+		"whitespace":   {token: "  " + token + "\n"},
+		"not-a-token":  {token: "hello", code: codes.InvalidArgument, text: "not a strike invitation"},
+		"bad-encoding": {token: inviteTokenPrefix + "!!", code: codes.InvalidArgument, text: "malformed"},
+		"expired": {
+			token: edited(func(inv *fedpb.FederationInvite) { inv.ExpiresAt = past }, s.signingKey),
+			code:  codes.FailedPrecondition, text: "expired",
+		},
+		"no-expiry": {
+			token: edited(func(inv *fedpb.FederationInvite) { inv.ExpiresAt = nil }, s.signingKey),
+			code:  codes.FailedPrecondition, text: "expired",
+		},
+		"extended-expiry": {
+			token: edited(func(inv *fedpb.FederationInvite) {
+				inv.ExpiresAt = timestamppb.New(time.Now().Add(time.Hour * 24 * 365))
+			}, nil),
+			code: codes.InvalidArgument, text: "bad signature",
+		},
+		"new-nonce": {
+			token: edited(func(inv *fedpb.FederationInvite) { inv.Nonce = []byte("fresh nonce 16 b") }, nil),
+			code:  codes.InvalidArgument, text: "bad signature",
+		},
+		"changed-address": {
+			token: edited(func(inv *fedpb.FederationInvite) { inv.Inviter.Address = "attacker:9000" }, nil),
+			code:  codes.InvalidArgument, text: "bad signature",
+		},
+		"changed-ca": {
+			token: edited(func(inv *fedpb.FederationInvite) { inv.Inviter.CaCertificate = other.cert.Raw }, nil),
+			code:  codes.InvalidArgument, text: "bad signature",
+		},
+		"signed-by-other-key": {
+			token: edited(func(*fedpb.FederationInvite) {}, other.key),
+			code:  codes.InvalidArgument, text: "bad signature",
+		},
+		"unsigned": {
+			token: edited(func(inv *fedpb.FederationInvite) { inv.Signature = nil }, nil),
+			code:  codes.InvalidArgument, text: "bad signature",
+		},
+		"bad-inviter-key": {
+			token: edited(func(inv *fedpb.FederationInvite) { inv.Inviter.PublicKey = []byte("not a key") }, nil),
+			code:  codes.InvalidArgument, text: "bad inviter key",
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseInviteToken(tc.token)
+			if tc.code != codes.OK {
+				wantCode(t, err, tc.code, tc.text)
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if !proto.Equal(got, inv) {
+				t.Errorf("parsed invitation differs from the one issued")
+			}
+		})
+	}
+}
+
+func TestVerifyInviteRejectsSelfSignedForgery(t *testing.T) {
+	s := newInviter(t)
+	_, inv := createInvite(t, s)
+	attacker := newTestPeer(t, nil, "attacker")
+
+	// Swapping in your own key and re-signing gives a token that is
+	// internally consistent, so parseInviteToken accepts it; the inviting
+	// server must still refuse it against its real key.
+	forged := proto.Clone(inv).(*fedpb.FederationInvite)
+	forged.Inviter = attacker.details(t, attacker.cert)
+	forged.Inviter.ServerId = inv.Inviter.ServerId
+	forged.Signature = ed25519.Sign(attacker.key, inviteBytes(forged))
+
+	if _, err := parseInviteToken(inviteToken(t, forged)); err != nil {
+		t.Fatalf("self-consistent forgery not parsed: %v", err)
+	}
+	self, err := s.selfDetails("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCode(t, verifyInvite(forged, self.PublicKey), codes.InvalidArgument, "bad signature")
+	if err := verifyInvite(inv, self.PublicKey); err != nil {
+		t.Errorf("genuine invitation refused: %v", err)
+	}
+
+	// A joiner reading the forged token sees an ID that is not the key's.
+	if _, err := peerConfigFromDetails(forged.Inviter); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("server_id not checked against the key: %v", err)
+	}
+	if _, err := peerConfigFromDetails(inv.Inviter); err != nil {
+		t.Errorf("genuine inviter details refused: %v", err)
+	}
+}
+
+func TestCheckJoinerCertificate(t *testing.T) {
+	joinerCA := newTestCA(t)
+	otherCA := newTestCA(t)
+	joiner := newTestPeer(t, joinerCA, "joiner")
+	sibling := newTestPeer(t, joinerCA, "sibling")
+	selfSigned := newTestPeer(t, nil, "joiner")
+
+	cfg, err := peerConfigFromDetails(joiner.details(t, joinerCA.cert))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCfg, err := peerConfigFromDetails(joiner.details(t, otherCA.cert))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		certs   []*x509.Certificate
+		otherCA bool
+		ok      bool
+	}{
+		"matching":              {certs: []*x509.Certificate{joiner.cert}, ok: true},
+		"no-certificate":        {},
+		"other-key-same-ca":     {certs: []*x509.Certificate{sibling.cert}},
+		"issued-by-another-ca":  {certs: []*x509.Certificate{joiner.cert}, otherCA: true},
+		"self-signed-same-name": {certs: []*x509.Certificate{selfSigned.cert}},
+		"ca-as-leaf":            {certs: []*x509.Certificate{joinerCA.cert}},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := cfg
+			if tc.otherCA {
+				c = otherCfg
+			}
+			err := checkJoinerCertificate(tc.certs, c)
+			if tc.ok && err != nil {
+				t.Fatalf("refused: %v", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("accepted")
+			}
+		})
+	}
+}
+
+func TestJoinRefusals(t *testing.T) {
+	s := newInviter(t)
+	fo := NewFederationOrchestrator(s)
+	_, inv := createInvite(t, s)
+
+	joinerCA := newTestCA(t)
+	joiner := newTestPeer(t, joinerCA, "joiner")
+	member := newTestPeer(t, joinerCA, "member")
+
+	// member has already joined, so replaying an invitation for it is
+	// refused before the invitation is redeemed.
+	memberCfg, err := peerConfigFromDetails(member.details(t, joinerCA.cert))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.PeerMgr.Add(memberCfg)
+
+	foreign := newInviter(t)
+	_, foreignInv := createInvite(t, foreign)
+
+	expired := proto.Clone(inv).(*fedpb.FederationInvite)
+	expired.ExpiresAt = timestamppb.New(time.Now().Add(-time.Second))
+	expired.Signature = ed25519.Sign(s.signingKey, inviteBytes(expired))
+
+	cases := map[string]struct {
+		inv    *fedpb.FederationInvite
+		joiner *testPeer
+		certs  []*x509.Certificate
+		code   codes.Code
+		text   string
+	}{
+		"other-servers-invitation": {inv: foreignInv, joiner: joiner, certs: []*x509.Certificate{joiner.cert}, text: "not issued by this server"},
+		"expired":                  {inv: expired, joiner: joiner, certs: []*x509.Certificate{joiner.cert}, text: "invalid or expired"},
+		"certificate-for-other-key": {
+			inv: inv, joiner: joiner, certs: []*x509.Certificate{member.cert},
+			code: codes.Unauthenticated, text: "does not match",
+		},
+		"no-certificate": {inv: inv, joiner: joiner, code: codes.Unauthenticated, text: "does not match"},
+		"already-a-peer": {
+			inv: inv, joiner: member, certs: []*x509.Certificate{member.cert},
+			code: codes.AlreadyExists, text: "already exists",
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			resp, err := fo.Join(peerContext(tc.certs...), &fedpb.JoinReq{
+				Invite: tc.inv,
+				Joiner: tc.joiner.details(t, joinerCA.cert),
+			})
+			if tc.code != codes.OK {
+				wantCode(t, err, tc.code, tc.text)
+				return
+			}
+			if err != nil {
+				t.Fatalf("join: %v", err)
+			}
+			if resp.Ok || !strings.Contains(resp.Message, tc.text) {
+				t.Fatalf("response = %+v, want refusal containing %q", resp, tc.text)
+			}
+		})
+	}
+
+	if data, err := os.ReadFile(s.federationPeers); err != nil || string(data) != "peers: []\n" {
+		t.Errorf("federation.yaml changed by refused joins: %q %v", data, err)
+	}
+}
+
+// useTestDatabase connects s to the database at STRIKE_TEST_DATABASE_URL,
+// created from config/db/init.sql, and skips the test when it is not set.
+func useTestDatabase(t *testing.T, s *StrikeServer) {
+	t.Helper()
+	url := os.Getenv("STRIKE_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("STRIKE_TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	if err := EnsureSchema(ctx, pool); err != nil {
+		t.Fatal(err)
+	}
+	if s.PStatements, err = InitStatements(ctx, pool); err != nil {
+		t.Fatal(err)
+	}
+	s.DBpool = pool
+}
+
+func TestJoinRedeemsInvitationOnce(t *testing.T) {
+	s := newInviter(t)
+	useTestDatabase(t, s)
+	fo := NewFederationOrchestrator(s)
+	_, inv := createInvite(t, s)
+
+	joinerCA := newTestCA(t)
+	first := newTestPeer(t, joinerCA, "first")
+	second := newTestPeer(t, joinerCA, "second")
+
+	resp, err := fo.Join(peerContext(first.cert), &fedpb.JoinReq{Invite: inv, Joiner: first.details(t, joinerCA.cert)})
+	if err != nil || !resp.Ok {
+		t.Fatalf("first join: %+v %v", resp, err)
+	}
+
+	resp, err = fo.Join(peerContext(second.cert), &fedpb.JoinReq{Invite: inv, Joiner: second.details(t, joinerCA.cert)})
+	if err != nil {
+		t.Fatalf("second join: %v", err)
+	}
+	if resp.Ok || !strings.Contains(resp.Message, "already been used") {
+		t.Fatalf("replayed invitation accepted: %+v", resp)
+	}
+
+	peers, err := LoadPeers(s.federationPeers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || peers[0].Name != "first" {
+		t.Errorf("federation.yaml peers = %+v, want only first", peers)
+	}
+}
+
+func TestAppendPeerConfig(t *testing.T) {
+	ca := newTestCA(t)
+	added, err := peerConfigFromDetails(newTestPeer(t, ca, "added").details(t, ca.cert))
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := newTestPeer(t, nil, "existing").details(t, ca.cert)
+	existingEntry := "  - id: " + existing.ServerId + "\n" +
+		"    name: existing\n" +
+		"    addr: existing:9000\n" +
+		"    pubkey: " + base64.StdEncoding.EncodeToString(existing.PublicKey) + "\n"
+
+	cases := map[string]struct {
+		before string
+		peers  []string // names after the append
+		err    string
+	}{
+		"empty-file":  {before: "", peers: []string{"added"}},
+		"null-peers":  {before: "peers:\n", peers: []string{"added"}},
+		"empty-peers": {before: "peers: []\n", peers: []string{"added"}},
+		"keeps-other-peers-and-comments": {
+			before: "# managed by hand\nlocal: inviter\npeers:\n" + existingEntry,
+			peers:  []string{"existing", "added"},
+		},
+		"peers-not-a-list": {before: "peers: nope\n", err: "not a list"},
+		"not-a-mapping":    {before: "- one\n- two\n", err: "expected a mapping"},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "federation.yaml")
+			if err := os.WriteFile(path, []byte(tc.before), 0640); err != nil {
+				t.Fatal(err)
+			}
+
+			err := appendPeerConfig(path, added)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tc.err)
+				}
+				if data, _ := os.ReadFile(path); string(data) != tc.before {
+					t.Errorf("file changed on error: %q", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("append: %v", err)
+			}
+			once, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Appending the same peer again leaves the file as it was.
+			if err := appendPeerConfig(path, added); err != nil {
+				t.Fatalf("second append: %v", err)
+			}
+			twice, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(once, twice) {
+				t.Errorf("second append changed the file:\n%s\nthen\n%s", once, twice)
+			}
+
+			if strings.HasPrefix(tc.before, "#") && !strings.Contains(string(twice), "# managed by hand") {
+				t.Errorf("comment dropped:\n%s", twice)
+			}
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0640 {
+				t.Errorf("mode not kept: %v %v", info.Mode(), err)
+			}
+
+			peers, err := LoadPeers(path)
+			if err != nil {
+				t.Fatalf("appended file does not load: %v\n%s", err, twice)
+			}
+			var names []string
+			for _, p := range peers {
+				names = append(names, p.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tc.peers, ",") {
+				t.Fatalf("peers = %v, want %v", names, tc.peers)
+			}
+			got := peers[len(peers)-1]
+			if got.ID != added.ID || got.Address != added.Address || !got.PubKey.Equal(added.PubKey) || !got.CACert.Equal(added.CACert) {
+				t.Errorf("appended peer = %+v, want %+v", got, added)
+			}
+		})
+	}
+}
+
+func TestAppendPeerConfigMatchesDashedIDs(t *testing.T) {
+	ca := newTestCA(t)
+	p, err := peerConfigFromDetails(newTestPeer(t, ca, "added").details(t, ca.cert))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A hand-written entry with a dashed, upper-case ID is the same peer.
+	before := "peers:\n  - id: " + strings.ToUpper(p.ID.String()) + "\n    name: added\n    addr: added:9000\n    pubkey: " + p.RawKey + "\n"
+	path := filepath.Join(t.TempDir(), "federation.yaml")
+	if err := os.WriteFile(path, []byte(before), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendPeerConfig(path, p); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != before {
+		t.Errorf("peer appended twice:\n%s", data)
+	}
+}
+
+// testStream is a server stream carrying only a context.
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s testStream) Context() context.Context { return s.ctx }
+
+func TestFederationAuth(t *testing.T) {
+	ca := newTestCA(t)
+	self := newTestPeer(t, ca, "self")
+	trust, err := LoadFederationTrust(self.certPath, self.keyPath, ca.certPath, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	member := newTestPeer(t, ca, "member")
+	stranger := newTestPeer(t, newTestCA(t), "stranger")
+
+	// Join is exempt from the unary check only: no stream is exempt.
+	cases := map[string]struct {
+		certs    []*x509.Certificate
+		method   string
+		unaryOK  bool
+		streamOK bool
+	}{
+		"member":            {certs: []*x509.Certificate{member.cert}, method: fedpb.Federation_Relay_FullMethodName, unaryOK: true, streamOK: true},
+		"stranger":          {certs: []*x509.Certificate{stranger.cert}, method: fedpb.Federation_Relay_FullMethodName},
+		"no-certificate":    {method: fedpb.Federation_Relay_FullMethodName},
+		"join-from-anyone":  {certs: []*x509.Certificate{stranger.cert}, method: fedpb.Federation_Join_FullMethodName, unaryOK: true},
+		"join-without-cert": {method: fedpb.Federation_Join_FullMethodName, unaryOK: true},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ctx := peerContext(tc.certs...)
+
+			called := false
+			_, err := trust.UnaryAuth(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, func(context.Context, any) (any, error) {
+				called = true
+				return nil, nil
+			})
+			if tc.unaryOK != (err == nil) || tc.unaryOK != called {
+				t.Errorf("unary: error = %v, handler called = %v, want allowed = %v", err, called, tc.unaryOK)
+			}
+			if err != nil && status.Code(err) != codes.Unauthenticated {
+				t.Errorf("unary: code = %s, want Unauthenticated", status.Code(err))
+			}
+
+			called = false
+			err = trust.StreamAuth(nil, testStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: tc.method}, func(any, grpc.ServerStream) error {
+				called = true
+				return nil
+			})
+			if tc.streamOK != (err == nil) || tc.streamOK != called {
+				t.Errorf("stream: error = %v, handler called = %v, want allowed = %v", err, called, tc.streamOK)
+			}
+			if err != nil && status.Code(err) != codes.Unauthenticated {
+				t.Errorf("stream: code = %s, want Unauthenticated", status.Code(err))
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Metrics holds the Prometheus collectors exported on the admin listener.
+// A nil *Metrics is valid and records nothing, so handlers can be exercised
+// without an admin listener configured.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	deliveryLatency *prometheus.HistogramVec
+	deliveries      *prometheus.CounterVec
+	relays          *prometheus.CounterVec
+	rateLimited     *prometheus.CounterVec
+	deadLetters     prometheus.Counter
+}
+
+func NewMetrics(s *StrikeServer) *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		deliveryLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "strike",
+			Name:      "delivery_latency_seconds",
+			Help:      "Time from a payload being queued to it being handed to the recipient or a federation peer.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"route"}),
+		deliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "strike",
+			Name:      "deliveries_total",
+			Help:      "Delivery attempts by route and result.",
+		}, []string{"route", "result"}),
+		relays: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "strike",
+			Name:      "federation_relays_total",
+			Help:      "Outbound federation relays by peer and result.",
+		}, []string{"peer", "result"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "strike",
+			Name:      "rate_limited_total",
+			Help:      "Requests rejected by a rate limit, by method and limit.",
+		}, []string{"method", "limit"}),
+		deadLetters: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "strike",
+			Name:      "dead_letters_total",
+			Help:      "Payloads moved to the dead-letter table after running out of delivery attempts.",
+		}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.deliveryLatency,
+		m.deliveries,
+		m.relays,
+		m.rateLimited,
+		m.deadLetters,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "strike",
+			Name:      "connected_users",
+			Help:      "Users with an active status stream.",
+		}, func() float64 { return float64(s.ConnectedCount()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "strike",
+			Name:      "pending_messages",
+			Help:      "Payloads queued for delivery.",
+		}, func() float64 { return float64(s.PendingCount()) }),
+		&peerCollector{pm: s.PeerMgr},
+	)
+
+	return m
+}
+
+func (m *Metrics) observeDelivery(route string, delivered bool, queued time.Time) {
+	if m == nil {
+		return
+	}
+
+	result := "failed"
+	if delivered {
+		result = "delivered"
+		m.deliveryLatency.WithLabelValues(route).Observe(time.Since(queued).Seconds())
+	}
+	m.deliveries.WithLabelValues(route, result).Inc()
+}
+
+func (m *Metrics) observeRelay(peer string, err error) {
+	if m == nil {
+		return
+	}
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.relays.WithLabelValues(peer, result).Inc()
+}
+
+func (m *Metrics) observeRateLimited(method, limit string) {
+	if m == nil {
+		return
+	}
+	m.rateLimited.WithLabelValues(method, limit).Inc()
+}
+
+func (m *Metrics) observeDeadLetter() {
+	if m == nil {
+		return
+	}
+	m.deadLetters.Inc()
+}
+
+// peerCollector reports PeerRuntime state at scrape time rather than
+// tracking it in gauges, so it can never drift from the PeerManager.
+type peerCollector struct {
+	pm *PeerManager
+}
+
+var (
+	peerUpDesc = prometheus.NewDesc(
+		"strike_federation_peer_up",
+		"Whether the federation peer connection is established.",
+		[]string{"peer", "addr"}, nil,
+	)
+	peerHandshakeDesc = prometheus.NewDesc(
+		"strike_federation_peer_handshaken",
+		"Whether the federation handshake with the peer has completed.",
+		[]string{"peer", "addr"}, nil,
+	)
+)
+
+func (pc *peerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- peerUpDesc
+	ch <- peerHandshakeDesc
+}
+
+func (pc *peerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, p := range pc.pm.Snapshot() {
+		ch <- prometheus.MustNewConstMetric(peerUpDesc, prometheus.GaugeValue, boolGauge(p.Online), p.Name, p.Address)
+		ch <- prometheus.MustNewConstMetric(peerHandshakeDesc, prometheus.GaugeValue, boolGauge(p.Handshaken), p.Name, p.Address)
+	}
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
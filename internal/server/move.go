@@ -0,0 +1,323 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+	fedpb "github.com/JohnnyGlynn/strike/msgdef/federation"
+	pb "github.com/JohnnyGlynn/strike/msgdef/message"
+)
+
+// RenameAccount changes the caller's username on this server. The old name
+// becomes a redirect to the new one.
+func (s *StrikeServer) RenameAccount(ctx context.Context, req *pb.MoveAccountRequest) (*pb.ServerResponse, error) {
+	if err := s.checkMoveRequest(req); err != nil {
+		return nil, err
+	}
+	if req.Notice.NewDomain != s.Name {
+		return nil, invalidArgument("notice.new_domain", "a rename stays on this server, use MoveAccount")
+	}
+
+	uInfo, err := s.reauthenticate(ctx, req.Username, req.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkMoveNotice(req.Notice, uInfo); err != nil {
+		return nil, err
+	}
+
+	s.countersign(req.Notice)
+	if err := s.renameUser(ctx, req.Username, req.Notice); err != nil {
+		return nil, err
+	}
+	queued := s.announceRedirect(ctx, uuid.MustParse(uInfo.UserId), req.Notice, req.Notify)
+
+	slog.InfoContext(ctx, "account renamed", "user_id", uInfo.UserId, "old_username", req.Username, "new_username", req.Notice.NewUsername, "notices", queued)
+	return &pb.ServerResponse{Success: true, Message: fmt.Sprintf("renamed to %s, %d friends notified", req.Notice.NewUsername, queued)}, nil
+}
+
+// MoveAccount hands the caller's account over to another federated server.
+// The account must already exist there with the same user ID and signing
+// key. The local account is removed and its old address redirects to the
+// new one; queued payloads are forwarded.
+func (s *StrikeServer) MoveAccount(ctx context.Context, req *pb.MoveAccountRequest) (*pb.ServerResponse, error) {
+	if err := s.checkMoveRequest(req); err != nil {
+		return nil, err
+	}
+	if req.Notice.NewDomain == s.Name {
+		return nil, invalidArgument("notice.new_domain", "already on this server, use RenameAccount")
+	}
+
+	uInfo, err := s.reauthenticate(ctx, req.Username, req.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkMoveNotice(req.Notice, uInfo); err != nil {
+		return nil, err
+	}
+
+	client, ok := s.PeerMgr.ClientByName(req.Notice.NewDomain)
+	if !ok {
+		return nil, notFound("domain", req.Notice.NewDomain)
+	}
+	resp, err := client.UserLookup(ctx, &fedpb.UserLookupReq{Username: req.Notice.NewUsername})
+	if err != nil {
+		return nil, peerUnavailable(req.Notice.NewDomain, err)
+	}
+	if !resp.Found || resp.UserInfo.GetUserId() != uInfo.UserId || !bytes.Equal(resp.UserInfo.GetSigningPublicKey(), uInfo.SigningPublicKey) {
+		return nil, failedPrecondition(shared.FormatAddress(req.Notice.NewUsername, req.Notice.NewDomain),
+			"sign up on the new server with the same user ID and signing key first")
+	}
+
+	id, err := s.removeUser(ctx, req.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	queued, err := s.issueRedirect(ctx, id, req.Notice, req.Notify)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "account moved", "user_id", id, "username", req.Username, "new_domain", req.Notice.NewDomain, "notices", queued)
+	return &pb.ServerResponse{Success: true, Message: fmt.Sprintf("moved to %s, %d friends notified",
+		shared.FormatAddress(req.Notice.NewUsername, req.Notice.NewDomain), queued)}, nil
+}
+
+func (s *StrikeServer) checkMoveRequest(req *pb.MoveAccountRequest) error {
+	if req.GetUsername() == "" {
+		return invalidArgument("username", "missing username")
+	}
+	if req.Notice == nil {
+		return invalidArgument("notice", "missing signed notice")
+	}
+	if req.Notice.NewUsername == "" || req.Notice.NewDomain == "" {
+		return invalidArgument("notice", "missing new username or domain")
+	}
+	if len(req.Notify) > maxNotices {
+		return invalidArgument("notify", fmt.Sprintf("at most %d addresses", maxNotices))
+	}
+	if s.isDraining() {
+		return shuttingDown()
+	}
+	return nil
+}
+
+// checkMoveNotice verifies that the notice moves this user away from their
+// current address and is signed with their registered signing key.
+func (s *StrikeServer) checkMoveNotice(n *common_pb.AccountMoved, uInfo *common_pb.UserInfo) error {
+	if n.UserId != uInfo.UserId || n.OldUsername != uInfo.Username || n.OldDomain != s.Name {
+		return invalidArgument("notice", "does not match the account")
+	}
+	if n.NewUsername == n.OldUsername && n.NewDomain == n.OldDomain {
+		return invalidArgument("notice", "new address is the same as the old one")
+	}
+	if n.MovedAt == nil {
+		return invalidArgument("notice", "missing moved_at")
+	}
+	if skew := time.Since(n.MovedAt.AsTime()).Abs(); skew > noticeClockSkew {
+		return invalidArgument("notice", "moved_at too far from server time")
+	}
+
+	pub, err := keys.ParseSigningPublicKey(uInfo.SigningPublicKey)
+	if err != nil {
+		return internalError("move account: parse signing key", err)
+	}
+	if !ed25519.Verify(pub, shared.AccountMovedBytes(n), n.Signature) {
+		return invalidArgument("notice", "bad signature")
+	}
+	return nil
+}
+
+// renameUser renames the account and records the redirect from its old name
+// in one transaction, so the old address never stops resolving.
+func (s *StrikeServer) renameUser(ctx context.Context, username string, n *common_pb.AccountMoved) error {
+	tx, err := s.DBpool.Begin(ctx)
+	if err != nil {
+		return dbError("rename account", "user", username, err)
+	}
+	defer tx.Rollback(ctx) // no-op after commit
+
+	if _, err := tx.Exec(ctx, s.PStatements.User.RenameUser, username, n.NewUsername); err != nil {
+		return dbError("rename account", "user", n.NewUsername, err)
+	}
+	if err := s.saveRedirect(ctx, tx, n); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return dbError("rename account: commit", "user", username, err)
+	}
+	return nil
+}
+
+// countersign signs the notice as the server the user is leaving.
+func (s *StrikeServer) countersign(n *common_pb.AccountMoved) {
+	n.ServerSignature = ed25519.Sign(s.signingKey, shared.RedirectBytes(n))
+}
+
+// issueRedirect countersigns the notice, records it so lookups of the old
+// address follow it, and forwards it to the user's friends and to every
+// peer.
+func (s *StrikeServer) issueRedirect(ctx context.Context, user uuid.UUID, n *common_pb.AccountMoved, notify []*common_pb.UserAddress) (int, error) {
+	s.countersign(n)
+	if err := s.saveRedirect(ctx, s.DBpool, n); err != nil {
+		return 0, err
+	}
+	return s.announceRedirect(ctx, user, n, notify), nil
+}
+
+// announceRedirect routes to the user's new address and forwards the
+// recorded notice to their friends and to every peer.
+func (s *StrikeServer) announceRedirect(ctx context.Context, user uuid.UUID, n *common_pb.AccountMoved, notify []*common_pb.UserAddress) int {
+	s.applyMove(user, n)
+
+	queued := s.queueNotices(ctx, user, notify, &pb.StreamPayload{
+		Payload: &pb.StreamPayload_AccountMoved{AccountMoved: n},
+		Info:    "Account moved notice",
+	})
+
+	fedReq := &fedpb.AccountMovedReq{OriginServer: s.ID.String(), Notice: n}
+	s.notifyPeers(ctx, "redirect", func(ctx context.Context, c fedpb.FederationClient) error {
+		_, err := c.AccountMoved(ctx, fedReq)
+		return err
+	})
+
+	return queued
+}
+
+// checkRedirect verifies that a redirect was countersigned by the server the
+// user is moving away from, which must be peerID.
+func (s *StrikeServer) checkRedirect(peerID string, n *common_pb.AccountMoved) error {
+	pub, ok := s.PeerMgr.PubKey(peerID)
+	if !ok {
+		return fmt.Errorf("unknown peer %s", peerID)
+	}
+	if s.PeerMgr.NameOf(peerID) != n.GetOldDomain() {
+		return fmt.Errorf("peer %s is not the home server of %s", s.PeerMgr.NameOf(peerID), shared.FormatAddress(n.GetOldUsername(), n.GetOldDomain()))
+	}
+	if !ed25519.Verify(pub, shared.RedirectBytes(n), n.GetServerSignature()) {
+		return fmt.Errorf("bad server signature")
+	}
+	return nil
+}
+
+// execer runs a statement on the pool or within a transaction.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func (s *StrikeServer) saveRedirect(ctx context.Context, db execer, n *common_pb.AccountMoved) error {
+	raw, err := proto.Marshal(n)
+	if err != nil {
+		return internalError("save redirect: marshal", err)
+	}
+
+	_, err = db.Exec(ctx, s.PStatements.Redirect.Save, n.OldDomain, n.OldUsername, n.UserId, n.NewUsername, n.NewDomain, raw)
+	if err != nil {
+		return dbError("save redirect", "redirect", shared.FormatAddress(n.OldUsername, n.OldDomain), err)
+	}
+	return nil
+}
+
+// redirectFor returns the redirect recorded for username@domain, or nil.
+func (s *StrikeServer) redirectFor(ctx context.Context, domain, username string) (*common_pb.AccountMoved, error) {
+	var raw []byte
+	err := s.DBpool.QueryRow(ctx, s.PStatements.Redirect.Get, domain, username).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, dbError("redirect lookup", "redirect", shared.FormatAddress(username, domain), err)
+	}
+
+	n := &common_pb.AccountMoved{}
+	if err := proto.Unmarshal(raw, n); err != nil {
+		return nil, internalError("redirect lookup: unmarshal", err)
+	}
+	return n, nil
+}
+
+// followRedirect looks the user up at the address a redirect points to.
+// Only one hop is followed.
+func (s *StrikeServer) followRedirect(ctx context.Context, n *common_pb.AccountMoved) (*common_pb.UserInfo, error) {
+	var (
+		uInfo *common_pb.UserInfo
+		err   error
+	)
+	if n.NewDomain == s.Name {
+		uInfo, err = s.localUserLookup(ctx, n.NewUsername)
+	} else {
+		uInfo, err = s.federatedUserLookup(ctx, n.NewUsername, n.NewDomain, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A redirect is only honoured if it leads back to the same account.
+	if uInfo.UserId != n.UserId {
+		return nil, notFound("user", shared.FormatAddress(n.OldUsername, n.OldDomain))
+	}
+	return uInfo, nil
+}
+
+// applyMove routes payloads for a user who changed server to the new
+// domain and redelivers anything queued for them.
+func (s *StrikeServer) applyMove(user uuid.UUID, n *common_pb.AccountMoved) {
+	if n.NewDomain == n.OldDomain {
+		return
+	}
+
+	s.mu.Lock()
+	s.mapInit()
+	s.moved[user] = n.NewDomain
+	delete(s.RemotePresence, user)
+	if n.NewDomain != s.Name && s.PeerMgr != nil {
+		if peerID, ok := s.PeerMgr.IDByName(n.NewDomain); ok {
+			s.RemotePresence[user] = peerID
+		}
+	}
+	s.mu.Unlock()
+
+	s.flushPendingFor(user)
+}
+
+// LoadRedirects restores the routes for users who moved server, oldest
+// first so the latest move wins.
+func (s *StrikeServer) LoadRedirects(ctx context.Context) error {
+	if s.DBpool == nil {
+		return nil
+	}
+
+	rows, err := s.DBpool.Query(ctx, s.PStatements.Redirect.Moved)
+	if err != nil {
+		return fmt.Errorf("load redirects: %w", err)
+	}
+	defer rows.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mapInit()
+
+	for rows.Next() {
+		var user uuid.UUID
+		var domain string
+		if err := rows.Scan(&user, &domain); err != nil {
+			return fmt.Errorf("load redirects: %w", err)
+		}
+		s.moved[user] = domain
+	}
+	return rows.Err()
+}
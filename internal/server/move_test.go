@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+)
+
+// TestRenameUserIsAtomic runs against STRIKE_TEST_DATABASE_URL.
+func TestRenameUserIsAtomic(t *testing.T) {
+	s := &StrikeServer{Name: "a.example"}
+	useTestDatabase(t, s)
+	ctx := context.Background()
+
+	id := uuid.New()
+	oldName := "rename-" + id.String()[:8]
+	newName := oldName + "-new"
+	if _, err := s.DBpool.Exec(ctx, s.PStatements.User.CreateUser, id, oldName, "hash", []byte("salt")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, name := range []string{oldName, newName} {
+			_, _ = s.DBpool.Exec(ctx, s.PStatements.User.DeleteUser, name)
+			_, _ = s.DBpool.Exec(ctx, "DELETE FROM account_redirects WHERE username = $1", name)
+		}
+	})
+
+	userID := func(name string) (uuid.UUID, bool) {
+		var got uuid.UUID
+		err := s.DBpool.QueryRow(ctx, s.PStatements.User.GetUser, name).Scan(&got)
+		return got, err == nil
+	}
+	notice := &common_pb.AccountMoved{UserId: id.String(), OldUsername: oldName, OldDomain: s.Name, NewUsername: newName, NewDomain: s.Name}
+
+	// Postgres refuses NUL in text, so the redirect cannot be saved once the
+	// account has been renamed; the rename must not survive it.
+	bad := proto.Clone(notice).(*common_pb.AccountMoved)
+	bad.OldDomain = "a.example\x00"
+	if err := s.renameUser(ctx, oldName, bad); err == nil {
+		t.Fatal("rename succeeded without its redirect")
+	}
+	if got, ok := userID(oldName); !ok || got != id {
+		t.Fatal("account renamed although its redirect was not saved")
+	}
+	if _, ok := userID(newName); ok {
+		t.Fatal("new name taken although the rename failed")
+	}
+
+	if err := s.renameUser(ctx, oldName, notice); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if got, ok := userID(newName); !ok || got != id {
+		t.Fatal("account not renamed")
+	}
+	redirect, err := s.redirectFor(ctx, s.Name, oldName)
+	if err != nil || redirect == nil || redirect.NewUsername != newName {
+		t.Fatalf("redirect = %v, %v, want one to %s", redirect, err, newName)
+	}
+}
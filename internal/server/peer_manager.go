@@ -2,15 +2,18 @@ package server
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/tls"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 
+	"github.com/JohnnyGlynn/strike/internal/logging"
 	"github.com/JohnnyGlynn/strike/internal/server/types"
+	"github.com/JohnnyGlynn/strike/internal/tracing"
 	fedpb "github.com/JohnnyGlynn/strike/msgdef/federation"
 )
 
@@ -19,36 +22,106 @@ type PeerManager struct {
 	peers   map[string]*types.PeerRuntime
 	conns   map[string]*grpc.ClientConn
 	clients map[string]fedpb.FederationClient
+
+	// relayLimits holds a bucket for each peer whose policy sets relay_rate.
+	relayLimits map[string]*limiterSet
+
+	// Set by ConnectAll so peers added later can be dialled the same way.
+	ctx       context.Context
+	trust     *FederationTrust
+	localID   string
+	localName string
+
+	// onConnect is called with a peer's name once a handshake with it
+	// succeeds.
+	onConnect func(name string)
 }
 
-func NewPeerManager(peers []types.PeerConfig) *PeerManager {
+// NewPeerManager tracks every peer in the federation config except the
+// local server, which federation.yaml lists alongside its peers.
+func NewPeerManager(peers []types.PeerConfig, localName string) *PeerManager {
 	pm := &PeerManager{
-		peers:   make(map[string]*types.PeerRuntime, len(peers)),
-		conns:   make(map[string]*grpc.ClientConn),
-		clients: make(map[string]fedpb.FederationClient),
+		peers:       make(map[string]*types.PeerRuntime, len(peers)),
+		conns:       make(map[string]*grpc.ClientConn),
+		clients:     make(map[string]fedpb.FederationClient),
+		relayLimits: make(map[string]*limiterSet),
 	}
 	for _, p := range peers {
-		pm.peers[p.ID.String()] = &types.PeerRuntime{Cfg: p}
+		if p.Name == localName {
+			continue
+		}
+		pm.track(p)
 	}
 	return pm
 }
 
+// track registers a peer and its relay limit. The caller holds pm.mu, or
+// owns pm.
+func (pm *PeerManager) track(p types.PeerConfig) *types.PeerRuntime {
+	peer := &types.PeerRuntime{Cfg: p}
+	pm.peers[p.ID.String()] = peer
+	if p.Policy.RelayRate > 0 {
+		burst := max(p.Policy.RelayBurst, 1)
+		pm.relayLimits[p.ID.String()] = newLimiterSet(p.Policy.RelayRate, burst)
+	}
+	return peer
+}
+
 func (pm *PeerManager) ConnectAll(
 	ctx context.Context,
-	tlsConf *tls.Config,
+	trust *FederationTrust,
 	localID string,
 	localName string,
 ) {
 
-	pm.mu.RLock()
-	defer pm.mu.RUnlock()
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.ctx, pm.trust, pm.localID, pm.localName = ctx, trust, localID, localName
 
 	for _, peer := range pm.peers {
 		if peer.Cfg.Name == localName {
 			continue
 		}
-		go pm.connectPeer(ctx, peer, tlsConf, localID, localName)
+		go pm.connectPeer(ctx, peer, trust.ClientConfig(), localID, localName)
+	}
+}
+
+// OnConnect sets fn to be called with a peer's name each time a handshake
+// with it succeeds.
+func (pm *PeerManager) OnConnect(fn func(name string)) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.onConnect = fn
+}
+
+// Add starts tracking a peer that joined by invitation and, once ConnectAll
+// has run, connects to it. It reports false if the peer is already known.
+func (pm *PeerManager) Add(p types.PeerConfig) bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if _, ok := pm.peers[p.ID.String()]; ok {
+		return false
 	}
+	peer := pm.track(p)
+
+	if pm.trust != nil {
+		go pm.connectPeer(pm.ctx, peer, pm.trust.ClientConfig(), pm.localID, pm.localName)
+	}
+	return true
+}
+
+// configs returns the configuration of every tracked peer.
+func (pm *PeerManager) configs() []types.PeerConfig {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	out := make([]types.PeerConfig, 0, len(pm.peers))
+	for _, peer := range pm.peers {
+		out = append(out, peer.Cfg)
+	}
+	return out
 }
 
 func (pm *PeerManager) connectPeer(
@@ -61,11 +134,17 @@ func (pm *PeerManager) connectPeer(
 
 	creds := credentials.NewTLS(tlsConf)
 
-	log.Printf("federation: connecting to peer %s@%s", peer.Cfg.Name, peer.Cfg.Address)
+	log := slog.With("peer", peer.Cfg.Name, "addr", peer.Cfg.Address)
+	log.Info("federation: connecting to peer")
 
-	conn, err := grpc.NewClient(peer.Cfg.Address, grpc.WithTransportCredentials(creds))
+	conn, err := grpc.NewClient(peer.Cfg.Address,
+		grpc.WithTransportCredentials(creds),
+		tracing.DialOption(),
+		grpc.WithChainUnaryInterceptor(logging.UnaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(logging.StreamClientInterceptor),
+	)
 	if err != nil {
-		log.Printf("federation: dial failed for %s: %v", peer.Cfg.Name, err)
+		log.Error("federation: dial failed", "error", err)
 		return
 	}
 
@@ -81,7 +160,7 @@ func (pm *PeerManager) connectPeer(
 			break
 		}
 
-		log.Printf("federation: handshake attempt %d failed for %s: %v", attempt, peer.Cfg.Name, err)
+		log.Warn("federation: handshake failed", "attempt", attempt, "error", err)
 
 		select {
 		case <-ctx.Done():
@@ -96,16 +175,17 @@ func (pm *PeerManager) connectPeer(
 	}
 
 	if err != nil {
-		log.Printf("federation: giving up on peer %s after retries", peer.Cfg.Name)
+		log.Error("federation: giving up on peer after retries")
 		conn.Close()
 		return
 	}
 
-	log.Printf("federation: connected to peer %s", peer.Cfg.Name)
+	log.Info("federation: connected to peer")
 
 	pm.mu.Lock()
 	pm.conns[peer.Cfg.ID.String()] = conn
 	pm.clients[peer.Cfg.ID.String()] = client
+	onConnect := pm.onConnect
 	pm.mu.Unlock()
 
 	peer.Mu.Lock()
@@ -113,7 +193,12 @@ func (pm *PeerManager) connectPeer(
 	peer.Client = client
 	peer.Online = true
 	peer.Handshaken = true
+	peer.LastSeen = time.Now()
 	peer.Mu.Unlock()
+
+	if onConnect != nil {
+		onConnect(peer.Cfg.Name)
+	}
 }
 
 func (pm *PeerManager) Client(peerID string) (fedpb.FederationClient, bool) {
@@ -137,3 +222,133 @@ func (pm *PeerManager) ClientByName(name string) (fedpb.FederationClient, bool)
 	}
 	return nil, false
 }
+
+// Clients returns a copy of the connected peer clients keyed by peer ID.
+func (pm *PeerManager) Clients() map[string]fedpb.FederationClient {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	out := make(map[string]fedpb.FederationClient, len(pm.clients))
+	for id, c := range pm.clients {
+		out[id] = c
+	}
+	return out
+}
+
+// PubKey returns the signing key federation.yaml lists for a peer ID.
+func (pm *PeerManager) PubKey(peerID string) (ed25519.PublicKey, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	peer, ok := pm.peers[peerID]
+	if !ok || peer.Cfg.PubKey == nil {
+		return nil, false
+	}
+	return peer.Cfg.PubKey, true
+}
+
+// ByKey finds the peer whose federation.yaml signing key is pub. Federation
+// certificates are issued for that key, so this names the caller of a
+// federation RPC.
+func (pm *PeerManager) ByKey(pub ed25519.PublicKey) (types.PeerConfig, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	for _, peer := range pm.peers {
+		if peer.Cfg.PubKey != nil && peer.Cfg.PubKey.Equal(pub) {
+			return peer.Cfg, true
+		}
+	}
+	return types.PeerConfig{}, false
+}
+
+// Policy returns the policy federation.yaml sets for a peer ID. Unknown
+// peers get the zero policy, which allows everything.
+func (pm *PeerManager) Policy(peerID string) types.PeerPolicy {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	if peer, ok := pm.peers[peerID]; ok {
+		return peer.Cfg.Policy
+	}
+	return types.PeerPolicy{}
+}
+
+// AllowRelay takes a token from the peer's relay_rate bucket, or reports how
+// long until one is available.
+func (pm *PeerManager) AllowRelay(peerID string) (bool, time.Duration) {
+	pm.mu.RLock()
+	l := pm.relayLimits[peerID]
+	pm.mu.RUnlock()
+
+	return l.allow(peerID)
+}
+
+// IDByName looks up a peer ID by its server name (domain).
+func (pm *PeerManager) IDByName(name string) (string, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	for id, peer := range pm.peers {
+		if peer.Cfg.Name == name {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// NameOf returns the configured name for a peer ID, falling back to the ID.
+func (pm *PeerManager) NameOf(peerID string) string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	if peer, ok := pm.peers[peerID]; ok {
+		return peer.Cfg.Name
+	}
+	return peerID
+}
+
+// Snapshot returns a point-in-time copy of every configured peer's state.
+func (pm *PeerManager) Snapshot() []types.PeerStatus {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	out := make([]types.PeerStatus, 0, len(pm.peers))
+	for _, peer := range pm.peers {
+		peer.Mu.RLock()
+		out = append(out, types.PeerStatus{
+			ID:         peer.Cfg.ID.String(),
+			Name:       peer.Cfg.Name,
+			Address:    peer.Cfg.Address,
+			Online:     peer.Online,
+			Handshaken: peer.Handshaken,
+			LastSeen:   peer.LastSeen,
+			Required:   peer.Cfg.Required,
+		})
+		peer.Mu.RUnlock()
+	}
+	return out
+}
+
+// Close tears down every federation connection and marks peers offline.
+func (pm *PeerManager) Close() {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	for id, conn := range pm.conns {
+		if err := conn.Close(); err != nil {
+			slog.Warn("federation: closing connection", "peer_id", id, "error", err)
+		}
+		delete(pm.conns, id)
+		delete(pm.clients, id)
+	}
+
+	for _, peer := range pm.peers {
+		peer.Mu.Lock()
+		peer.Conn = nil
+		peer.Client = nil
+		peer.Online = false
+		peer.Handshaken = false
+		peer.Mu.Unlock()
+	}
+}
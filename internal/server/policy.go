@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/server/types"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+	fedpb "github.com/JohnnyGlynn/strike/msgdef/federation"
+	pb "github.com/JohnnyGlynn/strike/msgdef/message"
+)
+
+// maxBlocks bounds each list in a user's block list.
+const maxBlocks = 1000
+
+// payloadTypes are the StreamPayload oneof field names a peer policy can
+// allow or deny.
+var payloadTypes = func() []string {
+	var names []string
+	fields := (&pb.StreamPayload{}).ProtoReflect().Descriptor().Oneofs().ByName("payload").Fields()
+	for i := 0; i < fields.Len(); i++ {
+		names = append(names, string(fields.Get(i).Name()))
+	}
+	return names
+}()
+
+// validatePolicy checks a peer policy read from federation.yaml.
+func validatePolicy(p types.PeerPolicy) error {
+	switch p.Direction {
+	case "", types.DirectionBoth, types.DirectionInbound, types.DirectionOutbound:
+	default:
+		return fmt.Errorf("direction must be %s, %s or %s", types.DirectionBoth, types.DirectionInbound, types.DirectionOutbound)
+	}
+	for _, t := range slices.Concat(p.AllowTypes, p.DenyTypes) {
+		if !slices.Contains(payloadTypes, t) {
+			return fmt.Errorf("unknown payload type %q (one of %s)", t, strings.Join(payloadTypes, ", "))
+		}
+	}
+	if p.RelayRate < 0 || p.RelayBurst < 0 || p.MaxSize < 0 {
+		return fmt.Errorf("relay_rate, relay_burst and max_payload_size must not be negative")
+	}
+	return nil
+}
+
+// payloadType names the oneof field set in a marshalled StreamPayload.
+func payloadType(data []byte) (string, error) {
+	var sp pb.StreamPayload
+	if err := proto.Unmarshal(data, &sp); err != nil {
+		return "", err
+	}
+	m := sp.ProtoReflect()
+	fd := m.WhichOneof(m.Descriptor().Oneofs().ByName("payload"))
+	if fd == nil {
+		return "", nil
+	}
+	return string(fd.Name()), nil
+}
+
+// callingPeer identifies the peer making a federation call by the key its
+// certificate was issued for. UnaryAuth has already verified the chain.
+func (s *StrikeServer) callingPeer(ctx context.Context) (types.PeerConfig, bool) {
+	certs := peerCertificates(ctx)
+	if len(certs) == 0 {
+		return types.PeerConfig{}, false
+	}
+	pub, ok := certs[0].PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return types.PeerConfig{}, false
+	}
+	return s.PeerMgr.ByKey(pub)
+}
+
+// checkRelayPolicy applies the calling peer's policy to a relayed payload.
+// Peers trusted through the shared CA but missing from federation.yaml get
+// the zero policy.
+func (s *StrikeServer) checkRelayPolicy(ctx context.Context, peer types.PeerConfig, rp *fedpb.RelayPayload) error {
+	p := peer.Policy
+
+	if !p.Inbound() {
+		return status.Error(codes.PermissionDenied, "this server does not accept payloads from "+peer.Name)
+	}
+	if p.MaxSize > 0 && len(rp.PayloadData) > p.MaxSize {
+		return invalidArgument("payload_data", fmt.Sprintf("larger than %d bytes", p.MaxSize))
+	}
+	if len(p.AllowTypes) > 0 || len(p.DenyTypes) > 0 {
+		t, err := payloadType(rp.PayloadData)
+		if err != nil {
+			return invalidArgument("payload_data", "not a payload")
+		}
+		if !p.AllowsType(t) {
+			return status.Error(codes.PermissionDenied, fmt.Sprintf("%s payloads are not accepted from %s", t, peer.Name))
+		}
+	}
+	if ok, retry := s.PeerMgr.AllowRelay(peer.ID.String()); !ok {
+		s.Metrics.observeRateLimited(fedpb.Federation_Relay_FullMethodName, "peer")
+		return rateLimited("relay rate limit exceeded for "+peer.Name, retry)
+	}
+	return nil
+}
+
+// blockedBy reports whether recipient has blocked the sender's user ID or
+// any of the given domains.
+func (s *StrikeServer) blockedBy(ctx context.Context, recipient uuid.UUID, sender string, domains ...string) (bool, error) {
+	for _, domain := range domains {
+		var blocked bool
+		if err := s.DBpool.QueryRow(ctx, s.PStatements.Blocks.Check, recipient, domain, sender).Scan(&blocked); err != nil {
+			return false, dbError("block check", "user", recipient.String(), err)
+		}
+		if blocked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UpdateBlocks replaces the caller's block list. The list is signed with the
+// user's signing key rather than carrying a password, and an older list
+// never replaces a newer one.
+func (s *StrikeServer) UpdateBlocks(ctx context.Context, l *common_pb.BlockList) (*pb.ServerResponse, error) {
+	id, err := uuid.Parse(l.GetUserId())
+	if err != nil {
+		return nil, invalidArgument("user_id", "not a valid user id")
+	}
+	if l.UpdatedAt == nil {
+		return nil, invalidArgument("updated_at", "missing updated_at")
+	}
+	if skew := time.Since(l.UpdatedAt.AsTime()).Abs(); skew > noticeClockSkew {
+		return nil, invalidArgument("updated_at", "too far from server time")
+	}
+	if len(l.Domains) > maxBlocks || len(l.UserIds) > maxBlocks {
+		return nil, invalidArgument("block_list", fmt.Sprintf("at most %d domains and %d users", maxBlocks, maxBlocks))
+	}
+	for _, u := range l.UserIds {
+		if _, err := uuid.Parse(u); err != nil {
+			return nil, invalidArgument("user_ids", u+" is not a valid user id")
+		}
+	}
+
+	var encKey, sigKey []byte
+	if err := s.DBpool.QueryRow(ctx, s.PStatements.Keys.GetPublicKeys, id).Scan(&encKey, &sigKey); err != nil {
+		return nil, dbError("update blocks: keys", "user", id.String(), err)
+	}
+	pub, err := keys.ParseSigningPublicKey(sigKey)
+	if err != nil {
+		return nil, internalError("update blocks: parse key", err)
+	}
+	if !ed25519.Verify(pub, shared.BlockListBytes(l), l.Signature) {
+		return nil, unauthenticated("block list signature does not match the account's signing key")
+	}
+
+	tag, err := s.DBpool.Exec(ctx, s.PStatements.Blocks.Save, id, l.Domains, l.UserIds, l.UpdatedAt.AsTime())
+	if err != nil {
+		return nil, dbError("update blocks", "user", id.String(), err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, failedPrecondition("block_list", "a newer block list is already stored")
+	}
+
+	slog.InfoContext(ctx, "block list updated", "user_id", id, "domains", len(l.Domains), "users", len(l.UserIds))
+	return &pb.ServerResponse{Success: true, Message: fmt.Sprintf("blocking %d domains and %d users", len(l.Domains), len(l.UserIds))}, nil
+}
@@ -0,0 +1,351 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/JohnnyGlynn/strike/internal/server/types"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+	fedpb "github.com/JohnnyGlynn/strike/msgdef/federation"
+	pb "github.com/JohnnyGlynn/strike/msgdef/message"
+)
+
+func TestValidatePolicy(t *testing.T) {
+	cases := map[string]struct {
+		policy types.PeerPolicy
+		err    string
+	}{
+		"zero":              {},
+		"both":              {policy: types.PeerPolicy{Direction: types.DirectionBoth}},
+		"inbound":           {policy: types.PeerPolicy{Direction: types.DirectionInbound}},
+		"outbound":          {policy: types.PeerPolicy{Direction: types.DirectionOutbound}},
+		"unknown-direction": {policy: types.PeerPolicy{Direction: "sideways"}, err: "direction must be"},
+		"known-types": {policy: types.PeerPolicy{
+			AllowTypes: []string{"encenv", "friend_request"},
+			DenyTypes:  []string{"key_rotation"},
+		}},
+		"unknown-allow-type": {policy: types.PeerPolicy{AllowTypes: []string{"encenv", "files"}}, err: `unknown payload type "files"`},
+		"unknown-deny-type":  {policy: types.PeerPolicy{DenyTypes: []string{"FriendRequest"}}, err: `unknown payload type "FriendRequest"`},
+		"limits":             {policy: types.PeerPolicy{RelayRate: 5, RelayBurst: 10, MaxSize: 1 << 20}},
+		"negative-rate":      {policy: types.PeerPolicy{RelayRate: -1}, err: "must not be negative"},
+		"negative-burst":     {policy: types.PeerPolicy{RelayBurst: -1}, err: "must not be negative"},
+		"negative-size":      {policy: types.PeerPolicy{MaxSize: -1}, err: "must not be negative"},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validatePolicy(tc.policy)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("refused: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("error = %v, want it to contain %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestLoadPeersRejectsInvalidPolicy(t *testing.T) {
+	ca := newTestCA(t)
+	p, err := peerConfigFromDetails(newTestPeer(t, ca, "peer").details(t, ca.cert))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "federation.yaml")
+	yaml := "peers:\n  - id: " + p.ID.String() + "\n    name: peer\n    addr: peer:9000\n    pubkey: " + p.RawKey +
+		"\n    policy:\n      direction: sideways\n"
+	if err := os.WriteFile(path, []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPeers(path); err == nil || !strings.Contains(err.Error(), "invalid policy for peer peer") {
+		t.Fatalf("error = %v, want the policy refused", err)
+	}
+}
+
+func marshalPayload(t *testing.T, sp *pb.StreamPayload) []byte {
+	t.Helper()
+	data, err := proto.Marshal(sp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestPayloadType(t *testing.T) {
+	cases := map[string]struct {
+		payload *pb.StreamPayload
+		data    []byte
+		want    string
+		invalid bool
+	}{
+		"encenv":          {payload: &pb.StreamPayload{Payload: &pb.StreamPayload_Encenv{Encenv: &common_pb.EncryptedEnvelope{}}}, want: "encenv"},
+		"friend-request":  {payload: &pb.StreamPayload{Payload: &pb.StreamPayload_FriendRequest{FriendRequest: &pb.FriendRequest{}}}, want: "friend_request"},
+		"key-rotation":    {payload: &pb.StreamPayload{Payload: &pb.StreamPayload_KeyRotation{KeyRotation: &common_pb.KeyRotation{}}}, want: "key_rotation"},
+		"friend-removed":  {payload: &pb.StreamPayload{Payload: &pb.StreamPayload_FriendRemoved{FriendRemoved: &common_pb.FriendRemoved{}}}, want: "friend_removed"},
+		"no-payload":      {payload: &pb.StreamPayload{Info: "hello"}, want: ""},
+		"empty":           {data: []byte{}, want: ""},
+		"not-a-payload":   {data: []byte{0xff, 0xff, 0xff}, invalid: true},
+		"truncated-field": {data: []byte{0x32, 0x05, 0x01}, invalid: true},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data := tc.data
+			if tc.payload != nil {
+				data = marshalPayload(t, tc.payload)
+			}
+			got, err := payloadType(data)
+			if tc.invalid {
+				if err == nil {
+					t.Fatalf("parsed %x as %q", data, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("payloadType: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("payloadType = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckRelayPolicy(t *testing.T) {
+	encenv := marshalPayload(t, &pb.StreamPayload{Payload: &pb.StreamPayload_Encenv{Encenv: &common_pb.EncryptedEnvelope{EncryptedMessage: make([]byte, 100)}}})
+	request := marshalPayload(t, &pb.StreamPayload{Payload: &pb.StreamPayload_FriendRequest{FriendRequest: &pb.FriendRequest{}}})
+
+	cases := map[string]struct {
+		policy types.PeerPolicy
+		data   []byte
+		code   codes.Code
+		text   string
+	}{
+		"zero-policy":          {data: encenv},
+		"direction-both":       {policy: types.PeerPolicy{Direction: types.DirectionBoth}, data: encenv},
+		"direction-inbound":    {policy: types.PeerPolicy{Direction: types.DirectionInbound}, data: encenv},
+		"direction-outbound":   {policy: types.PeerPolicy{Direction: types.DirectionOutbound}, data: encenv, code: codes.PermissionDenied, text: "does not accept payloads"},
+		"within-max-size":      {policy: types.PeerPolicy{MaxSize: len(encenv)}, data: encenv},
+		"over-max-size":        {policy: types.PeerPolicy{MaxSize: len(encenv) - 1}, data: encenv, code: codes.InvalidArgument, text: "larger than"},
+		"allowed-type":         {policy: types.PeerPolicy{AllowTypes: []string{"encenv"}}, data: encenv},
+		"not-in-allow-list":    {policy: types.PeerPolicy{AllowTypes: []string{"encenv"}}, data: request, code: codes.PermissionDenied, text: "friend_request payloads are not accepted"},
+		"denied-type":          {policy: types.PeerPolicy{DenyTypes: []string{"friend_request"}}, data: request, code: codes.PermissionDenied, text: "friend_request payloads are not accepted"},
+		"other-than-denied":    {policy: types.PeerPolicy{DenyTypes: []string{"friend_request"}}, data: encenv},
+		"deny-beats-allow":     {policy: types.PeerPolicy{AllowTypes: []string{"encenv"}, DenyTypes: []string{"encenv"}}, data: encenv, code: codes.PermissionDenied, text: "encenv payloads"},
+		"garbage-type-rules":   {policy: types.PeerPolicy{AllowTypes: []string{"encenv"}}, data: []byte{0xff, 0xff}, code: codes.InvalidArgument, text: "not a payload"},
+		"garbage-no-rules":     {data: []byte{0xff, 0xff}},
+		"untyped-allow-list":   {policy: types.PeerPolicy{AllowTypes: []string{"encenv"}}, data: []byte{}, code: codes.PermissionDenied, text: " payloads are not accepted"},
+		"untyped-no-allow-set": {policy: types.PeerPolicy{DenyTypes: []string{"encenv"}}, data: []byte{}},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			peer := types.PeerConfig{ID: uuid.New(), Name: "peer", Policy: tc.policy}
+			s := &StrikeServer{PeerMgr: NewPeerManager([]types.PeerConfig{peer}, "self")}
+
+			err := s.checkRelayPolicy(context.Background(), peer, &fedpb.RelayPayload{PayloadData: tc.data})
+			if tc.code == codes.OK {
+				if err != nil {
+					t.Fatalf("refused: %v", err)
+				}
+				return
+			}
+			wantCode(t, err, tc.code, tc.text)
+		})
+	}
+}
+
+func TestCheckRelayPolicyRate(t *testing.T) {
+	peer := types.PeerConfig{ID: uuid.New(), Name: "peer", Policy: types.PeerPolicy{RelayRate: 0.001, RelayBurst: 2}}
+	other := types.PeerConfig{ID: uuid.New(), Name: "other", Policy: types.PeerPolicy{RelayRate: 0.001, RelayBurst: 2}}
+	s := &StrikeServer{PeerMgr: NewPeerManager([]types.PeerConfig{peer, other}, "self")}
+	rp := &fedpb.RelayPayload{}
+
+	for i := range 2 {
+		if err := s.checkRelayPolicy(context.Background(), peer, rp); err != nil {
+			t.Fatalf("relay %d within the burst refused: %v", i+1, err)
+		}
+	}
+	wantCode(t, s.checkRelayPolicy(context.Background(), peer, rp), codes.ResourceExhausted, "relay rate limit exceeded for peer")
+	if err := s.checkRelayPolicy(context.Background(), other, rp); err != nil {
+		t.Errorf("another peer's relays limited: %v", err)
+	}
+}
+
+func TestRelayRefusedByPolicy(t *testing.T) {
+	ca := newTestCA(t)
+	caller := newTestPeer(t, ca, "caller")
+	request := marshalPayload(t, &pb.StreamPayload{Payload: &pb.StreamPayload_FriendRequest{FriendRequest: &pb.FriendRequest{}}})
+
+	cases := map[string]struct {
+		policy types.PeerPolicy
+		info   string
+	}{
+		"outbound-only": {policy: types.PeerPolicy{Direction: types.DirectionOutbound}, info: "does not accept payloads from caller"},
+		"denied-type":   {policy: types.PeerPolicy{DenyTypes: []string{"friend_request"}}, info: "friend_request payloads are not accepted from caller"},
+		"too-large":     {policy: types.PeerPolicy{MaxSize: 1}, info: "larger than 1 bytes"},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := types.PeerConfig{ID: uuid.New(), Name: "caller", PubKey: caller.pub(), Policy: tc.policy}
+			s := &StrikeServer{PeerMgr: NewPeerManager([]types.PeerConfig{cfg}, "self")}
+			s.mapInit()
+			fo := NewFederationOrchestrator(s)
+
+			sender := uuid.New()
+			ack, err := fo.Relay(peerContext(caller.cert), &fedpb.RelayPayload{
+				EnvelopeId:   "env-1",
+				OriginServer: cfg.ID.String(),
+				Sender:       &common_pb.UserAddress{Domain: "caller", UInfo: &common_pb.UserInfo{UserId: sender.String()}},
+				Recipient:    &common_pb.UserAddress{Domain: "self", UInfo: &common_pb.UserInfo{Username: "bob"}},
+				PayloadData:  request,
+			})
+			if err != nil {
+				t.Fatalf("relay: %v", err)
+			}
+			if ack.Accepted || ack.EnvelopeId != "env-1" || !strings.Contains(ack.Info, tc.info) {
+				t.Fatalf("ack = %+v, want refusal containing %q", ack, tc.info)
+			}
+
+			// A refused relay leaves no trace of the sender.
+			s.mu.Lock()
+			_, seen := s.RemotePresence[sender]
+			s.mu.Unlock()
+			if seen {
+				t.Error("presence recorded for a refused relay")
+			}
+		})
+	}
+}
+
+func TestCheckLookupPolicy(t *testing.T) {
+	ca := newTestCA(t)
+	denied := newTestPeer(t, ca, "denied")
+	allowed := newTestPeer(t, ca, "allowed")
+	unlisted := newTestPeer(t, ca, "unlisted")
+
+	s := &StrikeServer{PeerMgr: NewPeerManager([]types.PeerConfig{
+		{ID: uuid.New(), Name: "denied", PubKey: denied.pub(), Policy: types.PeerPolicy{DenyLookups: true}},
+		{ID: uuid.New(), Name: "allowed", PubKey: allowed.pub()},
+	}, "self")}
+	fo := NewFederationOrchestrator(s)
+
+	wantCode(t, fo.checkLookupPolicy(peerContext(denied.cert)), codes.PermissionDenied, "not allowed for denied")
+	for _, p := range []*testPeer{allowed, unlisted} {
+		if err := fo.checkLookupPolicy(peerContext(p.cert)); err != nil {
+			t.Errorf("%s refused: %v", p.name, err)
+		}
+	}
+
+	resp, err := fo.UserLookup(peerContext(denied.cert), &fedpb.UserLookupReq{Username: "bob"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("UserLookup = %v, %v, want PermissionDenied", resp, err)
+	}
+}
+
+// TestBlockedSendersRefused runs against STRIKE_TEST_DATABASE_URL.
+func TestBlockedSendersRefused(t *testing.T) {
+	ca := newTestCA(t)
+	caller := newTestPeer(t, ca, "caller")
+	cfg := types.PeerConfig{ID: uuid.New(), Name: "caller", PubKey: caller.pub()}
+	s := &StrikeServer{Name: "self", PeerMgr: NewPeerManager([]types.PeerConfig{cfg}, "self")}
+	useTestDatabase(t, s)
+	s.initLifecycle()
+	s.mapInit()
+	fo := NewFederationOrchestrator(s)
+	ctx := context.Background()
+	// Accepted relays start deliveries; let them finish before the
+	// database closes.
+	t.Cleanup(func() { _ = s.WaitDeliveries(ctx) })
+
+	blockedUser := uuid.NewString()
+	cases := map[string]struct {
+		domains, users []string
+		senderID       string
+		senderDomain   string
+		wantBlocked    bool
+	}{
+		"blocked-user":      {users: []string{blockedUser}, senderID: blockedUser, senderDomain: "caller", wantBlocked: true},
+		"blocked-domain":    {domains: []string{"spam.example"}, senderID: uuid.NewString(), senderDomain: "spam.example", wantBlocked: true},
+		"blocked-peer":      {domains: []string{"caller"}, senderID: uuid.NewString(), senderDomain: "elsewhere.example", wantBlocked: true},
+		"blocked-local":     {domains: []string{"self"}, senderID: uuid.NewString(), senderDomain: "self", wantBlocked: true},
+		"other-user":        {users: []string{blockedUser}, senderID: uuid.NewString(), senderDomain: "caller"},
+		"other-domain":      {domains: []string{"spam.example"}, senderID: uuid.NewString(), senderDomain: "caller"},
+		"empty-block-list":  {senderID: blockedUser, senderDomain: "caller"},
+		"local-not-blocked": {domains: []string{"spam.example"}, senderID: uuid.NewString(), senderDomain: "self"},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			recipient := uuid.New()
+			username := "blocking-" + recipient.String()[:8]
+			if _, err := s.DBpool.Exec(ctx, s.PStatements.User.CreateUser, recipient, username, "hash", []byte("salt")); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _, _ = s.DBpool.Exec(ctx, s.PStatements.User.DeleteUser, username) })
+			if _, err := s.DBpool.Exec(ctx, s.PStatements.Blocks.Save, recipient, append([]string{}, tc.domains...), append([]string{}, tc.users...), time.Now()); err != nil {
+				t.Fatal(err)
+			}
+
+			// A relay from the peer is refused outright...
+			if tc.senderDomain != s.Name {
+				ack, err := fo.Relay(peerContext(caller.cert), &fedpb.RelayPayload{
+					EnvelopeId:   "env-" + name,
+					OriginServer: cfg.ID.String(),
+					Sender:       &common_pb.UserAddress{Domain: tc.senderDomain, UInfo: &common_pb.UserInfo{UserId: tc.senderID}},
+					Recipient:    &common_pb.UserAddress{Domain: "self", UInfo: &common_pb.UserInfo{UserId: recipient.String(), Username: username}},
+					PayloadData:  marshalPayload(t, &pb.StreamPayload{Info: "hello"}),
+				})
+				if err != nil {
+					t.Fatalf("relay: %v", err)
+				}
+				if refused := !ack.Accepted && ack.Info == "blocked by recipient"; refused != tc.wantBlocked {
+					t.Fatalf("ack = %+v, want refused as blocked = %v", ack, tc.wantBlocked)
+				}
+				return
+			}
+
+			// ...and a queued local payload is dropped at delivery.
+			msgID := uuid.New()
+			s.mu.Lock()
+			s.Pending[msgID] = &types.PendingMsg{MessageID: msgID, From: uuid.MustParse(tc.senderID), To: recipient, Attempts: deliveryAttempts}
+			s.mu.Unlock()
+			s.attemptDelivery(ctx, msgID)
+			s.mu.Lock()
+			_, queued := s.Pending[msgID]
+			s.mu.Unlock()
+			if queued == tc.wantBlocked {
+				t.Fatalf("payload still queued = %v, want %v", queued, !tc.wantBlocked)
+			}
+		})
+	}
+}
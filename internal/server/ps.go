@@ -12,14 +12,66 @@ type ServerDB struct {
 		LoginUser  string
 		GetUser    string
 		SaltMine   string
+		DeleteUser string
+		RenameUser string
+		GetStatus  string
 	}
 
 	Keys struct {
 		GetPublicKeys    string
 		CreatePublicKeys string
+		RotateKeys       string
+		LatestSerial     string
+		SaveRotation     string
+		History          string
+	}
+
+	Pending struct {
+		SavePending  string
+		DrainPending string
+	}
+
+	Login struct {
+		GetLockout    string
+		RecordFailure string
+		SetLockout    string
+		ClearFailures string
+	}
+
+	Admin struct {
+		ListUsers   string
+		SetDisabled string
+		ResetKeys   string
+	}
+
+	Redirect struct {
+		Save  string
+		Get   string
+		Moved string
+	}
+
+	DeadLetter struct {
+		Save     string
+		List     string
+		TakeOne  string
+		TakeAll  string
+		PurgeOne string
+		PurgeAll string
+	}
+
+	Invite struct {
+		Redeem       string
+		PurgeExpired string
+	}
+
+	Blocks struct {
+		Save  string
+		Check string
 	}
 }
 
+const deadLetterColumns = "message_id, sender, recipient, sender_domain, target_domain, payload, created_at, failed_at, reason, correlation_id, trace_parent"
+
 // InitStatements stores the SQL strings directly.
 // pgxpool handles automatic statement caching per connection,
 // so explicit Prepare calls are not needed.
@@ -35,18 +87,126 @@ func InitStatements(ctx context.Context, dbpool *pgxpool.Pool) (*ServerDB, error
 			LoginUser  string
 			GetUser    string
 			SaltMine   string
+			DeleteUser string
+			RenameUser string
+			GetStatus  string
 		}{
 			CreateUser: "INSERT INTO users (user_id, username, password_hash, salt) VALUES ($1, $2, $3, $4)",
-			LoginUser:  "SELECT password_hash FROM users WHERE username = $1",
+			LoginUser:  "SELECT password_hash, disabled FROM users WHERE username = $1",
 			GetUser:    "SELECT user_id FROM users WHERE username = $1",
 			SaltMine:   "SELECT salt FROM users WHERE username = $1",
+			DeleteUser: "DELETE FROM users WHERE username = $1 RETURNING user_id",
+			RenameUser: "UPDATE users SET username = $2 WHERE username = $1",
+			GetStatus:  "SELECT username, disabled FROM users WHERE user_id = $1",
 		},
 		Keys: struct {
 			GetPublicKeys    string
 			CreatePublicKeys string
+			RotateKeys       string
+			LatestSerial     string
+			SaveRotation     string
+			History          string
 		}{
 			GetPublicKeys:    "SELECT encryption_public_key, signing_public_key FROM user_keys WHERE user_id = $1",
 			CreatePublicKeys: "INSERT INTO user_keys (user_id, encryption_public_key, signing_public_key) VALUES ($1, $2, $3)",
+			RotateKeys: `UPDATE user_keys SET encryption_public_key = $2, signing_public_key = $3
+				WHERE user_id = $1 AND signing_public_key = $4`,
+			LatestSerial: "SELECT COALESCE(MAX(serial), 0) FROM key_history WHERE user_id = $1",
+			SaveRotation: `INSERT INTO key_history (user_id, serial, encryption_public_key, signing_public_key, rotation)
+				VALUES ($1, $2, $3, $4, $5)`,
+			History: "SELECT rotation FROM key_history WHERE user_id = $1 ORDER BY serial",
+		},
+		Pending: struct {
+			SavePending  string
+			DrainPending string
+		}{
+			SavePending: `INSERT INTO pending_messages (message_id, sender, recipient, sender_domain, target_domain, payload, attempts, created_at, correlation_id, trace_parent)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (message_id) DO NOTHING`,
+			DrainPending: `DELETE FROM pending_messages
+				RETURNING message_id, sender, recipient, sender_domain, target_domain, payload, attempts, created_at, correlation_id, trace_parent`,
+		},
+		Login: struct {
+			GetLockout    string
+			RecordFailure string
+			SetLockout    string
+			ClearFailures string
+		}{
+			GetLockout: "SELECT locked_until FROM login_attempts WHERE username = $1",
+			RecordFailure: `INSERT INTO login_attempts (username, failures, last_failure) VALUES ($1, 1, now())
+				ON CONFLICT (username) DO UPDATE SET
+					failures = CASE WHEN login_attempts.last_failure < now() - $2::interval THEN 1 ELSE login_attempts.failures + 1 END,
+					last_failure = now()
+				RETURNING failures`,
+			SetLockout:    "UPDATE login_attempts SET locked_until = now() + $2::interval WHERE username = $1",
+			ClearFailures: "DELETE FROM login_attempts WHERE username = $1",
+		},
+		Admin: struct {
+			ListUsers   string
+			SetDisabled string
+			ResetKeys   string
+		}{
+			ListUsers: `SELECT user_id, username, disabled, created_at FROM users
+				WHERE strpos(username, $1) > 0 ORDER BY username LIMIT $2 OFFSET $3`,
+			SetDisabled: "UPDATE users SET disabled = $2 WHERE username = $1 RETURNING user_id",
+			ResetKeys: `INSERT INTO user_keys (user_id, encryption_public_key, signing_public_key)
+				SELECT user_id, $2, $3 FROM users WHERE username = $1
+				ON CONFLICT (user_id) DO UPDATE SET
+					encryption_public_key = EXCLUDED.encryption_public_key,
+					signing_public_key = EXCLUDED.signing_public_key
+				RETURNING user_id`,
+		},
+		Redirect: struct {
+			Save  string
+			Get   string
+			Moved string
+		}{
+			Save: `INSERT INTO account_redirects (domain, username, user_id, new_username, new_domain, notice)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				ON CONFLICT (domain, username) DO UPDATE SET
+					user_id = EXCLUDED.user_id,
+					new_username = EXCLUDED.new_username,
+					new_domain = EXCLUDED.new_domain,
+					notice = EXCLUDED.notice,
+					created_at = now()`,
+			Get:   "SELECT notice FROM account_redirects WHERE domain = $1 AND username = $2",
+			Moved: "SELECT user_id, new_domain FROM account_redirects WHERE new_domain <> domain ORDER BY created_at",
+		},
+		DeadLetter: struct {
+			Save     string
+			List     string
+			TakeOne  string
+			TakeAll  string
+			PurgeOne string
+			PurgeAll string
+		}{
+			Save: `INSERT INTO dead_letters (` + deadLetterColumns + `)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, now(), $8, $9, $10) ON CONFLICT (message_id) DO NOTHING`,
+			List:     "SELECT " + deadLetterColumns + " FROM dead_letters ORDER BY failed_at DESC LIMIT $1",
+			TakeOne:  "DELETE FROM dead_letters WHERE message_id = $1 RETURNING " + deadLetterColumns,
+			TakeAll:  "DELETE FROM dead_letters RETURNING " + deadLetterColumns,
+			PurgeOne: "DELETE FROM dead_letters WHERE message_id = $1",
+			PurgeAll: "DELETE FROM dead_letters",
+		},
+		Invite: struct {
+			Redeem       string
+			PurgeExpired string
+		}{
+			Redeem: `INSERT INTO federation_invites (nonce, peer_id, expires_at) VALUES ($1, $2, $3)
+				ON CONFLICT (nonce) DO NOTHING`,
+			PurgeExpired: "DELETE FROM federation_invites WHERE expires_at < now()",
+		},
+		Blocks: struct {
+			Save  string
+			Check string
+		}{
+			Save: `INSERT INTO user_blocks (user_id, domains, user_ids, updated_at) VALUES ($1, $2, $3, $4)
+				ON CONFLICT (user_id) DO UPDATE SET
+					domains = EXCLUDED.domains,
+					user_ids = EXCLUDED.user_ids,
+					updated_at = EXCLUDED.updated_at
+				WHERE user_blocks.updated_at < EXCLUDED.updated_at`,
+			Check: `SELECT EXISTS (SELECT 1 FROM user_blocks
+				WHERE user_id = $1 AND ($2 = ANY(domains) OR $3 = ANY(user_ids)))`,
 		},
 	}, nil
 }
@@ -0,0 +1,320 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/JohnnyGlynn/strike/internal/config"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+	pb "github.com/JohnnyGlynn/strike/msgdef/message"
+)
+
+// authMethods are the unauthenticated RPCs that can be used to enumerate
+// usernames or guess passwords, so they draw from the stricter auth bucket.
+var authMethods = map[string]bool{
+	pb.Strike_Signup_FullMethodName:        true,
+	pb.Strike_Login_FullMethodName:         true,
+	pb.Strike_SaltMine_FullMethodName:      true,
+	pb.Strike_UserRequest_FullMethodName:   true,
+	pb.Strike_DeleteAccount_FullMethodName: true,
+	pb.Strike_RenameAccount_FullMethodName: true,
+	pb.Strike_MoveAccount_FullMethodName:   true,
+	pb.Strike_RotateKeys_FullMethodName:    true,
+}
+
+// RateLimiter holds per-user, per-IP and per-IP auth token buckets, and the
+// per-sender friend request buckets.
+type RateLimiter struct {
+	user           *limiterSet
+	ip             *limiterSet
+	auth           *limiterSet
+	friendRequests *limiterSet
+
+	maxPendingPerUser int
+	loginMaxFailures  int
+	loginLockout      time.Duration
+	loginLockoutMax   time.Duration
+}
+
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		user:              newLimiterSet(cfg.UserRate, cfg.UserBurst),
+		ip:                newLimiterSet(cfg.IPRate, cfg.IPBurst),
+		auth:              newLimiterSet(cfg.AuthRate, cfg.AuthBurst),
+		friendRequests:    newLimiterSet(cfg.FriendRequestRate, cfg.FriendRequestBurst),
+		maxPendingPerUser: cfg.MaxPendingPerUser,
+		loginMaxFailures:  cfg.LoginMaxFailures,
+		loginLockout:      time.Duration(cfg.LoginLockout),
+		loginLockoutMax:   time.Duration(cfg.LoginLockoutMax),
+	}
+}
+
+// limiterSet is a keyed set of token buckets. Buckets that have refilled are
+// indistinguishable from new ones, so they are swept to bound memory.
+type limiterSet struct {
+	limit rate.Limit
+	burst int
+	idle  time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	lim  *rate.Limiter
+	seen time.Time
+}
+
+// newLimiterSet returns nil, which allows everything, for a negative rate.
+func newLimiterSet(perSecond float64, burst int) *limiterSet {
+	if perSecond < 0 {
+		return nil
+	}
+	return &limiterSet{
+		limit:   rate.Limit(perSecond),
+		burst:   burst,
+		idle:    time.Duration(float64(burst) / perSecond * float64(time.Second)),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token for key, or reports how long until one is available.
+func (l *limiterSet) allow(key string) (bool, time.Duration) {
+	return l.allowAt(key, time.Now())
+}
+
+// allowAt is allow as of now.
+func (l *limiterSet) allowAt(key string, now time.Time) (bool, time.Duration) {
+	if l == nil || key == "" {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > time.Minute {
+		for k, b := range l.buckets {
+			if now.Sub(b.seen) > l.idle {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{lim: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.seen = now
+
+	r := b.lim.ReserveN(now, 1)
+	if !r.OK() {
+		return false, l.idle
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// rateLimited builds the ResourceExhausted status returned to clients, with
+// a RetryInfo detail so they can tell the user when to try again.
+func rateLimited(msg string, retry time.Duration) error {
+	return withDetails(status.New(codes.ResourceExhausted, msg),
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(retry.Round(time.Second))})
+}
+
+// allowFriendRequest takes a friend request token for sender, which is a
+// user ID for local senders and domain/user ID for relayed ones.
+func (s *StrikeServer) allowFriendRequest(method, sender string) error {
+	if s.Limits == nil {
+		return nil
+	}
+	if ok, retry := s.Limits.friendRequests.allow(sender); !ok {
+		s.Metrics.observeRateLimited(method, "friend_request")
+		return rateLimited("too many friend requests, try again later", retry)
+	}
+	return nil
+}
+
+// UnaryRateLimit applies the per-IP (or auth) bucket and then the per-user
+// bucket to every unary Strike RPC.
+func (s *StrikeServer) UnaryRateLimit(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if s.Limits == nil {
+		return handler(ctx, req)
+	}
+
+	ipSet, scope := s.Limits.ip, "ip"
+	if authMethods[info.FullMethod] {
+		ipSet, scope = s.Limits.auth, "auth"
+	}
+
+	if ok, retry := ipSet.allow(peerIP(ctx)); !ok {
+		s.Metrics.observeRateLimited(info.FullMethod, scope)
+		return nil, rateLimited("too many requests from this address", retry)
+	}
+
+	if ok, retry := s.Limits.user.allow(requestUser(req)); !ok {
+		s.Metrics.observeRateLimited(info.FullMethod, "user")
+		return nil, rateLimited("too many requests for this user", retry)
+	}
+
+	return handler(ctx, req)
+}
+
+// StreamRateLimit applies the per-IP bucket to stream opens. The request
+// message is not read until the handler runs, so there is no per-user check.
+func (s *StrikeServer) StreamRateLimit(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if s.Limits == nil {
+		return handler(srv, ss)
+	}
+
+	if ok, retry := s.Limits.ip.allow(peerIP(ss.Context())); !ok {
+		s.Metrics.observeRateLimited(info.FullMethod, "ip")
+		return rateLimited("too many requests from this address", retry)
+	}
+
+	return handler(srv, ss)
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// requestUser is the user a request acts as. Clients assert this themselves,
+// which is why the per-IP bucket is always checked first.
+func requestUser(req any) string {
+	switch r := req.(type) {
+	case *pb.StreamPayload:
+		return r.Sender
+	case *pb.LoginVerify:
+		return r.Username
+	case *pb.InitUser:
+		return r.Username
+	case *pb.DeleteAccountRequest:
+		return r.Username
+	case *pb.MoveAccountRequest:
+		return r.Username
+	case *pb.RotateKeysRequest:
+		return r.Username
+	case *common_pb.BlockList:
+		return r.UserId
+	case *common_pb.UserInfo:
+		if r.UserId != "" {
+			return r.UserId
+		}
+		return r.Username
+	default:
+		return ""
+	}
+}
+
+// pendingFromLocked counts queued payloads from sender. Callers hold s.mu.
+func (s *StrikeServer) pendingFromLocked(sender string) int {
+	n := 0
+	for _, pmsg := range s.Pending {
+		if pmsg.From.String() == sender {
+			n++
+		}
+	}
+	return n
+}
+
+// checkLockout rejects a login while the account is locked out.
+func (s *StrikeServer) checkLockout(ctx context.Context, username string) error {
+	if s.Limits == nil {
+		return nil
+	}
+
+	var lockedUntil *time.Time
+	err := s.DBpool.QueryRow(ctx, s.PStatements.Login.GetLockout, username).Scan(&lockedUntil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return dbError("check lockout", "user", username, err)
+	}
+
+	if lockedUntil != nil {
+		if wait := time.Until(*lockedUntil); wait > 0 {
+			return rateLimited("too many failed logins, account temporarily locked", wait)
+		}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login and, past the configured number
+// of failures, locks the account for a period that doubles with each further
+// failure. Failures older than the longest lockout are forgotten.
+func (s *StrikeServer) recordLoginFailure(ctx context.Context, username string) {
+	if s.Limits == nil {
+		return
+	}
+	rl := s.Limits
+
+	var failures int
+	err := s.DBpool.QueryRow(ctx, s.PStatements.Login.RecordFailure, username, rl.loginLockoutMax).Scan(&failures)
+	if err != nil {
+		slog.ErrorContext(ctx, "record login failure", "error", err)
+		return
+	}
+
+	lockout, locked := rl.lockoutAfter(failures)
+	if !locked {
+		return
+	}
+
+	if _, err := s.DBpool.Exec(ctx, s.PStatements.Login.SetLockout, username, lockout); err != nil {
+		slog.ErrorContext(ctx, "set login lockout", "error", err)
+		return
+	}
+	slog.WarnContext(ctx, "login locked out", "username", username, "lockout", lockout, "failures", failures)
+}
+
+// lockoutAfter is how long an account is locked after its failures-th
+// recent failed login: loginLockout at loginMaxFailures, doubling with each
+// further failure up to loginLockoutMax.
+func (rl *RateLimiter) lockoutAfter(failures int) (time.Duration, bool) {
+	over := failures - rl.loginMaxFailures
+	if over < 0 {
+		return 0, false
+	}
+	if over >= 32 {
+		return rl.loginLockoutMax, true
+	}
+	return time.Duration(math.Min(float64(rl.loginLockout)*math.Pow(2, float64(over)), float64(rl.loginLockoutMax))), true
+}
+
+func (s *StrikeServer) clearLoginFailures(ctx context.Context, username string) {
+	if s.Limits == nil {
+		return
+	}
+	if _, err := s.DBpool.Exec(ctx, s.PStatements.Login.ClearFailures, username); err != nil {
+		slog.ErrorContext(ctx, "clear login failures", "error", err)
+	}
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/JohnnyGlynn/strike/internal/config"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+	pb "github.com/JohnnyGlynn/strike/msgdef/message"
+)
+
+func TestLimiterSetRefill(t *testing.T) {
+	l := newLimiterSet(2, 3) // a token every 500ms, three at once
+	t0 := time.Now()
+
+	for i := range 3 {
+		if ok, _ := l.allowAt("a", t0); !ok {
+			t.Fatalf("request %d within the burst refused", i+1)
+		}
+	}
+
+	// Refused requests do not take a token, so retrying does not push the
+	// next one further away.
+	for range 3 {
+		ok, retry := l.allowAt("a", t0)
+		if ok {
+			t.Fatal("request past the burst allowed")
+		}
+		if retry != 500*time.Millisecond {
+			t.Fatalf("retry = %s, want 500ms", retry)
+		}
+	}
+	if ok, retry := l.allowAt("a", t0.Add(250*time.Millisecond)); ok || retry != 250*time.Millisecond {
+		t.Fatalf("half refilled: ok = %v, retry = %s, want refused for 250ms", ok, retry)
+	}
+
+	// Other keys have their own bucket.
+	if ok, _ := l.allowAt("b", t0); !ok {
+		t.Fatal("another key refused")
+	}
+
+	if ok, _ := l.allowAt("a", t0.Add(500*time.Millisecond)); !ok {
+		t.Fatal("refilled token refused")
+	}
+	if ok, _ := l.allowAt("a", t0.Add(500*time.Millisecond)); ok {
+		t.Fatal("one refilled token allowed twice")
+	}
+
+	// A long rest refills to the burst and no further.
+	later := t0.Add(time.Hour)
+	for i := range 3 {
+		if ok, _ := l.allowAt("a", later); !ok {
+			t.Fatalf("request %d after a rest refused", i+1)
+		}
+	}
+	if ok, _ := l.allowAt("a", later); ok {
+		t.Fatal("bucket refilled past its burst")
+	}
+}
+
+func TestLimiterSetUnlimited(t *testing.T) {
+	if l := newLimiterSet(-1, 5); l != nil {
+		t.Fatal("negative rate did not disable the limit")
+	}
+
+	var l *limiterSet
+	for range 100 {
+		if ok, _ := l.allow("a"); !ok {
+			t.Fatal("disabled limit refused a request")
+		}
+	}
+
+	// Requests with no key, such as an unknown peer address, are not
+	// limited.
+	l = newLimiterSet(1, 1)
+	for range 3 {
+		if ok, _ := l.allow(""); !ok {
+			t.Fatal("request without a key refused")
+		}
+	}
+	if len(l.buckets) != 0 {
+		t.Fatal("bucket kept for an empty key")
+	}
+}
+
+func TestLimiterSetEvictsIdleBuckets(t *testing.T) {
+	l := newLimiterSet(1, 5) // full again after 5s idle
+	t0 := time.Now()
+
+	for range 5 {
+		l.allowAt("idle", t0)
+	}
+	l.allowAt("also-idle", t0.Add(30*time.Second))
+	if len(l.buckets) != 2 {
+		t.Fatalf("buckets = %d before a sweep is due, want 2", len(l.buckets))
+	}
+
+	// Sweeps run at most once a minute and drop buckets unused for longer
+	// than it takes them to refill.
+	l.allowAt("recent", t0.Add(2*time.Minute))
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("idle bucket kept")
+	}
+	if _, ok := l.buckets["also-idle"]; ok {
+		t.Error("idle bucket kept")
+	}
+
+	l.allowAt("busy", t0.Add(3*time.Minute-2*time.Second))
+	l.allowAt("new", t0.Add(3*time.Minute+time.Second))
+	if _, ok := l.buckets["recent"]; ok {
+		t.Error("bucket idle for a minute kept")
+	}
+	if _, ok := l.buckets["busy"]; !ok {
+		t.Error("bucket used within its refill time evicted")
+	}
+
+	// An evicted bucket starts full, as it would have been anyway.
+	for i := range 5 {
+		if ok, _ := l.allowAt("idle", t0.Add(3*time.Minute+time.Second)); !ok {
+			t.Fatalf("request %d to an evicted bucket refused", i+1)
+		}
+	}
+}
+
+func TestLockoutAfter(t *testing.T) {
+	rl := NewRateLimiter(config.RateLimitConfig{
+		LoginMaxFailures: 3,
+		LoginLockout:     config.Duration(time.Minute),
+		LoginLockoutMax:  config.Duration(10 * time.Minute),
+	})
+
+	cases := map[string]struct {
+		failures int
+		lockout  time.Duration // 0 when not locked
+	}{
+		"first-failure":    {failures: 1},
+		"below-threshold":  {failures: 2},
+		"threshold":        {failures: 3, lockout: time.Minute},
+		"one-more":         {failures: 4, lockout: 2 * time.Minute},
+		"two-more":         {failures: 5, lockout: 4 * time.Minute},
+		"three-more":       {failures: 6, lockout: 8 * time.Minute},
+		"capped":           {failures: 7, lockout: 10 * time.Minute},
+		"past-float-range": {failures: 3 + 40, lockout: 10 * time.Minute},
+		"huge":             {failures: 1 << 30, lockout: 10 * time.Minute},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, locked := rl.lockoutAfter(tc.failures)
+			if locked != (tc.lockout > 0) || got != tc.lockout {
+				t.Errorf("lockoutAfter(%d) = %s, %v, want %s", tc.failures, got, locked, tc.lockout)
+			}
+		})
+	}
+}
+
+func TestUnaryRateLimit(t *testing.T) {
+	s := &StrikeServer{Limits: NewRateLimiter(config.RateLimitConfig{
+		UserRate: 0.001, UserBurst: 2,
+		IPRate: 0.001, IPBurst: 3,
+		AuthRate: 0.001, AuthBurst: 1,
+	})}
+
+	// call makes a request from 198.51.100.ip.
+	call := func(ip byte, method string, req any) error {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(198, 51, 100, ip), Port: 4000}})
+		_, err := s.UnaryRateLimit(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, any) (any, error) {
+			return nil, nil
+		})
+		return err
+	}
+	wantLimited := func(err error, text string) {
+		t.Helper()
+		wantCode(t, err, codes.ResourceExhausted, text)
+		for _, d := range status.Convert(err).Details() {
+			if ri, ok := d.(*errdetails.RetryInfo); ok && ri.RetryDelay.AsDuration() > 0 {
+				return
+			}
+		}
+		t.Errorf("no retry delay in %v", err)
+	}
+
+	// Login draws from the auth bucket, so one attempt uses it up without
+	// touching the general per-IP bucket.
+	login := &pb.LoginVerify{Username: "alice"}
+	if err := call(1, pb.Strike_Login_FullMethodName, login); err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	wantLimited(call(1, pb.Strike_Login_FullMethodName, login), "from this address")
+	if err := call(1, pb.Strike_SaltMine_FullMethodName, &common_pb.UserInfo{Username: "bob"}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("SaltMine not in the auth bucket: %v", err)
+	}
+	if err := call(1, pb.Strike_SendPayload_FullMethodName, &pb.StreamPayload{Sender: uuid.NewString()}); err != nil {
+		t.Errorf("general request refused after the auth bucket ran out: %v", err)
+	}
+
+	// The per-user bucket follows the user across addresses.
+	sender := uuid.NewString()
+	for ip := byte(10); ip < 12; ip++ {
+		if err := call(ip, pb.Strike_SendPayload_FullMethodName, &pb.StreamPayload{Sender: sender}); err != nil {
+			t.Fatalf("send from .%d: %v", ip, err)
+		}
+	}
+	wantLimited(call(12, pb.Strike_SendPayload_FullMethodName, &pb.StreamPayload{Sender: sender}), "for this user")
+
+	// The per-IP bucket is checked first, so a refused address does not
+	// spend the user's tokens.
+	for i := range 3 {
+		if err := call(20, pb.Strike_SendPayload_FullMethodName, &pb.StreamPayload{Sender: uuid.NewString()}); err != nil {
+			t.Fatalf("request %d from .20: %v", i+1, err)
+		}
+	}
+	other := uuid.NewString()
+	wantLimited(call(20, pb.Strike_SendPayload_FullMethodName, &pb.StreamPayload{Sender: other}), "from this address")
+	for ip := byte(30); ip < 32; ip++ {
+		if err := call(ip, pb.Strike_SendPayload_FullMethodName, &pb.StreamPayload{Sender: other}); err != nil {
+			t.Fatalf("user's tokens spent by a refused request: %v", err)
+		}
+	}
+}
+
+// TestLoginLockout runs against STRIKE_TEST_DATABASE_URL, where the failure
+// counts are kept.
+func TestLoginLockout(t *testing.T) {
+	s := &StrikeServer{Limits: NewRateLimiter(config.RateLimitConfig{
+		LoginMaxFailures: 2,
+		LoginLockout:     config.Duration(time.Minute),
+		LoginLockoutMax:  config.Duration(time.Hour),
+	})}
+	useTestDatabase(t, s)
+	ctx := context.Background()
+	username := "lockout-" + uuid.NewString()
+	t.Cleanup(func() { s.clearLoginFailures(ctx, username) })
+
+	s.recordLoginFailure(ctx, username)
+	if err := s.checkLockout(ctx, username); err != nil {
+		t.Fatalf("locked after one failure: %v", err)
+	}
+	s.recordLoginFailure(ctx, username)
+	wantCode(t, s.checkLockout(ctx, username), codes.ResourceExhausted, "temporarily locked")
+
+	// A successful login clears the count, so the next failure starts over.
+	s.clearLoginFailures(ctx, username)
+	if err := s.checkLockout(ctx, username); err != nil {
+		t.Fatalf("still locked after a successful login: %v", err)
+	}
+	s.recordLoginFailure(ctx, username)
+	if err := s.checkLockout(ctx, username); err != nil {
+		t.Fatalf("failures not reset by a successful login: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/JohnnyGlynn/strike/internal/shared"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+	fedpb "github.com/JohnnyGlynn/strike/msgdef/federation"
+	pb "github.com/JohnnyGlynn/strike/msgdef/message"
+)
+
+// RotateKeys replaces the caller's public keys. The rotation must be signed
+// by the current signing key and the new one, and follow the last recorded
+// rotation. It is kept in key_history and forwarded to every address in
+// notify.
+func (s *StrikeServer) RotateKeys(ctx context.Context, req *pb.RotateKeysRequest) (*pb.ServerResponse, error) {
+	if req.GetUsername() == "" {
+		return nil, invalidArgument("username", "missing username")
+	}
+	r := req.GetRotation()
+	if r == nil {
+		return nil, invalidArgument("rotation", "missing signed rotation")
+	}
+	if !isPublicKeyPEM(r.NewEncryptionPublicKey) || !isPublicKeyPEM(r.NewSigningPublicKey) {
+		return nil, invalidArgument("rotation", "new keys must be PEM public keys")
+	}
+	if r.RotatedAt == nil {
+		return nil, invalidArgument("rotation", "missing rotated_at")
+	}
+	if skew := time.Since(r.RotatedAt.AsTime()).Abs(); skew > noticeClockSkew {
+		return nil, invalidArgument("rotation", "rotated_at too far from server time")
+	}
+	if len(req.Notify) > maxNotices {
+		return nil, invalidArgument("notify", fmt.Sprintf("at most %d addresses", maxNotices))
+	}
+	if s.isDraining() {
+		return nil, shuttingDown()
+	}
+
+	uInfo, err := s.reauthenticate(ctx, req.Username, req.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+	if r.UserId != uInfo.UserId {
+		return nil, invalidArgument("rotation", "does not match the account")
+	}
+	if err := shared.VerifyKeyRotation(r, uInfo.SigningPublicKey); err != nil {
+		return nil, invalidArgument("rotation", err.Error())
+	}
+
+	id := uuid.MustParse(uInfo.UserId)
+	if err := s.saveRotation(ctx, id, r); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if u, ok := s.Connected[id]; ok {
+		u.EncryptionPublicKey = r.NewEncryptionPublicKey
+		u.SigningPublicKey = r.NewSigningPublicKey
+	}
+	s.mu.Unlock()
+
+	queued := s.queueNotices(ctx, id, req.Notify, &pb.StreamPayload{
+		Payload: &pb.StreamPayload_KeyRotation{KeyRotation: r},
+		Info:    "Key rotation notice",
+	})
+
+	slog.InfoContext(ctx, "keys rotated", "username", req.Username, "user_id", id, "serial", r.Serial, "notices", queued)
+	return &pb.ServerResponse{Success: true, Message: fmt.Sprintf("keys rotated (serial %d), %d friends notified", r.Serial, queued)}, nil
+}
+
+// saveRotation swaps the user's keys and records the rotation together.
+func (s *StrikeServer) saveRotation(ctx context.Context, id uuid.UUID, r *common_pb.KeyRotation) error {
+	raw, err := proto.Marshal(r)
+	if err != nil {
+		return internalError("rotate keys: marshal", err)
+	}
+
+	tx, err := s.DBpool.Begin(ctx)
+	if err != nil {
+		return dbError("rotate keys", "keys for user", id.String(), err)
+	}
+	defer tx.Rollback(ctx) // no-op after commit
+
+	var latest uint64
+	if err := tx.QueryRow(ctx, s.PStatements.Keys.LatestSerial, id).Scan(&latest); err != nil {
+		return dbError("rotate keys: serial", "keys for user", id.String(), err)
+	}
+	if r.Serial != latest+1 {
+		return failedPrecondition("rotation", fmt.Sprintf("serial must be %d", latest+1))
+	}
+
+	tag, err := tx.Exec(ctx, s.PStatements.Keys.RotateKeys, id, r.NewEncryptionPublicKey, r.NewSigningPublicKey, r.OldSigningPublicKey)
+	if err != nil {
+		return dbError("rotate keys: update", "keys for user", id.String(), err)
+	}
+	if tag.RowsAffected() != 1 {
+		return failedPrecondition("rotation", "signing key changed concurrently")
+	}
+
+	_, err = tx.Exec(ctx, s.PStatements.Keys.SaveRotation, id, r.Serial, r.NewEncryptionPublicKey, r.NewSigningPublicKey, raw)
+	if err != nil {
+		return dbError("rotate keys: history", "rotation", fmt.Sprintf("%s/%d", id, r.Serial), err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return dbError("rotate keys: commit", "keys for user", id.String(), err)
+	}
+	return nil
+}
+
+// KeyHistory returns a user's key rotations, oldest first, asking the
+// user's home server when they live elsewhere.
+func (s *StrikeServer) KeyHistory(ctx context.Context, addr *common_pb.UserAddress) (*pb.KeyHistoryResponse, error) {
+	if addr.GetUsername() == "" {
+		return nil, invalidArgument("username", "missing username")
+	}
+
+	if addr.Domain != "" && addr.Domain != s.Name {
+		client, ok := s.PeerMgr.ClientByName(addr.Domain)
+		if !ok {
+			return nil, notFound("domain", addr.Domain)
+		}
+		resp, err := client.KeyHistory(ctx, &fedpb.UserLookupReq{Username: addr.Username})
+		if err != nil {
+			return nil, peerUnavailable(addr.Domain, err)
+		}
+		if !resp.Found {
+			return nil, notFound("user", shared.FormatAddress(addr.Username, addr.Domain))
+		}
+		return &pb.KeyHistoryResponse{Rotations: resp.Rotations}, nil
+	}
+
+	rotations, err := s.localKeyHistory(ctx, addr.Username)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.KeyHistoryResponse{Rotations: rotations}, nil
+}
+
+func (s *StrikeServer) localKeyHistory(ctx context.Context, username string) ([]*common_pb.KeyRotation, error) {
+	var id uuid.UUID
+	if err := s.DBpool.QueryRow(ctx, s.PStatements.User.GetUser, username).Scan(&id); err != nil {
+		return nil, dbError("key history", "user", username, err)
+	}
+
+	rows, err := s.DBpool.Query(ctx, s.PStatements.Keys.History, id)
+	if err != nil {
+		return nil, dbError("key history", "user", username, err)
+	}
+	defer rows.Close()
+
+	var rotations []*common_pb.KeyRotation
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, dbError("key history: scan", "user", username, err)
+		}
+		r := &common_pb.KeyRotation{}
+		if err := proto.Unmarshal(raw, r); err != nil {
+			return nil, internalError("key history: unmarshal", err)
+		}
+		rotations = append(rotations, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("key history", "user", username, err)
+	}
+	return rotations, nil
+}
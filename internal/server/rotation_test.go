@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/shared"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+)
+
+// TestSaveRotationRefusesReplay runs against STRIKE_TEST_DATABASE_URL.
+func TestSaveRotationRefusesReplay(t *testing.T) {
+	s := &StrikeServer{Name: "a.example"}
+	useTestDatabase(t, s)
+	ctx := context.Background()
+
+	type signingKey struct {
+		priv ed25519.PrivateKey
+		pub  []byte
+	}
+	newKey := func() signingKey {
+		privPEM, pubPEM, err := keys.GenerateSigningKeyPEM()
+		if err != nil {
+			t.Fatal(err)
+		}
+		priv, err := keys.ParseSigningPrivateKey(privPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signingKey{priv: priv, pub: pubPEM}
+	}
+	id := uuid.New()
+	rotation := func(serial uint64, old, next signingKey) *common_pb.KeyRotation {
+		r := &common_pb.KeyRotation{
+			UserId:                 id.String(),
+			Serial:                 serial,
+			OldSigningPublicKey:    old.pub,
+			NewEncryptionPublicKey: []byte("enc"),
+			NewSigningPublicKey:    next.pub,
+			RotatedAt:              timestamppb.Now(),
+		}
+		msg := shared.KeyRotationBytes(r)
+		r.Signature = ed25519.Sign(old.priv, msg)
+		r.NewSignature = ed25519.Sign(next.priv, msg)
+		return r
+	}
+
+	k0, k1, k2 := newKey(), newKey(), newKey()
+	username := "rotating-" + id.String()[:8]
+	if _, err := s.DBpool.Exec(ctx, s.PStatements.User.CreateUser, id, username, "hash", []byte("salt")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _, _ = s.DBpool.Exec(ctx, s.PStatements.User.DeleteUser, username) })
+	if _, err := s.DBpool.Exec(ctx, s.PStatements.Keys.CreatePublicKeys, id, []byte("enc"), k0.pub); err != nil {
+		t.Fatal(err)
+	}
+
+	r1 := rotation(1, k0, k1)
+	if err := s.saveRotation(ctx, id, r1); err != nil {
+		t.Fatalf("first rotation: %v", err)
+	}
+	wantCode(t, s.saveRotation(ctx, id, r1), codes.FailedPrecondition, "serial must be 2")
+
+	// A fresh rotation from the replaced key, with the next serial, no
+	// longer matches the stored signing key.
+	wantCode(t, s.saveRotation(ctx, id, rotation(2, k0, k2)), codes.FailedPrecondition, "signing key changed")
+
+	if err := s.saveRotation(ctx, id, rotation(2, k1, k2)); err != nil {
+		t.Fatalf("second rotation: %v", err)
+	}
+	history, err := s.localKeyHistory(ctx, username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := shared.FollowKeyChain(k0.pub, history); err != nil {
+		t.Fatalf("stored history does not chain: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// schemaStatements brings a database created from an older config/db/init.sql
+// up to date. Every statement must be idempotent: they run on each startup.
+var schemaStatements = []string{
+	`CREATE TABLE IF NOT EXISTS pending_messages (
+		message_id UUID PRIMARY KEY,
+		sender UUID NOT NULL,
+		recipient UUID NOT NULL,
+		sender_domain TEXT NOT NULL DEFAULT '',
+		target_domain TEXT NOT NULL DEFAULT '',
+		payload BYTEA NOT NULL,
+		attempts INTEGER NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS login_attempts (
+		username TEXT PRIMARY KEY,
+		failures INTEGER NOT NULL,
+		last_failure TIMESTAMPTZ NOT NULL,
+		locked_until TIMESTAMPTZ
+	)`,
+	`ALTER TABLE pending_messages ADD COLUMN IF NOT EXISTS correlation_id TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE pending_messages ADD COLUMN IF NOT EXISTS trace_parent TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT false`,
+	`CREATE TABLE IF NOT EXISTS dead_letters (
+		message_id UUID PRIMARY KEY,
+		sender UUID NOT NULL,
+		recipient UUID NOT NULL,
+		sender_domain TEXT NOT NULL DEFAULT '',
+		target_domain TEXT NOT NULL DEFAULT '',
+		payload BYTEA NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		failed_at TIMESTAMPTZ NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		correlation_id TEXT NOT NULL DEFAULT '',
+		trace_parent TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE TABLE IF NOT EXISTS account_redirects (
+		domain TEXT NOT NULL,
+		username TEXT NOT NULL,
+		user_id UUID NOT NULL,
+		new_username TEXT NOT NULL,
+		new_domain TEXT NOT NULL,
+		notice BYTEA NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		PRIMARY KEY (domain, username)
+	)`,
+	`CREATE TABLE IF NOT EXISTS key_history (
+		user_id UUID REFERENCES users(user_id) ON DELETE CASCADE,
+		serial BIGINT NOT NULL,
+		encryption_public_key BYTEA NOT NULL,
+		signing_public_key BYTEA NOT NULL,
+		rotation BYTEA NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		PRIMARY KEY (user_id, serial)
+	)`,
+	`CREATE TABLE IF NOT EXISTS federation_invites (
+		nonce BYTEA PRIMARY KEY,
+		peer_id TEXT NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		used_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE TABLE IF NOT EXISTS user_blocks (
+		user_id UUID PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+		domains TEXT[] NOT NULL DEFAULT '{}',
+		user_ids TEXT[] NOT NULL DEFAULT '{}',
+		updated_at TIMESTAMPTZ NOT NULL
+	)`,
+}
+
+func EnsureSchema(ctx context.Context, dbpool *pgxpool.Pool) error {
+	for _, stmt := range schemaStatements {
+		if _, err := dbpool.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("ensure schema: %w", err)
+		}
+	}
+	return nil
+}
@@ -2,24 +2,37 @@ package server
 
 import (
 	"context"
+	"crypto/ed25519"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/JohnnyGlynn/strike/internal/logging"
 	"github.com/JohnnyGlynn/strike/internal/server/types"
 	"github.com/JohnnyGlynn/strike/internal/shared"
+	"github.com/JohnnyGlynn/strike/internal/tracing"
 	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
 	fedpb "github.com/JohnnyGlynn/strike/msgdef/federation"
 	pb "github.com/JohnnyGlynn/strike/msgdef/message"
 )
 
+// deliveryAttempts is how many times a payload is tried before it is moved to
+// the dead-letter table.
+const deliveryAttempts = 3
+
 type StrikeServer struct {
 	pb.UnimplementedStrikeServer
 
@@ -38,6 +51,42 @@ type StrikeServer struct {
 	Pending        map[uuid.UUID]*types.PendingMsg
 	mu             sync.Mutex
 	RemotePresence map[uuid.UUID]string
+
+	// kicks holds a channel per connected user, closed by disconnect to end
+	// their streams when an admin disables or deletes the account.
+	kicks map[uuid.UUID]chan struct{}
+
+	// moved maps users who have moved server to their new domain, so
+	// payloads still addressed to the old one are routed to them.
+	moved map[uuid.UUID]string
+
+	// remoteKeys holds the signing keys federated lookups returned, by user
+	// ID, for verifying deletion notices from their home servers.
+	remoteKeys map[uuid.UUID]ed25519.PublicKey
+
+	// signingKey countersigns account redirects and federation invitations.
+	signingKey ed25519.PrivateKey
+
+	// fedTrust holds the CAs federation peers are verified against;
+	// federationPeers is the federation.yaml that peers joining by
+	// invitation are appended to, and federationAddress the address our
+	// invitations advertise.
+	fedTrust          *FederationTrust
+	federationPeers   string
+	federationAddress string
+
+	Metrics *Metrics
+	Limits  *RateLimiter
+
+	DeliveryTimeout time.Duration
+	RelayTimeout    time.Duration
+
+	draining         chan struct{}
+	drainOnce        sync.Once
+	deliveries       sync.WaitGroup
+	delivering       map[uuid.UUID]struct{} // payloads with a delivery running
+	deliveryCtx      context.Context
+	cancelDeliveries context.CancelFunc
 }
 
 func (s *StrikeServer) mapInit() {
@@ -56,26 +105,69 @@ func (s *StrikeServer) mapInit() {
 	if s.RemotePresence == nil {
 		s.RemotePresence = make(map[uuid.UUID]string)
 	}
+	if s.kicks == nil {
+		s.kicks = make(map[uuid.UUID]chan struct{})
+	}
+	if s.moved == nil {
+		s.moved = make(map[uuid.UUID]string)
+	}
+	if s.remoteKeys == nil {
+		s.remoteKeys = make(map[uuid.UUID]ed25519.PublicKey)
+	}
+	if s.delivering == nil {
+		s.delivering = make(map[uuid.UUID]struct{})
+	}
+}
+
+func (s *StrikeServer) ConnectedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.Connected)
+}
+
+func (s *StrikeServer) PendingCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.Pending)
 }
 
 func (s *StrikeServer) SendPayload(ctx context.Context, payload *pb.StreamPayload) (*pb.ServerResponse, error) {
 
 	if payload == nil {
-		return &pb.ServerResponse{Success: false, Message: "payload empty"}, fmt.Errorf("send payload: empty payload")
+		return nil, invalidArgument("payload", "empty payload")
+	}
+
+	if s.isDraining() {
+		return nil, shuttingDown()
 	}
 
 	if payload.Target == "" {
-		return &pb.ServerResponse{Success: false, Message: "missing target"}, fmt.Errorf("send payload: missing target")
+		return nil, invalidArgument("target", "missing target")
 	}
 
 	parsedTarget, err := uuid.Parse(payload.Target)
 	if err != nil {
-		return &pb.ServerResponse{Success: false, Message: "invalid target id"}, fmt.Errorf("send payload: invalid target")
+		return nil, invalidArgument("target", "not a valid user id")
 	}
 
 	parsedSender, err := uuid.Parse(payload.Sender)
 	if err != nil {
-		return &pb.ServerResponse{Success: false, Message: "invalid sender id"}, fmt.Errorf("send payload: sender target")
+		return nil, invalidArgument("sender", "not a valid user id")
+	}
+	if err := s.checkActive(ctx, parsedSender); err != nil {
+		return nil, err
+	}
+
+	if payload.TargetDomain != "" && payload.TargetDomain != s.Name {
+		if id, ok := s.PeerMgr.IDByName(payload.TargetDomain); ok && !s.PeerMgr.Policy(id).Outbound() {
+			return nil, failedPrecondition(payload.TargetDomain, "this server does not relay to "+payload.TargetDomain)
+		}
+	}
+
+	if payload.GetFriendRequest() != nil {
+		if err := s.allowFriendRequest(pb.Strike_SendPayload_FullMethodName, parsedSender.String()); err != nil {
+			return nil, err
+		}
 	}
 
 	//TODO: Handle some federated origin tracking here?
@@ -84,11 +176,16 @@ func (s *StrikeServer) SendPayload(ctx context.Context, payload *pb.StreamPayloa
 
 	payloadBytes, err := proto.Marshal(payload)
 	if err != nil {
-		return &pb.ServerResponse{Success: false, Message: "failed to marshal payload"}, fmt.Errorf("send payload: marshal: %v", err)
+		return nil, internalError("send payload: marshal", err)
 	}
 
 	s.mu.Lock()
 	s.mapInit()
+	if s.Limits != nil && s.Limits.maxPendingPerUser > 0 && s.pendingFromLocked(payload.Sender) >= s.Limits.maxPendingPerUser {
+		s.mu.Unlock()
+		s.Metrics.observeRateLimited(pb.Strike_SendPayload_FullMethodName, "pending")
+		return nil, rateLimited("too many undelivered payloads queued, try again once they are delivered", s.DeliveryTimeout)
+	}
 	pmsg := &types.PendingMsg{
 		MessageID:    messageID,
 		From:         parsedSender,
@@ -97,13 +194,20 @@ func (s *StrikeServer) SendPayload(ctx context.Context, payload *pb.StreamPayloa
 		TargetDomain: payload.TargetDomain,
 		Created:      time.Now(),
 		Payload:      payloadBytes,
-		Attempts:     3,
+		Attempts:     deliveryAttempts,
+
+		CorrelationID: logging.CorrelationID(ctx),
+		TraceParent:   tracing.Inject(ctx),
 	}
 
 	s.Pending[messageID] = pmsg
 	s.mu.Unlock()
 
-	go s.attemptDelivery(context.TODO(), messageID)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("strike.message_id", messageID.String()))
+
+	slog.DebugContext(ctx, "payload queued", "message_id", messageID, "target_domain", payload.TargetDomain)
+
+	s.startDelivery(messageID)
 
 	return &pb.ServerResponse{Success: true, Message: fmt.Sprintf("relay-OK: %s", messageID.String())}, nil
 
@@ -121,6 +225,22 @@ func (s *StrikeServer) attemptDelivery(
 		return
 	}
 
+	ctx = logging.WithCorrelationID(ctx, pmsg.CorrelationID)
+
+	if domain, ok := s.moved[pmsg.To]; ok {
+		pmsg.TargetDomain = domain
+	}
+
+	// Delivery runs after SendPayload/Relay has returned, so its span is
+	// parented on the queued trace context rather than the request context.
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, pmsg.TraceParent), "strike.deliver",
+		trace.WithAttributes(
+			attribute.String("strike.message_id", msgID.String()),
+			attribute.String("strike.target_domain", pmsg.TargetDomain),
+			attribute.Int("strike.attempts_left", pmsg.Attempts),
+		))
+	defer span.End()
+
 	// Route by domain: empty or matching our name means local
 	isLocal := pmsg.TargetDomain == "" || pmsg.TargetDomain == s.Name
 
@@ -128,9 +248,34 @@ func (s *StrikeServer) attemptDelivery(
 		ch, connected := s.PayloadChannels[pmsg.To]
 		s.mu.Unlock()
 
+		senderDomain := pmsg.SenderDomain
+		if senderDomain == "" {
+			senderDomain = s.Name
+		}
+		// A failed lookup delivers anyway; the client drops blocked senders
+		// too, so the server list only saves the recipient the traffic.
+		blocked, err := s.blockedBy(ctx, pmsg.To, pmsg.From.String(), senderDomain)
+		if err != nil {
+			slog.WarnContext(ctx, "block check failed", "message_id", msgID, "error", err)
+		}
+		if blocked {
+			span.SetAttributes(attribute.String("strike.route", "blocked"))
+			slog.DebugContext(ctx, "payload dropped, sender is blocked", "message_id", msgID)
+			s.mu.Lock()
+			delete(s.Pending, msgID)
+			s.mu.Unlock()
+			return
+		}
+
 		if connected {
-			delivered, err := s.localDelivery(ctx, ch, pmsg, 5*time.Second)
+			delivered, err := s.localDelivery(ctx, ch, pmsg, s.DeliveryTimeout)
+			s.Metrics.observeDelivery("local", err == nil && delivered, pmsg.Created)
+			if err != nil {
+				slog.WarnContext(ctx, "local delivery failed", "message_id", msgID, "error", err)
+			}
 			if err == nil && delivered {
+				span.SetAttributes(attribute.String("strike.route", "local"))
+				slog.DebugContext(ctx, "payload delivered", "message_id", msgID, "route", "local")
 				s.mu.Lock()
 				delete(s.Pending, msgID)
 				s.mu.Unlock()
@@ -142,30 +287,52 @@ func (s *StrikeServer) attemptDelivery(
 	}
 
 	// Fall back to federation (domain-based lookup)
+	span.SetAttributes(attribute.String("strike.route", "federated"))
 	delivered, err := s.fedDelivery(ctx, pmsg)
+	s.Metrics.observeDelivery("federated", err == nil && delivered, pmsg.Created)
 	if err != nil || !delivered {
 		s.mu.Lock()
 		pmsg.Attempts--
-		if pmsg.Attempts <= 0 {
+		remaining := pmsg.Attempts
+		if remaining <= 0 {
 			delete(s.Pending, msgID)
 		}
 		s.mu.Unlock()
+
+		if err != nil {
+			span.RecordError(err)
+			slog.WarnContext(ctx, "federated delivery failed", "message_id", msgID, "target_domain", pmsg.TargetDomain, "attempts_left", remaining, "error", err)
+		}
+		span.SetStatus(otelcodes.Error, "not delivered")
+		if remaining <= 0 {
+			span.AddEvent("dead-lettered after retries")
+			reason := "no route to recipient"
+			if err != nil {
+				reason = err.Error()
+			}
+			s.deadLetter(ctx, pmsg, reason)
+		}
 		return
 	}
+	slog.DebugContext(ctx, "payload delivered", "message_id", msgID, "route", "federated", "target_domain", pmsg.TargetDomain)
 	s.mu.Lock()
 	delete(s.Pending, msgID)
 	s.mu.Unlock()
 }
 func (s *StrikeServer) EnqueueFederated(ctx context.Context, rp *fedpb.RelayPayload) error {
 
-	from, err := uuid.Parse(rp.Sender.UInfo.UserId)
+	if s.isDraining() {
+		return shuttingDown()
+	}
+
+	from, err := uuid.Parse(rp.Sender.GetUInfo().GetUserId())
 	if err != nil {
-		return fmt.Errorf("invalid sender id")
+		return invalidArgument("sender", "not a valid user id")
 	}
 
-	to, err := uuid.Parse(rp.Recipient.UInfo.UserId)
+	to, err := uuid.Parse(rp.Recipient.GetUInfo().GetUserId())
 	if err != nil {
-		return fmt.Errorf("invalid recipient id")
+		return invalidArgument("recipient", "not a valid user id")
 	}
 
 	msgID := uuid.New()
@@ -180,11 +347,21 @@ func (s *StrikeServer) EnqueueFederated(ctx context.Context, rp *fedpb.RelayPayl
 		TargetDomain: rp.Recipient.Domain,
 		Payload:      rp.PayloadData,
 		Created:      time.Now(),
-		Attempts:     3,
+		Attempts:     deliveryAttempts,
+
+		CorrelationID: logging.CorrelationID(ctx),
+		TraceParent:   tracing.Inject(ctx),
 	}
 	s.mu.Unlock()
 
-	go s.attemptDelivery(ctx, msgID)
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("strike.message_id", msgID.String()),
+		attribute.String("strike.envelope_id", rp.EnvelopeId),
+	)
+
+	slog.DebugContext(ctx, "federated payload queued", "message_id", msgID, "envelope_id", rp.EnvelopeId, "origin", rp.OriginServer)
+
+	s.startDelivery(msgID)
 	return nil
 }
 
@@ -214,7 +391,23 @@ func (s *StrikeServer) UpdateRemotePresence(user uuid.UUID, peerID string) {
 func (s *StrikeServer) fedDelivery(
 	ctx context.Context,
 	pmsg *types.PendingMsg,
-) (bool, error) {
+) (delivered bool, err error) {
+
+	ctx, span := tracing.Tracer().Start(ctx, "strike.relay", trace.WithSpanKind(trace.SpanKindInternal))
+	defer func() {
+		span.SetAttributes(attribute.Bool("strike.delivered", delivered))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if s.RelayTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.RelayTimeout)
+		defer cancel()
+	}
 
 	relay := &fedpb.RelayPayload{
 		EnvelopeId:   uuid.NewString(),
@@ -231,15 +424,18 @@ func (s *StrikeServer) fedDelivery(
 		SentAt:      timestamppb.Now(),
 	}
 
+	span.SetAttributes(
+		attribute.String("strike.envelope_id", relay.EnvelopeId),
+		attribute.String("strike.peer", pmsg.TargetDomain),
+	)
+	slog.DebugContext(ctx, "relaying payload", "message_id", pmsg.MessageID, "envelope_id", relay.EnvelopeId, "target_domain", pmsg.TargetDomain)
+
 	// Try domain-based routing first
 	if pmsg.TargetDomain != "" {
 		client, ok := s.PeerMgr.ClientByName(pmsg.TargetDomain)
 		if ok {
-			_, err := client.Relay(ctx, relay)
-			if err != nil {
-				return false, err
-			}
-			return true, nil
+			peerID, _ := s.PeerMgr.IDByName(pmsg.TargetDomain)
+			return s.relayTo(ctx, peerID, client, relay)
 		}
 	}
 
@@ -254,15 +450,32 @@ func (s *StrikeServer) fedDelivery(
 		return false, fmt.Errorf("peer %s not connected", peerID)
 	}
 
-	_, err := client.Relay(ctx, relay)
+	return s.relayTo(ctx, peerID, client, relay)
+}
+
+// relayTo sends a payload to a peer our policy lets us relay to. A payload
+// the peer refuses counts as undelivered.
+func (s *StrikeServer) relayTo(ctx context.Context, peerID string, client fedpb.FederationClient, relay *fedpb.RelayPayload) (bool, error) {
+	name := s.PeerMgr.NameOf(peerID)
+	if !s.PeerMgr.Policy(peerID).Outbound() {
+		return false, fmt.Errorf("policy does not allow relaying to %s", name)
+	}
+
+	ack, err := client.Relay(ctx, relay)
+	s.Metrics.observeRelay(name, err)
 	if err != nil {
 		return false, err
 	}
-
+	if !ack.Accepted {
+		return false, fmt.Errorf("%s refused the payload: %s", name, ack.Info)
+	}
 	return true, nil
 }
 
 func (s *StrikeServer) localDelivery(ctx context.Context, ch chan<- *pb.StreamPayload, pmsg *types.PendingMsg, timeout time.Duration) (bool, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "strike.deliver.local")
+	defer span.End()
+
 	out := &pb.StreamPayload{}
 	if err := proto.Unmarshal(pmsg.Payload, out); err != nil {
 		return false, fmt.Errorf("unmarshal payload: %v", err)
@@ -281,15 +494,13 @@ func (s *StrikeServer) localDelivery(ctx context.Context, ch chan<- *pb.StreamPa
 func (s *StrikeServer) SaltMine(ctx context.Context, userInfo *common_pb.UserInfo) (*pb.Salt, error) {
 	var salt []byte
 
-	// TODO: ERROR this fails after server has been running long
+	if userInfo.GetUsername() == "" {
+		return nil, invalidArgument("username", "missing username")
+	}
+
 	err := s.DBpool.QueryRow(ctx, s.PStatements.User.SaltMine, userInfo.Username).Scan(&salt)
 	if err != nil {
-		if pgerr, ok := err.(*pgconn.PgError); ok && pgerr.Code == "no-data-found" {
-			fmt.Printf("Unable mine salt: %v", err)
-			return nil, nil
-		}
-		fmt.Printf("An Error occured while mining salt: %v", err)
-		return nil, nil
+		return nil, dbError("salt mine", "user", userInfo.Username, err)
 	}
 
 	return &pb.Salt{Salt: salt}, nil
@@ -297,42 +508,81 @@ func (s *StrikeServer) SaltMine(ctx context.Context, userInfo *common_pb.UserInf
 
 func (s *StrikeServer) Login(ctx context.Context, clientLogin *pb.LoginVerify) (*pb.ServerResponse, error) {
 	var storedHash string
+	var disabled bool
+
+	if err := s.checkLockout(ctx, clientLogin.Username); err != nil {
+		return nil, err
+	}
 
-	err := s.DBpool.QueryRow(ctx, s.PStatements.User.LoginUser, clientLogin.Username).Scan(&storedHash)
+	// Unknown users and wrong passwords get the same answer, so Login cannot
+	// be used to discover usernames.
+	err := s.DBpool.QueryRow(ctx, s.PStatements.User.LoginUser, clientLogin.Username).Scan(&storedHash, &disabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		s.recordLoginFailure(ctx, clientLogin.Username)
+		return nil, unauthenticated("invalid username or password")
+	}
 	if err != nil {
-		if pgerr, ok := err.(*pgconn.PgError); ok && pgerr.Code == "no-data-found" {
-			fmt.Printf("Unable to verify user: %v", err)
-			return nil, nil
-		}
-		fmt.Printf("An Error occured while verifying user: %v", err)
-		return nil, nil
+		return nil, dbError("login", "user", clientLogin.Username, err)
 	}
 
 	// verify our password is right
 	// TODO: Check efficiency here, i.e. argon2 using 128mb ram
 	passMatch, err := shared.VerifyPassword(clientLogin.PasswordHash, storedHash)
 	if err != nil {
-		return &pb.ServerResponse{Success: false, Message: "an error occured"}, err
+		return nil, internalError("login: verify password", err)
 	}
 
-	if passMatch {
-		return &pb.ServerResponse{Success: passMatch, Message: "User verification successful"}, nil
-	} else {
-		return &pb.ServerResponse{Success: passMatch, Message: "Unable to verify user"}, nil
+	if !passMatch {
+		s.recordLoginFailure(ctx, clientLogin.Username)
+		return nil, unauthenticated("invalid username or password")
 	}
+
+	s.clearLoginFailures(ctx, clientLogin.Username)
+
+	// Only reported once the password checks out, for the same reason.
+	if disabled {
+		return nil, accountDisabled(clientLogin.Username)
+	}
+
+	return &pb.ServerResponse{Success: true, Message: "User verification successful"}, nil
 }
 
 func (s *StrikeServer) Signup(ctx context.Context, userInit *pb.InitUser) (*pb.ServerResponse, error) {
+	userID, err := uuid.Parse(userInit.UserId)
+	if err != nil {
+		return nil, invalidArgument("user_id", "not a valid user id")
+	}
+	if userInit.Username == "" {
+		return nil, invalidArgument("username", "missing username")
+	}
+	if userInit.PasswordHash == "" || len(userInit.GetSalt().GetSalt()) == 0 {
+		return nil, invalidArgument("password_hash", "missing password hash or salt")
+	}
+	if len(userInit.EncryptionPublicKey) == 0 || len(userInit.SigningPublicKey) == 0 {
+		return nil, invalidArgument("public_keys", "missing encryption or signing public key")
+	}
+
+	// The user and their keys are created together or not at all.
+	tx, err := s.DBpool.Begin(ctx)
+	if err != nil {
+		return nil, dbError("signup", "user", userInit.Username, err)
+	}
+	defer tx.Rollback(ctx) // no-op after commit
+
 	// user: uuid, username, password_hash, salt
-	_, err := s.DBpool.Exec(ctx, s.PStatements.User.CreateUser, uuid.MustParse(userInit.UserId), userInit.Username, userInit.PasswordHash, userInit.Salt.Salt)
+	_, err = tx.Exec(ctx, s.PStatements.User.CreateUser, userID, userInit.Username, userInit.PasswordHash, userInit.Salt.Salt)
 	if err != nil {
-		return &pb.ServerResponse{Success: false, Message: "failed to register user"}, err
+		return nil, dbError("signup: create user", "user", userInit.Username, err)
 	}
 
 	// keys: uuid, encryption, signing
-	_, err = s.DBpool.Exec(ctx, s.PStatements.Keys.CreatePublicKeys, uuid.MustParse(userInit.UserId), userInit.EncryptionPublicKey, userInit.SigningPublicKey)
+	_, err = tx.Exec(ctx, s.PStatements.Keys.CreatePublicKeys, userID, userInit.EncryptionPublicKey, userInit.SigningPublicKey)
 	if err != nil {
-		return &pb.ServerResponse{Success: false, Message: "failed to register user keys"}, err
+		return nil, dbError("signup: create keys", "user", userInit.Username, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, dbError("signup: commit", "user", userInit.Username, err)
 	}
 
 	return &pb.ServerResponse{
@@ -345,7 +595,10 @@ func (s *StrikeServer) StatusStream(req *common_pb.UserInfo, stream pb.Strike_St
 
 	parsedId, err := uuid.Parse(req.UserId)
 	if err != nil {
-		return fmt.Errorf("failed to parse user ID: %v", err)
+		return invalidArgument("user_id", "not a valid user id")
+	}
+	if err := s.checkActive(stream.Context(), parsedId); err != nil {
+		return err
 	}
 
 	s.mu.Lock()
@@ -356,28 +609,47 @@ func (s *StrikeServer) StatusStream(req *common_pb.UserInfo, stream pb.Strike_St
 		EncryptionPublicKey: req.EncryptionPublicKey,
 		SigningPublicKey:    req.SigningPublicKey,
 	}
+	kicked := s.kickLocked(parsedId)
 	s.mu.Unlock()
 
 	defer func() {
 		s.mu.Lock()
 		delete(s.Connected, parsedId)
 		s.mu.Unlock()
-		log.Printf("%s is now offline.\n", req.Username)
+		slog.Info("user offline", "username", req.Username, "user_id", req.UserId)
 	}()
 
-	log.Printf("%s is online.\n", req.Username)
+	slog.Info("user online", "username", req.Username, "user_id", req.UserId)
 
 	for {
 		select {
 		case <-stream.Context().Done():
 			return nil
+		case <-s.draining:
+			err := stream.Send(&pb.StatusUpdate{
+				Message:   "Server shutting down",
+				UpdatedAt: timestamppb.Now(),
+			})
+			if err != nil {
+				slog.Warn("failed to send shutdown notice", "username", req.Username, "error", err)
+			}
+			return nil
+		case <-kicked:
+			err := stream.Send(&pb.StatusUpdate{
+				Message:   "Disconnected by the server administrator",
+				UpdatedAt: timestamppb.Now(),
+			})
+			if err != nil {
+				slog.Warn("failed to send disconnect notice", "username", req.Username, "error", err)
+			}
+			return nil
 		case <-time.After(2 * time.Minute):
 			err := stream.Send(&pb.StatusUpdate{
 				Message:   "Still alive",
 				UpdatedAt: timestamppb.Now(),
 			})
 			if err != nil {
-				log.Printf("Failed to send status update: %v\n", err)
+				slog.Warn("failed to send status update", "username", req.Username, "error", err)
 				return err
 			}
 		}
@@ -385,12 +657,29 @@ func (s *StrikeServer) StatusStream(req *common_pb.UserInfo, stream pb.Strike_St
 }
 
 func (s *StrikeServer) UserRequest(ctx context.Context, addr *common_pb.UserAddress) (*common_pb.UserInfo, error) {
+	if addr.GetUsername() == "" {
+		return nil, invalidArgument("username", "missing username")
+	}
+
 	// If domain is set and doesn't match us, forward to the peer
 	if addr.Domain != "" && addr.Domain != s.Name {
-		return s.federatedUserLookup(ctx, addr.Username, addr.Domain)
+		return s.federatedUserLookup(ctx, addr.Username, addr.Domain, true)
+	}
+
+	uInfo, err := s.localUserLookup(ctx, addr.Username)
+	if status.Code(err) != codes.NotFound {
+		return uInfo, err
 	}
 
-	return s.localUserLookup(ctx, addr.Username)
+	// Renamed or moved away: follow the redirect.
+	n, rerr := s.redirectFor(ctx, s.Name, addr.Username)
+	if rerr != nil {
+		return nil, rerr
+	}
+	if n == nil {
+		return nil, err
+	}
+	return s.followRedirect(ctx, n)
 }
 
 func (s *StrikeServer) localUserLookup(ctx context.Context, username string) (*common_pb.UserInfo, error) {
@@ -399,43 +688,63 @@ func (s *StrikeServer) localUserLookup(ctx context.Context, username string) (*c
 
 	err := s.DBpool.QueryRow(ctx, s.PStatements.User.GetUser, username).Scan(&userid)
 	if err != nil {
-		if pgerr, ok := err.(*pgconn.PgError); ok && pgerr.Code == "no-data-found" {
-			fmt.Printf("Unable get username: %v", err)
-			return nil, nil
-		}
-		fmt.Printf("Error acquiring username: %v", err)
-		return nil, nil
+		return nil, dbError("user lookup", "user", username, err)
 	}
 
 	row := s.DBpool.QueryRow(ctx, s.PStatements.Keys.GetPublicKeys, userid)
 	if err := row.Scan(&encryptionPubKey, &signingPubKey); err != nil {
-		fmt.Println("Failed to get keys")
-		return nil, err
+		return nil, dbError("user lookup: keys", "keys for user", username, err)
 	}
 
-	return &common_pb.UserInfo{UserId: userid.String(), Username: username, EncryptionPublicKey: encryptionPubKey, SigningPublicKey: signingPubKey}, nil
+	return &common_pb.UserInfo{UserId: userid.String(), Username: username, EncryptionPublicKey: encryptionPubKey, SigningPublicKey: signingPubKey, Domain: s.Name}, nil
 }
 
-func (s *StrikeServer) federatedUserLookup(ctx context.Context, username string, domain string) (*common_pb.UserInfo, error) {
+// federatedUserLookup asks the user's home server. With follow set, a
+// redirect we already know of, or one the home server returns, is followed.
+func (s *StrikeServer) federatedUserLookup(ctx context.Context, username string, domain string, follow bool) (*common_pb.UserInfo, error) {
+	if follow {
+		n, err := s.redirectFor(ctx, domain, username)
+		if err != nil {
+			return nil, err
+		}
+		if n != nil {
+			return s.followRedirect(ctx, n)
+		}
+	}
+
 	client, ok := s.PeerMgr.ClientByName(domain)
 	if !ok {
-		return nil, fmt.Errorf("unknown domain: %s", domain)
+		return nil, notFound("domain", domain)
 	}
 
 	resp, err := client.UserLookup(ctx, &fedpb.UserLookupReq{Username: username})
 	if err != nil {
-		return nil, fmt.Errorf("federated lookup failed: %v", err)
+		return nil, peerUnavailable(domain, err)
+	}
+
+	if resp.MovedTo != nil && follow {
+		peerID, _ := s.PeerMgr.IDByName(domain)
+		if err := s.checkRedirect(peerID, resp.MovedTo); err != nil {
+			slog.WarnContext(ctx, "federation: ignoring redirect", "peer", domain, "username", username, "error", err)
+			return nil, notFound("user", username+"@"+domain)
+		}
+		if err := s.saveRedirect(ctx, s.DBpool, resp.MovedTo); err != nil {
+			slog.WarnContext(ctx, "federation: caching redirect failed", "peer", domain, "username", username, "error", err)
+		}
+		return s.followRedirect(ctx, resp.MovedTo)
 	}
 
 	if !resp.Found {
-		return nil, nil
+		return nil, notFound("user", username+"@"+domain)
 	}
 
+	resp.UserInfo.Domain = domain
+	s.rememberRemoteKey(resp.UserInfo)
 	return resp.UserInfo, nil
 }
 
 func (s *StrikeServer) OnlineUsers(ctx context.Context, userInfo *common_pb.UserInfo) (*common_pb.Users, error) {
-	log.Printf("%s (%s) requested active user list\n", userInfo.Username, userInfo.UserId)
+	slog.DebugContext(ctx, "active user list requested", "username", userInfo.Username, "user_id", userInfo.UserId)
 
 	s.mu.Lock()
 	users := make([]*common_pb.UserInfo, 0, len(s.Connected))
@@ -475,11 +784,15 @@ func (s *StrikeServer) PollServer(ctx context.Context, userInfo *common_pb.UserI
 }
 
 func (s *StrikeServer) PayloadStream(user *common_pb.UserInfo, stream pb.Strike_PayloadStreamServer) error {
-	log.Printf("Stream Established: %v online\n", user.Username)
+	ctx := stream.Context()
+	slog.InfoContext(ctx, "payload stream established", "username", user.Username, "user_id", user.UserId)
 
 	parsedId, err := uuid.Parse(user.UserId)
 	if err != nil {
-		return fmt.Errorf("failed to parse user id: %v", err)
+		return invalidArgument("user_id", "not a valid user id")
+	}
+	if err := s.checkActive(ctx, parsedId); err != nil {
+		return err
 	}
 
 	s.mu.Lock()
@@ -491,30 +804,44 @@ func (s *StrikeServer) PayloadStream(user *common_pb.UserInfo, stream pb.Strike_
 
 	s.mu.Lock()
 	s.PayloadChannels[parsedId] = payloadChannel
+	kicked := s.kickLocked(parsedId)
 	s.mu.Unlock()
 
+	// The channel is never closed: localDelivery may still hold it after the
+	// stream ends, and a send on a closed channel would panic.
 	defer func() {
 		s.mu.Lock()
 		delete(s.PayloadStreams, parsedId)
 		delete(s.PayloadChannels, parsedId)
-		close(payloadChannel)
 		s.mu.Unlock()
-		log.Printf("Client %s disconnected.\n", user.Username)
+		slog.InfoContext(ctx, "payload stream closed", "username", user.Username, "user_id", user.UserId)
 	}()
 
 	go func() {
-		for msg := range payloadChannel {
-			if err := stream.Send(msg); err != nil {
-				log.Printf("Failed to send message to %s: %v\n", user.Username, err)
+		for {
+			select {
+			case <-stream.Context().Done():
 				return
+			case msg := <-payloadChannel:
+				if err := stream.Send(msg); err != nil {
+					slog.WarnContext(ctx, "failed to send payload", "username", user.Username, "error", err)
+					return
+				}
 			}
 		}
 	}()
 
+	s.flushPendingFor(parsedId)
+
 	for {
 		select {
-		case <-stream.Context().Done():
-			log.Println("Client disconnected.")
+		case <-ctx.Done():
+			return nil
+		case <-s.draining:
+			slog.InfoContext(ctx, "closing payload stream: server shutting down", "username", user.Username)
+			return nil
+		case <-kicked:
+			slog.InfoContext(ctx, "closing payload stream: disconnected by admin", "username", user.Username)
 			return nil
 		case <-time.After(1 * time.Minute):
 			// TODO: Heart Beat
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/JohnnyGlynn/strike/internal/server/types"
+)
+
+// initLifecycle prepares the drain signal and the context shared by delivery
+// goroutines. Deliveries must not hang off a request context: Relay and
+// SendPayload return long before the payload reaches its recipient.
+func (s *StrikeServer) initLifecycle() {
+	s.draining = make(chan struct{})
+	s.deliveryCtx, s.cancelDeliveries = context.WithCancel(context.Background())
+}
+
+// isDraining reports whether shutdown has begun.
+func (s *StrikeServer) isDraining() bool {
+	select {
+	case <-s.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// startDelivery tracks an attemptDelivery goroutine so shutdown can wait for
+// it. A payload is only delivered by one goroutine at a time: startDelivery
+// reports false, starting nothing, while one is already running.
+func (s *StrikeServer) startDelivery(msgID uuid.UUID) bool {
+	s.mu.Lock()
+	s.mapInit()
+	if _, running := s.delivering[msgID]; running {
+		s.mu.Unlock()
+		return false
+	}
+	s.delivering[msgID] = struct{}{}
+	s.mu.Unlock()
+
+	s.deliveries.Add(1)
+	go func() {
+		defer s.deliveries.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.delivering, msgID)
+			s.mu.Unlock()
+		}()
+		s.attemptDelivery(s.deliveryCtx, msgID)
+	}()
+	return true
+}
+
+// BeginDrain stops accepting payloads and signals every StatusStream and
+// PayloadStream to notify its client and return.
+func (s *StrikeServer) BeginDrain() {
+	s.drainOnce.Do(func() {
+		close(s.draining)
+	})
+}
+
+// deliveryStopGrace bounds the wait for cancelled deliveries to return. A
+// cancelled delivery may still record its attempt or dead-letter the payload,
+// which must happen before pending payloads are persisted and the database
+// is closed.
+const deliveryStopGrace = 5 * time.Second
+
+// WaitDeliveries blocks until in-flight deliveries finish or ctx expires, at
+// which point the remaining deliveries are cancelled and given
+// deliveryStopGrace to return.
+func (s *StrikeServer) WaitDeliveries(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.deliveries.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.cancelDeliveries()
+	}
+
+	select {
+	case <-done:
+		return fmt.Errorf("deliveries cancelled: %w", ctx.Err())
+	case <-time.After(deliveryStopGrace):
+		return fmt.Errorf("deliveries still running after cancel: %w", ctx.Err())
+	}
+}
+
+// PersistPending writes undelivered payloads to the database so the next
+// start can resume them.
+func (s *StrikeServer) PersistPending(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	pending := make([]*types.PendingMsg, 0, len(s.Pending))
+	for _, pmsg := range s.Pending {
+		pending = append(pending, pmsg)
+	}
+	s.mu.Unlock()
+
+	saved := 0
+	for _, pmsg := range pending {
+		_, err := s.DBpool.Exec(ctx, s.PStatements.Pending.SavePending,
+			pmsg.MessageID, pmsg.From, pmsg.To, pmsg.SenderDomain, pmsg.TargetDomain, pmsg.Payload, pmsg.Attempts, pmsg.Created, pmsg.CorrelationID, pmsg.TraceParent)
+		if err != nil {
+			return saved, fmt.Errorf("persist pending %s: %w", pmsg.MessageID, err)
+		}
+		saved++
+	}
+
+	return saved, nil
+}
+
+// RestorePending loads payloads persisted by a previous shutdown back into
+// the Pending map. Local payloads are delivered when their recipient next
+// connects, and payloads for a peer once the handshake with it succeeds (see
+// flushPendingTo).
+func (s *StrikeServer) RestorePending(ctx context.Context) (int, error) {
+	rows, err := s.DBpool.Query(ctx, s.PStatements.Pending.DrainPending)
+	if err != nil {
+		return 0, fmt.Errorf("restore pending: %w", err)
+	}
+	defer rows.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mapInit()
+
+	restored := 0
+	for rows.Next() {
+		var pmsg types.PendingMsg
+		var created time.Time
+		if err := rows.Scan(&pmsg.MessageID, &pmsg.From, &pmsg.To, &pmsg.SenderDomain, &pmsg.TargetDomain, &pmsg.Payload, &pmsg.Attempts, &created, &pmsg.CorrelationID, &pmsg.TraceParent); err != nil {
+			return restored, fmt.Errorf("restore pending: %w", err)
+		}
+		pmsg.Created = created
+		s.Pending[pmsg.MessageID] = &pmsg
+		restored++
+	}
+
+	return restored, rows.Err()
+}
+
+// flushPendingFor retries queued payloads addressed to a user who has just
+// opened a PayloadStream.
+func (s *StrikeServer) flushPendingFor(user uuid.UUID) {
+	s.mu.Lock()
+	var ids []uuid.UUID
+	for id, pmsg := range s.Pending {
+		if pmsg.To == user {
+			ids = append(ids, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range ids {
+		s.startDelivery(id)
+	}
+}
+
+// flushPendingTo retries queued payloads bound for a peer that has just
+// completed its handshake, such as those restored at startup.
+func (s *StrikeServer) flushPendingTo(domain string) {
+	s.mu.Lock()
+	var ids []uuid.UUID
+	for id, pmsg := range s.Pending {
+		if pmsg.TargetDomain == domain {
+			ids = append(ids, id)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(ids) > 0 {
+		slog.Info("retrying pending payloads for peer", "peer", domain, "count", len(ids))
+	}
+	for _, id := range ids {
+		s.startDelivery(id)
+	}
+}
+
+// Stop shuts the server down in order: stop reporting healthy, tell clients
+// we are going away, stop accepting RPCs, let deliveries finish, persist what
+// is left, close federation connections and finally the database. Every wait
+// is bounded by ctx.
+func (b *Bootstrap) Stop(ctx context.Context) {
+	slog.Info("shutting down")
+
+	b.setServing(healthpb.HealthCheckResponse_NOT_SERVING)
+
+	if b.Strike != nil {
+		b.Strike.BeginDrain()
+	}
+
+	gracefulStop(ctx, "strike", b.grpcStrike)
+	gracefulStop(ctx, "federation", b.grpcFed)
+	gracefulStop(ctx, "admin", b.grpcAdmin)
+
+	if b.Strike != nil {
+		if err := b.Strike.WaitDeliveries(ctx); err != nil {
+			slog.Warn("shutdown: deliveries abandoned", "error", err)
+		}
+
+		if b.DB != nil {
+			// Persisting must happen even if the drain used up the deadline.
+			persistCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			saved, err := b.Strike.PersistPending(persistCtx)
+			cancel()
+			if err != nil {
+				slog.Error("shutdown: persisting pending payloads failed", "error", err)
+			}
+			slog.Info("shutdown: persisted pending payloads", "count", saved)
+		}
+
+		b.Strike.PeerMgr.Close()
+	}
+
+	if b.admin != nil {
+		if err := b.admin.Shutdown(ctx); err != nil {
+			b.admin.Close()
+		}
+	}
+
+	if b.DB != nil {
+		b.DB.Close()
+	}
+
+	slog.Info("shutdown complete")
+}
+
+// gracefulStop waits for in-flight RPCs, forcing the server closed if ctx
+// expires first.
+func gracefulStop(ctx context.Context, name string, srv *grpc.Server) {
+	if srv == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("shutdown: server stopped", "server", name)
+	case <-ctx.Done():
+		slog.Warn("shutdown: drain timed out, forcing stop", "server", name)
+		srv.Stop()
+	}
+}
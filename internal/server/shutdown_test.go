@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/JohnnyGlynn/strike/internal/server/types"
+)
+
+func TestFlushPendingTo(t *testing.T) {
+	s := &StrikeServer{Name: "a.example", PeerMgr: NewPeerManager(nil, "a.example")}
+	s.initLifecycle()
+	s.mapInit()
+
+	queue := func(domain string) uuid.UUID {
+		id := uuid.New()
+		s.Pending[id] = &types.PendingMsg{MessageID: id, To: uuid.New(), TargetDomain: domain, Attempts: deliveryAttempts}
+		return id
+	}
+	forPeer := []uuid.UUID{queue("b.example"), queue("b.example")}
+	forOther := queue("c.example")
+	local := queue("")
+
+	// The peer is not reachable in the test, so each delivery started
+	// spends one attempt.
+	s.flushPendingTo("b.example")
+	if err := s.WaitDeliveries(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range forPeer {
+		if got := s.Pending[id].Attempts; got != deliveryAttempts-1 {
+			t.Errorf("payload for the peer: attempts = %d, want %d", got, deliveryAttempts-1)
+		}
+	}
+	for _, id := range []uuid.UUID{forOther, local} {
+		if got := s.Pending[id].Attempts; got != deliveryAttempts {
+			t.Errorf("payload %s for %q retried", id, s.Pending[id].TargetDomain)
+		}
+	}
+}
+
+func TestWaitDeliveriesWaitsForCancelled(t *testing.T) {
+	s := &StrikeServer{}
+	s.initLifecycle()
+
+	// A delivery that, once cancelled, still takes a moment to record the
+	// outcome.
+	var finished atomic.Bool
+	s.deliveries.Add(1)
+	go func() {
+		defer s.deliveries.Done()
+		<-s.deliveryCtx.Done()
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := s.WaitDeliveries(ctx)
+	if err == nil || !strings.Contains(err.Error(), "deliveries cancelled") {
+		t.Fatalf("error = %v, want the deliveries reported cancelled", err)
+	}
+	if !finished.Load() {
+		t.Fatal("WaitDeliveries returned before the cancelled delivery finished")
+	}
+}
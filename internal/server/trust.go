@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/JohnnyGlynn/strike/internal/server/types"
+	fedpb "github.com/JohnnyGlynn/strike/msgdef/federation"
+)
+
+// FederationTrust decides which federation certificates are accepted: those
+// issued by federation_ca_path, and those issued by a CA a peer brought with
+// it when it joined by invitation. Incoming peers are checked by UnaryAuth
+// and StreamAuth rather than in the TLS handshake, so a server we do not trust yet can
+// still reach Join.
+type FederationTrust struct {
+	cert tls.Certificate
+	ca   *x509.Certificate // issuer of cert, sent with our invitations
+	crl  *crlChecker
+
+	mu    sync.RWMutex
+	roots *x509.CertPool
+	base  map[string]bool
+	// pinned limits a CA brought by an invited peer to the keys of the
+	// peers it was added for, so it cannot vouch for any other server.
+	pinned map[string][]ed25519.PublicKey
+}
+
+// LoadFederationTrust loads our federation certificate, the shared CA
+// bundle, the CAs of peers added by invitation and, when crlFile is set, the
+// CA's revocation list.
+func LoadFederationTrust(certFile, keyFile, caFile, crlFile string, peers []types.PeerConfig) (*FederationTrust, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	cas, err := parseCerts(caPEM)
+	if err != nil {
+		return nil, err
+	}
+	if len(cas) == 0 {
+		return nil, fmt.Errorf("invalid CA pem")
+	}
+
+	t := &FederationTrust{
+		cert:   cert,
+		roots:  x509.NewCertPool(),
+		base:   make(map[string]bool),
+		pinned: make(map[string][]ed25519.PublicKey),
+	}
+	for _, ca := range cas {
+		t.roots.AddCert(ca)
+		t.base[string(ca.Raw)] = true
+	}
+
+	// Our own issuer: one of the shared CAs, or the certificate itself when
+	// it is self-signed.
+	t.ca = cert.Leaf
+	if chains, err := cert.Leaf.Verify(x509.VerifyOptions{Roots: t.roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err == nil {
+		chain := chains[0]
+		t.ca = chain[len(chain)-1]
+	}
+
+	for _, p := range peers {
+		if p.CACert != nil {
+			t.AddPeerCA(p.CACert, p.PubKey)
+		}
+	}
+
+	if crlFile != "" {
+		if t.crl, err = newCRLChecker(crlFile, cas); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// AddPeerCA trusts ca for the peer holding key. A CA that is already one of
+// the shared ones is left unpinned.
+func (t *FederationTrust) AddPeerCA(ca *x509.Certificate, key ed25519.PublicKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.base[string(ca.Raw)] {
+		return
+	}
+	if _, ok := t.pinned[string(ca.Raw)]; !ok {
+		roots := t.roots.Clone()
+		roots.AddCert(ca)
+		t.roots = roots
+	}
+	t.pinned[string(ca.Raw)] = append(t.pinned[string(ca.Raw)], key)
+}
+
+// CACertificate is the DER certificate peers need to trust ours.
+func (t *FederationTrust) CACertificate() []byte {
+	return t.ca.Raw
+}
+
+// ServerConfig asks every peer for a certificate but leaves verifying it to
+// UnaryAuth and StreamAuth.
+func (t *FederationTrust) ServerConfig() *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{t.cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		MinVersion:   tls.VersionTLS13,
+	}
+}
+
+// ClientConfig is used to dial peers, trusting the CAs known at the time of
+// the call.
+func (t *FederationTrust) ClientConfig() *tls.Config {
+	t.mu.RLock()
+	roots := t.roots
+	t.mu.RUnlock()
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{t.cert},
+		RootCAs:      roots,
+		MinVersion:   tls.VersionTLS13,
+		VerifyPeerCertificate: func(_ [][]byte, chains [][]*x509.Certificate) error {
+			for _, chain := range chains {
+				if err := t.checkChain(chain); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// Verify checks a peer's certificates against the trusted CAs.
+func (t *FederationTrust) Verify(certs []*x509.Certificate) error {
+	if len(certs) == 0 {
+		return fmt.Errorf("no client certificate")
+	}
+
+	t.mu.RLock()
+	roots := t.roots
+	t.mu.RUnlock()
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	chains, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return err
+	}
+	for _, chain := range chains {
+		if err := t.checkChain(chain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkChain applies revocation and CA pinning to a verified chain.
+func (t *FederationTrust) checkChain(chain []*x509.Certificate) error {
+	if t.crl != nil {
+		if err := t.crl.check(chain); err != nil {
+			return err
+		}
+	}
+
+	root := chain[len(chain)-1]
+	t.mu.RLock()
+	keys, pinned := t.pinned[string(root.Raw)]
+	t.mu.RUnlock()
+	if !pinned {
+		return nil
+	}
+
+	leafKey, ok := chain[0].PublicKey.(ed25519.PublicKey)
+	if ok {
+		for _, k := range keys {
+			if leafKey.Equal(k) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("certificate %q is not for a peer its CA was trusted for", chain[0].Subject.CommonName)
+}
+
+// UnaryAuth rejects federation calls from peers whose certificate we do not
+// trust, except Join, which carries its own proof in the invitation.
+func (t *FederationTrust) UnaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if info.FullMethod == fedpb.Federation_Join_FullMethodName {
+		return handler(ctx, req)
+	}
+	if err := t.Verify(peerCertificates(ctx)); err != nil {
+		slog.WarnContext(ctx, "federation: rejected peer certificate", "method", info.FullMethod, "ip", peerIP(ctx), "error", err)
+		return nil, unauthenticated("federation certificate not trusted")
+	}
+	return handler(ctx, req)
+}
+
+// StreamAuth rejects federation streams from peers whose certificate we do
+// not trust. Join is unary, so no stream is exempt.
+func (t *FederationTrust) StreamAuth(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := t.Verify(peerCertificates(ss.Context())); err != nil {
+		slog.WarnContext(ss.Context(), "federation: rejected peer certificate", "method", info.FullMethod, "ip", peerIP(ss.Context()), "error", err)
+		return unauthenticated("federation certificate not trusted")
+	}
+	return handler(srv, ss)
+}
+
+// peerCertificates returns the certificates the caller presented.
+func peerCertificates(ctx context.Context) []*x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return info.State.PeerCertificates
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"crypto/x509"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	"github.com/JohnnyGlynn/strike/internal/server/types"
+)
+
+func TestFederationTrustVerify(t *testing.T) {
+	shared := newTestCA(t)
+	self := newTestPeer(t, shared, "self")
+	member := newTestPeer(t, shared, "member")
+	revoked := newTestPeer(t, shared, "revoked")
+
+	// invited brought its own CA when it joined; that CA also issued a
+	// certificate for a server nobody invited.
+	invitedCA := newTestCA(t)
+	invited := newTestPeer(t, invitedCA, "invited")
+	impostor := newTestPeer(t, invitedCA, "impostor")
+
+	// A second invited peer with a CA of its own: its CA must not vouch for
+	// the first one's key either.
+	otherCA := newTestCA(t)
+	other := newTestPeer(t, otherCA, "other")
+	reissued := newTestPeer(t, otherCA, "invited")
+
+	stranger := newTestPeer(t, newTestCA(t), "stranger")
+	selfSigned := newTestPeer(t, nil, "self-signed")
+
+	crlPath := filepath.Join(t.TempDir(), "federation.crl")
+	if err := keys.RevokeCert(crlPath, revoked.certPath, shared.cert, shared.key); err != nil {
+		t.Fatal(err)
+	}
+
+	trust, err := LoadFederationTrust(self.certPath, self.keyPath, shared.certPath, crlPath, []types.PeerConfig{
+		{ID: uuid.New(), Name: "invited", PubKey: invited.pub(), CACert: invitedCA.cert},
+		{ID: uuid.New(), Name: "member", PubKey: member.pub()},
+	})
+	if err != nil {
+		t.Fatalf("load trust: %v", err)
+	}
+	trust.AddPeerCA(otherCA.cert, other.pub())
+
+	// Listing the shared CA for a peer must not pin it to that peer.
+	trust.AddPeerCA(shared.cert, member.pub())
+
+	cases := map[string]struct {
+		certs []*x509.Certificate
+		text  string // empty when the certificate is accepted
+	}{
+		"shared-ca-member":          {certs: []*x509.Certificate{member.cert}},
+		"shared-ca-unlisted-server": {certs: []*x509.Certificate{self.cert}},
+		"invited-peer":              {certs: []*x509.Certificate{invited.cert}},
+		"second-invited-peer":       {certs: []*x509.Certificate{other.cert}},
+		"revoked":                   {certs: []*x509.Certificate{revoked.cert}, text: "is revoked"},
+		"other-key-from-invited-ca": {certs: []*x509.Certificate{impostor.cert}, text: "not for a peer its CA was trusted for"},
+		"invited-name-from-other-ca": {
+			certs: []*x509.Certificate{reissued.cert},
+			text:  "not for a peer its CA was trusted for",
+		},
+		"unknown-ca":  {certs: []*x509.Certificate{stranger.cert}, text: "unknown authority"},
+		"self-signed": {certs: []*x509.Certificate{selfSigned.cert}, text: "unknown authority"},
+		"no-cert":     {text: "no client certificate"},
+		"ca-as-leaf":  {certs: []*x509.Certificate{invitedCA.cert}, text: "not for a peer its CA was trusted for"},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := trust.Verify(tc.certs)
+			if tc.text == "" {
+				if err != nil {
+					t.Fatalf("refused: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.text) {
+				t.Fatalf("error = %v, want it to contain %q", err, tc.text)
+			}
+		})
+	}
+}
+
+func TestFederationTrustClientConfigChecksChains(t *testing.T) {
+	shared := newTestCA(t)
+	self := newTestPeer(t, shared, "self")
+	invitedCA := newTestCA(t)
+	invited := newTestPeer(t, invitedCA, "invited")
+	impostor := newTestPeer(t, invitedCA, "impostor")
+
+	trust, err := LoadFederationTrust(self.certPath, self.keyPath, shared.certPath, "", nil)
+	if err != nil {
+		t.Fatalf("load trust: %v", err)
+	}
+	trust.AddPeerCA(invitedCA.cert, invited.pub())
+
+	conf := trust.ClientConfig()
+	if conf.RootCAs == nil || conf.VerifyPeerCertificate == nil {
+		t.Fatal("client config does not verify peers")
+	}
+
+	// Dialling applies the same pinning as accepting a call.
+	if err := conf.VerifyPeerCertificate(nil, [][]*x509.Certificate{{invited.cert, invitedCA.cert}}); err != nil {
+		t.Errorf("invited peer refused: %v", err)
+	}
+	if err := conf.VerifyPeerCertificate(nil, [][]*x509.Certificate{{impostor.cert, invitedCA.cert}}); err == nil {
+		t.Error("certificate for another key accepted from a pinned CA")
+	}
+
+	// The config is built from the CAs known when it is asked for.
+	if _, err := invited.cert.Verify(x509.VerifyOptions{Roots: conf.RootCAs, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}); err != nil {
+		t.Errorf("invited CA missing from client roots: %v", err)
+	}
+}
+
+func TestFederationTrustCACertificate(t *testing.T) {
+	shared := newTestCA(t)
+	issued := newTestPeer(t, shared, "issued")
+	selfSigned := newTestPeer(t, nil, "self-signed")
+
+	trust, err := LoadFederationTrust(issued.certPath, issued.keyPath, shared.certPath, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := trust.CACertificate(); string(got) != string(shared.cert.Raw) {
+		t.Error("CA sent with invitations is not the issuing CA")
+	}
+
+	// A self-signed certificate is its own CA.
+	trust, err = LoadFederationTrust(selfSigned.certPath, selfSigned.keyPath, shared.certPath, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := trust.CACertificate(); string(got) != string(selfSigned.cert.Raw) {
+		t.Error("self-signed server does not send its own certificate as CA")
+	}
+}
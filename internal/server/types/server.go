@@ -2,6 +2,8 @@ package types
 
 import (
 	"crypto/ed25519"
+	"crypto/x509"
+	"slices"
 	"sync"
 	"time"
 
@@ -19,6 +21,20 @@ type PendingMsg struct {
 	Payload      []byte
 	Created      time.Time
 	Attempts     int
+
+	// CorrelationID ties log lines for this payload together across servers.
+	CorrelationID string
+
+	// TraceParent is the W3C traceparent of the span that queued the payload,
+	// so delivery stays in the sender's trace even after a restart.
+	TraceParent string
+}
+
+// DeadLetter is a payload that ran out of delivery attempts.
+type DeadLetter struct {
+	PendingMsg
+	FailedAt time.Time
+	Reason   string
 }
 
 type PeerConfig struct {
@@ -27,6 +43,58 @@ type PeerConfig struct {
 	Address string            `yaml:"addr"`
 	PubKey  ed25519.PublicKey `yaml:"-"`
 	RawKey  string            `yaml:"pubkey"`
+
+	// RawCA is the base64 DER certificate of the CA that issued the peer's
+	// federation certificate, for peers added by invitation that do not
+	// share federation_ca_path.
+	RawCA  string            `yaml:"ca,omitempty"`
+	CACert *x509.Certificate `yaml:"-"`
+
+	Policy PeerPolicy `yaml:"policy,omitempty"`
+
+	// Required holds /readyz at 503 while the peer is not connected and
+	// handshaken. Off by default, as peers requiring each other would never
+	// become ready.
+	Required bool `yaml:"required,omitempty"`
+}
+
+// Directions a peer policy may allow payloads to flow in.
+const (
+	DirectionBoth     = "both"
+	DirectionInbound  = "inbound"  // the peer may relay to us; we do not relay to it
+	DirectionOutbound = "outbound" // we relay to the peer; it may not relay to us
+)
+
+// PeerPolicy limits what a peer may do. The zero value allows everything,
+// which is how peers without a policy in federation.yaml behave. Payload
+// types are the StreamPayload oneof field names, e.g. encenv or
+// friend_request.
+type PeerPolicy struct {
+	Direction   string   `yaml:"direction,omitempty"`
+	AllowTypes  []string `yaml:"allow_payload_types,omitempty"`
+	DenyTypes   []string `yaml:"deny_payload_types,omitempty"`
+	RelayRate   float64  `yaml:"relay_rate,omitempty"` // payloads per second, 0 for no limit
+	RelayBurst  int      `yaml:"relay_burst,omitempty"`
+	MaxSize     int      `yaml:"max_payload_size,omitempty"` // bytes, 0 for no limit
+	DenyLookups bool     `yaml:"deny_user_lookup,omitempty"`
+}
+
+// Inbound reports whether the peer may relay payloads to us.
+func (p PeerPolicy) Inbound() bool {
+	return p.Direction != DirectionOutbound
+}
+
+// Outbound reports whether we relay payloads to the peer.
+func (p PeerPolicy) Outbound() bool {
+	return p.Direction != DirectionInbound
+}
+
+// AllowsType reports whether payloads of the given type are accepted.
+func (p PeerPolicy) AllowsType(t string) bool {
+	if slices.Contains(p.DenyTypes, t) {
+		return false
+	}
+	return len(p.AllowTypes) == 0 || slices.Contains(p.AllowTypes, t)
 }
 
 type PeerRuntime struct {
@@ -39,6 +107,17 @@ type PeerRuntime struct {
 	Online     bool
 }
 
+// PeerStatus is a lock-free copy of PeerRuntime state for reporting.
+type PeerStatus struct {
+	ID         string
+	Name       string
+	Address    string
+	Online     bool
+	Handshaken bool
+	LastSeen   time.Time
+	Required   bool
+}
+
 type FederationConfig struct {
 	Peers []PeerConfig `yaml:"peers"`
 }
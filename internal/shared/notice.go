@@ -0,0 +1,146 @@
+package shared
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"strconv"
+
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+)
+
+// AccountDeletedBytes is what the departing user signs. Fields are joined
+// with a NUL so no two notices share the same bytes.
+func AccountDeletedBytes(n *common_pb.AccountDeleted) []byte {
+	b := []byte("strike-account-deleted")
+	for _, f := range []string{
+		n.GetUserId(),
+		n.GetUsername(),
+		n.GetDomain(),
+		strconv.FormatInt(n.GetDeletedAt().AsTime().UnixNano(), 10),
+	} {
+		b = append(b, 0)
+		b = append(b, f...)
+	}
+	return b
+}
+
+// AccountMovedBytes is what the user signs to rename or move their account.
+func AccountMovedBytes(n *common_pb.AccountMoved) []byte {
+	b := []byte("strike-account-moved")
+	for _, f := range []string{
+		n.GetUserId(),
+		n.GetOldUsername(),
+		n.GetOldDomain(),
+		n.GetNewUsername(),
+		n.GetNewDomain(),
+		strconv.FormatInt(n.GetMovedAt().AsTime().UnixNano(), 10),
+	} {
+		b = append(b, 0)
+		b = append(b, f...)
+	}
+	return b
+}
+
+// RedirectBytes is what the old server countersigns: the notice and the
+// user's signature over it.
+func RedirectBytes(n *common_pb.AccountMoved) []byte {
+	b := AccountMovedBytes(n)
+	b = append(b, 0)
+	return append(b, n.GetSignature()...)
+}
+
+// KeyRotationBytes is what both the old and the new signing key sign.
+func KeyRotationBytes(r *common_pb.KeyRotation) []byte {
+	b := []byte("strike-key-rotation")
+	for _, f := range []string{
+		r.GetUserId(),
+		strconv.FormatUint(r.GetSerial(), 10),
+		string(r.GetOldSigningPublicKey()),
+		string(r.GetNewEncryptionPublicKey()),
+		string(r.GetNewSigningPublicKey()),
+		strconv.FormatInt(r.GetRotatedAt().AsTime().UnixNano(), 10),
+	} {
+		b = append(b, 0)
+		b = append(b, f...)
+	}
+	return b
+}
+
+// VerifyKeyRotation checks that r replaces oldSigKey and is signed by both
+// the old and the new signing key.
+func VerifyKeyRotation(r *common_pb.KeyRotation, oldSigKey []byte) error {
+	if !bytes.Equal(r.GetOldSigningPublicKey(), oldSigKey) {
+		return fmt.Errorf("rotation %d does not follow the current signing key", r.GetSerial())
+	}
+
+	oldPub, err := keys.ParseSigningPublicKey(oldSigKey)
+	if err != nil {
+		return err
+	}
+	newPub, err := keys.ParseSigningPublicKey(r.GetNewSigningPublicKey())
+	if err != nil {
+		return err
+	}
+
+	msg := KeyRotationBytes(r)
+	if !ed25519.Verify(oldPub, msg, r.GetSignature()) {
+		return fmt.Errorf("rotation %d: bad signature from the old key", r.GetSerial())
+	}
+	if !ed25519.Verify(newPub, msg, r.GetNewSignature()) {
+		return fmt.Errorf("rotation %d: bad signature from the new key", r.GetSerial())
+	}
+	return nil
+}
+
+// BlockListBytes is what the user signs to replace their block list. The
+// domain count keeps the two lists apart.
+func BlockListBytes(l *common_pb.BlockList) []byte {
+	b := []byte("strike-block-list")
+	fields := []string{
+		l.GetUserId(),
+		strconv.FormatInt(l.GetUpdatedAt().AsTime().UnixNano(), 10),
+		strconv.Itoa(len(l.GetDomains())),
+	}
+	fields = append(fields, l.GetDomains()...)
+	fields = append(fields, l.GetUserIds()...)
+	for _, f := range fields {
+		b = append(b, 0)
+		b = append(b, f...)
+	}
+	return b
+}
+
+// FriendRemovedBytes is what the user signs to end a friendship.
+func FriendRemovedBytes(n *common_pb.FriendRemoved) []byte {
+	b := []byte("strike-friend-removed")
+	for _, f := range []string{
+		n.GetUserId(),
+		n.GetTarget(),
+		strconv.FormatInt(n.GetRemovedAt().AsTime().UnixNano(), 10),
+	} {
+		b = append(b, 0)
+		b = append(b, f...)
+	}
+	return b
+}
+
+// FollowKeyChain walks rotations, oldest first, from the trusted signing
+// key and returns the last rotation that links to it, or nil if none do.
+// Rotations before the trusted key are skipped; a broken link is an error.
+func FollowKeyChain(trusted []byte, rotations []*common_pb.KeyRotation) (*common_pb.KeyRotation, error) {
+	var last *common_pb.KeyRotation
+	current := trusted
+	for _, r := range rotations {
+		if last == nil && !bytes.Equal(r.GetOldSigningPublicKey(), current) {
+			continue
+		}
+		if err := VerifyKeyRotation(r, current); err != nil {
+			return nil, err
+		}
+		last = r
+		current = r.GetNewSigningPublicKey()
+	}
+	return last, nil
+}
@@ -0,0 +1,160 @@
+package shared
+
+import (
+	"crypto/ed25519"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/JohnnyGlynn/strike/internal/keys"
+	common_pb "github.com/JohnnyGlynn/strike/msgdef/common"
+)
+
+const testUserID = "11111111-1111-1111-1111-111111111111"
+
+type testSigningKey struct {
+	priv ed25519.PrivateKey
+	pub  []byte // PEM
+}
+
+func newTestSigningKey(t *testing.T) testSigningKey {
+	t.Helper()
+	privPEM, pubPEM, err := keys.GenerateSigningKeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := keys.ParseSigningPrivateKey(privPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testSigningKey{priv: priv, pub: pubPEM}
+}
+
+// testRotation returns rotation serial from old to next, signed by both.
+func testRotation(serial uint64, old, next testSigningKey) *common_pb.KeyRotation {
+	r := &common_pb.KeyRotation{
+		UserId:                 testUserID,
+		Serial:                 serial,
+		OldSigningPublicKey:    old.pub,
+		NewEncryptionPublicKey: []byte("enc"),
+		NewSigningPublicKey:    next.pub,
+		RotatedAt:              timestamppb.New(time.Unix(1700000000, 0).Add(time.Duration(serial) * time.Hour)),
+	}
+	msg := KeyRotationBytes(r)
+	r.Signature = ed25519.Sign(old.priv, msg)
+	r.NewSignature = ed25519.Sign(next.priv, msg)
+	return r
+}
+
+func TestVerifyKeyRotation(t *testing.T) {
+	k0, k1, k2 := newTestSigningKey(t), newTestSigningKey(t), newTestSigningKey(t)
+	r1 := testRotation(1, k0, k1)
+
+	modified := func(mod func(r *common_pb.KeyRotation)) *common_pb.KeyRotation {
+		r := proto.Clone(r1).(*common_pb.KeyRotation)
+		mod(r)
+		return r
+	}
+
+	cases := map[string]struct {
+		rotation *common_pb.KeyRotation
+		current  []byte
+		errText  string
+	}{
+		"valid": {rotation: r1, current: k0.pub},
+		"missing-old-signature": {
+			rotation: modified(func(r *common_pb.KeyRotation) { r.Signature = nil }),
+			current:  k0.pub,
+			errText:  "bad signature from the old key",
+		},
+		"missing-new-signature": {
+			rotation: modified(func(r *common_pb.KeyRotation) { r.NewSignature = nil }),
+			current:  k0.pub,
+			errText:  "bad signature from the new key",
+		},
+		"signed-by-new-key-only": {
+			rotation: modified(func(r *common_pb.KeyRotation) { r.Signature = r.NewSignature }),
+			current:  k0.pub,
+			errText:  "bad signature from the old key",
+		},
+		"changed-after-signing": {
+			rotation: modified(func(r *common_pb.KeyRotation) { r.NewEncryptionPublicKey = []byte("other") }),
+			current:  k0.pub,
+			errText:  "bad signature from the old key",
+		},
+		"replayed-old-rotation": {
+			// r1 again, after a second rotation moved the key on to k2.
+			rotation: r1,
+			current:  k2.pub,
+			errText:  "does not follow the current signing key",
+		},
+		"malformed-new-key": {
+			rotation: modified(func(r *common_pb.KeyRotation) { r.NewSigningPublicKey = []byte("not a key") }),
+			current:  k0.pub,
+			errText:  "failed to decode PEM",
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := VerifyKeyRotation(tc.rotation, tc.current)
+			switch {
+			case tc.errText == "" && err != nil:
+				t.Fatalf("VerifyKeyRotation: %v", err)
+			case tc.errText != "" && (err == nil || !strings.Contains(err.Error(), tc.errText)):
+				t.Fatalf("error = %v, want it to contain %q", err, tc.errText)
+			}
+		})
+	}
+}
+
+func TestFollowKeyChain(t *testing.T) {
+	k0, k1, k2, k3 := newTestSigningKey(t), newTestSigningKey(t), newTestSigningKey(t), newTestSigningKey(t)
+	r1, r2, r3 := testRotation(1, k0, k1), testRotation(2, k1, k2), testRotation(3, k2, k3)
+
+	unsignedR2 := proto.Clone(r2).(*common_pb.KeyRotation)
+	unsignedR2.NewSignature = nil
+
+	cases := map[string]struct {
+		trusted   []byte
+		rotations []*common_pb.KeyRotation
+		want      *common_pb.KeyRotation
+		errText   string
+	}{
+		"full-chain":        {trusted: k0.pub, rotations: []*common_pb.KeyRotation{r1, r2, r3}, want: r3},
+		"skips-older":       {trusted: k1.pub, rotations: []*common_pb.KeyRotation{r1, r2, r3}, want: r3},
+		"already-current":   {trusted: k3.pub, rotations: []*common_pb.KeyRotation{r1, r2, r3}},
+		"unknown-key":       {trusted: newTestSigningKey(t).pub, rotations: []*common_pb.KeyRotation{r1, r2, r3}},
+		"no-history":        {trusted: k0.pub},
+		"broken-link":       {trusted: k0.pub, rotations: []*common_pb.KeyRotation{r1, r3}, errText: "rotation 3 does not follow"},
+		"replayed-rotation": {trusted: k0.pub, rotations: []*common_pb.KeyRotation{r1, r2, r1}, errText: "rotation 1 does not follow"},
+		"unsigned-link":     {trusted: k0.pub, rotations: []*common_pb.KeyRotation{r1, unsignedR2, r3}, errText: "rotation 2: bad signature from the new key"},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := FollowKeyChain(tc.trusted, tc.rotations)
+			if tc.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errText) {
+					t.Fatalf("error = %v, want it to contain %q", err, tc.errText)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FollowKeyChain: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("FollowKeyChain = rotation %d, want rotation %d", got.GetSerial(), tc.want.GetSerial())
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package shared
+
+import "strings"
+
+// Suggest returns the candidate closest to input by edit distance, or "" if
+// nothing is close enough to be a plausible typo.
+func Suggest(input string, candidates []string) string {
+	best := ""
+	bestDist := len(input)/2 + 1
+
+	for _, c := range candidates {
+		d := levenshtein(strings.ToLower(input), strings.ToLower(c))
+		if d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+
+	return best
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
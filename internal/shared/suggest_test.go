@@ -0,0 +1,37 @@
+package shared
+
+import "testing"
+
+func TestSuggest(t *testing.T) {
+	commands := []string{"/addfriend", "/chat", "/exit", "/friends", "/help", "/history", "/requests"}
+
+	cases := map[string]struct {
+		input      string
+		candidates []string
+		want       string
+	}{
+		"missing-letter":    {input: "/frends", candidates: commands, want: "/friends"},
+		"extra-letter":      {input: "/chatt", candidates: commands, want: "/chat"},
+		"swapped-letters":   {input: "/hlep", candidates: commands, want: "/help"},
+		"case-insensitive":  {input: "/HISTORY", candidates: commands, want: "/history"},
+		"exact":             {input: "/exit", candidates: commands, want: "/exit"},
+		"closest-wins":      {input: "/histor", candidates: []string{"/hist", "/history"}, want: "/history"},
+		"first-of-a-tie":    {input: "/cat", candidates: []string{"/chat", "/cap"}, want: "/chat"},
+		"too-far":           {input: "/xyz", candidates: commands},
+		"short-input-typo":  {input: "/q", candidates: commands},
+		"no-candidates":     {input: "/frends"},
+		"empty-input":       {input: "", candidates: commands},
+		"non-ascii-letters": {input: "/grüße", candidates: []string{"/grüsse"}, want: "/grüsse"},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Suggest(tc.input, tc.candidates); got != tc.want {
+				t.Errorf("Suggest(%q) = %q, want %q", tc.input, got, tc.want)
+			}
+		})
+	}
+}
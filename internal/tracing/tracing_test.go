@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/JohnnyGlynn/strike/internal/config"
+)
+
+func TestQueuedPayloadStaysInTrace(t *testing.T) {
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), config.TracingConfig{Exporter: "stdout", SampleRatio: 1}, "strike-test", &buf)
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+
+	ctx, send := Tracer().Start(context.Background(), "send")
+	traceparent := Inject(ctx)
+	send.End()
+
+	_, deliver := Tracer().Start(Extract(context.Background(), traceparent), "deliver")
+	deliver.End()
+
+	if got, want := deliver.SpanContext().TraceID(), send.SpanContext().TraceID(); got != want {
+		t.Errorf("delivery trace %s, want %s", got, want)
+	}
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"Name": "deliver"`) {
+		t.Errorf("span not exported: %s", buf.String())
+	}
+}
+
+func TestExtractIgnoresMalformed(t *testing.T) {
+	ctx := Extract(context.Background(), "not-a-traceparent")
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		t.Error("error: malformed traceparent produced a span context")
+	}
+}
@@ -0,0 +1,534 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             v5.29.3
+// source: admin/admin.proto
+
+package admin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	Admin_ListUsers_FullMethodName         = "/admin.Admin/ListUsers"
+	Admin_DeleteUser_FullMethodName        = "/admin.Admin/DeleteUser"
+	Admin_SetUserDisabled_FullMethodName   = "/admin.Admin/SetUserDisabled"
+	Admin_ResetKeys_FullMethodName         = "/admin.Admin/ResetKeys"
+	Admin_ListPending_FullMethodName       = "/admin.Admin/ListPending"
+	Admin_ReplayPending_FullMethodName     = "/admin.Admin/ReplayPending"
+	Admin_ListDeadLetters_FullMethodName   = "/admin.Admin/ListDeadLetters"
+	Admin_ReplayDeadLetters_FullMethodName = "/admin.Admin/ReplayDeadLetters"
+	Admin_PurgeDeadLetters_FullMethodName  = "/admin.Admin/PurgeDeadLetters"
+	Admin_PeerStatus_FullMethodName        = "/admin.Admin/PeerStatus"
+	Admin_CreateInvite_FullMethodName      = "/admin.Admin/CreateInvite"
+	Admin_JoinFederation_FullMethodName    = "/admin.Admin/JoinFederation"
+)
+
+// AdminClient is the client API for Admin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Admin is served on its own listener and requires the admin token in the
+// "authorization" metadata of every call.
+type AdminClient interface {
+	ListUsers(ctx context.Context, in *ListUsersReq, opts ...grpc.CallOption) (*ListUsersResp, error)
+	DeleteUser(ctx context.Context, in *UserRef, opts ...grpc.CallOption) (*AdminAck, error)
+	SetUserDisabled(ctx context.Context, in *SetUserDisabledReq, opts ...grpc.CallOption) (*AdminAck, error)
+	ResetKeys(ctx context.Context, in *ResetKeysReq, opts ...grpc.CallOption) (*AdminAck, error)
+	ListPending(ctx context.Context, in *ListQueueReq, opts ...grpc.CallOption) (*QueueEntries, error)
+	ReplayPending(ctx context.Context, in *QueueRef, opts ...grpc.CallOption) (*AdminAck, error)
+	ListDeadLetters(ctx context.Context, in *ListQueueReq, opts ...grpc.CallOption) (*QueueEntries, error)
+	ReplayDeadLetters(ctx context.Context, in *QueueRef, opts ...grpc.CallOption) (*AdminAck, error)
+	PurgeDeadLetters(ctx context.Context, in *QueueRef, opts ...grpc.CallOption) (*AdminAck, error)
+	PeerStatus(ctx context.Context, in *PeerStatusReq, opts ...grpc.CallOption) (*PeerStatusResp, error)
+	CreateInvite(ctx context.Context, in *CreateInviteReq, opts ...grpc.CallOption) (*CreateInviteResp, error)
+	JoinFederation(ctx context.Context, in *JoinFederationReq, opts ...grpc.CallOption) (*AdminAck, error)
+}
+
+type adminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) ListUsers(ctx context.Context, in *ListUsersReq, opts ...grpc.CallOption) (*ListUsersResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUsersResp)
+	err := c.cc.Invoke(ctx, Admin_ListUsers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) DeleteUser(ctx context.Context, in *UserRef, opts ...grpc.CallOption) (*AdminAck, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminAck)
+	err := c.cc.Invoke(ctx, Admin_DeleteUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) SetUserDisabled(ctx context.Context, in *SetUserDisabledReq, opts ...grpc.CallOption) (*AdminAck, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminAck)
+	err := c.cc.Invoke(ctx, Admin_SetUserDisabled_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ResetKeys(ctx context.Context, in *ResetKeysReq, opts ...grpc.CallOption) (*AdminAck, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminAck)
+	err := c.cc.Invoke(ctx, Admin_ResetKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ListPending(ctx context.Context, in *ListQueueReq, opts ...grpc.CallOption) (*QueueEntries, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueueEntries)
+	err := c.cc.Invoke(ctx, Admin_ListPending_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ReplayPending(ctx context.Context, in *QueueRef, opts ...grpc.CallOption) (*AdminAck, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminAck)
+	err := c.cc.Invoke(ctx, Admin_ReplayPending_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ListDeadLetters(ctx context.Context, in *ListQueueReq, opts ...grpc.CallOption) (*QueueEntries, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueueEntries)
+	err := c.cc.Invoke(ctx, Admin_ListDeadLetters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ReplayDeadLetters(ctx context.Context, in *QueueRef, opts ...grpc.CallOption) (*AdminAck, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminAck)
+	err := c.cc.Invoke(ctx, Admin_ReplayDeadLetters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) PurgeDeadLetters(ctx context.Context, in *QueueRef, opts ...grpc.CallOption) (*AdminAck, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminAck)
+	err := c.cc.Invoke(ctx, Admin_PurgeDeadLetters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) PeerStatus(ctx context.Context, in *PeerStatusReq, opts ...grpc.CallOption) (*PeerStatusResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PeerStatusResp)
+	err := c.cc.Invoke(ctx, Admin_PeerStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) CreateInvite(ctx context.Context, in *CreateInviteReq, opts ...grpc.CallOption) (*CreateInviteResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateInviteResp)
+	err := c.cc.Invoke(ctx, Admin_CreateInvite_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) JoinFederation(ctx context.Context, in *JoinFederationReq, opts ...grpc.CallOption) (*AdminAck, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminAck)
+	err := c.cc.Invoke(ctx, Admin_JoinFederation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServer is the server API for Admin service.
+// All implementations must embed UnimplementedAdminServer
+// for forward compatibility
+//
+// Admin is served on its own listener and requires the admin token in the
+// "authorization" metadata of every call.
+type AdminServer interface {
+	ListUsers(context.Context, *ListUsersReq) (*ListUsersResp, error)
+	DeleteUser(context.Context, *UserRef) (*AdminAck, error)
+	SetUserDisabled(context.Context, *SetUserDisabledReq) (*AdminAck, error)
+	ResetKeys(context.Context, *ResetKeysReq) (*AdminAck, error)
+	ListPending(context.Context, *ListQueueReq) (*QueueEntries, error)
+	ReplayPending(context.Context, *QueueRef) (*AdminAck, error)
+	ListDeadLetters(context.Context, *ListQueueReq) (*QueueEntries, error)
+	ReplayDeadLetters(context.Context, *QueueRef) (*AdminAck, error)
+	PurgeDeadLetters(context.Context, *QueueRef) (*AdminAck, error)
+	PeerStatus(context.Context, *PeerStatusReq) (*PeerStatusResp, error)
+	CreateInvite(context.Context, *CreateInviteReq) (*CreateInviteResp, error)
+	JoinFederation(context.Context, *JoinFederationReq) (*AdminAck, error)
+	mustEmbedUnimplementedAdminServer()
+}
+
+// UnimplementedAdminServer must be embedded to have forward compatible implementations.
+type UnimplementedAdminServer struct {
+}
+
+func (UnimplementedAdminServer) ListUsers(context.Context, *ListUsersReq) (*ListUsersResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUsers not implemented")
+}
+func (UnimplementedAdminServer) DeleteUser(context.Context, *UserRef) (*AdminAck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedAdminServer) SetUserDisabled(context.Context, *SetUserDisabledReq) (*AdminAck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetUserDisabled not implemented")
+}
+func (UnimplementedAdminServer) ResetKeys(context.Context, *ResetKeysReq) (*AdminAck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetKeys not implemented")
+}
+func (UnimplementedAdminServer) ListPending(context.Context, *ListQueueReq) (*QueueEntries, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPending not implemented")
+}
+func (UnimplementedAdminServer) ReplayPending(context.Context, *QueueRef) (*AdminAck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplayPending not implemented")
+}
+func (UnimplementedAdminServer) ListDeadLetters(context.Context, *ListQueueReq) (*QueueEntries, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeadLetters not implemented")
+}
+func (UnimplementedAdminServer) ReplayDeadLetters(context.Context, *QueueRef) (*AdminAck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplayDeadLetters not implemented")
+}
+func (UnimplementedAdminServer) PurgeDeadLetters(context.Context, *QueueRef) (*AdminAck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeDeadLetters not implemented")
+}
+func (UnimplementedAdminServer) PeerStatus(context.Context, *PeerStatusReq) (*PeerStatusResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PeerStatus not implemented")
+}
+func (UnimplementedAdminServer) CreateInvite(context.Context, *CreateInviteReq) (*CreateInviteResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateInvite not implemented")
+}
+func (UnimplementedAdminServer) JoinFederation(context.Context, *JoinFederationReq) (*AdminAck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method JoinFederation not implemented")
+}
+func (UnimplementedAdminServer) mustEmbedUnimplementedAdminServer() {}
+
+// UnsafeAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServer will
+// result in compilation errors.
+type UnsafeAdminServer interface {
+	mustEmbedUnimplementedAdminServer()
+}
+
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	s.RegisterService(&Admin_ServiceDesc, srv)
+}
+
+func _Admin_ListUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUsersReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ListUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ListUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ListUsers(ctx, req.(*ListUsersReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_DeleteUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).DeleteUser(ctx, req.(*UserRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_SetUserDisabled_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetUserDisabledReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).SetUserDisabled(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_SetUserDisabled_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).SetUserDisabled(ctx, req.(*SetUserDisabledReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ResetKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetKeysReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ResetKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ResetKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ResetKeys(ctx, req.(*ResetKeysReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ListPending_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListQueueReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ListPending(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ListPending_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ListPending(ctx, req.(*ListQueueReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ReplayPending_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueueRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ReplayPending(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ReplayPending_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ReplayPending(ctx, req.(*QueueRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ListDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListQueueReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ListDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ListDeadLetters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ListDeadLetters(ctx, req.(*ListQueueReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ReplayDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueueRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ReplayDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ReplayDeadLetters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ReplayDeadLetters(ctx, req.(*QueueRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_PurgeDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueueRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).PurgeDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_PurgeDeadLetters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).PurgeDeadLetters(ctx, req.(*QueueRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_PeerStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PeerStatusReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).PeerStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_PeerStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).PeerStatus(ctx, req.(*PeerStatusReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_CreateInvite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateInviteReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).CreateInvite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_CreateInvite_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).CreateInvite(ctx, req.(*CreateInviteReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_JoinFederation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JoinFederationReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).JoinFederation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_JoinFederation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).JoinFederation(ctx, req.(*JoinFederationReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Admin_ServiceDesc is the grpc.ServiceDesc for Admin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Admin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "admin.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListUsers",
+			Handler:    _Admin_ListUsers_Handler,
+		},
+		{
+			MethodName: "DeleteUser",
+			Handler:    _Admin_DeleteUser_Handler,
+		},
+		{
+			MethodName: "SetUserDisabled",
+			Handler:    _Admin_SetUserDisabled_Handler,
+		},
+		{
+			MethodName: "ResetKeys",
+			Handler:    _Admin_ResetKeys_Handler,
+		},
+		{
+			MethodName: "ListPending",
+			Handler:    _Admin_ListPending_Handler,
+		},
+		{
+			MethodName: "ReplayPending",
+			Handler:    _Admin_ReplayPending_Handler,
+		},
+		{
+			MethodName: "ListDeadLetters",
+			Handler:    _Admin_ListDeadLetters_Handler,
+		},
+		{
+			MethodName: "ReplayDeadLetters",
+			Handler:    _Admin_ReplayDeadLetters_Handler,
+		},
+		{
+			MethodName: "PurgeDeadLetters",
+			Handler:    _Admin_PurgeDeadLetters_Handler,
+		},
+		{
+			MethodName: "PeerStatus",
+			Handler:    _Admin_PeerStatus_Handler,
+		},
+		{
+			MethodName: "CreateInvite",
+			Handler:    _Admin_CreateInvite_Handler,
+		},
+		{
+			MethodName: "JoinFederation",
+			Handler:    _Admin_JoinFederation_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admin/admin.proto",
+}
@@ -345,6 +345,8 @@ type UserLookupResp struct {
 	Found    bool             `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
 	UserInfo *common.UserInfo `protobuf:"bytes,2,opt,name=user_info,json=userInfo,proto3" json:"user_info,omitempty"`
 	Domain   string           `protobuf:"bytes,3,opt,name=domain,proto3" json:"domain,omitempty"`
+	// Set instead of user_info when the user has been renamed or has moved.
+	MovedTo *common.AccountMoved `protobuf:"bytes,4,opt,name=moved_to,json=movedTo,proto3" json:"moved_to,omitempty"`
 }
 
 func (x *UserLookupResp) Reset() {
@@ -400,6 +402,564 @@ func (x *UserLookupResp) GetDomain() string {
 	return ""
 }
 
+func (x *UserLookupResp) GetMovedTo() *common.AccountMoved {
+	if x != nil {
+		return x.MovedTo
+	}
+	return nil
+}
+
+// UserDeletedReq tells a peer that one of our users is gone so it can forget
+// their presence and drop anything still queued for them.
+type UserDeletedReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	OriginServer string                 `protobuf:"bytes,1,opt,name=origin_server,json=originServer,proto3" json:"origin_server,omitempty"`
+	Notice       *common.AccountDeleted `protobuf:"bytes,2,opt,name=notice,proto3" json:"notice,omitempty"`
+}
+
+func (x *UserDeletedReq) Reset() {
+	*x = UserDeletedReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_federation_federation_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UserDeletedReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserDeletedReq) ProtoMessage() {}
+
+func (x *UserDeletedReq) ProtoReflect() protoreflect.Message {
+	mi := &file_federation_federation_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserDeletedReq.ProtoReflect.Descriptor instead.
+func (*UserDeletedReq) Descriptor() ([]byte, []int) {
+	return file_federation_federation_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UserDeletedReq) GetOriginServer() string {
+	if x != nil {
+		return x.OriginServer
+	}
+	return ""
+}
+
+func (x *UserDeletedReq) GetNotice() *common.AccountDeleted {
+	if x != nil {
+		return x.Notice
+	}
+	return nil
+}
+
+type UserDeletedAck struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Accepted bool   `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Info     string `protobuf:"bytes,2,opt,name=info,proto3" json:"info,omitempty"`
+}
+
+func (x *UserDeletedAck) Reset() {
+	*x = UserDeletedAck{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_federation_federation_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UserDeletedAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserDeletedAck) ProtoMessage() {}
+
+func (x *UserDeletedAck) ProtoReflect() protoreflect.Message {
+	mi := &file_federation_federation_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserDeletedAck.ProtoReflect.Descriptor instead.
+func (*UserDeletedAck) Descriptor() ([]byte, []int) {
+	return file_federation_federation_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UserDeletedAck) GetAccepted() bool {
+	if x != nil {
+		return x.Accepted
+	}
+	return false
+}
+
+func (x *UserDeletedAck) GetInfo() string {
+	if x != nil {
+		return x.Info
+	}
+	return ""
+}
+
+// AccountMovedReq carries a countersigned redirect from the user's old
+// server.
+type AccountMovedReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	OriginServer string               `protobuf:"bytes,1,opt,name=origin_server,json=originServer,proto3" json:"origin_server,omitempty"`
+	Notice       *common.AccountMoved `protobuf:"bytes,2,opt,name=notice,proto3" json:"notice,omitempty"`
+}
+
+func (x *AccountMovedReq) Reset() {
+	*x = AccountMovedReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_federation_federation_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AccountMovedReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccountMovedReq) ProtoMessage() {}
+
+func (x *AccountMovedReq) ProtoReflect() protoreflect.Message {
+	mi := &file_federation_federation_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccountMovedReq.ProtoReflect.Descriptor instead.
+func (*AccountMovedReq) Descriptor() ([]byte, []int) {
+	return file_federation_federation_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AccountMovedReq) GetOriginServer() string {
+	if x != nil {
+		return x.OriginServer
+	}
+	return ""
+}
+
+func (x *AccountMovedReq) GetNotice() *common.AccountMoved {
+	if x != nil {
+		return x.Notice
+	}
+	return nil
+}
+
+type AccountMovedAck struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Accepted bool   `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Info     string `protobuf:"bytes,2,opt,name=info,proto3" json:"info,omitempty"`
+}
+
+func (x *AccountMovedAck) Reset() {
+	*x = AccountMovedAck{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_federation_federation_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AccountMovedAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccountMovedAck) ProtoMessage() {}
+
+func (x *AccountMovedAck) ProtoReflect() protoreflect.Message {
+	mi := &file_federation_federation_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccountMovedAck.ProtoReflect.Descriptor instead.
+func (*AccountMovedAck) Descriptor() ([]byte, []int) {
+	return file_federation_federation_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AccountMovedAck) GetAccepted() bool {
+	if x != nil {
+		return x.Accepted
+	}
+	return false
+}
+
+func (x *AccountMovedAck) GetInfo() string {
+	if x != nil {
+		return x.Info
+	}
+	return ""
+}
+
+type KeyHistoryResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Found     bool                  `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	Rotations []*common.KeyRotation `protobuf:"bytes,2,rep,name=rotations,proto3" json:"rotations,omitempty"`
+}
+
+func (x *KeyHistoryResp) Reset() {
+	*x = KeyHistoryResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_federation_federation_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *KeyHistoryResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyHistoryResp) ProtoMessage() {}
+
+func (x *KeyHistoryResp) ProtoReflect() protoreflect.Message {
+	mi := &file_federation_federation_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyHistoryResp.ProtoReflect.Descriptor instead.
+func (*KeyHistoryResp) Descriptor() ([]byte, []int) {
+	return file_federation_federation_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *KeyHistoryResp) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *KeyHistoryResp) GetRotations() []*common.KeyRotation {
+	if x != nil {
+		return x.Rotations
+	}
+	return nil
+}
+
+// PeerDetails is one server's federation.yaml entry, plus the certificate of
+// the CA that issued its federation certificate.
+type PeerDetails struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ServerId      string `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	ServerName    string `protobuf:"bytes,2,opt,name=server_name,json=serverName,proto3" json:"server_name,omitempty"`
+	Address       string `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	PublicKey     []byte `protobuf:"bytes,4,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`             // SPKI DER
+	CaCertificate []byte `protobuf:"bytes,5,opt,name=ca_certificate,json=caCertificate,proto3" json:"ca_certificate,omitempty"` // DER
+}
+
+func (x *PeerDetails) Reset() {
+	*x = PeerDetails{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_federation_federation_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PeerDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerDetails) ProtoMessage() {}
+
+func (x *PeerDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_federation_federation_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerDetails.ProtoReflect.Descriptor instead.
+func (*PeerDetails) Descriptor() ([]byte, []int) {
+	return file_federation_federation_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PeerDetails) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *PeerDetails) GetServerName() string {
+	if x != nil {
+		return x.ServerName
+	}
+	return ""
+}
+
+func (x *PeerDetails) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *PeerDetails) GetPublicKey() []byte {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *PeerDetails) GetCaCertificate() []byte {
+	if x != nil {
+		return x.CaCertificate
+	}
+	return nil
+}
+
+// FederationInvite is signed by the inviting server's signing key and is
+// good for one Join before expires_at.
+type FederationInvite struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Inviter   *PeerDetails           `protobuf:"bytes,1,opt,name=inviter,proto3" json:"inviter,omitempty"`
+	Nonce     []byte                 `protobuf:"bytes,2,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	ExpiresAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	Signature []byte                 `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (x *FederationInvite) Reset() {
+	*x = FederationInvite{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_federation_federation_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FederationInvite) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederationInvite) ProtoMessage() {}
+
+func (x *FederationInvite) ProtoReflect() protoreflect.Message {
+	mi := &file_federation_federation_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederationInvite.ProtoReflect.Descriptor instead.
+func (*FederationInvite) Descriptor() ([]byte, []int) {
+	return file_federation_federation_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *FederationInvite) GetInviter() *PeerDetails {
+	if x != nil {
+		return x.Inviter
+	}
+	return nil
+}
+
+func (x *FederationInvite) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *FederationInvite) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *FederationInvite) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type JoinReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Invite *FederationInvite `protobuf:"bytes,1,opt,name=invite,proto3" json:"invite,omitempty"`
+	Joiner *PeerDetails      `protobuf:"bytes,2,opt,name=joiner,proto3" json:"joiner,omitempty"`
+}
+
+func (x *JoinReq) Reset() {
+	*x = JoinReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_federation_federation_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *JoinReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JoinReq) ProtoMessage() {}
+
+func (x *JoinReq) ProtoReflect() protoreflect.Message {
+	mi := &file_federation_federation_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JoinReq.ProtoReflect.Descriptor instead.
+func (*JoinReq) Descriptor() ([]byte, []int) {
+	return file_federation_federation_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *JoinReq) GetInvite() *FederationInvite {
+	if x != nil {
+		return x.Invite
+	}
+	return nil
+}
+
+func (x *JoinReq) GetJoiner() *PeerDetails {
+	if x != nil {
+		return x.Joiner
+	}
+	return nil
+}
+
+type JoinResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ok      bool         `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Inviter *PeerDetails `protobuf:"bytes,2,opt,name=inviter,proto3" json:"inviter,omitempty"`
+	Message string       `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *JoinResp) Reset() {
+	*x = JoinResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_federation_federation_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *JoinResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JoinResp) ProtoMessage() {}
+
+func (x *JoinResp) ProtoReflect() protoreflect.Message {
+	mi := &file_federation_federation_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JoinResp.ProtoReflect.Descriptor instead.
+func (*JoinResp) Descriptor() ([]byte, []int) {
+	return file_federation_federation_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *JoinResp) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *JoinResp) GetInviter() *PeerDetails {
+	if x != nil {
+		return x.Inviter
+	}
+	return nil
+}
+
+func (x *JoinResp) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
 var File_federation_federation_proto protoreflect.FileDescriptor
 
 var file_federation_federation_proto_rawDesc = []byte{
@@ -444,31 +1004,114 @@ var file_federation_federation_proto_rawDesc = []byte{
 	0x69, 0x6e, 0x66, 0x6f, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x69, 0x6e, 0x66, 0x6f,
 	0x22, 0x2b, 0x0a, 0x0d, 0x55, 0x73, 0x65, 0x72, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x52, 0x65,
 	0x71, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0x6d, 0x0a,
-	0x0e, 0x55, 0x73, 0x65, 0x72, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x52, 0x65, 0x73, 0x70, 0x12,
-	0x14, 0x0a, 0x05, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05,
-	0x66, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x2d, 0x0a, 0x09, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x6e,
-	0x66, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f,
-	0x6e, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72,
-	0x49, 0x6e, 0x66, 0x6f, 0x12, 0x16, 0x0a, 0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x18, 0x03,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x32, 0xcb, 0x01, 0x0a,
-	0x0a, 0x46, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x3f, 0x0a, 0x09, 0x48,
-	0x61, 0x6e, 0x64, 0x73, 0x68, 0x61, 0x6b, 0x65, 0x12, 0x18, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72,
-	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x48, 0x61, 0x6e, 0x64, 0x73, 0x68, 0x61, 0x6b, 0x65, 0x52,
-	0x65, 0x71, 0x1a, 0x18, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e,
-	0x48, 0x61, 0x6e, 0x64, 0x73, 0x68, 0x61, 0x6b, 0x65, 0x41, 0x63, 0x6b, 0x12, 0x37, 0x0a, 0x05,
-	0x52, 0x65, 0x6c, 0x61, 0x79, 0x12, 0x18, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69,
-	0x6f, 0x6e, 0x2e, 0x52, 0x65, 0x6c, 0x61, 0x79, 0x50, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x1a,
-	0x14, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x52, 0x65, 0x6c,
-	0x61, 0x79, 0x41, 0x63, 0x6b, 0x12, 0x43, 0x0a, 0x0a, 0x55, 0x73, 0x65, 0x72, 0x4c, 0x6f, 0x6f,
-	0x6b, 0x75, 0x70, 0x12, 0x19, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
-	0x2e, 0x55, 0x73, 0x65, 0x72, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x52, 0x65, 0x71, 0x1a, 0x1a,
-	0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x55, 0x73, 0x65, 0x72,
-	0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x52, 0x65, 0x73, 0x70, 0x42, 0x3c, 0x5a, 0x3a, 0x67, 0x69,
-	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x4a, 0x6f, 0x68, 0x6e, 0x6e, 0x79, 0x47,
-	0x6c, 0x79, 0x6e, 0x6e, 0x2f, 0x73, 0x74, 0x72, 0x69, 0x6b, 0x65, 0x2f, 0x6d, 0x73, 0x67, 0x64,
-	0x65, 0x66, 0x2f, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x3b, 0x66, 0x65,
-	0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61, 0x6d, 0x65, 0x22, 0x9e, 0x01,
+	0x0a, 0x0e, 0x55, 0x73, 0x65, 0x72, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x52, 0x65, 0x73, 0x70,
+	0x12, 0x14, 0x0a, 0x05, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x05, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x2d, 0x0a, 0x09, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69,
+	0x6e, 0x66, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x63, 0x6f, 0x6d, 0x6d,
+	0x6f, 0x6e, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x08, 0x75, 0x73, 0x65,
+	0x72, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x16, 0x0a, 0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x12, 0x2f, 0x0a,
+	0x08, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x5f, 0x74, 0x6f, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x14, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74,
+	0x4d, 0x6f, 0x76, 0x65, 0x64, 0x52, 0x07, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x54, 0x6f, 0x22, 0x65,
+	0x0a, 0x0e, 0x55, 0x73, 0x65, 0x72, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x52, 0x65, 0x71,
+	0x12, 0x23, 0x0a, 0x0d, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x53,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x12, 0x2e, 0x0a, 0x06, 0x6e, 0x6f, 0x74, 0x69, 0x63, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x41,
+	0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x52, 0x06, 0x6e,
+	0x6f, 0x74, 0x69, 0x63, 0x65, 0x22, 0x40, 0x0a, 0x0e, 0x55, 0x73, 0x65, 0x72, 0x44, 0x65, 0x6c,
+	0x65, 0x74, 0x65, 0x64, 0x41, 0x63, 0x6b, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x63, 0x63, 0x65, 0x70,
+	0x74, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x61, 0x63, 0x63, 0x65, 0x70,
+	0x74, 0x65, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x69, 0x6e, 0x66, 0x6f, 0x22, 0x64, 0x0a, 0x0f, 0x41, 0x63, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x4d, 0x6f, 0x76, 0x65, 0x64, 0x52, 0x65, 0x71, 0x12, 0x23, 0x0a, 0x0d, 0x6f, 0x72,
+	0x69, 0x67, 0x69, 0x6e, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0c, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x12,
+	0x2c, 0x0a, 0x06, 0x6e, 0x6f, 0x74, 0x69, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x14, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74,
+	0x4d, 0x6f, 0x76, 0x65, 0x64, 0x52, 0x06, 0x6e, 0x6f, 0x74, 0x69, 0x63, 0x65, 0x22, 0x41, 0x0a,
+	0x0f, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x4d, 0x6f, 0x76, 0x65, 0x64, 0x41, 0x63, 0x6b,
+	0x12, 0x1a, 0x0a, 0x08, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x08, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65, 0x64, 0x12, 0x12, 0x0a, 0x04,
+	0x69, 0x6e, 0x66, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x69, 0x6e, 0x66, 0x6f,
+	0x22, 0x59, 0x0a, 0x0e, 0x4b, 0x65, 0x79, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65,
+	0x73, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x05, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x31, 0x0a, 0x09, 0x72, 0x6f, 0x74, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x63, 0x6f,
+	0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x4b, 0x65, 0x79, 0x52, 0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x09, 0x72, 0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0xab, 0x01, 0x0a, 0x0b,
+	0x50, 0x65, 0x65, 0x72, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x49, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x5f, 0x6b, 0x65,
+	0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b,
+	0x65, 0x79, 0x12, 0x25, 0x0a, 0x0e, 0x63, 0x61, 0x5f, 0x63, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69,
+	0x63, 0x61, 0x74, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0d, 0x63, 0x61, 0x43, 0x65,
+	0x72, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x65, 0x22, 0xb4, 0x01, 0x0a, 0x10, 0x46, 0x65,
+	0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x76, 0x69, 0x74, 0x65, 0x12, 0x31,
+	0x0a, 0x07, 0x69, 0x6e, 0x76, 0x69, 0x74, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x17, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x50, 0x65, 0x65,
+	0x72, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x52, 0x07, 0x69, 0x6e, 0x76, 0x69, 0x74, 0x65,
+	0x72, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x39, 0x0a, 0x0a, 0x65, 0x78, 0x70, 0x69, 0x72,
+	0x65, 0x73, 0x5f, 0x61, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69,
+	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73,
+	0x41, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x22, 0x70, 0x0a, 0x07, 0x4a, 0x6f, 0x69, 0x6e, 0x52, 0x65, 0x71, 0x12, 0x34, 0x0a, 0x06, 0x69,
+	0x6e, 0x76, 0x69, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x66, 0x65,
+	0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x46, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x76, 0x69, 0x74, 0x65, 0x52, 0x06, 0x69, 0x6e, 0x76, 0x69, 0x74,
+	0x65, 0x12, 0x2f, 0x0a, 0x06, 0x6a, 0x6f, 0x69, 0x6e, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x17, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x50,
+	0x65, 0x65, 0x72, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x52, 0x06, 0x6a, 0x6f, 0x69, 0x6e,
+	0x65, 0x72, 0x22, 0x67, 0x0a, 0x08, 0x4a, 0x6f, 0x69, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x12, 0x0e,
+	0x0a, 0x02, 0x6f, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x02, 0x6f, 0x6b, 0x12, 0x31,
+	0x0a, 0x07, 0x69, 0x6e, 0x76, 0x69, 0x74, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x17, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x50, 0x65, 0x65,
+	0x72, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x73, 0x52, 0x07, 0x69, 0x6e, 0x76, 0x69, 0x74, 0x65,
+	0x72, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x32, 0xd4, 0x03, 0x0a, 0x0a,
+	0x46, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x3f, 0x0a, 0x09, 0x48, 0x61,
+	0x6e, 0x64, 0x73, 0x68, 0x61, 0x6b, 0x65, 0x12, 0x18, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x48, 0x61, 0x6e, 0x64, 0x73, 0x68, 0x61, 0x6b, 0x65, 0x52, 0x65,
+	0x71, 0x1a, 0x18, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x48,
+	0x61, 0x6e, 0x64, 0x73, 0x68, 0x61, 0x6b, 0x65, 0x41, 0x63, 0x6b, 0x12, 0x37, 0x0a, 0x05, 0x52,
+	0x65, 0x6c, 0x61, 0x79, 0x12, 0x18, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x2e, 0x52, 0x65, 0x6c, 0x61, 0x79, 0x50, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x1a, 0x14,
+	0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x52, 0x65, 0x6c, 0x61,
+	0x79, 0x41, 0x63, 0x6b, 0x12, 0x43, 0x0a, 0x0a, 0x55, 0x73, 0x65, 0x72, 0x4c, 0x6f, 0x6f, 0x6b,
+	0x75, 0x70, 0x12, 0x19, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e,
+	0x55, 0x73, 0x65, 0x72, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x52, 0x65, 0x71, 0x1a, 0x1a, 0x2e,
+	0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x4c,
+	0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x52, 0x65, 0x73, 0x70, 0x12, 0x45, 0x0a, 0x0b, 0x55, 0x73, 0x65,
+	0x72, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x12, 0x1a, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65,
+	0x64, 0x52, 0x65, 0x71, 0x1a, 0x1a, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x41, 0x63, 0x6b,
+	0x12, 0x48, 0x0a, 0x0c, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x4d, 0x6f, 0x76, 0x65, 0x64,
+	0x12, 0x1b, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x41, 0x63,
+	0x63, 0x6f, 0x75, 0x6e, 0x74, 0x4d, 0x6f, 0x76, 0x65, 0x64, 0x52, 0x65, 0x71, 0x1a, 0x1b, 0x2e,
+	0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x41, 0x63, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x4d, 0x6f, 0x76, 0x65, 0x64, 0x41, 0x63, 0x6b, 0x12, 0x43, 0x0a, 0x0a, 0x4b, 0x65,
+	0x79, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x12, 0x19, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70,
+	0x52, 0x65, 0x71, 0x1a, 0x1a, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x2e, 0x4b, 0x65, 0x79, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x12,
+	0x31, 0x0a, 0x04, 0x4a, 0x6f, 0x69, 0x6e, 0x12, 0x13, 0x2e, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x4a, 0x6f, 0x69, 0x6e, 0x52, 0x65, 0x71, 0x1a, 0x14, 0x2e, 0x66,
+	0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x4a, 0x6f, 0x69, 0x6e, 0x52, 0x65,
+	0x73, 0x70, 0x42, 0x3c, 0x5a, 0x3a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
+	0x2f, 0x4a, 0x6f, 0x68, 0x6e, 0x6e, 0x79, 0x47, 0x6c, 0x79, 0x6e, 0x6e, 0x2f, 0x73, 0x74, 0x72,
+	0x69, 0x6b, 0x65, 0x2f, 0x6d, 0x73, 0x67, 0x64, 0x65, 0x66, 0x2f, 0x66, 0x65, 0x64, 0x65, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x3b, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -483,7 +1126,7 @@ func file_federation_federation_proto_rawDescGZIP() []byte {
 	return file_federation_federation_proto_rawDescData
 }
 
-var file_federation_federation_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_federation_federation_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_federation_federation_proto_goTypes = []any{
 	(*HandshakeReq)(nil),          // 0: federation.HandshakeReq
 	(*HandshakeAck)(nil),          // 1: federation.HandshakeAck
@@ -491,26 +1134,55 @@ var file_federation_federation_proto_goTypes = []any{
 	(*RelayAck)(nil),              // 3: federation.RelayAck
 	(*UserLookupReq)(nil),         // 4: federation.UserLookupReq
 	(*UserLookupResp)(nil),        // 5: federation.UserLookupResp
-	(*common.UserAddress)(nil),    // 6: common.UserAddress
-	(*timestamppb.Timestamp)(nil), // 7: google.protobuf.Timestamp
-	(*common.UserInfo)(nil),       // 8: common.UserInfo
+	(*UserDeletedReq)(nil),        // 6: federation.UserDeletedReq
+	(*UserDeletedAck)(nil),        // 7: federation.UserDeletedAck
+	(*AccountMovedReq)(nil),       // 8: federation.AccountMovedReq
+	(*AccountMovedAck)(nil),       // 9: federation.AccountMovedAck
+	(*KeyHistoryResp)(nil),        // 10: federation.KeyHistoryResp
+	(*PeerDetails)(nil),           // 11: federation.PeerDetails
+	(*FederationInvite)(nil),      // 12: federation.FederationInvite
+	(*JoinReq)(nil),               // 13: federation.JoinReq
+	(*JoinResp)(nil),              // 14: federation.JoinResp
+	(*common.UserAddress)(nil),    // 15: common.UserAddress
+	(*timestamppb.Timestamp)(nil), // 16: google.protobuf.Timestamp
+	(*common.UserInfo)(nil),       // 17: common.UserInfo
+	(*common.AccountMoved)(nil),   // 18: common.AccountMoved
+	(*common.AccountDeleted)(nil), // 19: common.AccountDeleted
+	(*common.KeyRotation)(nil),    // 20: common.KeyRotation
 }
 var file_federation_federation_proto_depIdxs = []int32{
-	6, // 0: federation.RelayPayload.sender:type_name -> common.UserAddress
-	6, // 1: federation.RelayPayload.recipient:type_name -> common.UserAddress
-	7, // 2: federation.RelayPayload.sent_at:type_name -> google.protobuf.Timestamp
-	8, // 3: federation.UserLookupResp.user_info:type_name -> common.UserInfo
-	0, // 4: federation.Federation.Handshake:input_type -> federation.HandshakeReq
-	2, // 5: federation.Federation.Relay:input_type -> federation.RelayPayload
-	4, // 6: federation.Federation.UserLookup:input_type -> federation.UserLookupReq
-	1, // 7: federation.Federation.Handshake:output_type -> federation.HandshakeAck
-	3, // 8: federation.Federation.Relay:output_type -> federation.RelayAck
-	5, // 9: federation.Federation.UserLookup:output_type -> federation.UserLookupResp
-	7, // [7:10] is the sub-list for method output_type
-	4, // [4:7] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	15, // 0: federation.RelayPayload.sender:type_name -> common.UserAddress
+	15, // 1: federation.RelayPayload.recipient:type_name -> common.UserAddress
+	16, // 2: federation.RelayPayload.sent_at:type_name -> google.protobuf.Timestamp
+	17, // 3: federation.UserLookupResp.user_info:type_name -> common.UserInfo
+	18, // 4: federation.UserLookupResp.moved_to:type_name -> common.AccountMoved
+	19, // 5: federation.UserDeletedReq.notice:type_name -> common.AccountDeleted
+	18, // 6: federation.AccountMovedReq.notice:type_name -> common.AccountMoved
+	20, // 7: federation.KeyHistoryResp.rotations:type_name -> common.KeyRotation
+	11, // 8: federation.FederationInvite.inviter:type_name -> federation.PeerDetails
+	16, // 9: federation.FederationInvite.expires_at:type_name -> google.protobuf.Timestamp
+	12, // 10: federation.JoinReq.invite:type_name -> federation.FederationInvite
+	11, // 11: federation.JoinReq.joiner:type_name -> federation.PeerDetails
+	11, // 12: federation.JoinResp.inviter:type_name -> federation.PeerDetails
+	0,  // 13: federation.Federation.Handshake:input_type -> federation.HandshakeReq
+	2,  // 14: federation.Federation.Relay:input_type -> federation.RelayPayload
+	4,  // 15: federation.Federation.UserLookup:input_type -> federation.UserLookupReq
+	6,  // 16: federation.Federation.UserDeleted:input_type -> federation.UserDeletedReq
+	8,  // 17: federation.Federation.AccountMoved:input_type -> federation.AccountMovedReq
+	4,  // 18: federation.Federation.KeyHistory:input_type -> federation.UserLookupReq
+	13, // 19: federation.Federation.Join:input_type -> federation.JoinReq
+	1,  // 20: federation.Federation.Handshake:output_type -> federation.HandshakeAck
+	3,  // 21: federation.Federation.Relay:output_type -> federation.RelayAck
+	5,  // 22: federation.Federation.UserLookup:output_type -> federation.UserLookupResp
+	7,  // 23: federation.Federation.UserDeleted:output_type -> federation.UserDeletedAck
+	9,  // 24: federation.Federation.AccountMoved:output_type -> federation.AccountMovedAck
+	10, // 25: federation.Federation.KeyHistory:output_type -> federation.KeyHistoryResp
+	14, // 26: federation.Federation.Join:output_type -> federation.JoinResp
+	20, // [20:27] is the sub-list for method output_type
+	13, // [13:20] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_federation_federation_proto_init() }
@@ -591,6 +1263,114 @@ func file_federation_federation_proto_init() {
 				return nil
 			}
 		}
+		file_federation_federation_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*UserDeletedReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_federation_federation_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*UserDeletedAck); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_federation_federation_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*AccountMovedReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_federation_federation_proto_msgTypes[9].Exporter = func(v any, i int) any {
+			switch v := v.(*AccountMovedAck); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_federation_federation_proto_msgTypes[10].Exporter = func(v any, i int) any {
+			switch v := v.(*KeyHistoryResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_federation_federation_proto_msgTypes[11].Exporter = func(v any, i int) any {
+			switch v := v.(*PeerDetails); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_federation_federation_proto_msgTypes[12].Exporter = func(v any, i int) any {
+			switch v := v.(*FederationInvite); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_federation_federation_proto_msgTypes[13].Exporter = func(v any, i int) any {
+			switch v := v.(*JoinReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_federation_federation_proto_msgTypes[14].Exporter = func(v any, i int) any {
+			switch v := v.(*JoinResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -598,7 +1378,7 @@ func file_federation_federation_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_federation_federation_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,9 +19,13 @@ import (
 const _ = grpc.SupportPackageIsVersion8
 
 const (
-	Federation_Handshake_FullMethodName  = "/federation.Federation/Handshake"
-	Federation_Relay_FullMethodName      = "/federation.Federation/Relay"
-	Federation_UserLookup_FullMethodName = "/federation.Federation/UserLookup"
+	Federation_Handshake_FullMethodName    = "/federation.Federation/Handshake"
+	Federation_Relay_FullMethodName        = "/federation.Federation/Relay"
+	Federation_UserLookup_FullMethodName   = "/federation.Federation/UserLookup"
+	Federation_UserDeleted_FullMethodName  = "/federation.Federation/UserDeleted"
+	Federation_AccountMoved_FullMethodName = "/federation.Federation/AccountMoved"
+	Federation_KeyHistory_FullMethodName   = "/federation.Federation/KeyHistory"
+	Federation_Join_FullMethodName         = "/federation.Federation/Join"
 )
 
 // FederationClient is the client API for Federation service.
@@ -31,6 +35,12 @@ type FederationClient interface {
 	Handshake(ctx context.Context, in *HandshakeReq, opts ...grpc.CallOption) (*HandshakeAck, error)
 	Relay(ctx context.Context, in *RelayPayload, opts ...grpc.CallOption) (*RelayAck, error)
 	UserLookup(ctx context.Context, in *UserLookupReq, opts ...grpc.CallOption) (*UserLookupResp, error)
+	UserDeleted(ctx context.Context, in *UserDeletedReq, opts ...grpc.CallOption) (*UserDeletedAck, error)
+	AccountMoved(ctx context.Context, in *AccountMovedReq, opts ...grpc.CallOption) (*AccountMovedAck, error)
+	KeyHistory(ctx context.Context, in *UserLookupReq, opts ...grpc.CallOption) (*KeyHistoryResp, error)
+	// Join is the only call a server whose certificate we do not trust yet may
+	// make. It redeems an invitation and exchanges reciprocal peer details.
+	Join(ctx context.Context, in *JoinReq, opts ...grpc.CallOption) (*JoinResp, error)
 }
 
 type federationClient struct {
@@ -71,6 +81,46 @@ func (c *federationClient) UserLookup(ctx context.Context, in *UserLookupReq, op
 	return out, nil
 }
 
+func (c *federationClient) UserDeleted(ctx context.Context, in *UserDeletedReq, opts ...grpc.CallOption) (*UserDeletedAck, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserDeletedAck)
+	err := c.cc.Invoke(ctx, Federation_UserDeleted_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *federationClient) AccountMoved(ctx context.Context, in *AccountMovedReq, opts ...grpc.CallOption) (*AccountMovedAck, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AccountMovedAck)
+	err := c.cc.Invoke(ctx, Federation_AccountMoved_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *federationClient) KeyHistory(ctx context.Context, in *UserLookupReq, opts ...grpc.CallOption) (*KeyHistoryResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KeyHistoryResp)
+	err := c.cc.Invoke(ctx, Federation_KeyHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *federationClient) Join(ctx context.Context, in *JoinReq, opts ...grpc.CallOption) (*JoinResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JoinResp)
+	err := c.cc.Invoke(ctx, Federation_Join_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // FederationServer is the server API for Federation service.
 // All implementations must embed UnimplementedFederationServer
 // for forward compatibility
@@ -78,6 +128,12 @@ type FederationServer interface {
 	Handshake(context.Context, *HandshakeReq) (*HandshakeAck, error)
 	Relay(context.Context, *RelayPayload) (*RelayAck, error)
 	UserLookup(context.Context, *UserLookupReq) (*UserLookupResp, error)
+	UserDeleted(context.Context, *UserDeletedReq) (*UserDeletedAck, error)
+	AccountMoved(context.Context, *AccountMovedReq) (*AccountMovedAck, error)
+	KeyHistory(context.Context, *UserLookupReq) (*KeyHistoryResp, error)
+	// Join is the only call a server whose certificate we do not trust yet may
+	// make. It redeems an invitation and exchanges reciprocal peer details.
+	Join(context.Context, *JoinReq) (*JoinResp, error)
 	mustEmbedUnimplementedFederationServer()
 }
 
@@ -94,6 +150,18 @@ func (UnimplementedFederationServer) Relay(context.Context, *RelayPayload) (*Rel
 func (UnimplementedFederationServer) UserLookup(context.Context, *UserLookupReq) (*UserLookupResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UserLookup not implemented")
 }
+func (UnimplementedFederationServer) UserDeleted(context.Context, *UserDeletedReq) (*UserDeletedAck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UserDeleted not implemented")
+}
+func (UnimplementedFederationServer) AccountMoved(context.Context, *AccountMovedReq) (*AccountMovedAck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AccountMoved not implemented")
+}
+func (UnimplementedFederationServer) KeyHistory(context.Context, *UserLookupReq) (*KeyHistoryResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KeyHistory not implemented")
+}
+func (UnimplementedFederationServer) Join(context.Context, *JoinReq) (*JoinResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Join not implemented")
+}
 func (UnimplementedFederationServer) mustEmbedUnimplementedFederationServer() {}
 
 // UnsafeFederationServer may be embedded to opt out of forward compatibility for this service.
@@ -161,6 +229,78 @@ func _Federation_UserLookup_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Federation_UserDeleted_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserDeletedReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FederationServer).UserDeleted(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Federation_UserDeleted_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FederationServer).UserDeleted(ctx, req.(*UserDeletedReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Federation_AccountMoved_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountMovedReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FederationServer).AccountMoved(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Federation_AccountMoved_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FederationServer).AccountMoved(ctx, req.(*AccountMovedReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Federation_KeyHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserLookupReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FederationServer).KeyHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Federation_KeyHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FederationServer).KeyHistory(ctx, req.(*UserLookupReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Federation_Join_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JoinReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FederationServer).Join(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Federation_Join_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FederationServer).Join(ctx, req.(*JoinReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Federation_ServiceDesc is the grpc.ServiceDesc for Federation service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -180,6 +320,22 @@ var Federation_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UserLookup",
 			Handler:    _Federation_UserLookup_Handler,
 		},
+		{
+			MethodName: "UserDeleted",
+			Handler:    _Federation_UserDeleted_Handler,
+		},
+		{
+			MethodName: "AccountMoved",
+			Handler:    _Federation_AccountMoved_Handler,
+		},
+		{
+			MethodName: "KeyHistory",
+			Handler:    _Federation_KeyHistory_Handler,
+		},
+		{
+			MethodName: "Join",
+			Handler:    _Federation_Join_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "federation/federation.proto",
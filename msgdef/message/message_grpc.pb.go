@@ -29,6 +29,12 @@ const (
 	Strike_StatusStream_FullMethodName  = "/message.Strike/StatusStream"
 	Strike_OnlineUsers_FullMethodName   = "/message.Strike/OnlineUsers"
 	Strike_PollServer_FullMethodName    = "/message.Strike/PollServer"
+	Strike_DeleteAccount_FullMethodName = "/message.Strike/DeleteAccount"
+	Strike_RenameAccount_FullMethodName = "/message.Strike/RenameAccount"
+	Strike_MoveAccount_FullMethodName   = "/message.Strike/MoveAccount"
+	Strike_RotateKeys_FullMethodName    = "/message.Strike/RotateKeys"
+	Strike_KeyHistory_FullMethodName    = "/message.Strike/KeyHistory"
+	Strike_UpdateBlocks_FullMethodName  = "/message.Strike/UpdateBlocks"
 )
 
 // StrikeClient is the client API for Strike service.
@@ -44,6 +50,12 @@ type StrikeClient interface {
 	StatusStream(ctx context.Context, in *common.UserInfo, opts ...grpc.CallOption) (Strike_StatusStreamClient, error)
 	OnlineUsers(ctx context.Context, in *common.UserInfo, opts ...grpc.CallOption) (*common.Users, error)
 	PollServer(ctx context.Context, in *common.UserInfo, opts ...grpc.CallOption) (*ServerInfo, error)
+	DeleteAccount(ctx context.Context, in *DeleteAccountRequest, opts ...grpc.CallOption) (*ServerResponse, error)
+	RenameAccount(ctx context.Context, in *MoveAccountRequest, opts ...grpc.CallOption) (*ServerResponse, error)
+	MoveAccount(ctx context.Context, in *MoveAccountRequest, opts ...grpc.CallOption) (*ServerResponse, error)
+	RotateKeys(ctx context.Context, in *RotateKeysRequest, opts ...grpc.CallOption) (*ServerResponse, error)
+	KeyHistory(ctx context.Context, in *common.UserAddress, opts ...grpc.CallOption) (*KeyHistoryResponse, error)
+	UpdateBlocks(ctx context.Context, in *common.BlockList, opts ...grpc.CallOption) (*ServerResponse, error)
 }
 
 type strikeClient struct {
@@ -190,6 +202,66 @@ func (c *strikeClient) PollServer(ctx context.Context, in *common.UserInfo, opts
 	return out, nil
 }
 
+func (c *strikeClient) DeleteAccount(ctx context.Context, in *DeleteAccountRequest, opts ...grpc.CallOption) (*ServerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServerResponse)
+	err := c.cc.Invoke(ctx, Strike_DeleteAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *strikeClient) RenameAccount(ctx context.Context, in *MoveAccountRequest, opts ...grpc.CallOption) (*ServerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServerResponse)
+	err := c.cc.Invoke(ctx, Strike_RenameAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *strikeClient) MoveAccount(ctx context.Context, in *MoveAccountRequest, opts ...grpc.CallOption) (*ServerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServerResponse)
+	err := c.cc.Invoke(ctx, Strike_MoveAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *strikeClient) RotateKeys(ctx context.Context, in *RotateKeysRequest, opts ...grpc.CallOption) (*ServerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServerResponse)
+	err := c.cc.Invoke(ctx, Strike_RotateKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *strikeClient) KeyHistory(ctx context.Context, in *common.UserAddress, opts ...grpc.CallOption) (*KeyHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KeyHistoryResponse)
+	err := c.cc.Invoke(ctx, Strike_KeyHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *strikeClient) UpdateBlocks(ctx context.Context, in *common.BlockList, opts ...grpc.CallOption) (*ServerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServerResponse)
+	err := c.cc.Invoke(ctx, Strike_UpdateBlocks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StrikeServer is the server API for Strike service.
 // All implementations must embed UnimplementedStrikeServer
 // for forward compatibility
@@ -203,6 +275,12 @@ type StrikeServer interface {
 	StatusStream(*common.UserInfo, Strike_StatusStreamServer) error
 	OnlineUsers(context.Context, *common.UserInfo) (*common.Users, error)
 	PollServer(context.Context, *common.UserInfo) (*ServerInfo, error)
+	DeleteAccount(context.Context, *DeleteAccountRequest) (*ServerResponse, error)
+	RenameAccount(context.Context, *MoveAccountRequest) (*ServerResponse, error)
+	MoveAccount(context.Context, *MoveAccountRequest) (*ServerResponse, error)
+	RotateKeys(context.Context, *RotateKeysRequest) (*ServerResponse, error)
+	KeyHistory(context.Context, *common.UserAddress) (*KeyHistoryResponse, error)
+	UpdateBlocks(context.Context, *common.BlockList) (*ServerResponse, error)
 	mustEmbedUnimplementedStrikeServer()
 }
 
@@ -237,6 +315,24 @@ func (UnimplementedStrikeServer) OnlineUsers(context.Context, *common.UserInfo)
 func (UnimplementedStrikeServer) PollServer(context.Context, *common.UserInfo) (*ServerInfo, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PollServer not implemented")
 }
+func (UnimplementedStrikeServer) DeleteAccount(context.Context, *DeleteAccountRequest) (*ServerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAccount not implemented")
+}
+func (UnimplementedStrikeServer) RenameAccount(context.Context, *MoveAccountRequest) (*ServerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenameAccount not implemented")
+}
+func (UnimplementedStrikeServer) MoveAccount(context.Context, *MoveAccountRequest) (*ServerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveAccount not implemented")
+}
+func (UnimplementedStrikeServer) RotateKeys(context.Context, *RotateKeysRequest) (*ServerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateKeys not implemented")
+}
+func (UnimplementedStrikeServer) KeyHistory(context.Context, *common.UserAddress) (*KeyHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KeyHistory not implemented")
+}
+func (UnimplementedStrikeServer) UpdateBlocks(context.Context, *common.BlockList) (*ServerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateBlocks not implemented")
+}
 func (UnimplementedStrikeServer) mustEmbedUnimplementedStrikeServer() {}
 
 // UnsafeStrikeServer may be embedded to opt out of forward compatibility for this service.
@@ -418,6 +514,114 @@ func _Strike_PollServer_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Strike_DeleteAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StrikeServer).DeleteAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Strike_DeleteAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StrikeServer).DeleteAccount(ctx, req.(*DeleteAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Strike_RenameAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StrikeServer).RenameAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Strike_RenameAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StrikeServer).RenameAccount(ctx, req.(*MoveAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Strike_MoveAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StrikeServer).MoveAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Strike_MoveAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StrikeServer).MoveAccount(ctx, req.(*MoveAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Strike_RotateKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StrikeServer).RotateKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Strike_RotateKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StrikeServer).RotateKeys(ctx, req.(*RotateKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Strike_KeyHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(common.UserAddress)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StrikeServer).KeyHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Strike_KeyHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StrikeServer).KeyHistory(ctx, req.(*common.UserAddress))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Strike_UpdateBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(common.BlockList)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StrikeServer).UpdateBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Strike_UpdateBlocks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StrikeServer).UpdateBlocks(ctx, req.(*common.BlockList))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Strike_ServiceDesc is the grpc.ServiceDesc for Strike service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -453,6 +657,30 @@ var Strike_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PollServer",
 			Handler:    _Strike_PollServer_Handler,
 		},
+		{
+			MethodName: "DeleteAccount",
+			Handler:    _Strike_DeleteAccount_Handler,
+		},
+		{
+			MethodName: "RenameAccount",
+			Handler:    _Strike_RenameAccount_Handler,
+		},
+		{
+			MethodName: "MoveAccount",
+			Handler:    _Strike_MoveAccount_Handler,
+		},
+		{
+			MethodName: "RotateKeys",
+			Handler:    _Strike_RotateKeys_Handler,
+		},
+		{
+			MethodName: "KeyHistory",
+			Handler:    _Strike_KeyHistory_Handler,
+		},
+		{
+			MethodName: "UpdateBlocks",
+			Handler:    _Strike_UpdateBlocks_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{